@@ -4,9 +4,15 @@
 package client
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"strings"
 	"time"
 
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
 	"github.com/ory/x/stringsx"
 
 	"github.com/gobuffalo/pop/v6"
@@ -24,6 +30,29 @@ var (
 	_ fosite.Client              = (*Client)(nil)
 )
 
+const (
+	// BackchannelTokenDeliveryModePoll lets the client poll the token endpoint for the result of a backchannel
+	// authentication request.
+	BackchannelTokenDeliveryModePoll = "poll"
+
+	// BackchannelTokenDeliveryModePing notifies the client once the backchannel authentication request was handled,
+	// after which the client fetches the tokens from the token endpoint.
+	BackchannelTokenDeliveryModePing = "ping"
+
+	// ResponseModeJWT returns a JWT secured authorization response (JARM) in the query for the "code" response
+	// type and in the fragment otherwise.
+	ResponseModeJWT fosite.ResponseModeType = "jwt"
+
+	// ResponseModeQueryJWT returns a JWT secured authorization response in the query of the redirect URL.
+	ResponseModeQueryJWT fosite.ResponseModeType = "query.jwt"
+
+	// ResponseModeFragmentJWT returns a JWT secured authorization response in the fragment of the redirect URL.
+	ResponseModeFragmentJWT fosite.ResponseModeType = "fragment.jwt"
+
+	// ResponseModeFormPostJWT returns a JWT secured authorization response using an auto-submitted HTML form.
+	ResponseModeFormPostJWT fosite.ResponseModeType = "form_post.jwt"
+)
+
 // OAuth 2.0 Client
 //
 // OAuth 2.0 Clients are used to perform OAuth 2.0 and OpenID Connect flows. Usually, OAuth 2.0 clients are
@@ -71,7 +100,8 @@ type Client struct {
 	// - Authorization Code Grant: `authorization_code`
 	// - OpenID Connect Implicit Grant (deprecated!): `implicit`
 	// - Refresh Token Grant: `refresh_token`
-	// - OAuth 2.0 Token Exchange: `urn:ietf:params:oauth:grant-type:jwt-bearer`
+	// - OAuth 2.0 JWT Bearer Grant: `urn:ietf:params:oauth:grant-type:jwt-bearer`
+	// - OAuth 2.0 Token Exchange: `urn:ietf:params:oauth:grant-type:token-exchange`
 	GrantTypes sqlxx.StringSliceJSONFormat `json:"grant_types" db:"grant_types"`
 
 	// OAuth 2.0 Client Response Types
@@ -153,7 +183,9 @@ type Client struct {
 
 	// OAuth 2.0 Client Secret Expires At
 	//
-	// The field is currently not supported and its value is always 0.
+	// The time at which the client secret will expire, as seconds since the Unix epoch, or 0 if it will not
+	// expire. It is set by the server when the secret is created or replaced, according to the configured
+	// default secret lifespan (`ttl.client_secret`). The client can not authenticate with an expired secret.
 	SecretExpiresAt int `json:"client_secret_expires_at" db:"client_secret_expires_at"`
 
 	// OpenID Connect Subject Type
@@ -199,6 +231,8 @@ type Client struct {
 	// - `client_secret_basic`: Send `client_id` and `client_secret` as `application/x-www-form-urlencoded` encoded in the HTTP Authorization header.
 	// - `private_key_jwt`: Use JSON Web Tokens to authenticate the client.
 	// - `none`: Used for public clients (native apps, mobile apps) which can not have secrets.
+	// - `tls_client_auth`: Use a certificate issued by a trusted certificate authority (RFC 8705).
+	// - `self_signed_tls_client_auth`: Use a self-signed certificate whose key is registered in `jwks` or `jwks_uri` (RFC 8705).
 	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method,omitempty" db:"token_endpoint_auth_method" faker:"len=25"`
 
 	// OAuth 2.0 Token Endpoint Signing Algorithm
@@ -227,6 +261,14 @@ type Client struct {
 	// as a UTF-8 encoded JSON object using the application/json content-type.
 	UserinfoSignedResponseAlg string `json:"userinfo_signed_response_alg,omitempty" db:"userinfo_signed_response_alg" faker:"len=10"`
 
+	// OpenID Connect Default ACR Values
+	//
+	// Default requested Authentication Context Class Reference values. A remembered login session is only used
+	// without asking the End-User to log in again if it was authenticated with one of these values, unless the
+	// Authorization Request specifies an essential `acr` claim. The `acr_values` parameter is only a hint and does not
+	// override them.
+	DefaultACRValues sqlxx.StringSliceJSONFormat `json:"default_acr_values,omitempty" db:"default_acr_values"`
+
 	// OAuth 2.0 Client Creation Date
 	//
 	// CreatedAt returns the timestamp of the client's creation.
@@ -237,6 +279,18 @@ type Client struct {
 	// UpdatedAt returns the timestamp of the last update.
 	UpdatedAt time.Time `json:"updated_at,omitempty" db:"updated_at"`
 
+	// OAuth 2.0 Client Deletion Date
+	//
+	// DeletedAt is set while the client is soft-deleted. Soft-deleted clients can not authenticate or be used in
+	// flows. Unless they are restored, they are purged by the janitor once the retention period has passed.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at" faker:"-"`
+
+	// OAuth 2.0 Client Disabled
+	//
+	// Disabled clients can not authenticate, be used in flows, or have their tokens introspected or refreshed. Unlike
+	// deleting a client, disabling it keeps its secret, tokens and consent sessions.
+	Disabled bool `json:"disabled,omitempty" db:"disabled" faker:"-"`
+
 	// OpenID Connect Front-Channel Logout URI
 	//
 	// RP URL that will cause the RP to log itself out when rendered in an iframe by the OP. An iss (issuer) query
@@ -270,6 +324,89 @@ type Client struct {
 	// If omitted, the default value is false.
 	BackChannelLogoutSessionRequired bool `json:"backchannel_logout_session_required,omitempty" db:"backchannel_logout_session_required"`
 
+	// OAuth 2.0 Pushed Authorization Requests Required
+	//
+	// Boolean value specifying whether the client must use a Pushed Authorization Request (RFC 9126) to start an
+	// authorization request. If true, the authorization endpoint only accepts a request_uri obtained from the
+	// pushed authorization request endpoint. If omitted, the default value is false.
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests,omitempty" db:"require_pushed_authorization_requests"`
+
+	// OAuth 2.0 Token Exchange Policy
+	//
+	// Controls which tokens this client may exchange using the OAuth 2.0 Token Exchange grant (RFC 8693).
+	TokenExchangePolicy *TokenExchangePolicy `json:"token_exchange_policy,omitempty" db:"token_exchange_policy"`
+
+	// OAuth 2.0 Mutual-TLS Client Certificate Subject DN
+	//
+	// The expected subject distinguished name of the certificate the client uses for `tls_client_auth`
+	// authentication, in the string representation of RFC 4514.
+	TLSClientAuthSubjectDN string `json:"tls_client_auth_subject_dn,omitempty" db:"tls_client_auth_subject_dn"`
+
+	// OAuth 2.0 Mutual-TLS Client Certificate SAN DNS Name
+	//
+	// The expected dNSName SAN entry of the certificate the client uses for `tls_client_auth` authentication.
+	TLSClientAuthSANDNS string `json:"tls_client_auth_san_dns,omitempty" db:"tls_client_auth_san_dns"`
+
+	// OAuth 2.0 Mutual-TLS Client Certificate SAN URI
+	//
+	// The expected uniformResourceIdentifier SAN entry of the certificate the client uses for `tls_client_auth`
+	// authentication.
+	TLSClientAuthSANURI string `json:"tls_client_auth_san_uri,omitempty" db:"tls_client_auth_san_uri"`
+
+	// OAuth 2.0 Mutual-TLS Client Certificate SAN IP Address
+	//
+	// The expected iPAddress SAN entry of the certificate the client uses for `tls_client_auth` authentication.
+	TLSClientAuthSANIP string `json:"tls_client_auth_san_ip,omitempty" db:"tls_client_auth_san_ip"`
+
+	// OAuth 2.0 Mutual-TLS Client Certificate SAN Email Address
+	//
+	// The expected rfc822Name SAN entry of the certificate the client uses for `tls_client_auth` authentication.
+	TLSClientAuthSANEmail string `json:"tls_client_auth_san_email,omitempty" db:"tls_client_auth_san_email"`
+
+	// OAuth 2.0 Mutual-TLS Certificate-Bound Access Tokens
+	//
+	// Boolean value indicating whether access tokens issued to this client are bound to the client certificate
+	// presented at the token endpoint (RFC 8705). If omitted, the default value is false.
+	TLSClientCertificateBoundAccessTokens bool `json:"tls_client_certificate_bound_access_tokens,omitempty" db:"tls_client_certificate_bound_access_tokens"`
+
+	// OpenID Connect CIBA Token Delivery Mode
+	//
+	// The token delivery mode used by the client for Client-Initiated Backchannel Authentication. One of `poll`
+	// or `ping`. If omitted, the default value is `poll`.
+	BackchannelTokenDeliveryMode string `json:"backchannel_token_delivery_mode,omitempty" db:"backchannel_token_delivery_mode"`
+
+	// OpenID Connect CIBA Client Notification Endpoint
+	//
+	// The endpoint the authorization server notifies once a backchannel authentication request was handled.
+	// Required if the token delivery mode is `ping`.
+	BackchannelClientNotificationEndpoint string `json:"backchannel_client_notification_endpoint,omitempty" db:"backchannel_client_notification_endpoint"`
+
+	// JARM Authorization Signed Response Algorithm
+	//
+	// The JWS algorithm required for signing authorization responses using a JWT response mode (JARM). Must
+	// match the algorithm of the OpenID Connect signing key if set. If omitted, responses are signed using the
+	// algorithm of that key.
+	AuthorizationSignedResponseAlg string `json:"authorization_signed_response_alg,omitempty" db:"authorization_signed_response_alg"`
+
+	// JARM Authorization Encrypted Response Algorithm
+	//
+	// The JWE key management algorithm required for encrypting authorization responses using a JWT response
+	// mode. If omitted, responses are signed but not encrypted. Requires `jwks` or `jwks_uri` to be set.
+	AuthorizationEncryptedResponseAlg string `json:"authorization_encrypted_response_alg,omitempty" db:"authorization_encrypted_response_alg"`
+
+	// JARM Authorization Encrypted Response Encryption
+	//
+	// The JWE content encryption algorithm required for encrypting authorization responses. Only valid in
+	// combination with `authorization_encrypted_response_alg`. If omitted, the default value is `A128CBC-HS256`.
+	AuthorizationEncryptedResponseEnc string `json:"authorization_encrypted_response_enc,omitempty" db:"authorization_encrypted_response_enc"`
+
+	// OAuth 2.0 Authorization Details Types
+	//
+	// An allow-list of the authorization details types (RFC 9396) this client may request. Requests containing
+	// authorization details of any other type are rejected. If omitted, the client can not request authorization
+	// details.
+	AuthorizationDetailsTypes sqlxx.StringSliceJSONFormat `json:"authorization_details_types,omitempty" db:"authorization_details_types"`
+
 	// OAuth 2.0 Client Metadata
 	//
 	// Use this field to story arbitrary data about the OAuth 2.0 Client. Can not be modified using OpenID Connect Dynamic Client Registration protocol.
@@ -291,6 +428,22 @@ type Client struct {
 	// RegistrationClientURI is the URL used to update, get, or delete the OAuth2 Client.
 	RegistrationClientURI string `json:"registration_client_uri,omitempty" db:"-"`
 
+	// OpenID Connect Dynamic Client Registration Software Statement
+	//
+	// A JWT containing client metadata, signed by an issuer trusted through the trusted JWT grant issuer API.
+	// Its claims take precedence over the other metadata of the registration request. Only used when
+	// registering a client dynamically.
+	SoftwareStatement string `json:"software_statement,omitempty" db:"-" faker:"-"`
+
+	// OAuth 2.0 Client Template
+	//
+	// The name of the client template this client is instantiated from. Grant types, response types, the token
+	// endpoint authentication method, lifespans and metadata keys which are not set are taken from the template.
+	Template string `json:"template,omitempty" db:"template" faker:"-"`
+
+	// RotatedSecrets holds the hashes of previous client secrets which remain valid until they expire.
+	RotatedSecrets RotatedSecrets `json:"-" db:"rotated_secrets" faker:"-"`
+
 	Lifespans
 }
 
@@ -357,6 +510,75 @@ type Lifespans struct {
 	RefreshTokenGrantRefreshTokenLifespan x.NullDuration `json:"refresh_token_grant_refresh_token_lifespan,omitempty" db:"refresh_token_grant_refresh_token_lifespan"`
 }
 
+// OAuth 2.0 Client Token Exchange Policy
+//
+// Tokens issued to the client itself, and Ory Hydra access tokens whose audience contains the client's ID,
+// can always be exchanged. This policy allows additional subject and actor tokens.
+//
+// swagger:model oAuth2ClientTokenExchangePolicy
+type TokenExchangePolicy struct {
+	// Allowed Subject Token Clients
+	//
+	// The IDs of OAuth 2.0 Clients whose access tokens may be exchanged by this client.
+	AllowedSubjectClients []string `json:"allowed_subject_clients,omitempty"`
+
+	// Allowed Subject Token Issuers
+	//
+	// The issuers of JSON Web Tokens which may be exchanged by this client. The issuer must be trusted
+	// using the trusted OAuth2 JWT Bearer Grant Type issuers API.
+	AllowedSubjectIssuers []string `json:"allowed_subject_issuers,omitempty"`
+
+	// Allowed Actor Token Clients
+	//
+	// The IDs of OAuth 2.0 Clients whose access tokens may be used as actor tokens by this client. Access tokens
+	// issued to the client itself can always be used as actor tokens.
+	AllowedActorClients []string `json:"allowed_actor_clients,omitempty"`
+}
+
+func (p *TokenExchangePolicy) Scan(value interface{}) error {
+	var v []byte
+	switch t := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		v = t
+	case string:
+		v = []byte(t)
+	default:
+		return errors.Errorf("unable to scan type %T into TokenExchangePolicy", value)
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	return errorsx.WithStack(json.Unmarshal(v, p))
+}
+
+func (p *TokenExchangePolicy) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	value, err := json.Marshal(p)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	return string(value), nil
+}
+
+// AllowsSubjectClient returns true if access tokens issued to the given client may be exchanged.
+func (p *TokenExchangePolicy) AllowsSubjectClient(id string) bool {
+	return p != nil && stringslice.Has(p.AllowedSubjectClients, id)
+}
+
+// AllowsSubjectIssuer returns true if JSON Web Tokens of the given issuer may be exchanged.
+func (p *TokenExchangePolicy) AllowsSubjectIssuer(issuer string) bool {
+	return p != nil && stringslice.Has(p.AllowedSubjectIssuers, issuer)
+}
+
+// AllowsActorClient returns true if access tokens issued to the given client may be used as actor tokens.
+func (p *TokenExchangePolicy) AllowsActorClient(id string) bool {
+	return p != nil && stringslice.Has(p.AllowedActorClients, id)
+}
+
 func (Client) TableName() string {
 	return "hydra_client"
 }
@@ -378,6 +600,10 @@ func (c *Client) BeforeSave(_ *pop.Connection) error {
 		c.AllowedCORSOrigins = sqlxx.StringSliceJSONFormat{}
 	}
 
+	if c.AuthorizationDetailsTypes == nil {
+		c.AuthorizationDetailsTypes = sqlxx.StringSliceJSONFormat{}
+	}
+
 	if c.CreatedAt.IsZero() {
 		c.CreatedAt = time.Now()
 	}
@@ -403,6 +629,33 @@ func (c *Client) GetHashedSecret() []byte {
 	return []byte(c.Secret)
 }
 
+// PageToken returns the token of the page which starts after this client.
+func (c Client) PageToken() string {
+	return x.EncodePageToken(c.ID.String())
+}
+
+// SetDefaultSecretExpiresAt sets the expiry of a new client secret to the given lifespan, unless the secret
+// already expires or the client is public. A lifespan of zero means that the secret does not expire.
+func (c *Client) SetDefaultSecretExpiresAt(now time.Time, lifespan time.Duration) {
+	if c.SecretExpiresAt == 0 && lifespan > 0 && !c.IsPublic() {
+		c.SecretExpiresAt = int(now.Add(lifespan).Unix())
+	}
+}
+
+// IsSecretExpired returns true if the client secret expired at the given time.
+func (c *Client) IsSecretExpired(now time.Time) bool {
+	return c.SecretExpiresAt > 0 && !now.Before(time.Unix(int64(c.SecretExpiresAt), 0))
+}
+
+// GetRotatedHashes returns the hashes of rotated client secrets which have not yet expired.
+func (c *Client) GetRotatedHashes() [][]byte {
+	var hashes [][]byte
+	for _, secret := range c.RotatedSecrets.Active(time.Now()) {
+		hashes = append(hashes, []byte(secret.Hash))
+	}
+	return hashes
+}
+
 func (c *Client) GetScopes() fosite.Arguments {
 	return fosite.Arguments(strings.Fields(c.Scope))
 }
@@ -441,6 +694,10 @@ func (c *Client) GetResponseModes() []fosite.ResponseModeType {
 		fosite.ResponseModeFormPost,
 		fosite.ResponseModeQuery,
 		fosite.ResponseModeFragment,
+		ResponseModeJWT,
+		ResponseModeQueryJWT,
+		ResponseModeFragmentJWT,
+		ResponseModeFormPostJWT,
 	}
 }
 
@@ -486,6 +743,7 @@ func (c *Client) GetRequestURIs() []string {
 }
 
 var _ fosite.ClientWithCustomTokenLifespans = &Client{}
+var _ fosite.ClientWithSecretRotation = &Client{}
 
 func (c *Client) GetEffectiveLifespan(gt fosite.GrantType, tt fosite.TokenType, fallback time.Duration) time.Duration {
 	var cl *time.Duration
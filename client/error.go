@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/ory/fosite"
+	"github.com/ory/herodot"
 )
 
 var ErrInvalidClientMetadata = &fosite.RFC6749Error{
@@ -20,3 +21,19 @@ var ErrInvalidRedirectURI = &fosite.RFC6749Error{
 	ErrorField:       "invalid_redirect_uri",
 	CodeField:        http.StatusBadRequest,
 }
+
+var ErrInvalidSoftwareStatement = &fosite.RFC6749Error{
+	DescriptionField: "The software statement presented is invalid.",
+	ErrorField:       "invalid_software_statement",
+	CodeField:        http.StatusBadRequest,
+}
+
+var ErrUnapprovedSoftwareStatement = &fosite.RFC6749Error{
+	DescriptionField: "The software statement presented is not approved for use by this authorization server.",
+	ErrorField:       "unapproved_software_statement",
+	CodeField:        http.StatusBadRequest,
+}
+
+var ErrClientTemplateInUse = herodot.ErrConflict.WithReason("The client template can not be deleted because OAuth 2.0 Clients, including soft-deleted ones, still reference it.")
+
+var ErrClientDisabled = fosite.ErrInvalidClient.WithHint("The OAuth 2.0 Client is disabled.")
@@ -9,10 +9,11 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/ory/x/pagination/tokenpagination"
+	"github.com/ory/x/pagination/keysetpagination"
 
 	"github.com/ory/x/httprouterx"
 
@@ -21,6 +22,7 @@ import (
 	"github.com/ory/x/uuidx"
 
 	"github.com/ory/x/jsonx"
+	"github.com/ory/x/sqlxx"
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/fosite"
@@ -29,6 +31,7 @@ import (
 
 	"github.com/ory/herodot"
 	"github.com/ory/hydra/x"
+	"github.com/ory/hydra/x/events"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -39,8 +42,10 @@ type Handler struct {
 }
 
 const (
-	ClientsHandlerPath    = "/clients"
-	DynClientsHandlerPath = "/oauth2/register"
+	ClientsHandlerPath             = "/clients"
+	DynClientsHandlerPath          = "/oauth2/register"
+	InitialAccessTokensHandlerPath = "/oauth2/initial-access-tokens"
+	ClientTemplatesHandlerPath     = "/client-templates"
 )
 
 func NewHandler(r InternalRegistry) *Handler {
@@ -57,6 +62,20 @@ func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin, public *httprouterx.
 	admin.PATCH(ClientsHandlerPath+"/:id", h.patchOAuth2Client)
 	admin.DELETE(ClientsHandlerPath+"/:id", h.deleteOAuth2Client)
 	admin.PUT(ClientsHandlerPath+"/:id/lifespans", h.setOAuth2ClientLifespans)
+	admin.POST(ClientsHandlerPath+"/:id/secret/rotate", h.rotateOAuth2ClientSecret)
+	admin.POST(ClientsHandlerPath+"/:id/restore", h.restoreOAuth2Client)
+	admin.POST(ClientsHandlerPath+"/:id/disable", h.disableOAuth2Client)
+	admin.POST(ClientsHandlerPath+"/:id/enable", h.enableOAuth2Client)
+
+	admin.GET(InitialAccessTokensHandlerPath, h.listOAuth2InitialAccessTokens)
+	admin.POST(InitialAccessTokensHandlerPath, h.createOAuth2InitialAccessToken)
+	admin.DELETE(InitialAccessTokensHandlerPath+"/:id", h.deleteOAuth2InitialAccessToken)
+
+	admin.GET(ClientTemplatesHandlerPath, h.listOAuth2ClientTemplates)
+	admin.POST(ClientTemplatesHandlerPath, h.createOAuth2ClientTemplate)
+	admin.GET(ClientTemplatesHandlerPath+"/:name", h.getOAuth2ClientTemplate)
+	admin.PUT(ClientTemplatesHandlerPath+"/:name", h.setOAuth2ClientTemplate)
+	admin.DELETE(ClientTemplatesHandlerPath+"/:name", h.deleteOAuth2ClientTemplate)
 
 	public.POST(DynClientsHandlerPath, h.createOidcDynamicClient)
 	public.GET(DynClientsHandlerPath+"/:id", h.getOidcDynamicClient)
@@ -82,6 +101,8 @@ type createOAuth2Client struct {
 // Create a new OAuth 2.0 client. If you pass `client_secret` the secret is used, otherwise a random secret
 // is generated. The secret is echoed in the response. It is not possible to retrieve it later on.
 //
+// If you pass `template`, fields which are not set are taken from the client template with that name.
+//
 //	Consumes:
 //	- application/json
 //
@@ -131,6 +152,17 @@ type createOidcDynamicClient struct {
 // The `client_secret` will be returned in the response and you will not be able to retrieve it later on.
 // Write the secret down and keep it somewhere safe.
 //
+// If `oidc.dynamic_client_registration.require_initial_access_token` is enabled, the request must carry an initial
+// access token created with `createOAuth2InitialAccessToken` as bearer token.
+//
+// The request may contain an RFC 7591 `software_statement`, a JWT signed by an issuer trusted through the trusted
+// JWT grant issuer API. Its claims take precedence over the other metadata of the request. The scope of the trust
+// relationship limits the scope of the client, and `oidc.dynamic_client_registration.software_statement.allowed_grant_types`
+// limits its grant types.
+//
+// The request may name a client template in `template`, whose values are used for the fields the request does not
+// set. The template of a client can not be changed using `setOidcDynamicClient`.
+//
 //	Consumes:
 //	- application/json
 //
@@ -142,13 +174,52 @@ type createOidcDynamicClient struct {
 //	Responses:
 //	  201: oAuth2Client
 //	  400: errorOAuth2BadRequest
+//	  401: errorOAuth2Default
 //	  default: errorOAuth2Default
 func (h *Handler) createOidcDynamicClient(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	if err := h.requireDynamicAuth(r); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
-	c, err := h.CreateClient(r, h.r.ClientValidator().ValidateDynamicRegistration, true)
+
+	var initialAccessTokenSignature string
+	if h.r.Config().DynamicRegistrationRequiresInitialAccessToken(r.Context()) {
+		signature, err := h.initialAccessTokenSignature(r)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		initialAccessTokenSignature = signature
+	}
+
+	c, err := h.CreateClient(r, func(ctx context.Context, c *Client) error {
+		var allowedScope []string
+		if c.SoftwareStatement != "" {
+			scope, err := h.r.ClientValidator().ApplySoftwareStatement(ctx, c)
+			if err != nil {
+				return err
+			}
+			allowedScope = scope
+		}
+
+		if err := h.r.ClientValidator().ValidateDynamicRegistration(ctx, c); err != nil {
+			return err
+		}
+
+		if c.SoftwareStatement != "" {
+			if err := h.r.ClientValidator().ValidateSoftwareStatementPolicy(ctx, c, allowedScope); err != nil {
+				return err
+			}
+		}
+
+		// The token is only used up once the registration is known to be valid.
+		if initialAccessTokenSignature != "" {
+			if err := h.r.ClientManager().UseInitialAccessToken(ctx, initialAccessTokenSignature); err != nil {
+				return errorsx.WithStack(herodot.ErrUnauthorized.WithReason("The initial access token is invalid, expired, or has been used up.").WithDebug(err.Error()))
+			}
+		}
+		return nil
+	}, true)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
 		return
@@ -167,6 +238,11 @@ func (h *Handler) CreateClient(r *http.Request, validator func(context.Context,
 		if c.Secret != "" {
 			return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("It is not allowed to choose your own OAuth2 Client secret."))
 		}
+
+		// The token exchange policy can only be set using the admin API, and clients are only disabled using the
+		// disable endpoint.
+		c.TokenExchangePolicy = nil
+		c.Disabled = false
 	}
 
 	if len(c.LegacyClientID) > 0 {
@@ -176,6 +252,9 @@ func (h *Handler) CreateClient(r *http.Request, validator func(context.Context,
 	c.ID = uuidx.NewV4()
 	c.LegacyClientID = c.ID.String()
 
+	// Clients are only soft-deleted using the delete endpoint.
+	c.DeletedAt = nil
+
 	if len(c.Secret) == 0 {
 		secretb, err := x.GenerateSecret(26)
 		if err != nil {
@@ -204,6 +283,7 @@ func (h *Handler) CreateClient(r *http.Request, validator func(context.Context,
 	if err := h.r.ClientManager().CreateClient(r.Context(), &c); err != nil {
 		return nil, err
 	}
+	h.r.PurgeClientCaches(r.Context())
 	c.Secret = ""
 	if !c.IsPublic() {
 		c.Secret = secret
@@ -283,6 +363,7 @@ func (h *Handler) updateClient(ctx context.Context, c *Client, validator func(co
 	if err := h.r.ClientManager().UpdateClient(ctx, c); err != nil {
 		return err
 	}
+	h.r.PurgeClientCaches(ctx)
 	c.Secret = secret
 	return nil
 }
@@ -372,6 +453,12 @@ func (h *Handler) setOidcDynamicClient(w http.ResponseWriter, r *http.Request, p
 	c.RegistrationAccessTokenSignature = signature
 
 	c.LegacyClientID = client.GetID()
+	if cl, ok := client.(*Client); ok {
+		// Dynamic clients can not escape the template they were registered with, nor change their token exchange
+		// policy.
+		c.Template = cl.Template
+		c.TokenExchangePolicy = cl.TokenExchangePolicy
+	}
 	if err := h.updateClient(r.Context(), &c, h.r.ClientValidator().ValidateDynamicRegistration); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
@@ -461,7 +548,7 @@ func (h *Handler) patchOAuth2Client(w http.ResponseWriter, r *http.Request, ps h
 //
 // swagger:response listOAuth2Clients
 type listOAuth2ClientsResponse struct {
-	tokenpagination.ResponseHeaders
+	keysetpagination.ResponseHeaders
 
 	// List of OAuth 2.0 Clients
 	//
@@ -473,7 +560,7 @@ type listOAuth2ClientsResponse struct {
 //
 // swagger:parameters listOAuth2Clients
 type listOAuth2ClientsParameters struct {
-	tokenpagination.RequestParameters
+	keysetpagination.RequestParameters
 
 	// The name of the clients to filter by.
 	//
@@ -484,6 +571,141 @@ type listOAuth2ClientsParameters struct {
 	//
 	// in: query
 	Owner string `json:"owner"`
+
+	// Only list clients whose secret expires at or before this time, given as seconds since the Unix epoch.
+	// Clients whose secret does not expire are not listed.
+	//
+	// in: query
+	SecretExpiresBefore int64 `json:"client_secret_expires_before"`
+
+	// Only list clients which are allowed to use this grant type, for example `client_credentials`.
+	//
+	// in: query
+	GrantType string `json:"grant_type"`
+
+	// Only list clients with a redirect URI on this host, for example `app.example.com` or `localhost:3000`.
+	//
+	// in: query
+	RedirectURIHost string `json:"redirect_uri_host"`
+
+	// Only list clients which are allowed to request this audience.
+	//
+	// in: query
+	Audience string `json:"audience"`
+
+	// Only list clients which use this token endpoint authentication method, for example `private_key_jwt`.
+	//
+	// in: query
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method"`
+
+	// Only list clients created at or after this time.
+	//
+	// in: query
+	// format: date-time
+	CreatedAfter time.Time `json:"created_after"`
+
+	// Only list clients created before this time.
+	//
+	// in: query
+	// format: date-time
+	CreatedBefore time.Time `json:"created_before"`
+
+	// Only list clients updated at or after this time.
+	//
+	// in: query
+	// format: date-time
+	UpdatedAfter time.Time `json:"updated_after"`
+
+	// Only list clients updated before this time.
+	//
+	// in: query
+	// format: date-time
+	UpdatedBefore time.Time `json:"updated_before"`
+
+	// Only list clients whose metadata has a value at a dot-separated JSON path, given as `path=value`.
+	// For example, `tenant.region=eu` matches clients with the metadata `{"tenant":{"region":"eu"}}`.
+	// Values are compared as strings. Can be repeated, in which case all conditions must match.
+	//
+	// in: query
+	Metadata []string `json:"metadata"`
+
+	// If true, only soft-deleted clients are listed. Otherwise, soft-deleted clients are not listed.
+	//
+	// in: query
+	Deleted bool `json:"deleted"`
+
+	// The field to sort the clients by. Clients are returned in no particular order by default.
+	//
+	// in: query
+	// enum: created_at,updated_at,client_name
+	SortBy string `json:"sort_by"`
+
+	// The order to sort the clients in.
+	//
+	// in: query
+	// enum: asc,desc
+	// default: asc
+	SortOrder string `json:"sort_order"`
+}
+
+// parseFilter reads the filters, sort options and pagination of the list clients request.
+func parseFilter(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+	filters := Filter{
+		PageOpts:                x.ParseKeysetPagination(r),
+		Name:                    query.Get("client_name"),
+		Owner:                   query.Get("owner"),
+		GrantType:               query.Get("grant_type"),
+		RedirectURIHost:         query.Get("redirect_uri_host"),
+		Audience:                query.Get("audience"),
+		TokenEndpointAuthMethod: query.Get("token_endpoint_auth_method"),
+		SortBy:                  query.Get("sort_by"),
+		SortOrder:               query.Get("sort_order"),
+	}
+
+	if before := query.Get("client_secret_expires_before"); before != "" {
+		expiresBefore, err := strconv.ParseInt(before, 10, 64)
+		if err != nil {
+			return filters, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to parse query parameter client_secret_expires_before: %s", err))
+		}
+		filters.SecretExpiresBefore = expiresBefore
+	}
+
+	if deleted := query.Get("deleted"); deleted != "" {
+		d, err := strconv.ParseBool(deleted)
+		if err != nil {
+			return filters, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to parse query parameter deleted: %s", err))
+		}
+		filters.Deleted = d
+	}
+
+	for key, target := range map[string]*time.Time{
+		"created_after":  &filters.CreatedAfter,
+		"created_before": &filters.CreatedBefore,
+		"updated_after":  &filters.UpdatedAfter,
+		"updated_before": &filters.UpdatedBefore,
+	} {
+		if value := query.Get(key); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filters, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to parse query parameter %s as an RFC 3339 date: %s", key, err))
+			}
+			*target = t
+		}
+	}
+
+	for _, m := range query["metadata"] {
+		path, value, ok := strings.Cut(m, "=")
+		if !ok || path == "" {
+			return filters, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to parse query parameter metadata '%s', expected the format 'path=value'.", m))
+		}
+		if filters.Metadata == nil {
+			filters.Metadata = map[string]string{}
+		}
+		filters.Metadata[path] = value
+	}
+
+	return filters, nil
 }
 
 // swagger:route GET /admin/clients oAuth2 listOAuth2Clients
@@ -493,6 +715,11 @@ type listOAuth2ClientsParameters struct {
 // This endpoint lists all clients in the database, and never returns client secrets.
 // As a default it lists the first 100 clients.
 //
+// The `grant_type`, `audience`, `redirect_uri_host` and `metadata` filters match within JSON values and can not
+// use an index. They are evaluated against every client which matches the other filters, which is a full table scan
+// if none of the other filters is used. On large installations, combine them with the `owner`, `client_name`,
+// `token_endpoint_auth_method` or date filters.
+//
 //	Consumes:
 //	- application/json
 //
@@ -505,15 +732,13 @@ type listOAuth2ClientsParameters struct {
 //	  200: listOAuth2Clients
 //	  default: errorOAuth2Default
 func (h *Handler) listOAuth2Clients(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	page, itemsPerPage := x.ParsePagination(r)
-	filters := Filter{
-		Limit:  itemsPerPage,
-		Offset: page * itemsPerPage,
-		Name:   r.URL.Query().Get("client_name"),
-		Owner:  r.URL.Query().Get("owner"),
+	filters, err := parseFilter(r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
 	}
 
-	c, err := h.r.ClientManager().GetClients(r.Context(), filters)
+	c, nextPage, err := h.r.ClientManager().GetClients(r.Context(), filters)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
@@ -533,7 +758,7 @@ func (h *Handler) listOAuth2Clients(w http.ResponseWriter, r *http.Request, ps h
 		return
 	}
 
-	x.PaginationHeader(w, r.URL, int64(total), page, itemsPerPage)
+	x.KeysetPaginationHeader(w, r.URL, int64(total), nextPage)
 	h.r.Writer().Write(w, r, c)
 }
 
@@ -658,6 +883,10 @@ type deleteOAuth2Client struct {
 //
 // Delete an existing OAuth 2.0 Client by its ID.
 //
+// The client is soft-deleted: it can no longer authenticate or be used in flows, and its tokens are no longer
+// accepted. It can be restored using `restoreOAuth2Client` until the janitor purges it, together with its tokens and
+// consent sessions, after the retention period `ttl.deleted_client` has passed.
+//
 // OAuth 2.0 clients are used to perform OAuth 2.0 and OpenID Connect flows. Usually, OAuth 2.0 clients are
 // generated for applications which want to consume your OAuth 2.0 or OpenID Connect capabilities.
 //
@@ -680,10 +909,171 @@ func (h *Handler) deleteOAuth2Client(w http.ResponseWriter, r *http.Request, ps
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
+	h.r.PurgeClientCaches(r.Context())
 
+	h.r.EventHook().Emit(r.Context(), events.Event{Type: events.ClientDeleted, ClientID: id})
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Restore OAuth 2.0 Client Parameters
+//
+// swagger:parameters restoreOAuth2Client
+type restoreOAuth2Client struct {
+	// The id of the OAuth 2.0 Client.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:route POST /admin/clients/{id}/restore oAuth2 restoreOAuth2Client
+//
+// # Restore a deleted OAuth 2.0 Client
+//
+// Restores a soft-deleted OAuth 2.0 Client, together with its tokens and consent sessions. Clients can only be
+// restored until the janitor purges them.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2Client
+//	  404: errorOAuth2NotFound
+//	  default: errorOAuth2Default
+func (h *Handler) restoreOAuth2Client(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var id = ps.ByName("id")
+	if err := h.r.ClientManager().RestoreClient(r.Context(), id); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	h.r.PurgeClientCaches(r.Context())
+
+	c, err := h.r.ClientManager().GetConcreteClient(r.Context(), id)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	c.Secret = ""
+	h.r.Writer().Write(w, r, c)
+}
+
+// Disable OAuth 2.0 Client Request Body
+//
+// swagger:model disableOAuth2ClientBody
+type disableOAuth2ClientBody struct {
+	// If set, the client's access tokens are deleted and its refresh tokens are revoked.
+	RevokeTokens bool `json:"revoke_tokens"`
+}
+
+// Disable OAuth 2.0 Client Parameters
+//
+// swagger:parameters disableOAuth2Client
+type disableOAuth2Client struct {
+	// The id of the OAuth 2.0 Client.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Body disableOAuth2ClientBody
+}
+
+// swagger:route POST /admin/clients/{id}/disable oAuth2 disableOAuth2Client
+//
+// # Disable an OAuth 2.0 Client
+//
+// Disables an OAuth 2.0 Client without deleting it or changing its secret. A disabled client can no longer
+// authenticate or be used in flows, and its tokens can neither be introspected nor refreshed. Set `revoke_tokens` to
+// also delete its access tokens and revoke its refresh tokens, so that they stay invalid once the client is enabled
+// again.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2Client
+//	  404: errorOAuth2NotFound
+//	  default: errorOAuth2Default
+func (h *Handler) disableOAuth2Client(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var body disableOAuth2ClientBody
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body. Is it valid JSON?").WithDebug(err.Error())))
+			return
+		}
+	}
+
+	h.setOAuth2ClientDisabled(w, r, ps.ByName("id"), true, body.RevokeTokens)
+}
+
+// Enable OAuth 2.0 Client Parameters
+//
+// swagger:parameters enableOAuth2Client
+type enableOAuth2Client struct {
+	// The id of the OAuth 2.0 Client.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:route POST /admin/clients/{id}/enable oAuth2 enableOAuth2Client
+//
+// # Enable an OAuth 2.0 Client
+//
+// Enables a disabled OAuth 2.0 Client. Its tokens are accepted again unless they were revoked when it was disabled.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2Client
+//	  404: errorOAuth2NotFound
+//	  default: errorOAuth2Default
+func (h *Handler) enableOAuth2Client(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.setOAuth2ClientDisabled(w, r, ps.ByName("id"), false, false)
+}
+
+func (h *Handler) setOAuth2ClientDisabled(w http.ResponseWriter, r *http.Request, id string, disabled, revokeTokens bool) {
+	if err := h.r.ClientManager().SetClientDisabled(r.Context(), id, disabled); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	h.r.PurgeClientCaches(r.Context())
+
+	if revokeTokens {
+		if err := h.r.OAuth2Storage().DeleteAccessTokens(r.Context(), id); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		if err := h.r.OAuth2Storage().RevokeRefreshTokens(r.Context(), id); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		h.r.EventHook().Emit(r.Context(), events.Event{Type: events.TokenRevoked, ClientID: id})
+	}
+
+	c, err := h.r.ClientManager().GetConcreteClient(r.Context(), id)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	c.Secret = ""
+	h.r.Writer().Write(w, r, c)
+}
+
 // Set OAuth 2.0 Client Token Lifespans
 //
 // swagger:parameters setOAuth2ClientLifespans
@@ -737,6 +1127,111 @@ func (h *Handler) setOAuth2ClientLifespans(w http.ResponseWriter, r *http.Reques
 	h.r.Writer().Write(w, r, c)
 }
 
+// DefaultSecretRotationGracePeriod is how long the previous client secret remains valid after a rotation
+// if no grace period was requested.
+const DefaultSecretRotationGracePeriod = 24 * time.Hour
+
+// Rotate OAuth 2.0 Client Secret Request Body
+//
+// swagger:model rotateOAuth2ClientSecretBody
+type RotateSecretRequest struct {
+	// The new client secret. If empty, a random secret is generated.
+	Secret string `json:"client_secret,omitempty"`
+
+	// How long the previous client secret remains valid, for example `1h`. Defaults to 24 hours. If set to `0s`,
+	// the previous client secret is invalidated immediately.
+	//
+	// pattern: ^[0-9]+(ns|us|ms|s|m|h)$
+	GracePeriod *x.Duration `json:"grace_period,omitempty"`
+}
+
+// Rotate OAuth 2.0 Client Secret Parameters
+//
+// swagger:parameters rotateOAuth2ClientSecret
+type rotateOAuth2ClientSecret struct {
+	// OAuth 2.0 Client ID
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Body RotateSecretRequest
+}
+
+// swagger:route POST /admin/clients/{id}/secret/rotate oAuth2 rotateOAuth2ClientSecret
+//
+// # Rotate OAuth 2.0 Client Secret
+//
+// Replaces the secret of an OAuth 2.0 Client. The previous secret remains valid until the grace period ends, which
+// allows rolling out the new secret without downtime. Secrets whose grace period ended are removed.
+//
+// If you pass `client_secret` the secret is used, otherwise a random secret is generated. The secret is echoed
+// in the response. It is not possible to retrieve it later on.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2Client
+//	  400: errorOAuth2BadRequest
+//	  404: errorOAuth2NotFound
+//	  default: errorOAuth2Default
+func (h *Handler) rotateOAuth2ClientSecret(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var body RotateSecretRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+			return
+		}
+	}
+
+	gracePeriod := DefaultSecretRotationGracePeriod
+	if body.GracePeriod != nil {
+		gracePeriod = time.Duration(*body.GracePeriod)
+	}
+	if gracePeriod < 0 {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The grace period must not be negative.")))
+		return
+	}
+
+	c, err := h.r.ClientManager().GetConcreteClient(r.Context(), ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	} else if c.IsPublic() {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReason("Public OAuth 2.0 Clients do not have a client secret.")))
+		return
+	}
+
+	secret := body.Secret
+	if len(secret) == 0 {
+		secretb, err := x.GenerateSecret(26)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		secret = string(secretb)
+	} else if len(secret) < 6 {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(ErrInvalidClientMetadata.WithHint("Field client_secret must contain a secret that is at least 6 characters long.")))
+		return
+	}
+
+	c, err = h.r.ClientManager().RotateClientSecret(r.Context(), c.GetID(), secret, gracePeriod)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	c.Secret = secret
+	h.r.Writer().Write(w, r, c)
+}
+
 // swagger:parameters deleteOidcDynamicClient
 type dynamicClientRegistrationDeleteOAuth2Client struct {
 	// The id of the OAuth 2.0 Client.
@@ -788,7 +1283,9 @@ func (h *Handler) deleteOidcDynamicClient(w http.ResponseWriter, r *http.Request
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
+	h.r.PurgeClientCaches(r.Context())
 
+	h.r.EventHook().Emit(r.Context(), events.Event{Type: events.ClientDeleted, ClientID: client.GetID()})
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -805,6 +1302,11 @@ func (h *Handler) ValidDynamicAuth(r *http.Request, ps httprouter.Params) (fosit
 			WithReason("The requested OAuth 2.0 client does not exist or you provided incorrect credentials.").WithDebug("The OAuth2 Client does not have a registration access token."))
 	}
 
+	if c.Disabled {
+		return nil, errors.WithStack(herodot.ErrUnauthorized.
+			WithReason("The requested OAuth 2.0 client does not exist or you provided incorrect credentials.").WithDebug("The OAuth2 Client is disabled."))
+	}
+
 	token := strings.TrimPrefix(fosite.AccessTokenFromRequest(r), "ory_at_")
 	if err := h.r.OAuth2HMACStrategy().Enigma.Validate(r.Context(), token); err != nil {
 		return nil, herodot.ErrUnauthorized.
@@ -821,6 +1323,366 @@ func (h *Handler) ValidDynamicAuth(r *http.Request, ps httprouter.Params) (fosit
 	return c, nil
 }
 
+// initialAccessTokenSignature returns the signature of the initial access token sent as bearer token.
+func (h *Handler) initialAccessTokenSignature(r *http.Request) (string, error) {
+	token := strings.TrimPrefix(fosite.AccessTokenFromRequest(r), "ory_at_")
+	if token == "" {
+		return "", errors.WithStack(herodot.ErrUnauthorized.WithReason("An initial access token is required to register an OAuth 2.0 Client."))
+	}
+	if err := h.r.OAuth2HMACStrategy().Enigma.Validate(r.Context(), token); err != nil {
+		return "", errors.WithStack(herodot.ErrUnauthorized.WithTrace(err).WithReason("The initial access token is invalid, expired, or has been used up.").WithDebug(err.Error()))
+	}
+	return h.r.OAuth2HMACStrategy().Enigma.Signature(token), nil
+}
+
+// swagger:parameters createOAuth2InitialAccessToken
+type createOAuth2InitialAccessToken struct {
+	// in: body
+	Body CreateInitialAccessTokenRequest
+}
+
+// swagger:route POST /admin/oauth2/initial-access-tokens oAuth2 createOAuth2InitialAccessToken
+//
+// # Create an Initial Access Token for Dynamic Client Registration
+//
+// Creates an initial access token which allows registering OAuth 2.0 Clients using OpenID Connect Dynamic Client
+// Registration. The token must be sent as bearer token to the registration endpoint, and is required if
+// `oidc.dynamic_client_registration.require_initial_access_token` is enabled.
+//
+// The token is only contained in this response. It is not possible to retrieve it later on.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  201: oAuth2InitialAccessToken
+//	  400: errorOAuth2BadRequest
+//	  default: errorOAuth2Default
+func (h *Handler) createOAuth2InitialAccessToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body CreateInitialAccessTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+			return
+		}
+	}
+	if body.MaxRegistrations < 0 {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The maximum number of registrations must not be negative.")))
+		return
+	}
+
+	token, signature, err := h.r.OAuth2HMACStrategy().GenerateAccessToken(r.Context(), nil)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	t := InitialAccessToken{
+		Signature:        signature,
+		CreatedAt:        time.Now().UTC().Round(time.Second),
+		MaxRegistrations: body.MaxRegistrations,
+	}
+	if body.ExpiresAt != nil {
+		t.ExpiresAt = sqlxx.NullTime(body.ExpiresAt.UTC())
+	}
+	if err := h.r.ClientManager().CreateInitialAccessToken(r.Context(), &t); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	t.Token = token
+	h.r.Writer().WriteCreated(w, r, "/admin"+InitialAccessTokensHandlerPath, &t)
+}
+
+// Paginated Initial Access Token List Response
+//
+// swagger:response listOAuth2InitialAccessTokens
+type listOAuth2InitialAccessTokensResponse struct {
+	keysetpagination.ResponseHeaders
+
+	// List of initial access tokens, without the tokens themselves.
+	//
+	// in:body
+	Body []InitialAccessToken
+}
+
+// swagger:parameters listOAuth2InitialAccessTokens
+type listOAuth2InitialAccessTokensParameters struct {
+	keysetpagination.RequestParameters
+}
+
+// swagger:route GET /admin/oauth2/initial-access-tokens oAuth2 listOAuth2InitialAccessTokens
+//
+// # List Initial Access Tokens for Dynamic Client Registration
+//
+// Lists the initial access tokens together with how often they were used. The tokens themselves are never returned.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: listOAuth2InitialAccessTokens
+//	  default: errorOAuth2Default
+func (h *Handler) listOAuth2InitialAccessTokens(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ts, nextPage, err := h.r.ClientManager().GetInitialAccessTokens(r.Context(), x.ParseKeysetPagination(r)...)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	keysetpagination.Header(w, r.URL, nextPage)
+	h.r.Writer().Write(w, r, ts)
+}
+
+// swagger:parameters deleteOAuth2InitialAccessToken
+type deleteOAuth2InitialAccessToken struct {
+	// The ID of the initial access token.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /admin/oauth2/initial-access-tokens/{id} oAuth2 deleteOAuth2InitialAccessToken
+//
+// # Delete an Initial Access Token for Dynamic Client Registration
+//
+// Deletes an initial access token so that it can no longer be used to register OAuth 2.0 Clients. Clients which
+// were registered with the token are not affected.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  204: emptyResponse
+//	  404: errorOAuth2NotFound
+//	  default: errorOAuth2Default
+func (h *Handler) deleteOAuth2InitialAccessToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.r.ClientManager().DeleteInitialAccessToken(r.Context(), ps.ByName("id")); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:parameters createOAuth2ClientTemplate
+type createOAuth2ClientTemplate struct {
+	// in: body
+	// required: true
+	Body Template
+}
+
+// swagger:route POST /admin/client-templates oAuth2 createOAuth2ClientTemplate
+//
+// # Create OAuth 2.0 Client Template
+//
+// Creates a named client template. OAuth 2.0 Clients which reference the template in their `template` field take
+// the grant types, response types, token endpoint authentication method, lifespans and metadata defaults they do
+// not set from the template. If `enforce` is set, clients referencing the template must match it exactly.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  201: oAuth2ClientTemplate
+//	  400: errorOAuth2BadRequest
+//	  409: errorOAuth2Default
+//	  default: errorOAuth2Default
+func (h *Handler) createOAuth2ClientTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	t, err := decodeTemplate(r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	t.CreatedAt = time.Now().UTC().Round(time.Second)
+	t.UpdatedAt = t.CreatedAt
+	if err := h.r.ClientManager().CreateClientTemplate(r.Context(), t); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().WriteCreated(w, r, "/admin"+ClientTemplatesHandlerPath+"/"+t.Name, t)
+}
+
+// Paginated Client Template List Response
+//
+// swagger:response listOAuth2ClientTemplates
+type listOAuth2ClientTemplatesResponse struct {
+	keysetpagination.ResponseHeaders
+
+	// List of client templates.
+	//
+	// in:body
+	Body []Template
+}
+
+// swagger:parameters listOAuth2ClientTemplates
+type listOAuth2ClientTemplatesParameters struct {
+	keysetpagination.RequestParameters
+}
+
+// swagger:route GET /admin/client-templates oAuth2 listOAuth2ClientTemplates
+//
+// # List OAuth 2.0 Client Templates
+//
+// Lists the client templates ordered by name.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: listOAuth2ClientTemplates
+//	  default: errorOAuth2Default
+func (h *Handler) listOAuth2ClientTemplates(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ts, nextPage, err := h.r.ClientManager().GetClientTemplates(r.Context(), x.ParseKeysetPagination(r)...)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	keysetpagination.Header(w, r.URL, nextPage)
+	h.r.Writer().Write(w, r, ts)
+}
+
+// swagger:parameters getOAuth2ClientTemplate deleteOAuth2ClientTemplate
+type oAuth2ClientTemplateName struct {
+	// The name of the client template.
+	//
+	// in: path
+	// required: true
+	Name string `json:"name"`
+}
+
+// swagger:route GET /admin/client-templates/{name} oAuth2 getOAuth2ClientTemplate
+//
+// # Get OAuth 2.0 Client Template
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2ClientTemplate
+//	  404: errorOAuth2NotFound
+//	  default: errorOAuth2Default
+func (h *Handler) getOAuth2ClientTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	t, err := h.r.ClientManager().GetClientTemplate(r.Context(), ps.ByName("name"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, t)
+}
+
+// swagger:parameters setOAuth2ClientTemplate
+type setOAuth2ClientTemplate struct {
+	// The name of the client template.
+	//
+	// in: path
+	// required: true
+	Name string `json:"name"`
+
+	// in: body
+	// required: true
+	Body Template
+}
+
+// swagger:route PUT /admin/client-templates/{name} oAuth2 setOAuth2ClientTemplate
+//
+// # Set OAuth 2.0 Client Template
+//
+// Replaces an existing client template. Existing clients are not changed, but the template is applied and, if
+// enforced, checked the next time they are updated.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2ClientTemplate
+//	  400: errorOAuth2BadRequest
+//	  404: errorOAuth2NotFound
+//	  default: errorOAuth2Default
+func (h *Handler) setOAuth2ClientTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	t, err := decodeTemplate(r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	t.Name = ps.ByName("name")
+	t.UpdatedAt = time.Now().UTC().Round(time.Second)
+	if err := h.r.ClientManager().UpdateClientTemplate(r.Context(), t); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, t)
+}
+
+// swagger:route DELETE /admin/client-templates/{name} oAuth2 deleteOAuth2ClientTemplate
+//
+// # Delete OAuth 2.0 Client Template
+//
+// Deletes a client template. Templates which are still referenced by OAuth 2.0 Clients can not be deleted.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  204: emptyResponse
+//	  404: errorOAuth2NotFound
+//	  409: errorOAuth2Default
+//	  default: errorOAuth2Default
+func (h *Handler) deleteOAuth2ClientTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.r.ClientManager().DeleteClientTemplate(r.Context(), ps.ByName("name")); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeTemplate(r *http.Request) (*Template, error) {
+	var t Template
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err))
+	}
+	if r.Method == http.MethodPost && t.Name == "" {
+		return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("Field name must be set."))
+	}
+	if _, err := metadataObject(t.Metadata); err != nil {
+		return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("Field metadata must be a JSON object."))
+	}
+	return &t, nil
+}
+
 func (h *Handler) requireDynamicAuth(r *http.Request) *herodot.DefaultError {
 	if !h.r.Config().PublicAllowDynamicRegistration(r.Context()) {
 		return herodot.ErrNotFound.WithReason("Dynamic registration is not enabled.")
@@ -6,14 +6,21 @@ package client_test
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/ory/x/httprouterx"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
 
 	"github.com/tidwall/sjson"
 
@@ -32,8 +39,12 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/oauth2"
+	"github.com/ory/hydra/oauth2/trust"
+	"github.com/ory/hydra/x"
 )
 
 type responseSnapshot struct {
@@ -515,6 +526,30 @@ func TestHandler(t *testing.T) {
 			})
 		})
 
+		t.Run("case=only the admin API can set the token exchange policy", func(t *testing.T) {
+			policy := &client.TokenExchangePolicy{AllowedSubjectClients: []string{"other-client"}}
+
+			body, res := makeJSON(t, ts, "POST", client.DynClientsHandlerPath, &client.Client{
+				RedirectURIs:        []string{"http://localhost:3000/cb"},
+				TokenExchangePolicy: policy,
+			})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			assert.False(t, gjson.Get(body, "token_exchange_policy").Exists(), body)
+
+			expected := createClient(t, &client.Client{
+				RedirectURIs:        []string{"http://localhost:3000/cb"},
+				TokenExchangePolicy: policy,
+			}, ts, client.ClientsHandlerPath)
+			assert.JSONEq(t, `["other-client"]`, gjson.Get(expected, "token_exchange_policy.allowed_subject_clients").Raw, expected)
+
+			payload, _ := sjson.Delete(expected, "client_secret")
+			payload, _ = sjson.Delete(payload, "metadata")
+			payload, _ = sjson.Set(payload, "token_exchange_policy.allowed_subject_clients", []string{"attacker"})
+			body, res = fetchWithBearerAuth(t, "PUT", ts.URL+client.DynClientsHandlerPath+"/"+getClientID(expected), gjson.Get(expected, "registration_access_token").String(), bytes.NewBufferString(payload))
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.JSONEq(t, `["other-client"]`, gjson.Get(body, "token_exchange_policy.allowed_subject_clients").Raw, body)
+		})
+
 		t.Run("case=creating a client dynamically does not allow setting the secret", func(t *testing.T) {
 			body, res := makeJSON(t, ts, "POST", client.DynClientsHandlerPath, &client.Client{
 				TokenEndpointAuthMethod: "client_secret_basic",
@@ -539,6 +574,443 @@ func TestHandler(t *testing.T) {
 			snapshotx.SnapshotTExcept(t, newResponseSnapshot(body, res), []string{"body.client_id", "body.created_at", "body.updated_at"})
 		})
 
+		t.Run("case=rotate the secret of an OAuth2 client", func(t *testing.T) {
+			body, res := makeJSON(t, ts, "POST", client.ClientsHandlerPath, &client.Client{
+				Secret:                  "theoriginalsecret",
+				RedirectURIs:            []string{"http://localhost:3000/cb"},
+				TokenEndpointAuthMethod: "client_secret_basic",
+			})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			id := getClientID(body)
+
+			body, res = makeJSON(t, ts, "POST", client.ClientsHandlerPath+"/"+id+"/secret/rotate", map[string]string{"client_secret": "therotatedsecret", "grace_period": "1h"})
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.Equal(t, "therotatedsecret", gjson.Get(body, "client_secret").String(), body)
+			assert.False(t, gjson.Get(body, "rotated_secrets").Exists(), body)
+
+			for _, secret := range []string{"theoriginalsecret", "therotatedsecret"} {
+				_, err := reg.ClientManager().Authenticate(ctx, id, []byte(secret))
+				assert.NoError(t, err, secret)
+			}
+
+			body, res = makeJSON(t, ts, "POST", client.ClientsHandlerPath+"/"+id+"/secret/rotate", map[string]string{"grace_period": "0s"})
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			generated := gjson.Get(body, "client_secret").String()
+			require.NotEmpty(t, generated, body)
+
+			_, err := reg.ClientManager().Authenticate(ctx, id, []byte(generated))
+			assert.NoError(t, err)
+			_, err = reg.ClientManager().Authenticate(ctx, id, []byte("theoriginalsecret"))
+			assert.NoError(t, err, "the grace period of the original secret has not yet ended")
+			_, err = reg.ClientManager().Authenticate(ctx, id, []byte("therotatedsecret"))
+			assert.Error(t, err, "the rotated secret was invalidated immediately")
+
+			body, res = makeJSON(t, ts, "POST", client.ClientsHandlerPath+"/"+id+"/secret/rotate", map[string]string{"grace_period": "-1h"})
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+
+			body, res = makeJSON(t, ts, "POST", client.ClientsHandlerPath+"/does-not-exist/secret/rotate", map[string]string{})
+			assert.Equal(t, http.StatusNotFound, res.StatusCode, body)
+		})
+
+		t.Run("case=client secrets expire after the configured lifespan", func(t *testing.T) {
+			reg.Config().MustSet(ctx, config.KeyClientSecretLifespan, "1h")
+			t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyClientSecretLifespan, "0s") })
+
+			body, res := makeJSON(t, ts, "POST", client.ClientsHandlerPath, &client.Client{
+				RedirectURIs:            []string{"http://localhost:3000/cb"},
+				TokenEndpointAuthMethod: "client_secret_basic",
+				SecretExpiresAt:         1,
+			})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			id := getClientID(body)
+			expiresAt := gjson.Get(body, "client_secret_expires_at").Int()
+			assert.InDelta(t, time.Now().Add(time.Hour).Unix(), expiresAt, 5, body)
+
+			body, res = makeJSON(t, ts, "PUT", client.ClientsHandlerPath+"/"+id, &client.Client{
+				RedirectURIs:            []string{"http://localhost:3000/cb"},
+				TokenEndpointAuthMethod: "client_secret_basic",
+			})
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.EqualValues(t, expiresAt, gjson.Get(body, "client_secret_expires_at").Int(), "the expiry only changes with the secret")
+
+			body, res = makeJSON(t, ts, "GET", client.ClientsHandlerPath+"?client_secret_expires_before="+strconv.FormatInt(time.Now().Add(2*time.Hour).Unix(), 10), nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.Contains(t, body, id)
+			body, res = makeJSON(t, ts, "GET", client.ClientsHandlerPath+"?client_secret_expires_before="+strconv.FormatInt(time.Now().Unix(), 10), nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.NotContains(t, body, id)
+
+			reg.Config().MustSet(ctx, config.KeyClientSecretLifespan, "2h")
+			body, res = makeJSON(t, ts, "POST", client.ClientsHandlerPath+"/"+id+"/secret/rotate", map[string]string{})
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.InDelta(t, time.Now().Add(2*time.Hour).Unix(), gjson.Get(body, "client_secret_expires_at").Int(), 5, body)
+
+			body, res = makeJSON(t, ts, "GET", client.ClientsHandlerPath+"?client_secret_expires_before=soon", nil)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+		})
+
+		t.Run("case=list clients with filters and sort options", func(t *testing.T) {
+			var ids []string
+			for _, name := range []string{"filtered-b", "filtered-a"} {
+				body, res := makeJSON(t, ts, "POST", client.ClientsHandlerPath, &client.Client{
+					Name:         name,
+					GrantTypes:   []string{"client_credentials"},
+					RedirectURIs: []string{"https://filtered.example.com/cb"},
+					Metadata:     []byte(`{"team":{"name":"filtered"}}`),
+				})
+				require.Equal(t, http.StatusCreated, res.StatusCode, body)
+				ids = append(ids, getClientID(body))
+			}
+
+			query := url.Values{
+				"grant_type":        {"client_credentials"},
+				"redirect_uri_host": {"filtered.example.com"},
+				"metadata":          {"team.name=filtered"},
+				"created_after":     {time.Now().Add(-time.Hour).Format(time.RFC3339)},
+				"sort_by":           {"client_name"},
+				"sort_order":        {"desc"},
+			}
+			body, res := makeJSON(t, ts, "GET", client.ClientsHandlerPath+"?"+query.Encode(), nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.Equal(t, ids, []string{gjson.Get(body, "0.client_id").String(), gjson.Get(body, "1.client_id").String()}, body)
+			assert.Len(t, gjson.Parse(body).Array(), 2, body)
+
+			for _, q := range []string{"created_after=yesterday", "metadata=team.name", "sort_by=client_secret", "sort_order=random"} {
+				body, res := makeJSON(t, ts, "GET", client.ClientsHandlerPath+"?"+q, nil)
+				assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s: %s", q, body)
+			}
+		})
+
+		t.Run("case=register clients with initial access tokens", func(t *testing.T) {
+			reg.Config().MustSet(ctx, config.KeyDynamicRegistrationRequireInitialToken, true)
+			t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyDynamicRegistrationRequireInitialToken, false) })
+
+			register := func(t *testing.T, token string) (string, *http.Response) {
+				return fetchWithBearerAuth(t, "POST", ts.URL+client.DynClientsHandlerPath, token, bytes.NewBufferString(`{"redirect_uris":["http://localhost:3000/cb"]}`))
+			}
+
+			body, res := makeJSON(t, ts, "POST", client.DynClientsHandlerPath, &client.Client{RedirectURIs: []string{"http://localhost:3000/cb"}})
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body)
+			body, res = register(t, "ory_at_not-a-token")
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body)
+
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.InitialAccessTokensHandlerPath, &client.CreateInitialAccessTokenRequest{MaxRegistrations: 1})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			id, token := gjson.Get(body, "id").String(), gjson.Get(body, "token").String()
+			require.NotEmpty(t, token, body)
+
+			body, res = register(t, token)
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			body, res = register(t, token)
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "the token allows a single registration: %s", body)
+
+			body, res = makeJSON(t, ts, "GET", "/admin"+client.InitialAccessTokensHandlerPath, nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			listed := gjson.Get(body, fmt.Sprintf(`#(id=="%s")`, id))
+			assert.EqualValues(t, 1, listed.Get("registrations").Int(), body)
+			assert.False(t, listed.Get("token").Exists(), body)
+
+			expired := time.Now().Add(-time.Minute)
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.InitialAccessTokensHandlerPath, &client.CreateInitialAccessTokenRequest{ExpiresAt: &expired})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			body, res = register(t, gjson.Get(body, "token").String())
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body)
+
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.InitialAccessTokensHandlerPath, &client.CreateInitialAccessTokenRequest{})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			unlimited := gjson.Get(body, "token").String()
+			body, res = register(t, `not-a-json`+unlimited)
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body)
+			body, res = fetchWithBearerAuth(t, "POST", ts.URL+client.DynClientsHandlerPath, unlimited, bytes.NewBufferString(`{"client_secret":"not-allowed"}`))
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+			for i := 0; i < 2; i++ {
+				body, res = register(t, unlimited)
+				assert.Equal(t, http.StatusCreated, res.StatusCode, body)
+			}
+
+			req, err := http.NewRequest("DELETE", ts.URL+"/admin"+client.InitialAccessTokensHandlerPath+"/"+id, nil)
+			require.NoError(t, err)
+			res, err = ts.Client().Do(req)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusNoContent, res.StatusCode)
+			res, err = ts.Client().Do(req)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		})
+
+		t.Run("case=register clients with software statements", func(t *testing.T) {
+			issuer := "https://software-publisher.example.com"
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			require.NoError(t, err)
+			require.NoError(t, reg.GrantManager().CreateGrant(ctx, trust.Grant{
+				ID:              uuid.Must(uuid.NewV4()).String(),
+				Issuer:          issuer,
+				AllowAnySubject: true,
+				Scope:           []string{"openid", "offline"},
+				PublicKey:       trust.PublicKey{Set: issuer, KeyID: "statement-key"},
+				CreatedAt:       time.Now(),
+				ExpiresAt:       time.Now().Add(time.Hour),
+			}, jose.JSONWebKey{Key: key.Public(), KeyID: "statement-key", Algorithm: "RS256", Use: "sig"}))
+
+			sign := func(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+				signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: "statement-key"}}, (&jose.SignerOptions{}).WithType("JWT"))
+				require.NoError(t, err)
+				statement, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+				require.NoError(t, err)
+				return statement
+			}
+			statementClaims := func(claims map[string]interface{}) map[string]interface{} {
+				base := map[string]interface{}{
+					"iss":           issuer,
+					"sub":           "my-app",
+					"iat":           time.Now().Unix(),
+					"client_name":   "Statement App",
+					"redirect_uris": []string{"https://app.example.com/cb"},
+				}
+				for k, v := range claims {
+					base[k] = v
+				}
+				return base
+			}
+
+			body, res := makeJSON(t, ts, "POST", client.DynClientsHandlerPath, map[string]interface{}{
+				"client_name":        "Request App",
+				"redirect_uris":      []string{"https://evil.example.com/cb"},
+				"software_statement": sign(t, key, statementClaims(nil)),
+			})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			assert.Equal(t, "Statement App", gjson.Get(body, "client_name").String(), body)
+			assert.Equal(t, `["https://app.example.com/cb"]`, gjson.Get(body, "redirect_uris").Raw, body)
+			assert.Equal(t, "openid offline", gjson.Get(body, "scope").String(), body)
+
+			body, res = makeJSON(t, ts, "POST", client.DynClientsHandlerPath, map[string]interface{}{
+				"software_statement": sign(t, key, statementClaims(map[string]interface{}{
+					"token_exchange_policy": map[string]interface{}{"allowed_subject_clients": []string{"other-client"}},
+					"disabled":              true,
+					"deleted_at":            time.Now().UTC().Format(time.RFC3339),
+					"template":              "does-not-exist",
+					"client_credentials_grant_access_token_lifespan": "87600h",
+					"metadata": map[string]interface{}{"foo": "bar"},
+					"owner":    "admin",
+				})),
+			})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			assert.Equal(t, "Statement App", gjson.Get(body, "client_name").String(), body)
+			for _, field := range []string{"token_exchange_policy", "disabled", "deleted_at", "template", "metadata.foo"} {
+				assert.False(t, gjson.Get(body, field).Exists(), "%s: %s", field, body)
+			}
+			assert.Empty(t, gjson.Get(body, "client_credentials_grant_access_token_lifespan").Value(), body)
+			assert.Empty(t, gjson.Get(body, "owner").String(), body)
+
+			other, err := rsa.GenerateKey(rand.Reader, 2048)
+			require.NoError(t, err)
+			for _, tc := range []struct {
+				d         string
+				statement string
+				body      map[string]interface{}
+				error     string
+			}{
+				{d: "grant type is not allowed", statement: sign(t, key, statementClaims(map[string]interface{}{"grant_types": []string{"client_credentials"}})), error: "invalid_client_metadata"},
+				{d: "grant type of the request is not allowed", statement: sign(t, key, statementClaims(nil)), body: map[string]interface{}{"grant_types": []string{"client_credentials"}}, error: "invalid_client_metadata"},
+				{d: "scope is not allowed", statement: sign(t, key, statementClaims(map[string]interface{}{"scope": "openid admin"})), error: "invalid_client_metadata"},
+				{d: "issuer is not trusted", statement: sign(t, key, statementClaims(map[string]interface{}{"iss": "https://untrusted.example.com"})), error: "unapproved_software_statement"},
+				{d: "signature is invalid", statement: sign(t, other, statementClaims(nil)), error: "unapproved_software_statement"},
+				{d: "statement expired", statement: sign(t, key, statementClaims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})), error: "invalid_software_statement"},
+				{d: "statement is not a JWT", statement: "not-a-jwt", error: "invalid_software_statement"},
+			} {
+				t.Run("case="+tc.d, func(t *testing.T) {
+					payload := map[string]interface{}{"software_statement": tc.statement}
+					for k, v := range tc.body {
+						payload[k] = v
+					}
+					body, res := makeJSON(t, ts, "POST", client.DynClientsHandlerPath, payload)
+					assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+					assert.Equal(t, tc.error, gjson.Get(body, "error").String(), body)
+				})
+			}
+		})
+
+		t.Run("case=create clients from templates", func(t *testing.T) {
+			templates := "/admin" + client.ClientTemplatesHandlerPath
+			body, res := fetchWithBearerAuth(t, "POST", ts.URL+templates, "", bytes.NewBufferString(`{
+				"name": "web-app",
+				"grant_types": ["authorization_code", "refresh_token"],
+				"response_types": ["code"],
+				"token_endpoint_auth_method": "client_secret_post",
+				"metadata": {"team": "web"},
+				"authorization_code_grant_access_token_lifespan": "1h",
+				"enforce": true
+			}`))
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			body, res = makeJSON(t, ts, "POST", templates, &client.Template{Name: "web-app"})
+			assert.Equal(t, http.StatusConflict, res.StatusCode, body)
+
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath, &client.Client{
+				Template:     "web-app",
+				RedirectURIs: []string{"http://localhost:3000/cb"},
+				Metadata:     []byte(`{"owner":"alice"}`),
+			})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			assert.Equal(t, "web-app", gjson.Get(body, "template").String(), body)
+			assert.Equal(t, `["authorization_code","refresh_token"]`, gjson.Get(body, "grant_types").Raw, body)
+			assert.Equal(t, `["code"]`, gjson.Get(body, "response_types").Raw, body)
+			assert.Equal(t, "client_secret_post", gjson.Get(body, "token_endpoint_auth_method").String(), body)
+			assert.Equal(t, "1h0m0s", gjson.Get(body, "authorization_code_grant_access_token_lifespan").String(), body)
+			assert.JSONEq(t, `{"owner":"alice","team":"web"}`, gjson.Get(body, "metadata").Raw, body)
+			id := gjson.Get(body, "client_id").String()
+
+			for _, tc := range []struct {
+				d string
+				c client.Client
+			}{
+				{d: "grant types", c: client.Client{GrantTypes: []string{"client_credentials"}}},
+				{d: "auth method", c: client.Client{TokenEndpointAuthMethod: "client_secret_basic"}},
+				{d: "metadata", c: client.Client{Metadata: []byte(`{"team":"mobile"}`)}},
+				{d: "lifespans", c: client.Client{Lifespans: client.Lifespans{AuthorizationCodeGrantAccessTokenLifespan: x.NullDuration{Duration: time.Minute, Valid: true}}}},
+			} {
+				t.Run("case=enforced template rejects different "+tc.d, func(t *testing.T) {
+					tc.c.Template = "web-app"
+					body, res := makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath, &tc.c)
+					assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+				})
+			}
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath, &client.Client{Template: "does-not-exist"})
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+
+			body, res = makeJSON(t, ts, "POST", client.DynClientsHandlerPath, &client.Client{Template: "web-app", RedirectURIs: []string{"http://localhost:3000/cb"}})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			assert.Equal(t, "client_secret_post", gjson.Get(body, "token_endpoint_auth_method").String(), body)
+			assert.JSONEq(t, `{"team":"web"}`, gjson.Get(body, "metadata").Raw, body)
+			body, res = fetchWithBearerAuth(t, "PUT", ts.URL+client.DynClientsHandlerPath+"/"+gjson.Get(body, "client_id").String(), gjson.Get(body, "registration_access_token").String(),
+				bytes.NewBufferString(`{"redirect_uris":["http://localhost:3000/cb"],"grant_types":["client_credentials"]}`))
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "dynamic clients must keep their template: %s", body)
+
+			body, res = makeJSON(t, ts, "PUT", templates+"/web-app", &client.Template{GrantTypes: []string{"authorization_code"}})
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			body, res = makeJSON(t, ts, "GET", templates+"/web-app", nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.False(t, gjson.Get(body, "enforce").Bool(), body)
+			body, res = makeJSON(t, ts, "GET", templates, nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.Equal(t, "web-app", gjson.Get(body, "0.name").String(), body)
+
+			body, res = makeJSON(t, ts, "PUT", "/admin"+client.ClientsHandlerPath+"/"+id, &client.Client{Template: "web-app", GrantTypes: []string{"client_credentials"}})
+			require.Equal(t, http.StatusOK, res.StatusCode, "templates which are not enforced only provide defaults: %s", body)
+			assert.Equal(t, `["client_credentials"]`, gjson.Get(body, "grant_types").Raw, body)
+
+			req, err := http.NewRequest("DELETE", ts.URL+templates+"/web-app", nil)
+			require.NoError(t, err)
+			res, err = ts.Client().Do(req)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusConflict, res.StatusCode, "the template is still in use")
+		})
+
+		t.Run("case=restore a deleted client", func(t *testing.T) {
+			body, res := makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath, &client.Client{Name: "restore-deleted-client", Secret: "averylongsecret"})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			id := gjson.Get(body, "client_id").String()
+
+			req, err := http.NewRequest("DELETE", ts.URL+"/admin"+client.ClientsHandlerPath+"/"+id, nil)
+			require.NoError(t, err)
+			res, err = ts.Client().Do(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+			body, res = makeJSON(t, ts, "GET", "/admin"+client.ClientsHandlerPath+"/"+id, nil)
+			assert.Equal(t, http.StatusNotFound, res.StatusCode, body)
+			body, res = makeJSON(t, ts, "GET", "/admin"+client.ClientsHandlerPath+"?deleted=true&client_name=restore-deleted-client", nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.Equal(t, id, gjson.Get(body, "0.client_id").String(), body)
+			assert.True(t, gjson.Get(body, "0.deleted_at").Exists(), body)
+
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath+"/"+id+"/restore", nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.False(t, gjson.Get(body, "deleted_at").Exists(), body)
+			assert.False(t, gjson.Get(body, "client_secret").Exists(), body)
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath+"/"+id+"/restore", nil)
+			assert.Equal(t, http.StatusNotFound, res.StatusCode, body)
+			body, res = makeJSON(t, ts, "GET", "/admin"+client.ClientsHandlerPath+"/"+id, nil)
+			assert.Equal(t, http.StatusOK, res.StatusCode, body)
+		})
+
+		t.Run("case=deleted_at can not be set when creating or updating a client", func(t *testing.T) {
+			deletedAt := time.Now().UTC().Round(time.Second)
+			for _, path := range []string{"/admin" + client.ClientsHandlerPath, client.DynClientsHandlerPath} {
+				body, res := makeJSON(t, ts, "POST", path, &client.Client{RedirectURIs: []string{"http://localhost:3000/cb"}, DeletedAt: &deletedAt})
+				require.Equal(t, http.StatusCreated, res.StatusCode, body)
+				assert.False(t, gjson.Get(body, "deleted_at").Exists(), body)
+
+				body, res = makeJSON(t, ts, "GET", "/admin"+client.ClientsHandlerPath+"/"+gjson.Get(body, "client_id").String(), nil)
+				assert.Equal(t, http.StatusOK, res.StatusCode, body)
+			}
+
+			expected := createClient(t, &client.Client{RedirectURIs: []string{"http://localhost:3000/cb"}}, ts, client.ClientsHandlerPath)
+			id := getClientID(expected)
+			payload, _ := sjson.Set(expected, "deleted_at", deletedAt)
+			body, res := makeJSON(t, ts, "PUT", "/admin"+client.ClientsHandlerPath+"/"+id, json.RawMessage(payload))
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			body, res = makeJSON(t, ts, "GET", "/admin"+client.ClientsHandlerPath+"/"+id, nil)
+			assert.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.False(t, gjson.Get(body, "deleted_at").Exists(), body)
+		})
+
+		t.Run("case=disable and enable a client", func(t *testing.T) {
+			body, res := makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath, &client.Client{Name: "disable-client", Secret: "averylongsecret"})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			id := gjson.Get(body, "client_id").String()
+
+			signature := uuid.Must(uuid.NewV4()).String()
+			require.NoError(t, reg.OAuth2Storage().CreateAccessTokenSession(ctx, signature, &fosite.Request{
+				ID:          uuid.Must(uuid.NewV4()).String(),
+				Client:      &client.Client{LegacyClientID: id},
+				RequestedAt: time.Now().UTC().Round(time.Second),
+				Session:     oauth2.NewSession("foo"),
+			}))
+
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath+"/"+id+"/disable", map[string]bool{"revoke_tokens": true})
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.True(t, gjson.Get(body, "disabled").Bool(), body)
+			assert.False(t, gjson.Get(body, "client_secret").Exists(), body)
+
+			_, err := reg.ClientManager().Authenticate(ctx, id, []byte("averylongsecret"))
+			assert.ErrorIs(t, err, client.ErrClientDisabled)
+			_, err = reg.OAuth2Storage().GetAccessTokenSession(ctx, signature, oauth2.NewSession(""))
+			assert.ErrorIs(t, err, fosite.ErrNotFound)
+
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath+"/"+id+"/enable", nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.False(t, gjson.Get(body, "disabled").Exists(), body)
+			_, err = reg.ClientManager().Authenticate(ctx, id, []byte("averylongsecret"))
+			assert.NoError(t, err)
+
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath+"/does-not-exist/disable", nil)
+			assert.Equal(t, http.StatusNotFound, res.StatusCode, body)
+		})
+
+		t.Run("case=disabled can only be changed using the disable and enable endpoints", func(t *testing.T) {
+			body, res := makeJSON(t, ts, "POST", client.DynClientsHandlerPath, &client.Client{RedirectURIs: []string{"http://localhost:3000/cb"}, Disabled: true})
+			require.Equal(t, http.StatusCreated, res.StatusCode, body)
+			assert.False(t, gjson.Get(body, "disabled").Exists(), body)
+
+			expected := createClient(t, &client.Client{RedirectURIs: []string{"http://localhost:3000/cb"}}, ts, client.ClientsHandlerPath)
+			id := getClientID(expected)
+			payload, _ := sjson.Set(expected, "disabled", true)
+			body, res = makeJSON(t, ts, "PUT", "/admin"+client.ClientsHandlerPath+"/"+id, json.RawMessage(payload))
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			body, res = makeJSON(t, ts, "GET", "/admin"+client.ClientsHandlerPath+"/"+id, nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.False(t, gjson.Get(body, "disabled").Exists(), body)
+
+			body, res = makeJSON(t, ts, "POST", "/admin"+client.ClientsHandlerPath+"/"+id+"/disable", nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			payload, _ = sjson.Set(expected, "disabled", false)
+			body, res = makeJSON(t, ts, "PUT", "/admin"+client.ClientsHandlerPath+"/"+id, json.RawMessage(payload))
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			body, res = makeJSON(t, ts, "GET", "/admin"+client.ClientsHandlerPath+"/"+id, nil)
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.True(t, gjson.Get(body, "disabled").Bool(), body)
+		})
+
 		t.Run("case=delete existing client", func(t *testing.T) {
 			t.Run("endpoint=admin", func(t *testing.T) {
 				expected := createClient(t, &client.Client{
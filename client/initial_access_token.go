@@ -0,0 +1,76 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/hydra/x"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlxx"
+)
+
+// OAuth 2.0 Dynamic Client Registration Initial Access Token
+//
+// An initial access token allows registering OAuth 2.0 Clients using OpenID Connect Dynamic Client Registration.
+//
+// swagger:model oAuth2InitialAccessToken
+type InitialAccessToken struct {
+	// The ID of the initial access token.
+	ID  uuid.UUID `json:"id" db:"id"`
+	NID uuid.UUID `json:"-" db:"nid"`
+
+	// The initial access token. It is only returned when the token is created and can not be recovered afterwards.
+	Token string `json:"token,omitempty" db:"-"`
+
+	// Signature is the signature of the token, which is stored instead of the token.
+	Signature string `json:"-" db:"signature"`
+
+	// The time the initial access token was created at.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// The time the initial access token expires at. If not set, the token does not expire.
+	ExpiresAt sqlxx.NullTime `json:"expires_at" db:"expires_at"`
+
+	// The maximum number of clients which can be registered with this token. Zero means unlimited.
+	MaxRegistrations int `json:"max_registrations" db:"max_registrations"`
+
+	// The number of clients which have been registered with this token.
+	Registrations int `json:"registrations" db:"registrations"`
+}
+
+func (InitialAccessToken) TableName() string {
+	return "hydra_client_initial_access_token"
+}
+
+// PageToken returns the token of the page which starts after this initial access token.
+func (t InitialAccessToken) PageToken() string {
+	return x.EncodePageToken(t.ID.String())
+}
+
+// OAuth 2.0 Dynamic Client Registration Initial Access Token Request
+//
+// swagger:model createOAuth2InitialAccessTokenRequest
+type CreateInitialAccessTokenRequest struct {
+	// The time the initial access token expires at. If not set, the token does not expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// The maximum number of clients which can be registered with the token. Zero means unlimited.
+	MaxRegistrations int `json:"max_registrations"`
+}
+
+type InitialAccessTokenStorage interface {
+	CreateInitialAccessToken(ctx context.Context, t *InitialAccessToken) error
+
+	GetInitialAccessTokens(ctx context.Context, pageOpts ...keysetpagination.Option) ([]InitialAccessToken, *keysetpagination.Paginator, error)
+
+	DeleteInitialAccessToken(ctx context.Context, id string) error
+
+	// UseInitialAccessToken counts a registration against the initial access token with the given signature. It
+	// returns sqlcon.ErrNoRows if the token does not exist, has expired, or has been used up.
+	UseInitialAccessToken(ctx context.Context, signature string) error
+}
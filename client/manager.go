@@ -5,19 +5,16 @@ package client
 
 import (
 	"context"
+	"time"
 
 	"github.com/ory/fosite"
+	"github.com/ory/x/pagination/keysetpagination"
 )
 
 // swagger:ignore
 type Filter struct {
-	// The maximum amount of clients to returned, upper bound is 500 clients.
-	// in: query
-	Limit int `json:"limit"`
-
-	// The offset from where to start looking.
-	// in: query
-	Offset int `json:"offset"`
+	// PageOpts configures the page size and the page token of the keyset pagination.
+	PageOpts []keysetpagination.Option `json:"-"`
 
 	// The name of the clients to filter by.
 	// in: query
@@ -26,8 +23,61 @@ type Filter struct {
 	// The owner of the clients to filter by.
 	// in: query
 	Owner string `json:"owner"`
+
+	// Only return clients whose secret expires at or before this time, as seconds since the Unix epoch.
+	// in: query
+	SecretExpiresBefore int64 `json:"client_secret_expires_before"`
+
+	// Only return clients which are allowed to use this grant type. This and the RedirectURIHost, Audience and
+	// Metadata filters match within JSON columns and can not use an index, so they scan all clients which match the
+	// other filters.
+	GrantType string `json:"grant_type"`
+
+	// Only return clients with a redirect URI on this host, for example `app.example.com` or `localhost:3000`.
+	RedirectURIHost string `json:"redirect_uri_host"`
+
+	// Only return clients which are allowed to request this audience.
+	Audience string `json:"audience"`
+
+	// Only return clients which use this token endpoint authentication method.
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method"`
+
+	// Only return clients created at or after this time.
+	CreatedAfter time.Time `json:"created_after"`
+
+	// Only return clients created before this time.
+	CreatedBefore time.Time `json:"created_before"`
+
+	// Only return clients updated at or after this time.
+	UpdatedAfter time.Time `json:"updated_after"`
+
+	// Only return clients updated before this time.
+	UpdatedBefore time.Time `json:"updated_before"`
+
+	// Metadata maps dot-separated JSON paths into the client's metadata to the value they must have,
+	// for example `tenant.region` to `eu`. Values are compared as strings.
+	Metadata map[string]string `json:"metadata"`
+
+	// Deleted returns only soft-deleted clients instead of only clients which are not deleted.
+	Deleted bool `json:"deleted"`
+
+	// SortBy is one of SortByCreatedAt, SortByUpdatedAt or SortByName. If empty, clients are
+	// returned in the order of their internal primary key.
+	SortBy string `json:"sort_by"`
+
+	// SortOrder is either SortOrderAscending (default) or SortOrderDescending.
+	SortOrder string `json:"sort_order"`
 }
 
+const (
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+	SortByName      = "client_name"
+
+	SortOrderAscending  = "asc"
+	SortOrderDescending = "desc"
+)
+
 type Manager interface {
 	Storage
 
@@ -35,17 +85,41 @@ type Manager interface {
 }
 
 type Storage interface {
+	InitialAccessTokenStorage
+	TemplateStorage
+
 	GetClient(ctx context.Context, id string) (fosite.Client, error)
 
 	CreateClient(ctx context.Context, c *Client) error
 
 	UpdateClient(ctx context.Context, c *Client) error
 
+	// DeleteClient soft-deletes the client. A soft-deleted client is treated as if it does not exist until it is
+	// restored or purged.
 	DeleteClient(ctx context.Context, id string) error
 
-	GetClients(ctx context.Context, filters Filter) ([]Client, error)
+	// RestoreClient restores a soft-deleted client. It returns sqlcon.ErrNoRows if the client is not soft-deleted.
+	RestoreClient(ctx context.Context, id string) error
+
+	// SetClientDisabled disables or enables the client. Disabled clients are rejected by GetClient and Authenticate,
+	// but are still returned by GetConcreteClient.
+	SetClientDisabled(ctx context.Context, id string, disabled bool) error
+
+	// FlushDeletedClients purges clients which were soft-deleted before notAfter, but not before the configured
+	// retention period has passed.
+	FlushDeletedClients(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
+
+	GetClients(ctx context.Context, filters Filter) ([]Client, *keysetpagination.Paginator, error)
 
 	CountClients(ctx context.Context) (int, error)
 
+	// GetAllowedCORSOrigins returns the distinct origins which clients that are neither deleted nor disabled allow
+	// cross-origin requests from.
+	GetAllowedCORSOrigins(ctx context.Context) ([]string, error)
+
 	GetConcreteClient(ctx context.Context, id string) (*Client, error)
+
+	// RotateClientSecret replaces the secret of the client with the given one. The previous secret remains
+	// valid until the grace period ends.
+	RotateClientSecret(ctx context.Context, id string, secret string, gracePeriod time.Duration) (*Client, error)
 }
@@ -15,7 +15,9 @@ import (
 
 	"github.com/ory/x/assertx"
 	"github.com/ory/x/contextx"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
 
 	"github.com/bxcodec/faker/v3"
 	"github.com/gofrs/uuid"
@@ -23,6 +25,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/ory/fosite"
+	"github.com/ory/herodot"
 
 	testhelpersuuid "github.com/ory/hydra/internal/testhelpers/uuid"
 	"github.com/ory/hydra/x"
@@ -61,6 +64,28 @@ func TestHelperClientAuthenticate(k string, m Manager) func(t *testing.T) {
 		c, err = m.Authenticate(ctx, "1234321", []byte("secret"))
 		require.NoError(t, err)
 		assert.Equal(t, "1234321", c.GetID())
+
+		_, err = m.RotateClientSecret(ctx, "1234321", "rotated", time.Hour)
+		require.NoError(t, err)
+		for _, secret := range []string{"secret", "rotated"} {
+			_, err = m.Authenticate(ctx, "1234321", []byte(secret))
+			require.NoError(t, err, secret)
+		}
+
+		_, err = m.RotateClientSecret(ctx, "1234321", "rotated-again", 0)
+		require.NoError(t, err)
+		_, err = m.Authenticate(ctx, "1234321", []byte("rotated"))
+		require.Error(t, err)
+		_, err = m.Authenticate(ctx, "1234321", []byte("secret"))
+		require.NoError(t, err)
+
+		require.NoError(t, m.UpdateClient(ctx, &Client{LegacyClientID: "1234321", RedirectURIs: []string{"http://redirect"}}))
+		_, err = m.Authenticate(ctx, "1234321", []byte("secret"))
+		require.NoError(t, err, "updating the client keeps the rotated secrets")
+
+		require.NoError(t, m.UpdateClient(ctx, &Client{LegacyClientID: "1234321", Secret: "expired", SecretExpiresAt: int(time.Now().Add(-time.Minute).Unix()), RedirectURIs: []string{"http://redirect"}}))
+		_, err = m.Authenticate(ctx, "1234321", []byte("expired"))
+		require.ErrorIs(t, err, fosite.ErrInvalidClient, "the client secret has expired")
 	}
 }
 
@@ -201,6 +226,343 @@ func TestHelperCreateGetUpdateDeleteClientNext(t *testing.T, m Storage, networks
 	}
 }
 
+func TestHelperClientFilters(_ string, m Storage) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		owner := uuid.Must(uuid.NewV4()).String()
+		now := time.Now().Round(time.Second).UTC()
+
+		clients := []*Client{
+			{
+				LegacyClientID:          owner + "-a",
+				Name:                    "c",
+				Owner:                   owner,
+				GrantTypes:              []string{"client_credentials"},
+				RedirectURIs:            []string{"https://app.example.com/callback"},
+				Audience:                []string{"https://api.example.com"},
+				TokenEndpointAuthMethod: "private_key_jwt",
+				Metadata:                []byte(`{"tenant":{"region":"eu","tier":1}}`),
+				CreatedAt:               now.Add(-3 * time.Hour),
+			},
+			{
+				LegacyClientID:          owner + "-b",
+				Name:                    "a",
+				Owner:                   owner,
+				GrantTypes:              []string{"authorization_code"},
+				RedirectURIs:            []string{"http://localhost:3000?state=1"},
+				Audience:                []string{"https://other.example.com"},
+				TokenEndpointAuthMethod: "client_secret_basic",
+				Metadata:                []byte(`{"tenant":{"region":"us"}}`),
+				CreatedAt:               now.Add(-2 * time.Hour),
+			},
+			{
+				LegacyClientID:          owner + "-c",
+				Name:                    "b",
+				Owner:                   owner,
+				GrantTypes:              []string{"authorization_code", "refresh_token"},
+				RedirectURIs:            []string{"https://app.example.company/callback", "https://api-example.com/"},
+				TokenEndpointAuthMethod: "none",
+				Metadata:                []byte(`{}`),
+				CreatedAt:               now.Add(-time.Hour),
+			},
+		}
+		for _, c := range clients {
+			require.NoError(t, m.CreateClient(ctx, c))
+		}
+
+		ids := func(t *testing.T, f Filter) []string {
+			f.Owner = owner
+			cs, _, err := m.GetClients(ctx, f)
+			require.NoError(t, err)
+			ids := make([]string, len(cs))
+			for k := range cs {
+				ids[k] = cs[k].GetID()
+			}
+			return ids
+		}
+
+		for k, tc := range []struct {
+			f        Filter
+			expected []string
+		}{
+			{f: Filter{GrantType: "authorization_code"}, expected: []string{"b", "c"}},
+			{f: Filter{GrantType: "refresh_token"}, expected: []string{"c"}},
+			{f: Filter{GrantType: "refresh"}, expected: []string{}},
+			{f: Filter{RedirectURIHost: "app.example.com"}, expected: []string{"a"}},
+			{f: Filter{RedirectURIHost: "localhost:3000"}, expected: []string{"b"}},
+			{f: Filter{RedirectURIHost: "localhost"}, expected: []string{"b"}},
+			{f: Filter{RedirectURIHost: "api_example.com"}, expected: []string{}},
+			{f: Filter{Audience: "https://api.example.com"}, expected: []string{"a"}},
+			{f: Filter{TokenEndpointAuthMethod: "none"}, expected: []string{"c"}},
+			{f: Filter{CreatedAfter: now.Add(-2 * time.Hour)}, expected: []string{"b", "c"}},
+			{f: Filter{CreatedBefore: now.Add(-2 * time.Hour)}, expected: []string{"a"}},
+			{f: Filter{UpdatedAfter: now.Add(time.Hour)}, expected: []string{}},
+			{f: Filter{UpdatedBefore: now.Add(time.Hour)}, expected: []string{"a", "b", "c"}},
+			{f: Filter{Metadata: map[string]string{"tenant.region": "eu"}}, expected: []string{"a"}},
+			{f: Filter{Metadata: map[string]string{"tenant.region": "us", "tenant.tier": "1"}}, expected: []string{}},
+			{f: Filter{Metadata: map[string]string{"tenant.tier": "1"}}, expected: []string{"a"}},
+			{f: Filter{SortBy: SortByName}, expected: []string{"b", "c", "a"}},
+			{f: Filter{SortBy: SortByCreatedAt, SortOrder: SortOrderDescending}, expected: []string{"c", "b", "a"}},
+			{f: Filter{GrantType: "authorization_code", SortBy: SortByName, SortOrder: SortOrderDescending}, expected: []string{"c", "b"}},
+		} {
+			t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+				expected := make([]string, len(tc.expected))
+				for i, id := range tc.expected {
+					expected[i] = owner + "-" + id
+				}
+				actual := ids(t, tc.f)
+				if tc.f.SortBy == "" {
+					assert.ElementsMatch(t, expected, actual)
+				} else {
+					assert.Equal(t, expected, actual)
+				}
+			})
+		}
+
+		t.Run("case=sorted pages", func(t *testing.T) {
+			for _, sortBy := range []string{SortByName, SortByCreatedAt, SortByUpdatedAt} {
+				for _, order := range []string{SortOrderAscending, SortOrderDescending} {
+					expected := ids(t, Filter{SortBy: sortBy, SortOrder: order})
+					require.Len(t, expected, 3)
+
+					var actual []string
+					opts := []keysetpagination.Option{keysetpagination.WithSize(1)}
+					for {
+						cs, nextPage, err := m.GetClients(ctx, Filter{Owner: owner, SortBy: sortBy, SortOrder: order, PageOpts: opts})
+						require.NoError(t, err)
+						for _, c := range cs {
+							actual = append(actual, c.GetID())
+						}
+						if nextPage.IsLast() {
+							break
+						}
+						opts = nextPage.ToOptions()
+						require.Less(t, len(actual), 4)
+					}
+					assert.Equal(t, expected, actual, "%s %s", sortBy, order)
+				}
+			}
+		})
+
+		t.Run("case=invalid sort options", func(t *testing.T) {
+			_, _, err := m.GetClients(ctx, Filter{SortBy: "secret"})
+			assert.ErrorIs(t, err, herodot.ErrBadRequest)
+			_, _, err = m.GetClients(ctx, Filter{SortBy: SortByName, SortOrder: "up"})
+			assert.ErrorIs(t, err, herodot.ErrBadRequest)
+			_, _, err = m.GetClients(ctx, Filter{Metadata: map[string]string{"tenant..region": "eu"}})
+			assert.ErrorIs(t, err, herodot.ErrBadRequest)
+		})
+	}
+}
+
+func TestHelperInitialAccessTokens(_ string, m Storage) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+
+		limited := &InitialAccessToken{Signature: uuid.Must(uuid.NewV4()).String(), CreatedAt: time.Now().UTC(), MaxRegistrations: 2}
+		expired := &InitialAccessToken{Signature: uuid.Must(uuid.NewV4()).String(), CreatedAt: time.Now().UTC(), ExpiresAt: sqlxx.NullTime(time.Now().UTC().Add(-time.Minute))}
+		unlimited := &InitialAccessToken{Signature: uuid.Must(uuid.NewV4()).String(), CreatedAt: time.Now().UTC(), ExpiresAt: sqlxx.NullTime(time.Now().UTC().Add(time.Hour))}
+		for _, token := range []*InitialAccessToken{limited, expired, unlimited} {
+			require.NoError(t, m.CreateInitialAccessToken(ctx, token))
+			require.NotEqual(t, uuid.Nil, token.ID)
+		}
+
+		require.NoError(t, m.UseInitialAccessToken(ctx, limited.Signature))
+		require.NoError(t, m.UseInitialAccessToken(ctx, limited.Signature))
+		assert.ErrorIs(t, m.UseInitialAccessToken(ctx, limited.Signature), sqlcon.ErrNoRows)
+		assert.ErrorIs(t, m.UseInitialAccessToken(ctx, expired.Signature), sqlcon.ErrNoRows)
+		assert.ErrorIs(t, m.UseInitialAccessToken(ctx, "unknown"), sqlcon.ErrNoRows)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, m.UseInitialAccessToken(ctx, unlimited.Signature))
+		}
+
+		var listed []InitialAccessToken
+		opts := []keysetpagination.Option{keysetpagination.WithSize(1)}
+		for {
+			ts, nextPage, err := m.GetInitialAccessTokens(ctx, opts...)
+			require.NoError(t, err)
+			listed = append(listed, ts...)
+			if nextPage.IsLast() {
+				break
+			}
+			opts = nextPage.ToOptions()
+		}
+		registrations := map[uuid.UUID]int{}
+		for _, token := range listed {
+			registrations[token.ID] = token.Registrations
+		}
+		assert.Equal(t, 2, registrations[limited.ID])
+		assert.Equal(t, 0, registrations[expired.ID])
+		assert.Equal(t, 3, registrations[unlimited.ID])
+
+		require.NoError(t, m.DeleteInitialAccessToken(ctx, unlimited.ID.String()))
+		assert.ErrorIs(t, m.UseInitialAccessToken(ctx, unlimited.Signature), sqlcon.ErrNoRows)
+		assert.ErrorIs(t, m.DeleteInitialAccessToken(ctx, unlimited.ID.String()), sqlcon.ErrNoRows)
+	}
+}
+
+func TestHelperClientTemplates(_ string, m Storage) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		prefix := uuid.Must(uuid.NewV4()).String()
+
+		web := &Template{
+			Name:                    prefix + "-web",
+			GrantTypes:              []string{"authorization_code", "refresh_token"},
+			ResponseTypes:           []string{"code"},
+			TokenEndpointAuthMethod: "client_secret_post",
+			Metadata:                []byte(`{"team":"web"}`),
+			Enforce:                 true,
+			Lifespans:               Lifespans{AuthorizationCodeGrantAccessTokenLifespan: x.NullDuration{Duration: time.Hour, Valid: true}},
+		}
+		service := &Template{Name: prefix + "-service", GrantTypes: []string{"client_credentials"}}
+		for _, tmpl := range []*Template{web, service} {
+			require.NoError(t, m.CreateClientTemplate(ctx, tmpl))
+			require.NotEqual(t, uuid.Nil, tmpl.ID)
+		}
+		assert.ErrorIs(t, m.CreateClientTemplate(ctx, &Template{Name: web.Name}), sqlcon.ErrUniqueViolation)
+
+		actual, err := m.GetClientTemplate(ctx, web.Name)
+		require.NoError(t, err)
+		assert.Equal(t, web.GrantTypes, actual.GrantTypes)
+		assert.Equal(t, web.TokenEndpointAuthMethod, actual.TokenEndpointAuthMethod)
+		assert.JSONEq(t, `{"team":"web"}`, string(actual.Metadata))
+		assert.True(t, actual.Enforce)
+		assert.Equal(t, time.Hour, actual.AuthorizationCodeGrantAccessTokenLifespan.Duration)
+
+		service.Enforce = true
+		require.NoError(t, m.UpdateClientTemplate(ctx, service))
+		actual, err = m.GetClientTemplate(ctx, service.Name)
+		require.NoError(t, err)
+		assert.True(t, actual.Enforce)
+		assert.ErrorIs(t, m.UpdateClientTemplate(ctx, &Template{Name: prefix + "-unknown"}), sqlcon.ErrNoRows)
+
+		var listed []string
+		opts := []keysetpagination.Option{keysetpagination.WithSize(1)}
+		for {
+			ts, nextPage, err := m.GetClientTemplates(ctx, opts...)
+			require.NoError(t, err)
+			for _, tmpl := range ts {
+				listed = append(listed, tmpl.Name)
+			}
+			if nextPage.IsLast() {
+				break
+			}
+			opts = nextPage.ToOptions()
+		}
+		assert.Contains(t, listed, web.Name)
+		assert.Contains(t, listed, service.Name)
+
+		c := &Client{LegacyClientID: prefix, Secret: "secret", Template: web.Name}
+		require.NoError(t, m.CreateClient(ctx, c))
+		assert.ErrorIs(t, m.DeleteClientTemplate(ctx, web.Name), ErrClientTemplateInUse)
+		require.NoError(t, m.DeleteClient(ctx, c.GetID()))
+		assert.ErrorIs(t, m.DeleteClientTemplate(ctx, web.Name), ErrClientTemplateInUse, "soft-deleted clients can be restored")
+		require.NoError(t, m.RestoreClient(ctx, c.GetID()))
+		c.Template, c.Secret = "", ""
+		require.NoError(t, m.UpdateClient(ctx, c))
+		require.NoError(t, m.DeleteClientTemplate(ctx, web.Name))
+
+		_, err = m.GetClientTemplate(ctx, web.Name)
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		assert.ErrorIs(t, m.DeleteClientTemplate(ctx, web.Name), sqlcon.ErrNoRows)
+	}
+}
+
+func TestHelperSoftDeleteClient(_ string, m Manager) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		c := &Client{LegacyClientID: uuid.Must(uuid.NewV4()).String(), Secret: "secret", Name: "soft-deleted"}
+		require.NoError(t, m.CreateClient(ctx, c))
+		before, err := m.CountClients(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, m.DeleteClient(ctx, c.GetID()))
+		_, err = m.GetClient(ctx, c.GetID())
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		_, err = m.Authenticate(ctx, c.GetID(), []byte("secret"))
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		assert.ErrorIs(t, m.DeleteClient(ctx, c.GetID()), sqlcon.ErrNoRows)
+
+		after, err := m.CountClients(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, before-1, after)
+
+		active, _, err := m.GetClients(ctx, Filter{Name: c.Name})
+		require.NoError(t, err)
+		assert.Empty(t, active)
+		deleted, _, err := m.GetClients(ctx, Filter{Name: c.Name, Deleted: true})
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		assert.NotNil(t, deleted[0].DeletedAt)
+
+		require.NoError(t, m.RestoreClient(ctx, c.GetID()))
+		assert.ErrorIs(t, m.RestoreClient(ctx, c.GetID()), sqlcon.ErrNoRows)
+		restored, err := m.Authenticate(ctx, c.GetID(), []byte("secret"))
+		require.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+	}
+}
+
+func TestHelperDisableClient(_ string, m Manager) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		c := &Client{LegacyClientID: uuid.Must(uuid.NewV4()).String(), Secret: "secret", Name: "disabled"}
+		require.NoError(t, m.CreateClient(ctx, c))
+
+		require.NoError(t, m.SetClientDisabled(ctx, c.GetID(), true))
+		_, err := m.GetClient(ctx, c.GetID())
+		assert.ErrorIs(t, err, ErrClientDisabled)
+		_, err = m.Authenticate(ctx, c.GetID(), []byte("secret"))
+		assert.ErrorIs(t, err, ErrClientDisabled)
+
+		disabled, err := m.GetConcreteClient(ctx, c.GetID())
+		require.NoError(t, err)
+		assert.True(t, disabled.Disabled)
+		assert.Equal(t, c.Secret, disabled.Secret)
+
+		require.NoError(t, m.SetClientDisabled(ctx, c.GetID(), false))
+		enabled, err := m.Authenticate(ctx, c.GetID(), []byte("secret"))
+		require.NoError(t, err)
+		assert.False(t, enabled.Disabled)
+
+		assert.ErrorIs(t, m.SetClientDisabled(ctx, uuid.Must(uuid.NewV4()).String(), true), sqlcon.ErrNoRows)
+	}
+}
+
+func TestHelperAllowedCORSOrigins(_ string, m Manager) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		suffix := uuid.Must(uuid.NewV4()).String()
+		origin := func(name string) string { return "https://" + name + "-" + suffix + ".example.com" }
+
+		active := &Client{LegacyClientID: uuid.Must(uuid.NewV4()).String(), AllowedCORSOrigins: []string{origin("a"), origin("b")}}
+		duplicate := &Client{LegacyClientID: uuid.Must(uuid.NewV4()).String(), AllowedCORSOrigins: []string{origin("b")}}
+		disabled := &Client{LegacyClientID: uuid.Must(uuid.NewV4()).String(), AllowedCORSOrigins: []string{origin("disabled")}}
+		deleted := &Client{LegacyClientID: uuid.Must(uuid.NewV4()).String(), AllowedCORSOrigins: []string{origin("deleted")}}
+		for _, c := range []*Client{active, duplicate, disabled, deleted} {
+			require.NoError(t, m.CreateClient(ctx, c))
+		}
+		require.NoError(t, m.SetClientDisabled(ctx, disabled.GetID(), true))
+		require.NoError(t, m.DeleteClient(ctx, deleted.GetID()))
+
+		origins, err := m.GetAllowedCORSOrigins(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, origins, origin("a"))
+		assert.Contains(t, origins, origin("b"))
+		assert.NotContains(t, origins, origin("disabled"))
+		assert.NotContains(t, origins, origin("deleted"))
+
+		var count int
+		for _, o := range origins {
+			if o == origin("b") {
+				count++
+			}
+		}
+		assert.Equal(t, 1, count, "origins must not be duplicated")
+	}
+}
+
 func TestHelperCreateGetUpdateDeleteClient(k string, connection *pop.Connection, t1 Storage, t2 Storage) func(t *testing.T) {
 	return func(t *testing.T) {
 		ctx := context.Background()
@@ -208,37 +570,50 @@ func TestHelperCreateGetUpdateDeleteClient(k string, connection *pop.Connection,
 		require.Error(t, err)
 
 		t1c1 := &Client{
-			ID:                                uuid.FromStringOrNil("96bfe52e-af88-4cba-ab00-ae7a8b082228"),
-			LegacyClientID:                    "1234",
-			Name:                              "name",
-			Secret:                            "secret",
-			RedirectURIs:                      []string{"http://redirect", "http://redirect1"},
-			GrantTypes:                        []string{"implicit", "refresh_token"},
-			ResponseTypes:                     []string{"code token", "token id_token", "code"},
-			Scope:                             "scope-a scope-b",
-			Owner:                             "aeneas",
-			PolicyURI:                         "http://policy",
-			TermsOfServiceURI:                 "http://tos",
-			ClientURI:                         "http://client",
-			LogoURI:                           "http://logo",
-			Contacts:                          []string{"aeneas1", "aeneas2"},
-			SecretExpiresAt:                   0,
-			SectorIdentifierURI:               "https://sector",
-			JSONWebKeys:                       &x.JoseJSONWebKeySet{JSONWebKeySet: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{KeyID: "foo", Key: []byte("asdf"), Certificates: []*x509.Certificate{}, CertificateThumbprintSHA1: []uint8{}, CertificateThumbprintSHA256: []uint8{}}}}},
-			JSONWebKeysURI:                    "https://...",
-			TokenEndpointAuthMethod:           "none",
-			TokenEndpointAuthSigningAlgorithm: "RS256",
-			RequestURIs:                       []string{"foo", "bar"},
-			AllowedCORSOrigins:                []string{"foo", "bar"},
-			RequestObjectSigningAlgorithm:     "rs256",
-			UserinfoSignedResponseAlg:         "RS256",
-			CreatedAt:                         time.Now().Add(-time.Hour).Round(time.Second).UTC(),
-			UpdatedAt:                         time.Now().Add(-time.Minute).Round(time.Second).UTC(),
-			FrontChannelLogoutURI:             "http://fc-logout",
-			FrontChannelLogoutSessionRequired: true,
-			PostLogoutRedirectURIs:            []string{"hello", "mister"},
-			BackChannelLogoutURI:              "http://bc-logout",
-			BackChannelLogoutSessionRequired:  true,
+			ID:                                 uuid.FromStringOrNil("96bfe52e-af88-4cba-ab00-ae7a8b082228"),
+			LegacyClientID:                     "1234",
+			Name:                               "name",
+			Secret:                             "secret",
+			RedirectURIs:                       []string{"http://redirect", "http://redirect1"},
+			GrantTypes:                         []string{"implicit", "refresh_token"},
+			ResponseTypes:                      []string{"code token", "token id_token", "code"},
+			Scope:                              "scope-a scope-b",
+			Owner:                              "aeneas",
+			PolicyURI:                          "http://policy",
+			TermsOfServiceURI:                  "http://tos",
+			ClientURI:                          "http://client",
+			LogoURI:                            "http://logo",
+			Contacts:                           []string{"aeneas1", "aeneas2"},
+			SecretExpiresAt:                    0,
+			SectorIdentifierURI:                "https://sector",
+			JSONWebKeys:                        &x.JoseJSONWebKeySet{JSONWebKeySet: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{KeyID: "foo", Key: []byte("asdf"), Certificates: []*x509.Certificate{}, CertificateThumbprintSHA1: []uint8{}, CertificateThumbprintSHA256: []uint8{}}}}},
+			JSONWebKeysURI:                     "https://...",
+			TokenEndpointAuthMethod:            "none",
+			TokenEndpointAuthSigningAlgorithm:  "RS256",
+			RequestURIs:                        []string{"foo", "bar"},
+			AllowedCORSOrigins:                 []string{"foo", "bar"},
+			RequestObjectSigningAlgorithm:      "rs256",
+			UserinfoSignedResponseAlg:          "RS256",
+			CreatedAt:                          time.Now().Add(-time.Hour).Round(time.Second).UTC(),
+			UpdatedAt:                          time.Now().Add(-time.Minute).Round(time.Second).UTC(),
+			FrontChannelLogoutURI:              "http://fc-logout",
+			FrontChannelLogoutSessionRequired:  true,
+			PostLogoutRedirectURIs:             []string{"hello", "mister"},
+			BackChannelLogoutURI:               "http://bc-logout",
+			BackChannelLogoutSessionRequired:   true,
+			RequirePushedAuthorizationRequests: true,
+			TokenExchangePolicy: &TokenExchangePolicy{
+				AllowedSubjectClients: []string{"foo"},
+				AllowedSubjectIssuers: []string{"https://issuer.example.com"},
+				AllowedActorClients:   []string{"bar"},
+			},
+			TLSClientAuthSubjectDN:                "CN=client,O=Example",
+			TLSClientCertificateBoundAccessTokens: true,
+			BackchannelTokenDeliveryMode:          BackchannelTokenDeliveryModePing,
+			BackchannelClientNotificationEndpoint: "https://example.com/ciba",
+			AuthorizationSignedResponseAlg:        "RS256",
+			AuthorizationEncryptedResponseAlg:     "RSA-OAEP-256",
+			AuthorizationEncryptedResponseEnc:     "A256GCM",
 		}
 
 		require.NoError(t, t1.CreateClient(ctx, t1c1))
@@ -283,7 +658,7 @@ func TestHelperCreateGetUpdateDeleteClient(k string, connection *pop.Connection,
 
 		compare(t, t1c1, d, k)
 
-		ds, err := t1.GetClients(ctx, Filter{Limit: 100, Offset: 0})
+		ds, _, err := t1.GetClients(ctx, Filter{PageOpts: []keysetpagination.Option{keysetpagination.WithSize(100)}})
 		assert.NoError(t, err)
 		assert.Len(t, ds, 2)
 		assert.NotEqual(t, ds[0].GetID(), ds[1].GetID())
@@ -292,26 +667,33 @@ func TestHelperCreateGetUpdateDeleteClient(k string, connection *pop.Connection,
 		assert.Equal(t, ds[0].SecretExpiresAt, 0)
 		assert.Equal(t, ds[1].SecretExpiresAt, 1)
 
-		ds, err = t1.GetClients(ctx, Filter{Limit: 1, Offset: 0})
+		ds, nextPage, err := t1.GetClients(ctx, Filter{PageOpts: []keysetpagination.Option{keysetpagination.WithSize(1)}})
 		assert.NoError(t, err)
 		assert.Len(t, ds, 1)
+		require.False(t, nextPage.IsLast())
 
-		ds, err = t1.GetClients(ctx, Filter{Limit: 100, Offset: 100})
+		next, nextPage, err := t1.GetClients(ctx, Filter{PageOpts: nextPage.ToOptions()})
 		assert.NoError(t, err)
+		require.Len(t, next, 1)
+		assert.NotEqual(t, ds[0].GetID(), next[0].GetID())
+		assert.True(t, nextPage.IsLast())
+
+		_, _, err = t1.GetClients(ctx, Filter{PageOpts: []keysetpagination.Option{keysetpagination.WithToken("not-a-page-token")}})
+		assert.Error(t, err)
 
 		// get by name
-		ds, err = t1.GetClients(ctx, Filter{Limit: 100, Offset: 0, Name: "name"})
+		ds, _, err = t1.GetClients(ctx, Filter{Name: "name"})
 		assert.NoError(t, err)
 		assert.Len(t, ds, 1)
 		assert.Equal(t, ds[0].Name, "name")
 
 		// get by name not exist
-		ds, err = t1.GetClients(ctx, Filter{Limit: 100, Offset: 0, Name: "bad name"})
+		ds, _, err = t1.GetClients(ctx, Filter{Name: "bad name"})
 		assert.NoError(t, err)
 		assert.Len(t, ds, 0)
 
 		// get by owner
-		ds, err = t1.GetClients(ctx, Filter{Limit: 100, Offset: 0, Owner: "aeneas"})
+		ds, _, err = t1.GetClients(ctx, Filter{Owner: "aeneas"})
 		assert.NoError(t, err)
 		assert.Len(t, ds, 1)
 		assert.Equal(t, ds[0].Owner, "aeneas")
@@ -364,6 +746,15 @@ func compare(t *testing.T, expected *Client, actual fosite.Client, k string) {
 		assert.EqualValues(t, expected.PostLogoutRedirectURIs, actual.PostLogoutRedirectURIs)
 		assert.EqualValues(t, expected.BackChannelLogoutURI, actual.BackChannelLogoutURI)
 		assert.EqualValues(t, expected.BackChannelLogoutSessionRequired, actual.BackChannelLogoutSessionRequired)
+		assert.EqualValues(t, expected.RequirePushedAuthorizationRequests, actual.RequirePushedAuthorizationRequests)
+		assert.EqualValues(t, expected.TokenExchangePolicy, actual.TokenExchangePolicy)
+		assert.EqualValues(t, expected.TLSClientAuthSubjectDN, actual.TLSClientAuthSubjectDN)
+		assert.EqualValues(t, expected.TLSClientCertificateBoundAccessTokens, actual.TLSClientCertificateBoundAccessTokens)
+		assert.EqualValues(t, expected.BackchannelTokenDeliveryMode, actual.BackchannelTokenDeliveryMode)
+		assert.EqualValues(t, expected.BackchannelClientNotificationEndpoint, actual.BackchannelClientNotificationEndpoint)
+		assert.EqualValues(t, expected.AuthorizationSignedResponseAlg, actual.AuthorizationSignedResponseAlg)
+		assert.EqualValues(t, expected.AuthorizationEncryptedResponseAlg, actual.AuthorizationEncryptedResponseAlg)
+		assert.EqualValues(t, expected.AuthorizationEncryptedResponseEnc, actual.AuthorizationEncryptedResponseEnc)
 	}
 
 	if actual, ok := actual.(fosite.OpenIDConnectClient); ok {
@@ -4,17 +4,25 @@
 package client
 
 import (
+	"context"
+
 	"github.com/ory/hydra/driver/config"
 
 	"github.com/ory/fosite"
 	foauth2 "github.com/ory/fosite/handler/oauth2"
 	"github.com/ory/hydra/jwk"
 	"github.com/ory/hydra/x"
+	"github.com/ory/hydra/x/events"
 )
 
 type InternalRegistry interface {
 	x.RegistryWriter
+	events.Registry
 	Registry
+
+	// PurgeClientCaches drops data which is cached for the clients of the network of the context, such as their
+	// allowed CORS origins. The handler calls it whenever a client is created, changed or deleted.
+	PurgeClientCaches(ctx context.Context)
 }
 
 type Registry interface {
@@ -23,5 +31,6 @@ type Registry interface {
 	ClientHasher() fosite.Hasher
 	OpenIDJWTStrategy() jwk.JWTSigner
 	OAuth2HMACStrategy() *foauth2.HMACSHAStrategy
+	OAuth2Storage() x.FositeStorer
 	config.Provider
 }
@@ -0,0 +1,71 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/errorsx"
+)
+
+// RotatedSecret is the hash of a previous client secret which is still accepted until it expires.
+type RotatedSecret struct {
+	Hash      string    `json:"hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RotatedSecrets is stored as a JSON array in the `rotated_secrets` column of the client.
+type RotatedSecrets []RotatedSecret
+
+// Active returns the rotated secrets which have not expired at the given time.
+func (s RotatedSecrets) Active(now time.Time) RotatedSecrets {
+	var active RotatedSecrets
+	for _, secret := range s {
+		if secret.ExpiresAt.After(now) {
+			active = append(active, secret)
+		}
+	}
+	return active
+}
+
+func (s *RotatedSecrets) Scan(value interface{}) error {
+	var v []byte
+	switch t := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		v = t
+	case string:
+		v = []byte(t)
+	default:
+		return errors.Errorf("unable to scan type %T into RotatedSecrets", value)
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	return errorsx.WithStack(json.Unmarshal(v, s))
+}
+
+func (s RotatedSecrets) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	value, err := json.Marshal(s)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	return string(value), nil
+}
+
+// SecretFingerprint identifies a hashed client secret in logs without revealing the hash itself.
+func SecretFingerprint(hash []byte) string {
+	sum := sha256.Sum256(hash)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,117 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+)
+
+// softwareStatementClientMetadata are the claims of a software statement which are applied to the client. These
+// are the client metadata defined by RFC 7591, so that a statement can not set anything which is not
+// allowed for dynamic client registration, such as the client's template, lifespans or token exchange policy.
+var softwareStatementClientMetadata = []string{
+	"redirect_uris", "token_endpoint_auth_method", "grant_types", "response_types", "client_name", "client_uri",
+	"logo_uri", "scope", "contacts", "tos_uri", "policy_uri", "jwks_uri", "jwks",
+}
+
+// ApplySoftwareStatement verifies the software statement of the client and overwrites the client's metadata with
+// the statement's claims. The statement must be signed with a key of a trusted JWT grant issuer whose subject
+// matches the statement. The trust relationship's scope is returned, as it limits the scope the client may register.
+func (v *Validator) ApplySoftwareStatement(ctx context.Context, c *Client) (allowedScope []string, err error) {
+	token, err := jwt.ParseSigned(c.SoftwareStatement)
+	if err != nil {
+		return nil, errorsx.WithStack(ErrInvalidSoftwareStatement.WithHint("Unable to parse the software statement.").WithDebug(err.Error()))
+	}
+
+	var unverified jwt.Claims
+	if err := token.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, errorsx.WithStack(ErrInvalidSoftwareStatement.WithHint("Unable to decode the claims of the software statement.").WithDebug(err.Error()))
+	}
+	if unverified.Issuer == "" {
+		return nil, errorsx.WithStack(ErrInvalidSoftwareStatement.WithHint("The software statement must contain the 'iss' claim."))
+	}
+
+	var keys []jose.JSONWebKey
+	if kid := token.Headers[0].KeyID; kid != "" {
+		key, err := v.r.OAuth2Storage().GetPublicKey(ctx, unverified.Issuer, unverified.Subject, kid)
+		if err != nil {
+			return nil, errorsx.WithStack(ErrUnapprovedSoftwareStatement.WithHintf("The issuer '%s' of the software statement is not trusted or does not have a key with ID '%s'.", unverified.Issuer, kid).WithDebug(err.Error()))
+		}
+		keys = append(keys, *key)
+	} else {
+		set, err := v.r.OAuth2Storage().GetPublicKeys(ctx, unverified.Issuer, unverified.Subject)
+		if err != nil {
+			return nil, errorsx.WithStack(ErrUnapprovedSoftwareStatement.WithHintf("The issuer '%s' of the software statement is not trusted.", unverified.Issuer).WithDebug(err.Error()))
+		}
+		keys = set.Keys
+	}
+
+	var verified *jose.JSONWebKey
+	var claims jwt.Claims
+	metadata := map[string]interface{}{}
+	for k := range keys {
+		if err := token.Claims(keys[k].Key, &claims, &metadata); err == nil {
+			verified = &keys[k]
+			break
+		}
+	}
+	if verified == nil {
+		return nil, errorsx.WithStack(ErrUnapprovedSoftwareStatement.WithHintf("The signature of the software statement could not be verified with the keys of the trusted issuer '%s'.", unverified.Issuer))
+	}
+
+	if err := claims.ValidateWithLeeway(jwt.Expected{Time: time.Now()}, time.Minute); err != nil {
+		return nil, errorsx.WithStack(ErrInvalidSoftwareStatement.WithHint("The software statement is expired or not yet valid.").WithDebug(err.Error()))
+	}
+
+	allowedScope, err = v.r.OAuth2Storage().GetPublicKeyScopes(ctx, claims.Issuer, claims.Subject, verified.KeyID)
+	if err != nil {
+		return nil, errorsx.WithStack(ErrUnapprovedSoftwareStatement.WithHintf("The issuer '%s' of the software statement is not trusted.", claims.Issuer).WithDebug(err.Error()))
+	}
+
+	allowed := map[string]interface{}{}
+	for _, claim := range softwareStatementClientMetadata {
+		if value, ok := metadata[claim]; ok {
+			allowed[claim] = value
+		}
+	}
+	raw, err := json.Marshal(allowed)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, errorsx.WithStack(ErrInvalidSoftwareStatement.WithHint("The claims of the software statement are not valid client metadata.").WithDebug(err.Error()))
+	}
+
+	if c.Scope == "" {
+		c.Scope = strings.Join(allowedScope, " ")
+	}
+	return allowedScope, nil
+}
+
+// ValidateSoftwareStatementPolicy checks that a client registered with a software statement only uses the grant
+// types allowed for software statements and the scope allowed by the statement's trust relationship.
+func (v *Validator) ValidateSoftwareStatementPolicy(ctx context.Context, c *Client, allowedScope []string) error {
+	allowedGrantTypes := v.r.Config().SoftwareStatementAllowedGrantTypes(ctx)
+	for _, gt := range c.GetGrantTypes() {
+		if !stringslice.Has(allowedGrantTypes, gt) {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Grant type '%s' can not be registered with a software statement.", gt))
+		}
+	}
+
+	for _, scope := range strings.Fields(c.Scope) {
+		if !stringslice.Has(allowedScope, scope) {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Scope '%s' is not allowed by the software statement's trusted issuer.", scope))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/hydra/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/stringslice"
+)
+
+// OAuth 2.0 Client Template
+//
+// A client template holds the defaults for OAuth 2.0 Clients which reference it by name in their `template` field.
+//
+// swagger:model oAuth2ClientTemplate
+type Template struct {
+	ID  uuid.UUID `json:"-" db:"id"`
+	NID uuid.UUID `json:"-" db:"nid"`
+
+	// The unique name of the template.
+	Name string `json:"name" db:"name"`
+
+	// The grant types of clients created from this template.
+	GrantTypes sqlxx.StringSliceJSONFormat `json:"grant_types" db:"grant_types"`
+
+	// The response types of clients created from this template.
+	ResponseTypes sqlxx.StringSliceJSONFormat `json:"response_types" db:"response_types"`
+
+	// The token endpoint authentication method of clients created from this template.
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method,omitempty" db:"token_endpoint_auth_method"`
+
+	// Metadata defaults. Top-level keys which are missing from a client's metadata are copied from here.
+	Metadata sqlxx.JSONRawMessage `json:"metadata,omitempty" db:"metadata"`
+
+	// If set, clients referencing this template are rejected unless they use exactly the template's grant types,
+	// response types, token endpoint authentication method, lifespans and metadata defaults.
+	Enforce bool `json:"enforce" db:"enforce"`
+
+	// The time the template was created at.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// The time the template was last updated at.
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	Lifespans
+}
+
+func (Template) TableName() string {
+	return "hydra_client_template"
+}
+
+// PageToken returns the token of the page which starts after this template.
+func (t Template) PageToken() string {
+	return x.EncodePageToken(t.Name)
+}
+
+func (t *Template) BeforeSave(_ *pop.Connection) error {
+	if t.Metadata == nil {
+		t.Metadata = []byte("{}")
+	}
+
+	if t.GrantTypes == nil {
+		t.GrantTypes = sqlxx.StringSliceJSONFormat{}
+	}
+
+	if t.ResponseTypes == nil {
+		t.ResponseTypes = sqlxx.StringSliceJSONFormat{}
+	}
+	return nil
+}
+
+// Apply fills the fields of the client which are not set with the template's values.
+func (t *Template) Apply(c *Client) error {
+	if len(c.GrantTypes) == 0 {
+		c.GrantTypes = append(sqlxx.StringSliceJSONFormat{}, t.GrantTypes...)
+	}
+	if len(c.ResponseTypes) == 0 {
+		c.ResponseTypes = append(sqlxx.StringSliceJSONFormat{}, t.ResponseTypes...)
+	}
+	if c.TokenEndpointAuthMethod == "" {
+		c.TokenEndpointAuthMethod = t.TokenEndpointAuthMethod
+	}
+
+	clientLifespans := c.lifespans()
+	for k, lifespan := range t.lifespans() {
+		if lifespan.Valid && !clientLifespans[k].Valid {
+			*clientLifespans[k] = *lifespan
+		}
+	}
+
+	defaults, err := metadataObject(t.Metadata)
+	if err != nil {
+		return err
+	}
+	if len(defaults) == 0 {
+		return nil
+	}
+
+	metadata, err := metadataObject(c.Metadata)
+	if err != nil {
+		return err
+	}
+	for k, v := range defaults {
+		if _, ok := metadata[k]; !ok {
+			metadata[k] = v
+		}
+	}
+	c.Metadata, err = json.Marshal(metadata)
+	return errorsx.WithStack(err)
+}
+
+// Match returns an error if the client differs from the template.
+func (t *Template) Match(c *Client) error {
+	if len(t.GrantTypes) > 0 && !sameElements(t.GrantTypes, c.GrantTypes) {
+		return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Field grant_types must be the grant types of client template '%s'.", t.Name))
+	}
+	if len(t.ResponseTypes) > 0 && !sameElements(t.ResponseTypes, c.ResponseTypes) {
+		return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Field response_types must be the response types of client template '%s'.", t.Name))
+	}
+	if t.TokenEndpointAuthMethod != "" && t.TokenEndpointAuthMethod != c.TokenEndpointAuthMethod {
+		return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Field token_endpoint_auth_method must be '%s' as required by client template '%s'.", t.TokenEndpointAuthMethod, t.Name))
+	}
+
+	clientLifespans := c.lifespans()
+	for k, lifespan := range t.lifespans() {
+		if lifespan.Valid && *clientLifespans[k] != *lifespan {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("The token lifespans must be the lifespans of client template '%s'.", t.Name))
+		}
+	}
+
+	defaults, err := metadataObject(t.Metadata)
+	if err != nil {
+		return err
+	}
+	metadata, err := metadataObject(c.Metadata)
+	if err != nil {
+		return err
+	}
+	for k, v := range defaults {
+		var expected, actual bytes.Buffer
+		if err := json.Compact(&expected, v); err != nil {
+			return errorsx.WithStack(err)
+		}
+		if err := json.Compact(&actual, metadata[k]); err != nil || !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Metadata key '%s' must have the value of client template '%s'.", k, t.Name))
+		}
+	}
+	return nil
+}
+
+// lifespans returns pointers to all lifespans, in the same order for every Lifespans value.
+func (l *Lifespans) lifespans() []*x.NullDuration {
+	return []*x.NullDuration{
+		&l.AuthorizationCodeGrantAccessTokenLifespan,
+		&l.AuthorizationCodeGrantIDTokenLifespan,
+		&l.AuthorizationCodeGrantRefreshTokenLifespan,
+		&l.ClientCredentialsGrantAccessTokenLifespan,
+		&l.ImplicitGrantAccessTokenLifespan,
+		&l.ImplicitGrantIDTokenLifespan,
+		&l.JwtBearerGrantAccessTokenLifespan,
+		&l.RefreshTokenGrantIDTokenLifespan,
+		&l.RefreshTokenGrantAccessTokenLifespan,
+		&l.RefreshTokenGrantRefreshTokenLifespan,
+	}
+}
+
+func metadataObject(raw sqlxx.JSONRawMessage) (map[string]json.RawMessage, error) {
+	object := map[string]json.RawMessage{}
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return object, nil
+	}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, errorsx.WithStack(ErrInvalidClientMetadata.WithHint("Field metadata must be a JSON object to be combined with a client template.").WithDebug(err.Error()))
+	}
+	return object, nil
+}
+
+func sameElements(a, b []string) bool {
+	for _, v := range a {
+		if !stringslice.Has(b, v) {
+			return false
+		}
+	}
+	for _, v := range b {
+		if !stringslice.Has(a, v) {
+			return false
+		}
+	}
+	return true
+}
+
+type TemplateStorage interface {
+	CreateClientTemplate(ctx context.Context, t *Template) error
+
+	GetClientTemplate(ctx context.Context, name string) (*Template, error)
+
+	GetClientTemplates(ctx context.Context, pageOpts ...keysetpagination.Option) ([]Template, *keysetpagination.Paginator, error)
+
+	UpdateClientTemplate(ctx context.Context, t *Template) error
+
+	// DeleteClientTemplate deletes the template with the given name. It returns ErrClientTemplateInUse if clients
+	// still reference the template.
+	DeleteClientTemplate(ctx context.Context, name string) error
+}
@@ -7,12 +7,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
 
+	"github.com/pkg/errors"
+
 	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/jwk"
 	"github.com/ory/hydra/x"
 	"github.com/ory/x/ipx"
+	"github.com/ory/x/sqlcon"
 
 	"github.com/ory/x/errorsx"
 
@@ -31,11 +36,36 @@ var (
 		"ES384",
 		"ES512",
 	}
+
+	// AuthorizationEncryptionAlgValuesSupported are the JWE key management algorithms supported for encrypting
+	// JWT secured authorization responses.
+	AuthorizationEncryptionAlgValuesSupported = []string{
+		"RSA-OAEP",
+		"RSA-OAEP-256",
+		"ECDH-ES",
+		"ECDH-ES+A128KW",
+		"ECDH-ES+A192KW",
+		"ECDH-ES+A256KW",
+	}
+
+	// AuthorizationEncryptionEncValuesSupported are the JWE content encryption algorithms supported for
+	// encrypting JWT secured authorization responses.
+	AuthorizationEncryptionEncValuesSupported = []string{
+		"A128CBC-HS256",
+		"A192CBC-HS384",
+		"A256CBC-HS512",
+		"A128GCM",
+		"A192GCM",
+		"A256GCM",
+	}
 )
 
 type validatorRegistry interface {
 	x.HTTPClientProvider
 	config.Provider
+	OpenIDJWTStrategy() jwk.JWTSigner
+	OAuth2Storage() x.FositeStorer
+	ClientManager() Manager
 }
 
 type Validator struct {
@@ -49,6 +79,12 @@ func NewValidator(registry validatorRegistry) *Validator {
 }
 
 func (v *Validator) Validate(ctx context.Context, c *Client) error {
+	if c.Template != "" {
+		if err := v.ValidateTemplate(ctx, c); err != nil {
+			return err
+		}
+	}
+
 	if c.TokenEndpointAuthMethod == "" {
 		c.TokenEndpointAuthMethod = "client_secret_basic"
 	} else if c.TokenEndpointAuthMethod == "private_key_jwt" {
@@ -60,6 +96,59 @@ func (v *Validator) Validate(ctx context.Context, c *Client) error {
 		}
 	}
 
+	switch c.TokenEndpointAuthMethod {
+	case "tls_client_auth":
+		var set int
+		for _, v := range []string{c.TLSClientAuthSubjectDN, c.TLSClientAuthSANDNS, c.TLSClientAuthSANURI, c.TLSClientAuthSANIP, c.TLSClientAuthSANEmail} {
+			if v != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("When token_endpoint_auth_method is 'tls_client_auth', exactly one of tls_client_auth_subject_dn, tls_client_auth_san_dns, tls_client_auth_san_uri, tls_client_auth_san_ip, or tls_client_auth_san_email must be set."))
+		}
+		if c.TLSClientAuthSANIP != "" && net.ParseIP(c.TLSClientAuthSANIP) == nil {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("Field tls_client_auth_san_ip must be a valid IPv4 or IPv6 address."))
+		}
+	case "self_signed_tls_client_auth":
+		if len(c.JSONWebKeysURI) == 0 && c.JSONWebKeys == nil {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("When token_endpoint_auth_method is 'self_signed_tls_client_auth', either jwks or jwks_uri must be set."))
+		}
+	}
+
+	switch c.BackchannelTokenDeliveryMode {
+	case "", BackchannelTokenDeliveryModePoll:
+	case BackchannelTokenDeliveryModePing:
+		if u, err := url.ParseRequestURI(c.BackchannelClientNotificationEndpoint); err != nil || !u.IsAbs() {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("When backchannel_token_delivery_mode is 'ping', backchannel_client_notification_endpoint must be set to an absolute URL."))
+		}
+	default:
+		return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Field backchannel_token_delivery_mode must be one of '%s' or '%s'.", BackchannelTokenDeliveryModePoll, BackchannelTokenDeliveryModePing))
+	}
+
+	if c.AuthorizationSignedResponseAlg != "" {
+		// Authorization responses are signed with the OpenID Connect signing key, so its algorithm is the only one
+		// which can be honored.
+		key, err := v.r.OpenIDJWTStrategy().GetPublicKey(ctx)
+		if err != nil {
+			return err
+		} else if c.AuthorizationSignedResponseAlg != key.Algorithm {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Field authorization_signed_response_alg can only be '%s'.", key.Algorithm))
+		}
+	}
+
+	if c.AuthorizationEncryptedResponseAlg == "" && c.AuthorizationEncryptedResponseEnc != "" {
+		return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("Field authorization_encrypted_response_enc can only be set together with authorization_encrypted_response_alg."))
+	} else if c.AuthorizationEncryptedResponseAlg != "" {
+		if !stringslice.Has(AuthorizationEncryptionAlgValuesSupported, c.AuthorizationEncryptedResponseAlg) {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Field authorization_encrypted_response_alg must be one of: %s", strings.Join(AuthorizationEncryptionAlgValuesSupported, ", ")))
+		} else if c.AuthorizationEncryptedResponseEnc != "" && !stringslice.Has(AuthorizationEncryptionEncValuesSupported, c.AuthorizationEncryptedResponseEnc) {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Field authorization_encrypted_response_enc must be one of: %s", strings.Join(AuthorizationEncryptionEncValuesSupported, ", ")))
+		} else if len(c.JSONWebKeysURI) == 0 && c.JSONWebKeys == nil {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("When authorization_encrypted_response_alg is set, either jwks or jwks_uri must be set to provide the encryption key."))
+		}
+	}
+
 	if len(c.JSONWebKeysURI) > 0 && c.JSONWebKeys != nil {
 		return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("Fields jwks and jwks_uri can not both be set, you must choose one."))
 	}
@@ -68,6 +157,7 @@ func (v *Validator) Validate(ctx context.Context, c *Client) error {
 		values := map[string]string{
 			"jwks_uri":               c.JSONWebKeysURI,
 			"backchannel_logout_uri": c.BackChannelLogoutURI,
+			"backchannel_client_notification_endpoint": c.BackchannelClientNotificationEndpoint,
 		}
 
 		for k, v := range c.RequestURIs {
@@ -176,6 +266,25 @@ func (v *Validator) Validate(ctx context.Context, c *Client) error {
 	return nil
 }
 
+// ValidateTemplate fills the fields of the client which are not set from the client's template. If the template is
+// enforced, the client must match it.
+func (v *Validator) ValidateTemplate(ctx context.Context, c *Client) error {
+	t, err := v.r.ClientManager().GetClientTemplate(ctx, c.Template)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Client template '%s' does not exist.", c.Template))
+	} else if err != nil {
+		return err
+	}
+
+	if err := t.Apply(c); err != nil {
+		return err
+	}
+	if t.Enforce {
+		return t.Match(c)
+	}
+	return nil
+}
+
 func (v *Validator) ValidateDynamicRegistration(ctx context.Context, c *Client) error {
 	if c.Metadata != nil {
 		return errorsx.WithStack(ErrInvalidClientMetadata.
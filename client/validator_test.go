@@ -36,6 +36,12 @@ func TestValidate(t *testing.T) {
 	reg := internal.NewRegistryMemory(t, c, &contextx.Static{C: c.Source(ctx)})
 	v := NewValidator(reg)
 
+	// Validating authorization_signed_response_alg requires the OpenID Connect signing key, which can only be
+	// generated by a registry with a network.
+	withSigningKey := func(t *testing.T) *Validator {
+		return NewValidator(internal.NewMockedRegistry(t, &contextx.Default{}))
+	}
+
 	testCtx := context.TODO()
 
 	for k, tc := range []struct {
@@ -76,6 +82,28 @@ func TestValidate(t *testing.T) {
 			in:        &Client{LegacyClientID: "foo", JSONWebKeys: &x.JoseJSONWebKeySet{JSONWebKeySet: new(jose.JSONWebKeySet)}, JSONWebKeysURI: "asdf", TokenEndpointAuthMethod: "private_key_jwt"},
 			expectErr: true,
 		},
+		{
+			in:        &Client{LegacyClientID: "foo", TokenEndpointAuthMethod: "tls_client_auth"},
+			expectErr: true,
+		},
+		{
+			in:        &Client{LegacyClientID: "foo", TokenEndpointAuthMethod: "tls_client_auth", TLSClientAuthSubjectDN: "CN=foo", TLSClientAuthSANDNS: "foo.example.com"},
+			expectErr: true,
+		},
+		{
+			in:        &Client{LegacyClientID: "foo", TokenEndpointAuthMethod: "tls_client_auth", TLSClientAuthSANIP: "not-an-ip"},
+			expectErr: true,
+		},
+		{
+			in: &Client{LegacyClientID: "foo", TokenEndpointAuthMethod: "tls_client_auth", TLSClientAuthSubjectDN: "CN=foo"},
+			check: func(t *testing.T, c *Client) {
+				assert.Equal(t, "tls_client_auth", c.GetTokenEndpointAuthMethod())
+			},
+		},
+		{
+			in:        &Client{LegacyClientID: "foo", TokenEndpointAuthMethod: "self_signed_tls_client_auth"},
+			expectErr: true,
+		},
 		{
 			in:        &Client{LegacyClientID: "foo", JSONWebKeys: &x.JoseJSONWebKeySet{JSONWebKeySet: new(jose.JSONWebKeySet)}, TokenEndpointAuthMethod: "private_key_jwt", TokenEndpointAuthSigningAlgorithm: "HS256"},
 			expectErr: true,
@@ -124,6 +152,49 @@ func TestValidate(t *testing.T) {
 			in:        &Client{LegacyClientID: "foo", SubjectType: "foo"},
 			expectErr: true,
 		},
+		{
+			in:        &Client{LegacyClientID: "foo", BackchannelTokenDeliveryMode: "push"},
+			expectErr: true,
+		},
+		{
+			in:        &Client{LegacyClientID: "foo", BackchannelTokenDeliveryMode: BackchannelTokenDeliveryModePing},
+			expectErr: true,
+		},
+		{
+			in:        &Client{LegacyClientID: "foo", BackchannelTokenDeliveryMode: BackchannelTokenDeliveryModePing, BackchannelClientNotificationEndpoint: "/cb"},
+			expectErr: true,
+		},
+		{
+			in: &Client{LegacyClientID: "foo", BackchannelTokenDeliveryMode: BackchannelTokenDeliveryModePing, BackchannelClientNotificationEndpoint: "https://foo/cb"},
+			check: func(t *testing.T, c *Client) {
+				assert.Equal(t, "https://foo/cb", c.BackchannelClientNotificationEndpoint)
+			},
+		},
+		{
+			v:         withSigningKey,
+			in:        &Client{LegacyClientID: "foo", AuthorizationSignedResponseAlg: "HS256"},
+			expectErr: true,
+		},
+		{
+			in:        &Client{LegacyClientID: "foo", AuthorizationEncryptedResponseEnc: "A128GCM"},
+			expectErr: true,
+		},
+		{
+			in:        &Client{LegacyClientID: "foo", AuthorizationEncryptedResponseAlg: "RSA1_5", JSONWebKeysURI: "https://foo/jwks"},
+			expectErr: true,
+		},
+		{
+			in:        &Client{LegacyClientID: "foo", AuthorizationEncryptedResponseAlg: "RSA-OAEP"},
+			expectErr: true,
+		},
+		{
+			v:  withSigningKey,
+			in: &Client{LegacyClientID: "foo", AuthorizationSignedResponseAlg: "RS256", AuthorizationEncryptedResponseAlg: "RSA-OAEP", AuthorizationEncryptedResponseEnc: "A128GCM", JSONWebKeysURI: "https://foo/jwks"},
+			check: func(t *testing.T, c *Client) {
+				assert.Equal(t, "RSA-OAEP", c.AuthorizationEncryptedResponseAlg)
+				assert.Equal(t, "A128GCM", c.AuthorizationEncryptedResponseEnc)
+			},
+		},
 	} {
 		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
 			if tc.v == nil {
@@ -215,6 +286,7 @@ func TestValidateIPRanges(t *testing.T) {
 	require.NoError(t, v.ValidateDynamicRegistration(ctx, &Client{}))
 	require.ErrorContains(t, v.ValidateDynamicRegistration(ctx, &Client{JSONWebKeysURI: "https://localhost:1234"}), "invalid_client_metadata")
 	require.ErrorContains(t, v.ValidateDynamicRegistration(ctx, &Client{BackChannelLogoutURI: "https://localhost:1234"}), "invalid_client_metadata")
+	require.ErrorContains(t, v.ValidateDynamicRegistration(ctx, &Client{BackchannelTokenDeliveryMode: BackchannelTokenDeliveryModePing, BackchannelClientNotificationEndpoint: "https://localhost:1234"}), "invalid_client_metadata")
 	require.ErrorContains(t, v.ValidateDynamicRegistration(ctx, &Client{RequestURIs: []string{"https://google", "https://localhost:1234"}}), "invalid_client_metadata")
 
 	c.MustSet(ctx, config.ViperKeyClientHTTPNoPrivateIPRanges, false)
@@ -224,6 +296,17 @@ func TestValidateIPRanges(t *testing.T) {
 	require.NoError(t, v.ValidateDynamicRegistration(ctx, &Client{RequestURIs: []string{"https://google", "https://localhost:1234"}}))
 }
 
+func TestValidateAuthorizationSignedResponseAlg(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	_, err := reg.KeyManager().GenerateAndPersistKeySet(ctx, x.OpenIDConnectKeyName, "es256-key", string(jose.ES256), "sig")
+	require.NoError(t, err)
+
+	v := NewValidator(reg)
+	require.NoError(t, v.Validate(ctx, &Client{AuthorizationSignedResponseAlg: "ES256"}))
+	require.ErrorContains(t, v.Validate(ctx, &Client{AuthorizationSignedResponseAlg: "RS256"}), "invalid_client_metadata")
+}
+
 func TestValidateDynamicRegistration(t *testing.T) {
 	ctx := context.Background()
 	c := internal.NewConfigurationWithDefaults()
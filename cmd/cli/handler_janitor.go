@@ -31,9 +31,12 @@ const (
 	AccessLifespan         = "access-lifespan"
 	RefreshLifespan        = "refresh-lifespan"
 	ConsentRequestLifespan = "consent-request-lifespan"
+	DeletedClientRetention = "deleted-client-retention"
+	LoginSessionMaxAge     = "login-session-max-age"
 	OnlyTokens             = "tokens"
 	OnlyRequests           = "requests"
 	OnlyGrants             = "grants"
+	OnlyClients            = "clients"
 	ReadFromEnv            = "read-from-env"
 	Config                 = "config"
 )
@@ -64,9 +67,9 @@ func (_ *JanitorHandler) Args(cmd *cobra.Command, args []string) error {
 			"- Using the config file with flag -c, --config")
 	}
 
-	if !flagx.MustGetBool(cmd, OnlyTokens) && !flagx.MustGetBool(cmd, OnlyRequests) && !flagx.MustGetBool(cmd, OnlyGrants) {
+	if !flagx.MustGetBool(cmd, OnlyTokens) && !flagx.MustGetBool(cmd, OnlyRequests) && !flagx.MustGetBool(cmd, OnlyGrants) && !flagx.MustGetBool(cmd, OnlyClients) {
 		return fmt.Errorf("%s\n%s\n", cmd.UsageString(),
-			"Janitor requires at least one of --tokens, --requests or --grants to be set")
+			"Janitor requires at least one of --tokens, --requests, --grants or --clients to be set")
 	}
 
 	limit := flagx.MustGetInt(cmd, Limit)
@@ -100,6 +103,8 @@ func purge(cmd *cobra.Command, args []string, sl *servicelocatorx.Options, dOpts
 		AccessLifespan:         config.KeyAccessTokenLifespan,
 		RefreshLifespan:        config.KeyRefreshTokenLifespan,
 		ConsentRequestLifespan: config.KeyConsentRequestMaxAge,
+		DeletedClientRetention: config.KeyDeletedClientRetention,
+		LoginSessionMaxAge:     config.KeyLoginSessionMaxAge,
 	}
 
 	for k, v := range keys {
@@ -154,6 +159,10 @@ func purge(cmd *cobra.Command, args []string, sl *servicelocatorx.Options, dOpts
 		routineFlags = append(routineFlags, OnlyGrants)
 	}
 
+	if flagx.MustGetBool(cmd, OnlyClients) {
+		routineFlags = append(routineFlags, OnlyClients)
+	}
+
 	return cleanupRun(cmd.Context(), notAfter, limit, batchSize, addRoutine(p, routineFlags...)...)
 }
 
@@ -164,10 +173,17 @@ func addRoutine(p persistence.Persister, names ...string) []cleanupRoutine {
 		case OnlyTokens:
 			routines = append(routines, cleanup(p.FlushInactiveAccessTokens, "access tokens"))
 			routines = append(routines, cleanup(p.FlushInactiveRefreshTokens, "refresh tokens"))
+			routines = append(routines, cleanup(p.FlushInactiveDeviceCodes, "device codes"))
+			routines = append(routines, cleanup(p.FlushInactivePARSessions, "pushed authorization requests"))
 		case OnlyRequests:
 			routines = append(routines, cleanup(p.FlushInactiveLoginConsentRequests, "login-consent requests"))
+			routines = append(routines, cleanup(p.FlushInactiveBackchannelAuthenticationRequests, "backchannel authentication requests"))
+			routines = append(routines, cleanup(p.FlushExpiredLoginSessions, "expired login sessions"))
+			routines = append(routines, cleanup(p.FlushInactiveBackChannelLogoutDeliveries, "back-channel logout deliveries"))
 		case OnlyGrants:
 			routines = append(routines, cleanup(p.FlushInactiveGrants, "grants"))
+		case OnlyClients:
+			routines = append(routines, cleanup(p.FlushDeletedClients, "deleted clients"))
 		}
 	}
 	return routines
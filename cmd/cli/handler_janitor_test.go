@@ -13,11 +13,14 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/cmd/cli"
 	"github.com/ory/hydra/internal/testhelpers"
 	"github.com/ory/x/cmdx"
+	"github.com/ory/x/sqlcon"
 )
 
 func newJanitorCmd() *cobra.Command {
@@ -209,12 +212,17 @@ func TestJanitorHandler_Arguments(t *testing.T) {
 		fmt.Sprintf("--%s", cli.OnlyGrants),
 		"memory",
 	)
+	cmdx.ExecNoErr(t, cmd.NewRootCmd(nil, nil, nil),
+		"janitor",
+		fmt.Sprintf("--%s", cli.OnlyClients),
+		"memory",
+	)
 
 	_, _, err := cmdx.ExecCtx(context.Background(), cmd.NewRootCmd(nil, nil, nil), nil,
 		"janitor",
 		"memory")
 	require.Error(t, err)
-	require.Contains(t, err.Error(), "Janitor requires at least one of --tokens, --requests or --grants to be set")
+	require.Contains(t, err.Error(), "Janitor requires at least one of --tokens, --requests, --grants or --clients to be set")
 
 	cmdx.ExecNoErr(t, cmd.NewRootCmd(nil, nil, nil),
 		"janitor",
@@ -297,3 +305,38 @@ func TestJanitorHandler_PurgeGrantNotAfter(t *testing.T) {
 		})
 	}
 }
+
+func TestJanitorHandler_PurgeDeletedClients(t *testing.T) {
+	ctx := context.Background()
+	jt := testhelpers.NewConsentJanitorTestHelper(t.Name())
+	reg, err := jt.GetRegistry(ctx, "deleted-clients")
+	require.NoError(t, err)
+
+	active := &client.Client{LegacyClientID: "active-client", Secret: "secret"}
+	restored := &client.Client{LegacyClientID: "restored-client", Secret: "secret"}
+	purged := &client.Client{LegacyClientID: "purged-client", Secret: "secret"}
+	for _, c := range []*client.Client{active, restored, purged} {
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+	}
+	require.NoError(t, reg.ClientManager().DeleteClient(ctx, restored.GetID()))
+	require.NoError(t, reg.ClientManager().DeleteClient(ctx, purged.GetID()))
+
+	// Deleted clients are retained for 30 days by default.
+	cmdx.ExecNoErr(t, newJanitorCmd(), "janitor", fmt.Sprintf("--%s", cli.OnlyClients), jt.GetDSN(ctx))
+	require.NoError(t, reg.ClientManager().RestoreClient(ctx, restored.GetID()))
+
+	cmdx.ExecNoErr(t, newJanitorCmd(), "janitor",
+		fmt.Sprintf("--%s", cli.OnlyClients),
+		fmt.Sprintf("--%s=%s", cli.DeletedClientRetention, "1ns"),
+		jt.GetDSN(ctx),
+	)
+	assert.ErrorIs(t, reg.ClientManager().RestoreClient(ctx, purged.GetID()), sqlcon.ErrNoRows)
+	for _, c := range []*client.Client{active, restored} {
+		_, err := reg.ClientManager().GetConcreteClient(ctx, c.GetID())
+		assert.NoError(t, err, c.GetID())
+	}
+
+	deleted, _, err := reg.ClientManager().GetClients(ctx, client.Filter{Deleted: true})
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+}
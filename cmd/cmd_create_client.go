@@ -4,14 +4,19 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
 
 	"github.com/spf13/cobra"
 
+	hydra "github.com/ory/hydra-client-go/v2"
 	"github.com/ory/hydra/cmd/cliclient"
 	"github.com/ory/x/cmdx"
 	"github.com/ory/x/flagx"
 	"github.com/ory/x/pointerx"
+	"github.com/ory/x/urlx"
 
 	"github.com/ory/hydra/cmd/cli"
 )
@@ -43,6 +48,7 @@ const (
 	flagClientSecret                            = "secret"
 	flagClientTOSURI                            = "tos-uri"
 	flagClientBackChannelLogoutSessionRequired  = "backchannel-logout-session-required"
+	flagClientTemplate                          = "template"
 )
 
 func NewCreateClientsCommand() *cobra.Command {
@@ -69,6 +75,11 @@ the Authorize Code, Implicit, Refresh flow. This command allows settings all fie
 To encrypt an auto-generated OAuth2 Client Secret, use flags ` + "`--pgp-key`" + `, ` + "`--pgp-key-url`" + ` or ` + "`--keybase`" + ` flag, for example:
 
   {{ .CommandPath }} -n "my app" -g client_credentials -r token -a core,foobar --keybase keybase_username
+
+To instantiate the client from a client template, use flag ` + "`--template`" + `. Grant types, response types, the token endpoint
+authentication method, lifespans and metadata keys which are not set by flags are then taken from the template:
+
+  {{ .CommandPath }} -n "my app" -c http://localhost/cb --template web-app
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			m, _, err := cliclient.NewClient(cmd)
@@ -83,10 +94,18 @@ To encrypt an auto-generated OAuth2 Client Secret, use flags ` + "`--pgp-key`" +
 			}
 
 			secret := flagx.MustGetString(cmd, flagClientSecret)
-			//nolint:bodyclose
-			client, _, err := m.OAuth2Api.CreateOAuth2Client(cmd.Context()).OAuth2Client(clientFromFlags(cmd)).Execute()
-			if err != nil {
-				return cmdx.PrintOpenAPIError(cmd, err)
+			var client *hydra.OAuth2Client
+			if template := flagx.MustGetString(cmd, flagClientTemplate); template != "" {
+				client, err = createClientFromTemplate(cmd, m, template)
+				if err != nil {
+					return err
+				}
+			} else {
+				//nolint:bodyclose
+				client, _, err = m.OAuth2Api.CreateOAuth2Client(cmd.Context()).OAuth2Client(clientFromFlags(cmd)).Execute()
+				if err != nil {
+					return cmdx.PrintOpenAPIError(cmd, err)
+				}
 			}
 
 			if client.ClientSecret == nil && len(secret) > 0 {
@@ -108,5 +127,61 @@ To encrypt an auto-generated OAuth2 Client Secret, use flags ` + "`--pgp-key`" +
 		},
 	}
 	registerClientFlags(cmd.Flags())
+	cmd.Flags().String(flagClientTemplate, "", "The name of the client template to instantiate the client from.")
 	return cmd
 }
+
+// createClientFromTemplate creates the client using the raw API, as the SDK does not know the template field. Flags
+// with defaults are only sent if they were set explicitly, so that they do not override the template.
+func createClientFromTemplate(cmd *cobra.Command, m *hydra.APIClient, template string) (*hydra.OAuth2Client, error) {
+	cl := clientFromFlags(cmd)
+	if !cmd.Flags().Changed(flagClientGrantType) {
+		cl.GrantTypes = nil
+	}
+	if !cmd.Flags().Changed(flagClientResponseType) {
+		cl.ResponseTypes = nil
+	}
+	if !cmd.Flags().Changed(flagClientTokenEndpointAuthMethod) {
+		cl.TokenEndpointAuthMethod = nil
+	}
+
+	body := map[string]interface{}{}
+	raw, err := json.Marshal(cl)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, err
+	}
+	body["template"] = template
+	if raw, err = json.Marshal(body); err != nil {
+		return nil, err
+	}
+
+	conf := m.GetConfig()
+	target := urlx.AppendPaths(urlx.ParseOrPanic(conf.Servers[0].URL), "/admin/clients")
+	req, err := http.NewRequestWithContext(cmd.Context(), http.MethodPost, target.String(), bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := conf.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		var e hydra.ErrorOAuth2
+		_ = json.NewDecoder(res.Body).Decode(&e)
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to create the OAuth 2.0 Client: %s %s\n", pointerx.StringR(e.Error), pointerx.StringR(e.ErrorDescription))
+		return nil, cmdx.FailSilently(cmd)
+	}
+
+	var client hydra.OAuth2Client
+	if err := json.NewDecoder(res.Body).Decode(&client); err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
 
+	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/cmd"
 	"github.com/ory/x/cmdx"
 	"github.com/ory/x/snapshotx"
@@ -59,3 +60,32 @@ func TestCreateClient(t *testing.T) {
 		snapshotx.SnapshotT(t, json.RawMessage(actual.Raw), snapshotExcludedClientFields...)
 	})
 }
+
+func TestCreateClientFromTemplate(t *testing.T) {
+	ctx := context.Background()
+	c := cmd.NewCreateClientsCommand()
+	reg := setup(t, c)
+
+	require.NoError(t, reg.ClientManager().CreateClientTemplate(ctx, &client.Template{
+		Name:                    "machine",
+		GrantTypes:              []string{"client_credentials"},
+		ResponseTypes:           []string{"token"},
+		TokenEndpointAuthMethod: "client_secret_post",
+		Metadata:                []byte(`{"team":"cli"}`),
+	}))
+
+	actual := gjson.Parse(cmdx.ExecNoErr(t, c, "--template", "machine", "--metadata", `{"owner":"alice"}`))
+	assert.Equal(t, `["client_credentials"]`, actual.Get("grant_types").Raw, actual.Raw)
+	assert.Equal(t, `["token"]`, actual.Get("response_types").Raw, actual.Raw)
+	assert.Equal(t, "client_secret_post", actual.Get("token_endpoint_auth_method").String(), actual.Raw)
+	assert.NotEmpty(t, actual.Get("client_secret").String())
+
+	expected, err := reg.ClientManager().GetConcreteClient(ctx, actual.Get("client_id").String())
+	require.NoError(t, err)
+	assert.Equal(t, "machine", expected.Template)
+	assert.JSONEq(t, `{"owner":"alice","team":"cli"}`, string(expected.Metadata))
+
+	stdout, stderr, err := cmdx.Exec(t, c, nil, "--template", "does-not-exist")
+	require.Error(t, err, stdout)
+	assert.Contains(t, stderr, "does-not-exist")
+}
@@ -16,7 +16,10 @@ func NewDeleteClientCmd() *cobra.Command {
 		Aliases: []string{"client", "clients", "oauth2-clients"},
 		Args:    cobra.MinimumNArgs(1),
 		Short:   "Delete one or more OAuth 2.0 Clients by their ID(s)",
-		Long:    "This command deletes one or more OAuth 2.0 Clients by their respective IDs.",
+		Long: `This command deletes one or more OAuth 2.0 Clients by their respective IDs.
+
+Deleted clients can be restored using the admin API until the janitor purges them after the retention period
+configured in ttl.deleted_client.`,
 		Example: `{{ .CommandPath }} <client-1> <client-2> <client-3>
 
 To delete OAuth 2.0 Clients with the owner of "foo@bar.com", run:
@@ -0,0 +1,106 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/hydra/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/ory/x/pointerx"
+	"github.com/ory/x/urlx"
+)
+
+const flagRevokeTokens = "revoke-tokens"
+
+func NewDisableClientCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "oauth2-client <id-1> [<id-2> ...]",
+		Aliases: []string{"client", "clients", "oauth2-clients"},
+		Args:    cobra.MinimumNArgs(1),
+		Short:   "Disable one or more OAuth 2.0 Clients by their ID(s)",
+		Long: `This command disables one or more OAuth 2.0 Clients without deleting them or changing their secrets.
+
+Disabled clients can no longer authenticate or be used in flows, and their tokens can neither be introspected nor
+refreshed. Use --revoke-tokens to also revoke their tokens, so that they stay invalid once the clients are enabled again.`,
+		Example: `{{ .CommandPath }} <client-1> <client-2> --revoke-tokens`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setClientsDisabled(cmd, args, true, flagx.MustGetBool(cmd, flagRevokeTokens))
+		},
+	}
+	cmd.Flags().Bool(flagRevokeTokens, false, "Also delete the access tokens and revoke the refresh tokens of the clients.")
+	return cmd
+}
+
+func setClientsDisabled(cmd *cobra.Command, ids []string, disabled, revokeTokens bool) error {
+	m, _, err := cliclient.NewClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	var (
+		updated = make([]cmdx.OutputIder, 0, len(ids))
+		failed  = make(map[string]error)
+	)
+
+	for _, id := range ids {
+		if err := setClientDisabled(cmd, m, id, disabled, revokeTokens); err != nil {
+			failed[id] = err
+			continue
+		}
+		updated = append(updated, cmdx.OutputIder(id))
+	}
+
+	if len(updated) == 1 {
+		cmdx.PrintRow(cmd, &updated[0])
+	} else if len(updated) > 1 {
+		cmdx.PrintTable(cmd, &cmdx.OutputIderCollection{Items: updated})
+	}
+
+	cmdx.PrintErrors(cmd, failed)
+	if len(failed) != 0 {
+		return cmdx.FailSilently(cmd)
+	}
+
+	return nil
+}
+
+func setClientDisabled(cmd *cobra.Command, m *hydra.APIClient, id string, disabled, revokeTokens bool) error {
+	action, body := "enable", []byte("{}")
+	if disabled {
+		action = "disable"
+		var err error
+		if body, err = json.Marshal(map[string]bool{"revoke_tokens": revokeTokens}); err != nil {
+			return err
+		}
+	}
+
+	conf := m.GetConfig()
+	target := urlx.AppendPaths(urlx.ParseOrPanic(conf.Servers[0].URL), "/admin/clients", id, action)
+	req, err := http.NewRequestWithContext(cmd.Context(), http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := conf.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e hydra.ErrorOAuth2
+		_ = json.NewDecoder(res.Body).Decode(&e)
+		return fmt.Errorf("failed to %s the OAuth 2.0 Client: %s %s", action, pointerx.StringR(e.Error), pointerx.StringR(e.ErrorDescription))
+	}
+	return nil
+}
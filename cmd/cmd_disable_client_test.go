@@ -0,0 +1,60 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/cmd"
+	"github.com/ory/x/assertx"
+	"github.com/ory/x/cmdx"
+)
+
+func TestDisableClient(t *testing.T) {
+	ctx := context.Background()
+	c := cmd.NewDisableClientCmd()
+	reg := setup(t, c)
+
+	t.Run("case=disables clients", func(t *testing.T) {
+		expected1 := createClient(t, reg, nil)
+		expected2 := createClient(t, reg, nil)
+		assertx.EqualAsJSON(t, []string{expected1.GetID(), expected2.GetID()}, json.RawMessage(cmdx.ExecNoErr(t, c, expected1.GetID(), expected2.GetID(), "--revoke-tokens")))
+
+		for _, id := range []string{expected1.GetID(), expected2.GetID()} {
+			_, err := reg.ClientManager().GetClient(ctx, id)
+			assert.ErrorIs(t, err, client.ErrClientDisabled)
+		}
+	})
+
+	t.Run("case=one client fails", func(t *testing.T) {
+		expected := createClient(t, reg, nil)
+		stdout, stderr, err := cmdx.Exec(t, c, nil, "i-do-not-exist", expected.GetID())
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf(`"%s"`, expected.GetID()), strings.TrimSpace(stdout))
+		assert.Contains(t, stderr, "failed to disable the OAuth 2.0 Client")
+	})
+}
+
+func TestEnableClient(t *testing.T) {
+	ctx := context.Background()
+	c := cmd.NewEnableClientCmd()
+	reg := setup(t, c)
+
+	expected := createClient(t, reg, nil)
+	require.NoError(t, reg.ClientManager().SetClientDisabled(ctx, expected.GetID(), true))
+
+	stdout := cmdx.ExecNoErr(t, c, expected.GetID())
+	assert.Equal(t, fmt.Sprintf(`"%s"`, expected.GetID()), strings.TrimSpace(stdout))
+
+	_, err := reg.ClientManager().GetClient(ctx, expected.GetID())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,23 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewEnableClientCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "oauth2-client <id-1> [<id-2> ...]",
+		Aliases: []string{"client", "clients", "oauth2-clients"},
+		Args:    cobra.MinimumNArgs(1),
+		Short:   "Enable one or more disabled OAuth 2.0 Clients by their ID(s)",
+		Long: `This command enables one or more disabled OAuth 2.0 Clients. Their tokens are accepted again unless they were
+revoked when the clients were disabled.`,
+		Example: `{{ .CommandPath }} <client-1> <client-2>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setClientsDisabled(cmd, args, false, false)
+		},
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/hydra/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+)
+
+const exportClientsPageSize = 500
+
+type exportedClients []rawClient
+
+func (c exportedClients) String() string {
+	out, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%+v", []rawClient(c))
+	}
+	return string(out) + "\n"
+}
+
+func (c exportedClients) Interface() interface{} {
+	return []rawClient(c)
+}
+
+func NewExportClientsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "oauth2-clients",
+		Aliases: []string{"clients", "oauth2-client", "client"},
+		Short:   "Export all OAuth 2.0 Clients as JSON or YAML",
+		Long: `This command exports all OAuth 2.0 Clients in a format which can be imported again using "import oauth2-client --upsert".
+
+Hydra only stores hashes of client secrets, so the export never contains client secrets. Fields which are managed by
+Hydra, such as the creation date or the registration access token, are not exported either. When importing an exported
+client, its secret is kept as it is unless the file contains a new one.`,
+		Example: `{{ .CommandPath }} > clients.json
+
+To export the clients as YAML, run:
+
+	{{ .CommandPath }} --format yaml > clients.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, _, err := cliclient.NewClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			clients := exportedClients{}
+			query := url.Values{"page_size": {strconv.Itoa(exportClientsPageSize)}}
+			for {
+				var page []rawClient
+				res, err := doAdminClientRequest(cmd, m, http.MethodGet, query, nil, http.StatusOK, &page)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to list the OAuth 2.0 Clients: %s\n", err)
+					return cmdx.FailSilently(cmd)
+				}
+
+				for _, c := range page {
+					clients = append(clients, c.withoutServerManagedFields())
+				}
+
+				next := getPageToken(res)
+				if next == "" || len(page) == 0 {
+					break
+				}
+				query.Set("page_token", next)
+			}
+
+			cmdx.PrintJSONAble(cmd, clients)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/cmd"
+	"github.com/ory/x/cmdx"
+)
+
+func TestExportClients(t *testing.T) {
+	c := cmd.NewExportClientsCmd()
+	reg := setup(t, c)
+
+	expected1 := createClient(t, reg, &client.Client{Name: "export-1", Secret: "some-secret", Metadata: []byte(`{"team":"a"}`)})
+	expected2 := createClient(t, reg, &client.Client{Name: "export-2", Secret: "some-secret"})
+
+	t.Run("case=exports all clients without secrets", func(t *testing.T) {
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c))
+		require.Len(t, actual.Array(), 2, actual.Raw)
+
+		ids := []string{actual.Get("0.client_id").String(), actual.Get("1.client_id").String()}
+		assert.ElementsMatch(t, []string{expected1.GetID(), expected2.GetID()}, ids)
+		for _, exported := range actual.Array() {
+			for _, field := range []string{"client_secret", "created_at", "updated_at", "registration_access_token", "registration_client_uri"} {
+				assert.False(t, exported.Get(field).Exists(), "%s should not be exported: %s", field, exported.Raw)
+			}
+		}
+		assert.Equal(t, "a", actual.Get(`#(client_name=="export-1").metadata.team`).String(), actual.Raw)
+	})
+
+	t.Run("case=exports YAML which can be imported again", func(t *testing.T) {
+		stdout := cmdx.ExecNoErr(t, c, "--format", "yaml")
+		assert.Contains(t, stdout, "client_name: export-1")
+
+		file := filepath.Join(t.TempDir(), "clients.yaml")
+		require.NoError(t, os.WriteFile(file, []byte(stdout), 0600))
+
+		endpoint, err := c.Flags().GetString(cmdx.FlagEndpoint)
+		require.NoError(t, err)
+		importCmd := cmd.NewImportClientCmd()
+		cmdx.RegisterHTTPClientFlags(importCmd.Flags())
+		cmdx.RegisterFormatFlags(importCmd.Flags())
+		require.NoError(t, importCmd.Flags().Set(cmdx.FlagEndpoint, endpoint))
+
+		assert.Equal(t, sortedLines("= oauth2-client "+expected1.GetID()+"\n= oauth2-client "+expected2.GetID()+"\n"), sortedLines(cmdx.ExecNoErr(t, importCmd, file, "--upsert", "--dry-run")))
+	})
+}
+
+func sortedLines(s string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,48 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/hydra/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+)
+
+func NewGetLoginSessionsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "login-session <id-1> [<id-2> ...]",
+		Aliases: []string{"login-sessions"},
+		Args:    cobra.MinimumNArgs(1),
+		Short:   "Get one or more login sessions by their ID(s)",
+		Long:    `This command gets a login session, including the OAuth 2.0 Clients which used it. The ID of a login session is the "sid" claim of the ID Tokens issued during the session.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, _, err := cliclient.NewClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			sessions := make([]loginSession, 0, len(args))
+			for _, id := range args {
+				var session loginSession
+				if _, err := doAdminRequest(cmd, m, http.MethodGet, nil, nil, http.StatusOK, &session, "/admin/oauth2/auth/sessions/login", id); err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to get login session %s: %s\n", id, err)
+					return cmdx.FailSilently(cmd)
+				}
+				sessions = append(sessions, session)
+			}
+
+			if len(sessions) == 1 {
+				cmdx.PrintRow(cmd, (*outputLoginSession)(&sessions[0]))
+			} else if len(sessions) > 1 {
+				cmdx.PrintTable(cmd, &outputLoginSessionCollection{sessions})
+			}
+
+			return nil
+		},
+	}
+}
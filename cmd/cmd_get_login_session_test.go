@@ -0,0 +1,41 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/cmd"
+	"github.com/ory/x/cmdx"
+)
+
+func TestGetLoginSession(t *testing.T) {
+	c := cmd.NewGetLoginSessionsCmd()
+	reg := setup(t, c)
+
+	cl := createClient(t, reg, nil)
+	expected := createLoginSession(t, reg, "get-subject", cl)
+
+	t.Run("case=gets a session", func(t *testing.T) {
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, expected.ID))
+		assert.Equal(t, expected.ID, actual.Get("id").String(), actual.Raw)
+		assert.Equal(t, "get-subject", actual.Get("subject").String())
+		assert.True(t, actual.Get("remember").Bool())
+		assert.Equal(t, []interface{}{cl.GetID()}, actual.Get("client_ids").Value())
+	})
+
+	t.Run("case=gets multiple sessions", func(t *testing.T) {
+		other := createLoginSession(t, reg, "get-subject")
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, expected.ID, other.ID))
+		assert.Equal(t, []interface{}{expected.ID, other.ID}, actual.Get("#.id").Value())
+	})
+
+	t.Run("case=fails for an unknown session", func(t *testing.T) {
+		stderr := cmdx.ExecExpectedErr(t, c, "i-do-not-exist")
+		assert.Contains(t, stderr, "i-do-not-exist")
+	})
+}
@@ -0,0 +1,245 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+)
+
+// serverManagedClientFields are set by Hydra and are therefore neither exported nor compared when importing clients.
+var serverManagedClientFields = []string{
+	"client_secret",
+	"created_at",
+	"updated_at",
+	"registration_access_token",
+	"registration_client_uri",
+}
+
+type (
+	// rawClient is an OAuth 2.0 Client as returned by the admin API. It is used instead of hydra.OAuth2Client
+	// so that fields which are not part of the SDK yet survive an export and import.
+	rawClient map[string]interface{}
+
+	clientChange struct {
+		op       string
+		path     string
+		from     interface{}
+		to       interface{}
+		redacted bool
+	}
+)
+
+func (c rawClient) id() string {
+	id, _ := c["client_id"].(string)
+	return id
+}
+
+func (c rawClient) label() string {
+	if id := c.id(); id != "" {
+		return id
+	} else if name, _ := c["client_name"].(string); name != "" {
+		return fmt.Sprintf("%q", name)
+	}
+	return "(generated ID)"
+}
+
+// withoutServerManagedFields returns a copy of the client without the fields managed by Hydra.
+func (c rawClient) withoutServerManagedFields() rawClient {
+	out := make(rawClient, len(c))
+	for k, v := range c {
+		out[k] = v
+	}
+	for _, k := range serverManagedClientFields {
+		delete(out, k)
+	}
+	return out
+}
+
+// decodeRawClient decodes a client, keeping numbers as json.Number so that they are printed as given.
+func decodeRawClient(raw []byte) (rawClient, error) {
+	var c rawClient
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err := d.Decode(&c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// doAdminClientRequest sends a request to the OAuth 2.0 Client endpoints of the admin API.
+func doAdminClientRequest(cmd *cobra.Command, m *hydra.APIClient, method string, query url.Values, body interface{}, expected int, out interface{}, paths ...string) (*http.Response, error) {
+	return doAdminRequest(cmd, m, method, query, body, expected, out, append([]string{"/admin/clients"}, paths...)...)
+}
+
+// getRawClient returns the client with the given ID, or nil if it does not exist.
+func getRawClient(cmd *cobra.Command, m *hydra.APIClient, id string) (rawClient, error) {
+	var c rawClient
+	res, err := doAdminClientRequest(cmd, m, http.MethodGet, nil, nil, http.StatusOK, &c, id)
+	if res != nil && res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// diffClient returns the changes which updating existing with the fields of desired results in. Fields which are
+// not part of desired are kept as they are, so they are not compared. If existing is nil, the changes describe the
+// creation of the client.
+func diffClient(existing, desired rawClient) []clientChange {
+	var changes []clientChange
+	for _, k := range sortedKeys(desired) {
+		if k == "client_secret" {
+			// Hydra only stores a hash of the secret, so a secret in desired is always considered to be changed.
+			if secret, _ := desired[k].(string); secret != "" {
+				op := "~"
+				if existing == nil {
+					op = "+"
+				}
+				changes = append(changes, clientChange{op: op, path: k, redacted: true})
+			}
+			continue
+		} else if isServerManagedClientField(k) {
+			continue
+		}
+		changes = append(changes, diffValue(k, existing[k], desired[k])...)
+	}
+	return changes
+}
+
+func isServerManagedClientField(k string) bool {
+	for _, f := range serverManagedClientFields {
+		if f == k {
+			return true
+		}
+	}
+	return false
+}
+
+func diffValue(path string, from, to interface{}) []clientChange {
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		var changes []clientChange
+		keys := sortedKeys(fromMap)
+		for _, k := range sortedKeys(toMap) {
+			if _, ok := fromMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			changes = append(changes, diffValue(path+"."+k, fromMap[k], toMap[k])...)
+		}
+		return changes
+	}
+
+	switch {
+	case isEmptyValue(from) && isEmptyValue(to):
+		return nil
+	case isEmptyValue(from):
+		return []clientChange{{op: "+", path: path, to: to}}
+	case isEmptyValue(to):
+		return []clientChange{{op: "-", path: path, from: from}}
+	case reflect.DeepEqual(normalizeValue(from), normalizeValue(to)):
+		return nil
+	}
+	return []clientChange{{op: "~", path: path, from: from, to: to}}
+}
+
+// isEmptyValue reports whether the value is equivalent to an omitted field.
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	case float64:
+		return v == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// normalizeValue makes numbers comparable regardless of how they were written.
+func normalizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for k := range v {
+			out[k] = normalizeValue(v[k])
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k := range v {
+			out[k] = normalizeValue(v[k])
+		}
+		return out
+	}
+	return v
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printClientChanges(w io.Writer, op string, c rawClient, changes []clientChange) {
+	if op == "~" && len(changes) == 0 {
+		_, _ = fmt.Fprintf(w, "= oauth2-client %s\n", c.label())
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "%s oauth2-client %s\n", op, c.label())
+	for _, change := range changes {
+		switch {
+		case change.redacted:
+			_, _ = fmt.Fprintf(w, "    %s %s: (redacted)\n", change.op, change.path)
+		case change.op == "+":
+			_, _ = fmt.Fprintf(w, "    + %s: %s\n", change.path, formatChangeValue(change.to))
+		case change.op == "-":
+			_, _ = fmt.Fprintf(w, "    - %s: %s\n", change.path, formatChangeValue(change.from))
+		default:
+			_, _ = fmt.Fprintf(w, "    ~ %s: %s => %s\n", change.path, formatChangeValue(change.from), formatChangeValue(change.to))
+		}
+	}
+}
+
+func formatChangeValue(v interface{}) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return strconv.Quote(fmt.Sprintf("%v", v))
+	}
+	return strings.TrimSpace(string(raw))
+}
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 
@@ -18,12 +19,14 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/driver"
 	"github.com/ory/hydra/internal"
 	"github.com/ory/hydra/internal/testhelpers"
 	"github.com/ory/x/cmdx"
 	"github.com/ory/x/contextx"
 	"github.com/ory/x/snapshotx"
+	"github.com/ory/x/sqlxx"
 )
 
 func base64EncodedPGPPublicKey(t *testing.T) string {
@@ -83,6 +86,20 @@ func createClient(t *testing.T, reg driver.Registry, c *client.Client) *client.C
 	return c
 }
 
+func createLoginSession(t *testing.T, reg driver.Registry, subject string, clients ...*client.Client) *consent.LoginSession {
+	s := &consent.LoginSession{
+		ID:              uuid.Must(uuid.NewV4()).String(),
+		Subject:         subject,
+		AuthenticatedAt: sqlxx.NullTime(time.Now().Round(time.Second).UTC()),
+		Remember:        true,
+	}
+	require.NoError(t, reg.ConsentManager().CreateLoginSession(context.Background(), s))
+	for _, c := range clients {
+		consent.SaneMockAuthRequest(t, reg.ConsentManager(), s, c)
+	}
+	return s
+}
+
 func createJWK(t *testing.T, reg driver.Registry, set string, alg string) jose.JSONWebKey {
 	c, err := reg.KeyManager().GenerateAndPersistKeySet(context.Background(), set, "", alg, "sig")
 	require.NoError(t, err)
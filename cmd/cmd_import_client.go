@@ -6,20 +6,27 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 
+	"github.com/goccy/go-yaml"
 	"github.com/spf13/cobra"
 
 	hydra "github.com/ory/hydra-client-go/v2"
 	"github.com/ory/hydra/cmd/cli"
 	"github.com/ory/hydra/cmd/cliclient"
 	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
 	"github.com/ory/x/pointerx"
 )
 
+const (
+	flagUpsert = "upsert"
+	flagDryRun = "dry-run"
+)
+
 func NewImportClientCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "oauth2-client <file-1.json> [<file-2.json> ...]",
@@ -49,7 +56,7 @@ To encrypt an auto-generated OAuth2 Client Secret, use flags ` + "`--pgp-key`" +
 
   {{ .CommandPath }} -n "my app" -g client_credentials -r token -a core,foobar --keybase keybase_username
 `,
-		Long: `This command reads in each listed JSON file and imports their contents as a list of OAuth 2.0 Clients.
+		Long: `This command reads in each listed JSON or YAML file and imports their contents as a list of OAuth 2.0 Clients.
 
 The format for the JSON file is:
 
@@ -60,7 +67,16 @@ The format for the JSON file is:
   }
 ]
 
-Please be aware that this command does not update existing clients. If the client exists already, this command will fail.`,
+By default, this command does not update existing clients. If the client exists already, this command will fail.
+
+Use --upsert to update clients which exist already. Clients are matched by their client_id; clients without one are
+created, as Hydra generates the IDs of new clients. Fields given in the file overwrite the fields of the existing client,
+all other fields are kept as they are. This includes the client secret, which is only changed if the file contains one.
+Combined with "export oauth2-clients", this allows managing clients declaratively.
+
+Use --dry-run to print a field-level diff of the changes against the existing clients without applying them. Lines
+starting with "+" are added, "-" are removed and "~" are changed; clients marked with "=" are up to date. The diff is
+also written to STDERR when --upsert is used without --dry-run.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			m, _, err := cliclient.NewClient(cmd)
 			if err != nil {
@@ -73,6 +89,8 @@ Please be aware that this command does not update existing clients. If the clien
 				return cmdx.FailSilently(cmd)
 			}
 
+			upsert, dryRun := flagx.MustGetBool(cmd, flagUpsert), flagx.MustGetBool(cmd, flagDryRun)
+
 			streams := map[string]io.Reader{"STDIN": cmd.InOrStdin()}
 			for _, path := range args {
 				contents, err := os.ReadFile(path)
@@ -83,14 +101,11 @@ Please be aware that this command does not update existing clients. If the clien
 				streams[path] = bytes.NewReader(contents)
 			}
 
-			clients := map[string][]hydra.OAuth2Client{}
+			clients := map[string][]rawClient{}
 			for src, stream := range streams {
-				var current []hydra.OAuth2Client
-				if err := json.NewDecoder(stream).Decode(&current); err != nil {
-					if errors.Is(err, io.EOF) {
-						continue
-					}
-					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not decode JSON: %s", err)
+				current, err := decodeClientsFile(stream)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not decode JSON or YAML: %s", err)
 					return cmdx.FailSilently(cmd)
 				}
 				clients[src] = append(clients[src], current...)
@@ -99,16 +114,59 @@ Please be aware that this command does not update existing clients. If the clien
 			imported := make([]hydra.OAuth2Client, 0, len(clients))
 			failed := make(map[string]error)
 
+			diff := cmd.ErrOrStderr()
+			if dryRun {
+				diff = cmd.OutOrStdout()
+			}
+
 			for src, cc := range clients {
 				for _, c := range cc {
-					result, _, err := m.OAuth2Api.CreateOAuth2Client(cmd.Context()).OAuth2Client(c).Execute() //nolint:bodyclose
+					var existing rawClient
+					if id := c.id(); id != "" {
+						existing, err = getRawClient(cmd, m, id)
+						if err != nil {
+							failed[src] = err
+							continue
+						} else if existing == nil {
+							failed[src] = fmt.Errorf("OAuth 2.0 Client %s does not exist. Hydra generates the IDs of new clients, so remove the client_id to create it", id)
+							continue
+						} else if !upsert {
+							failed[src] = fmt.Errorf("OAuth 2.0 Client %s exists already, use --%s to update it", id, flagUpsert)
+							continue
+						}
+					}
+
+					changes := diffClient(existing, c)
+					if upsert || dryRun {
+						op := "+"
+						if existing != nil {
+							op = "~"
+						}
+						printClientChanges(diff, op, c, changes)
+					}
+					if dryRun {
+						continue
+					}
+
+					var result hydra.OAuth2Client
+					if existing == nil {
+						_, err = doAdminClientRequest(cmd, m, http.MethodPost, nil, c, http.StatusCreated, &result)
+					} else if len(changes) == 0 {
+						err = decodeInto(existing, &result)
+					} else {
+						update := existing.withoutServerManagedFields()
+						for k, v := range c {
+							update[k] = v
+						}
+						_, err = doAdminClientRequest(cmd, m, http.MethodPut, nil, update, http.StatusOK, &result, c.id())
+					}
 					if err != nil {
-						failed[src] = cmdx.PrintOpenAPIError(cmd, err)
+						failed[src] = err
 						continue
 					}
 
-					if result.ClientSecret == nil {
-						result.ClientSecret = c.ClientSecret
+					if secret, _ := c["client_secret"].(string); result.ClientSecret == nil && secret != "" {
+						result.ClientSecret = pointerx.String(secret)
 					}
 
 					if encryptSecret && result.ClientSecret != nil {
@@ -121,14 +179,17 @@ Please be aware that this command does not update existing clients. If the clien
 						result.ClientSecret = pointerx.String(enc.Base64Encode())
 					}
 
-					imported = append(imported, *result)
+					imported = append(imported, result)
 				}
 			}
 
-			if len(imported) == 1 {
-				cmdx.PrintRow(cmd, (*outputOAuth2Client)(&imported[0]))
-			} else {
-				cmdx.PrintTable(cmd, &outputOAuth2ClientCollection{clients: imported})
+			// The diff is the only output of a dry run.
+			if !dryRun {
+				if len(imported) == 1 {
+					cmdx.PrintRow(cmd, (*outputOAuth2Client)(&imported[0]))
+				} else {
+					cmdx.PrintTable(cmd, &outputOAuth2ClientCollection{clients: imported})
+				}
 			}
 
 			if len(failed) != 0 {
@@ -141,5 +202,44 @@ Please be aware that this command does not update existing clients. If the clien
 	}
 
 	registerEncryptFlags(cmd.Flags())
+	cmd.Flags().Bool(flagUpsert, false, "Update clients which exist already instead of failing.")
+	cmd.Flags().Bool(flagDryRun, false, "Print the changes against the existing clients without applying them.")
 	return cmd
 }
+
+// decodeClientsFile decodes a JSON or YAML list of clients. Empty input results in an empty list.
+func decodeClientsFile(stream io.Reader) ([]rawClient, error) {
+	raw, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, nil
+	}
+	if !json.Valid(raw) {
+		if raw, err = yaml.YAMLToJSON(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	clients := make([]rawClient, len(items))
+	for k := range items {
+		if clients[k], err = decodeRawClient(items[k]); err != nil {
+			return nil, err
+		}
+	}
+	return clients, nil
+}
+
+func decodeInto(in interface{}, out interface{}) error {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
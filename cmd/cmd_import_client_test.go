@@ -15,6 +15,7 @@ import (
 	"github.com/tidwall/gjson"
 
 	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/cmd"
 	"github.com/ory/x/cmdx"
 	"github.com/ory/x/pointerx"
@@ -103,3 +104,89 @@ func TestImportClient(t *testing.T) {
 		snapshotx.SnapshotT(t, json.RawMessage(actual.Raw), snapshotExcludedClientFields...)
 	})
 }
+
+func TestImportClientUpsert(t *testing.T) {
+	ctx := context.Background()
+	c := cmd.NewImportClientCmd()
+	reg := setup(t, c)
+
+	existing := createClient(t, reg, &client.Client{
+		Name:                    "upsert-me",
+		Scope:                   "foo",
+		Secret:                  "some-secret",
+		TokenEndpointAuthMethod: "client_secret_post",
+		Metadata:                []byte(`{"team":"a","keep":true}`),
+	})
+	desired := writeTempFile(t, []map[string]interface{}{
+		{"client_id": existing.GetID(), "scope": "foo bar", "metadata": map[string]interface{}{"team": "b"}},
+		{"client_name": "upsert-new-client", "client_secret": "another-secret"},
+	})
+
+	t.Run("case=fails for existing clients without upsert", func(t *testing.T) {
+		_, stderr, err := cmdx.Exec(t, c, nil, writeTempFile(t, []map[string]interface{}{{"client_id": existing.GetID()}}))
+		require.Error(t, err)
+		assert.Contains(t, stderr, "exists already, use --upsert to update it")
+	})
+
+	t.Run("case=dry run prints the diff without applying it", func(t *testing.T) {
+		stdout := cmdx.ExecNoErr(t, c, desired, "--dry-run", "--upsert")
+		assert.Contains(t, stdout, "~ oauth2-client "+existing.GetID()+"\n")
+		assert.Contains(t, stdout, `    ~ scope: "foo" => "foo bar"`)
+		assert.Contains(t, stdout, `    - metadata.keep: true`)
+		assert.Contains(t, stdout, `    ~ metadata.team: "a" => "b"`)
+		assert.Contains(t, stdout, `+ oauth2-client "upsert-new-client"`+"\n")
+		assert.Contains(t, stdout, `    + client_name: "upsert-new-client"`)
+		assert.Contains(t, stdout, `    + client_secret: (redacted)`)
+		assert.NotContains(t, stdout, "another-secret")
+
+		actual, err := reg.ClientManager().GetConcreteClient(ctx, existing.GetID())
+		require.NoError(t, err)
+		assert.Equal(t, "foo", actual.Scope)
+		created, _, err := reg.ClientManager().GetClients(ctx, client.Filter{Name: "upsert-new-client"})
+		require.NoError(t, err)
+		assert.Empty(t, created)
+	})
+
+	t.Run("case=upserts clients", func(t *testing.T) {
+		stdout, stderr, err := cmdx.Exec(t, c, nil, desired, "--upsert", "--dry-run=false")
+		require.NoError(t, err, stderr)
+		actual := gjson.Parse(stdout)
+		require.Len(t, actual.Array(), 2)
+		assert.Contains(t, stderr, `~ scope: "foo" => "foo bar"`)
+
+		updated, err := reg.ClientManager().Authenticate(ctx, existing.GetID(), []byte("some-secret"))
+		require.NoError(t, err)
+		assert.Equal(t, "foo bar", updated.Scope)
+		assert.Equal(t, "upsert-me", updated.Name)
+		assert.JSONEq(t, `{"team":"b"}`, string(updated.Metadata))
+
+		for _, c := range actual.Array() {
+			if c.Get("client_name").String() == "upsert-new-client" {
+				_, err = reg.ClientManager().Authenticate(ctx, c.Get("client_id").String(), []byte("another-secret"))
+				require.NoError(t, err)
+			}
+		}
+	})
+
+	t.Run("case=fails for unknown client IDs", func(t *testing.T) {
+		_, stderr, err := cmdx.Exec(t, c, nil, writeTempFile(t, []map[string]interface{}{{"client_id": "does-not-exist"}}), "--upsert")
+		require.Error(t, err)
+		assert.Contains(t, stderr, "OAuth 2.0 Client does-not-exist does not exist")
+	})
+
+	t.Run("case=reports clients which are up to date", func(t *testing.T) {
+		stdout := cmdx.ExecNoErr(t, c, writeTempFile(t, []map[string]interface{}{{"client_id": existing.GetID(), "scope": "foo bar"}}), "--dry-run", "--upsert")
+		assert.Equal(t, "= oauth2-client "+existing.GetID()+"\n", stdout)
+	})
+
+	t.Run("case=imports YAML", func(t *testing.T) {
+		f, err := os.CreateTemp(t.TempDir(), "*.yaml")
+		require.NoError(t, err)
+		_, err = f.WriteString("- client_name: upsert-yaml-client\n  scope: yaml\n")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		stdout := cmdx.ExecNoErr(t, c, f.Name(), "--dry-run=false", "--upsert=false")
+		assert.Equal(t, "yaml", gjson.Get(stdout, "scope").String(), stdout)
+	})
+}
@@ -4,14 +4,48 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	hydra "github.com/ory/hydra-client-go/v2"
 	"github.com/ory/hydra/cmd/cliclient"
 	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/ory/x/pointerx"
+	"github.com/ory/x/urlx"
 )
 
+const (
+	flagExpiringWithin  = "expiring-within"
+	flagRedirectURIHost = "redirect-uri-host"
+	flagCreatedAfter    = "created-after"
+	flagCreatedBefore   = "created-before"
+	flagUpdatedAfter    = "updated-after"
+	flagUpdatedBefore   = "updated-before"
+	flagSortBy          = "sort-by"
+	flagSortOrder       = "sort-order"
+)
+
+// listClientsQueryFlags maps the flags of the list clients command to the query parameters of the admin API.
+var listClientsQueryFlags = map[string]string{
+	flagClientGrantType:               "grant_type",
+	flagRedirectURIHost:               "redirect_uri_host",
+	flagClientAudience:                "audience",
+	flagClientTokenEndpointAuthMethod: "token_endpoint_auth_method",
+	flagCreatedAfter:                  "created_after",
+	flagCreatedBefore:                 "created_before",
+	flagUpdatedAfter:                  "updated_after",
+	flagUpdatedBefore:                 "updated_before",
+	flagSortBy:                        "sort_by",
+	flagSortOrder:                     "sort_order",
+}
+
 func NewListClientsCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "oauth2-clients",
@@ -19,7 +53,20 @@ func NewListClientsCmd() *cobra.Command {
 		Short:   "List OAuth 2.0 Clients",
 		Long:    `This command list an OAuth 2.0 Clients.`,
 		Args:    cobra.NoArgs,
-		Example: fmt.Sprintf("{{ .CommandPath }} --%s eyJwYWdlIjoxfQ --%s 10", cmdx.FlagPageToken, cmdx.FlagPageSize),
+		Example: fmt.Sprintf(`{{ .CommandPath }} --%[1]s 10
+
+To list the next page, pass the next_page_token of the previous page:
+
+  {{ .CommandPath }} --%[2]s <next_page_token> --%[1]s 10
+
+To list the clients whose secret expires within the next week:
+
+  {{ .CommandPath }} --%[3]s 7d
+
+To list the newest clients using the client credentials grant of a tenant:
+
+  {{ .CommandPath }} --%[4]s client_credentials --%[5]s tenant.id=acme --%[6]s created_at --%[7]s desc`,
+			cmdx.FlagPageSize, cmdx.FlagPageToken, flagExpiringWithin, flagClientGrantType, flagClientMetadata, flagSortBy, flagSortOrder),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			m, _, err := cliclient.NewClient(cmd)
 			if err != nil {
@@ -31,9 +78,36 @@ func NewListClientsCmd() *cobra.Command {
 				return err
 			}
 
-			list, resp, err := m.OAuth2Api.ListOAuth2Clients(cmd.Context()).PageSize(int64(pageSize)).PageToken(pageToken).Execute()
-			if err != nil {
-				return cmdx.PrintOpenAPIError(cmd, err)
+			query := url.Values{}
+			for flag, param := range listClientsQueryFlags {
+				if value := flagx.MustGetString(cmd, flag); value != "" {
+					query.Set(param, value)
+				}
+			}
+			for _, m := range flagx.MustGetStringArray(cmd, flagClientMetadata) {
+				query.Add("metadata", m)
+			}
+			if expiringWithin := flagx.MustGetString(cmd, flagExpiringWithin); expiringWithin != "" {
+				within, err := parseDuration(expiringWithin)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Unable to parse --%s: %s\n", flagExpiringWithin, err)
+					return cmdx.FailSilently(cmd)
+				}
+				query.Set("client_secret_expires_before", strconv.FormatInt(time.Now().Add(within).Unix(), 10))
+			}
+
+			var list []hydra.OAuth2Client
+			var resp *http.Response
+			if len(query) > 0 {
+				list, resp, err = listClientsWithQuery(cmd, m, pageToken, pageSize, query)
+				if err != nil {
+					return err
+				}
+			} else {
+				list, resp, err = m.OAuth2Api.ListOAuth2Clients(cmd.Context()).PageSize(int64(pageSize)).PageToken(pageToken).Execute()
+				if err != nil {
+					return cmdx.PrintOpenAPIError(cmd, err)
+				}
 			}
 
 			var collection outputOAuth2ClientCollection
@@ -54,5 +128,53 @@ func NewListClientsCmd() *cobra.Command {
 		},
 	}
 	cmdx.RegisterTokenPaginationFlags(cmd)
+	cmd.Flags().String(flagExpiringWithin, "", "Only list clients whose secret expires within the given duration, for example 7d or 12h. Clients whose secret already expired are included.")
+	cmd.Flags().String(flagClientGrantType, "", "Only list clients which are allowed to use this grant type.")
+	cmd.Flags().String(flagRedirectURIHost, "", "Only list clients with a redirect URI on this host, for example app.example.com or localhost:3000.")
+	cmd.Flags().String(flagClientAudience, "", "Only list clients which are allowed to request this audience.")
+	cmd.Flags().String(flagClientTokenEndpointAuthMethod, "", "Only list clients which use this token endpoint authentication method.")
+	cmd.Flags().String(flagCreatedAfter, "", "Only list clients created at or after this RFC 3339 date.")
+	cmd.Flags().String(flagCreatedBefore, "", "Only list clients created before this RFC 3339 date.")
+	cmd.Flags().String(flagUpdatedAfter, "", "Only list clients updated at or after this RFC 3339 date.")
+	cmd.Flags().String(flagUpdatedBefore, "", "Only list clients updated before this RFC 3339 date.")
+	cmd.Flags().StringArray(flagClientMetadata, nil, "Only list clients whose metadata has the value at the dot-separated JSON path, given as path=value. Can be repeated.")
+	cmd.Flags().String(flagSortBy, "", "Sort the clients by one of created_at, updated_at or client_name.")
+	cmd.Flags().String(flagSortOrder, "", "Sort the clients in asc (default) or desc order.")
 	return cmd
 }
+
+// listClientsWithQuery lists the clients matching the filters and sort options in the query. These are not part
+// of the SDK yet, so the request is built by hand.
+func listClientsWithQuery(cmd *cobra.Command, m *hydra.APIClient, pageToken string, pageSize int, query url.Values) ([]hydra.OAuth2Client, *http.Response, error) {
+	conf := m.GetConfig()
+	target := urlx.AppendPaths(urlx.ParseOrPanic(conf.Servers[0].URL), "/admin/clients")
+	query.Set("page_size", strconv.Itoa(pageSize))
+	if pageToken != "" {
+		query.Set("page_token", pageToken)
+	}
+	target.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(cmd.Context(), http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := conf.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e hydra.ErrorOAuth2
+		_ = json.NewDecoder(res.Body).Decode(&e)
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to list the OAuth 2.0 Clients: %s %s\n", pointerx.StringR(e.Error), pointerx.StringR(e.ErrorDescription))
+		return nil, nil, cmdx.FailSilently(cmd)
+	}
+
+	var list []hydra.OAuth2Client
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return nil, nil, err
+	}
+	return list, res, nil
+}
@@ -5,6 +5,7 @@ package cmd_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -42,8 +43,36 @@ func TestListClient(t *testing.T) {
 
 		actualSecond := gjson.Parse(cmdx.ExecNoErr(t, c, "--format", "json", "--page-size", "1", "--page-token", actualFirst.Get("next_page_token").String()))
 		assert.Len(t, actualSecond.Array(), 1)
+		assert.True(t, actualSecond.Get("is_last_page").Bool(), actualSecond.Raw)
 
 		assert.NotEmpty(t, actualFirst.Get("items.0.client_id").String())
 		assert.NotEqualValues(t, actualFirst.Get("items.0.client_id").String(), actualSecond.Get("items.0.client_id").String())
 	})
+
+	t.Run("case=lists clients whose secret expires soon", func(t *testing.T) {
+		expiring := createClient(t, reg, &client.Client{TokenEndpointAuthMethod: "client_secret_post", Secret: "a-secret", SecretExpiresAt: int(time.Now().Add(72 * time.Hour).Unix())})
+		createClient(t, reg, &client.Client{TokenEndpointAuthMethod: "client_secret_post", Secret: "a-secret", SecretExpiresAt: int(time.Now().Add(30 * 24 * time.Hour).Unix())})
+
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, "--format", "json", "--page-size", "100", "--page-token", "", "--expiring-within", "7d"))
+		require.Len(t, actual.Get("items").Array(), 1, actual.Raw)
+		assert.Equal(t, expiring.GetID(), actual.Get("items.0.client_id").String())
+
+		stderr := cmdx.ExecExpectedErr(t, c, "--expiring-within", "a week")
+		assert.Contains(t, stderr, "Unable to parse --expiring-within")
+	})
+
+	t.Run("case=lists clients matching filters sorted by name", func(t *testing.T) {
+		second := createClient(t, reg, &client.Client{Name: "filter-b", GrantTypes: []string{"client_credentials"}, Metadata: []byte(`{"team":"cli"}`)})
+		first := createClient(t, reg, &client.Client{Name: "filter-a", GrantTypes: []string{"client_credentials"}, Metadata: []byte(`{"team":"cli"}`)})
+		createClient(t, reg, &client.Client{Name: "filter-c", GrantTypes: []string{"authorization_code"}, Metadata: []byte(`{"team":"cli"}`)})
+
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, "--format", "json", "--page-size", "100", "--page-token", "", "--expiring-within", "",
+			"--grant-type", "client_credentials", "--metadata", "team=cli", "--sort-by", "client_name", "--sort-order", "desc"))
+		require.Len(t, actual.Get("items").Array(), 2, actual.Raw)
+		assert.Equal(t, second.GetID(), actual.Get("items.0.client_id").String())
+		assert.Equal(t, first.GetID(), actual.Get("items.1.client_id").String())
+
+		stderr := cmdx.ExecExpectedErr(t, c, "--sort-by", "secret")
+		assert.Contains(t, stderr, "Unable to sort clients by")
+	})
 }
@@ -0,0 +1,76 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/hydra/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+)
+
+const flagSubject = "subject"
+
+func NewListLoginSessionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "login-sessions",
+		Aliases: []string{"login-session"},
+		Short:   "List the login sessions of a subject",
+		Long: `This command lists the login sessions of a subject, starting with the most recently authenticated one.
+
+Each login session includes the OAuth 2.0 Clients which used it, so you can see where a subject is logged in.`,
+		Args:    cobra.NoArgs,
+		Example: fmt.Sprintf(`{{ .CommandPath }} --%s foo@bar.com --%s 10`, flagSubject, cmdx.FlagPageSize),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, _, err := cliclient.NewClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			subject := flagx.MustGetString(cmd, flagSubject)
+			if subject == "" {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Please provide the subject using flag --%s.\n", flagSubject)
+				return cmdx.FailSilently(cmd)
+			}
+
+			pageToken, pageSize, err := cmdx.ParseTokenPaginationArgs(cmd)
+			if err != nil {
+				return err
+			}
+
+			query := url.Values{"subject": {subject}, "page_size": {strconv.Itoa(pageSize)}}
+			if pageToken != "" {
+				query.Set("page_token", pageToken)
+			}
+
+			var sessions []loginSession
+			res, err := doAdminRequest(cmd, m, http.MethodGet, query, nil, http.StatusOK, &sessions, "/admin/oauth2/auth/sessions/login")
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to list the login sessions: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			collection := outputLoginSessionCollection{sessions: sessions}
+			items := make([]interface{}, len(sessions))
+			for k := range sessions {
+				items[k] = (*outputLoginSession)(&sessions[k])
+			}
+
+			result := &cmdx.PaginatedList{Items: items, Collection: collection}
+			result.NextPageToken = getPageToken(res)
+			result.IsLastPage = result.NextPageToken == ""
+			cmdx.PrintTable(cmd, result)
+			return nil
+		},
+	}
+	cmdx.RegisterTokenPaginationFlags(cmd)
+	cmd.Flags().String(flagSubject, "", "The subject to list the login sessions for.")
+	return cmd
+}
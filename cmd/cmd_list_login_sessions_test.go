@@ -0,0 +1,56 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/cmd"
+	"github.com/ory/x/cmdx"
+)
+
+func TestListLoginSessions(t *testing.T) {
+	c := cmd.NewListLoginSessionsCmd()
+	reg := setup(t, c)
+
+	cl := createClient(t, reg, nil)
+	used := createLoginSession(t, reg, "list-subject", cl)
+	unused := createLoginSession(t, reg, "list-subject")
+	createLoginSession(t, reg, "other-subject", cl)
+
+	t.Run("case=requires a subject", func(t *testing.T) {
+		stderr := cmdx.ExecExpectedErr(t, c, "--subject", "")
+		assert.Contains(t, stderr, "--subject")
+	})
+
+	t.Run("case=lists the sessions of the subject", func(t *testing.T) {
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, "--subject", "list-subject"))
+		require.Len(t, actual.Get("items").Array(), 2, actual.Raw)
+		assert.ElementsMatch(t, []string{used.ID, unused.ID}, []string{actual.Get("items.0.id").String(), actual.Get("items.1.id").String()})
+
+		for _, item := range actual.Get("items").Array() {
+			assert.Equal(t, "list-subject", item.Get("subject").String())
+			if item.Get("id").String() == used.ID {
+				assert.Equal(t, []interface{}{cl.GetID()}, item.Get("client_ids").Value())
+			} else {
+				assert.Empty(t, item.Get("client_ids").Array())
+			}
+		}
+	})
+
+	t.Run("case=lists the sessions with pagination", func(t *testing.T) {
+		first := gjson.Parse(cmdx.ExecNoErr(t, c, "--subject", "list-subject", "--page-size", "1"))
+		require.Len(t, first.Get("items").Array(), 1, first.Raw)
+		require.NotEmpty(t, first.Get("next_page_token").String(), first.Raw)
+
+		second := gjson.Parse(cmdx.ExecNoErr(t, c, "--subject", "list-subject", "--page-size", "1", "--page-token", first.Get("next_page_token").String()))
+		require.Len(t, second.Get("items").Array(), 1, second.Raw)
+		assert.True(t, second.Get("is_last_page").Bool(), second.Raw)
+		assert.NotEqual(t, first.Get("items.0.id").String(), second.Get("items.0.id").String())
+	})
+}
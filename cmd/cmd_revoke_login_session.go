@@ -0,0 +1,61 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/hydra/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+)
+
+func NewRevokeLoginSessionsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "login-session <id-1> [<id-2> ...]",
+		Aliases: []string{"login-sessions"},
+		Args:    cobra.MinimumNArgs(1),
+		Short:   "Revoke one or more login sessions by their ID(s)",
+		Long: `This command revokes login sessions by their IDs, while the other login sessions of the subject remain valid.
+
+The subject has to re-authenticate the next time the user-agent which used the session starts an OAuth 2.0 flow.
+Issued tokens are not revoked.`,
+		Example: `To revoke all login sessions of a subject which were used by a client, run:
+
+	{{ .CommandPath }} $({{ .Root.Name }} list login-sessions --subject foo@bar.com --format json | jq -r '.items | map(select(.client_ids | index("my-client"))) | .[].id')`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, _, err := cliclient.NewClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			var (
+				revoked = make([]cmdx.OutputIder, 0, len(args))
+				failed  = make(map[string]error)
+			)
+
+			for _, id := range args {
+				if _, err := doAdminRequest(cmd, m, http.MethodDelete, nil, nil, http.StatusNoContent, nil, "/admin/oauth2/auth/sessions/login", id); err != nil {
+					failed[id] = err
+					continue
+				}
+				revoked = append(revoked, cmdx.OutputIder(id))
+			}
+
+			if len(revoked) == 1 {
+				cmdx.PrintRow(cmd, &revoked[0])
+			} else if len(revoked) > 1 {
+				cmdx.PrintTable(cmd, &cmdx.OutputIderCollection{Items: revoked})
+			}
+
+			cmdx.PrintErrors(cmd, failed)
+			if len(failed) != 0 {
+				return cmdx.FailSilently(cmd)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/cmd"
+	"github.com/ory/hydra/x"
+	"github.com/ory/x/assertx"
+	"github.com/ory/x/cmdx"
+)
+
+func TestRevokeLoginSession(t *testing.T) {
+	ctx := context.Background()
+	c := cmd.NewRevokeLoginSessionsCmd()
+	reg := setup(t, c)
+
+	t.Run("case=revokes a session", func(t *testing.T) {
+		revoked := createLoginSession(t, reg, "revoke-subject")
+		kept := createLoginSession(t, reg, "revoke-subject")
+
+		stdout := cmdx.ExecNoErr(t, c, revoked.ID)
+		assert.Equal(t, fmt.Sprintf(`"%s"`, revoked.ID), strings.TrimSpace(stdout))
+
+		_, err := reg.ConsentManager().GetLoginSession(ctx, revoked.ID)
+		assert.ErrorIs(t, err, x.ErrNotFound)
+		_, err = reg.ConsentManager().GetLoginSession(ctx, kept.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("case=revokes multiple sessions", func(t *testing.T) {
+		s1 := createLoginSession(t, reg, "revoke-subject")
+		s2 := createLoginSession(t, reg, "revoke-subject")
+		assertx.EqualAsJSON(t, []string{s1.ID, s2.ID}, json.RawMessage(cmdx.ExecNoErr(t, c, s1.ID, s2.ID)))
+	})
+
+	t.Run("case=one session revocation fails", func(t *testing.T) {
+		s := createLoginSession(t, reg, "revoke-subject")
+		stdout, stderr, err := cmdx.Exec(t, c, nil, "i-do-not-exist", s.ID)
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf(`"%s"`, s.ID), strings.TrimSpace(stdout))
+		assert.Contains(t, stderr, "i-do-not-exist")
+
+		_, err = reg.ConsentManager().GetLoginSession(ctx, s.ID)
+		assert.ErrorIs(t, err, x.ErrNotFound)
+	})
+}
@@ -0,0 +1,103 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/hydra/cmd/cli"
+	"github.com/ory/hydra/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/ory/x/pointerx"
+	"github.com/ory/x/urlx"
+)
+
+const flagGracePeriod = "grace-period"
+
+func NewRotateClientSecretCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "oauth2-client-secret [id]",
+		Aliases: []string{"client-secret"},
+		Short:   "Rotate the secret of an OAuth 2.0 Client",
+		Args:    cobra.ExactArgs(1),
+		Example: `{{ .CommandPath }} <client-id-here> --grace-period 48h
+
+To encrypt the auto-generated OAuth2 Client Secret, use flags ` + "`--pgp-key`" + `, ` + "`--pgp-key-url`" + ` or ` + "`--keybase`" + ` flag, for example:
+
+  {{ .CommandPath }} e6e96aa5-9cd2-4a70-bf56-ad6434c8aaa2 --keybase keybase_username
+`,
+		Long: `This command replaces the secret of an OAuth 2.0 Client. The previous secret remains valid until the grace period ends, so that the new secret can be rolled out to the client without downtime.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, _, err := cliclient.NewClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			ek, encryptSecret, err := cli.NewEncryptionKey(cmd, nil)
+			if err != nil {
+				return err
+			}
+
+			body, err := json.Marshal(map[string]string{
+				"client_secret": flagx.MustGetString(cmd, flagClientSecret),
+				"grace_period":  flagx.MustGetDuration(cmd, flagGracePeriod).String(),
+			})
+			if err != nil {
+				return err
+			}
+
+			conf := m.GetConfig()
+			target := urlx.AppendPaths(urlx.ParseOrPanic(conf.Servers[0].URL), "/admin/clients", args[0], "secret", "rotate")
+			req, err := http.NewRequestWithContext(cmd.Context(), http.MethodPost, target.String(), bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			res, err := conf.HTTPClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != http.StatusOK {
+				var e hydra.ErrorOAuth2
+				_ = json.NewDecoder(res.Body).Decode(&e)
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to rotate the client secret: %s %s\n", pointerx.StringR(e.Error), pointerx.StringR(e.ErrorDescription))
+				return cmdx.FailSilently(cmd)
+			}
+
+			var client hydra.OAuth2Client
+			if err := json.NewDecoder(res.Body).Decode(&client); err != nil {
+				return err
+			}
+
+			if encryptSecret && client.ClientSecret != nil {
+				enc, err := ek.Encrypt([]byte(*client.ClientSecret))
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to encrypt client secret: %s", err)
+					return cmdx.FailSilently(cmd)
+				}
+
+				client.ClientSecret = pointerx.String(enc.Base64Encode())
+			}
+
+			cmdx.PrintRow(cmd, (*outputOAuth2Client)(&client))
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagClientSecret, "", "Provide the new client secret. If empty, a secret is generated.")
+	cmd.Flags().Duration(flagGracePeriod, 24*time.Hour, "How long the previous client secret remains valid.")
+	registerEncryptFlags(cmd.Flags())
+	return cmd
+}
@@ -0,0 +1,52 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/cmd"
+	"github.com/ory/x/cmdx"
+)
+
+func TestRotateClientSecret(t *testing.T) {
+	ctx := context.Background()
+	c := cmd.NewRotateClientSecretCmd()
+	reg := setup(t, c)
+
+	original := createClient(t, reg, nil)
+	t.Run("case=rotates successfully", func(t *testing.T) {
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, original.GetID(), "--secret", "a-rotated-secret", "--grace-period", "1h"))
+		assert.Equal(t, original.GetID(), actual.Get("client_id").String())
+		assert.Equal(t, "a-rotated-secret", actual.Get("client_secret").String())
+
+		_, err := reg.ClientManager().Authenticate(ctx, original.GetID(), []byte("a-rotated-secret"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("case=generates a secret", func(t *testing.T) {
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, original.GetID(), "--secret", "", "--grace-period", "0s"))
+		secret := actual.Get("client_secret").String()
+		assert.NotEmpty(t, secret)
+
+		_, err := reg.ClientManager().Authenticate(ctx, original.GetID(), []byte(secret))
+		assert.NoError(t, err)
+		_, err = reg.ClientManager().Authenticate(ctx, original.GetID(), []byte("a-rotated-secret"))
+		assert.Error(t, err)
+	})
+
+	t.Run("case=supports encryption", func(t *testing.T) {
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, original.GetID(), "--pgp-key", base64EncodedPGPPublicKey(t)))
+		assert.NotEmpty(t, actual.Get("client_secret").String())
+	})
+
+	t.Run("case=fails for unknown clients", func(t *testing.T) {
+		stderr := cmdx.ExecExpectedErr(t, c, "does-not-exist")
+		assert.Contains(t, stderr, "Failed to rotate the client secret")
+	})
+}
@@ -4,12 +4,23 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/spf13/cobra"
 	"github.com/tomnomnom/linkheader"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/x/pointerx"
+	"github.com/ory/x/urlx"
 )
 
 var osExit = os.Exit
@@ -20,7 +31,7 @@ func fatal(message string, args ...interface{}) {
 }
 
 func getPageToken(resp *http.Response) string {
-	for _, link := range linkheader.Parse(resp.Header.Get("Link")) {
+	for _, link := range linkheader.ParseMultiple(resp.Header.Values("Link")) {
 		if link.Rel != "next" {
 			continue
 		}
@@ -37,3 +48,64 @@ func getPageToken(resp *http.Response) string {
 
 	return ""
 }
+
+// parseDuration parses a duration like time.ParseDuration does, but also accepts a number of days such as "7d".
+func parseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// doAdminRequest sends a request to an admin API endpoint which is not part of the SDK yet, and decodes the response
+// into out. Responses with a status code other than expected are returned as an error.
+func doAdminRequest(cmd *cobra.Command, m *hydra.APIClient, method string, query url.Values, body interface{}, expected int, out interface{}, paths ...string) (*http.Response, error) {
+	conf := m.GetConfig()
+	target := urlx.AppendPaths(urlx.ParseOrPanic(conf.Servers[0].URL), paths...)
+	target.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(cmd.Context(), method, target.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := conf.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res, err
+	}
+
+	if res.StatusCode != expected {
+		var e hydra.ErrorOAuth2
+		_ = json.Unmarshal(raw, &e)
+		return res, fmt.Errorf("the server responded with status code %d: %s %s", res.StatusCode, pointerx.StringR(e.Error), pointerx.StringR(e.ErrorDescription))
+	}
+
+	if out == nil {
+		return res, nil
+	}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	return res, d.Decode(out)
+}
@@ -10,6 +10,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/x"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/pagination/tokenpagination"
 )
 
@@ -34,4 +37,16 @@ func TestGetPageToken(t *testing.T) {
 	rec := httptest.NewRecorder()
 	tokenpagination.PaginationHeader(rec, u, 100, 3, 10)
 	assert.Equal(t, `eyJwYWdlIjoiNDAiLCJ2IjoxfQ`, getPageToken(rec.Result()), rec.Result().Header.Get("Link"))
+
+	t.Run("case=keyset pagination", func(t *testing.T) {
+		token := x.EncodePageToken("some-id")
+		rec := httptest.NewRecorder()
+		x.KeysetPaginationHeader(rec, u, 100, keysetpagination.GetPaginator(keysetpagination.WithToken(token)))
+		assert.Equal(t, token, getPageToken(rec.Result()), rec.Result().Header.Get("Link"))
+
+		rec = httptest.NewRecorder()
+		_, last := keysetpagination.Result([]client.Client{}, keysetpagination.GetPaginator())
+		x.KeysetPaginationHeader(rec, u, 100, last)
+		assert.Empty(t, getPageToken(rec.Result()), "the last page has no next link")
+	})
 }
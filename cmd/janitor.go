@@ -58,9 +58,13 @@ Janitor can be used in several ways.
 
 		hydra janitor --grants {database-url}
 
+    or
+
+		hydra janitor --clients --deleted-client-retention 168h {database-url}
+
    or any combination of them
 
-		hydra janitor --tokens --requests --grants {database-url}
+		hydra janitor --tokens --requests --grants --clients {database-url}
 `,
 		RunE: cli.NewHandler(slOpts, dOpts, cOpts).Janitor.RunE,
 		Args: cli.NewHandler(slOpts, dOpts, cOpts).Janitor.Args,
@@ -71,9 +75,12 @@ Janitor can be used in several ways.
 	cmd.Flags().Duration(cli.AccessLifespan, 0, "Set the access token lifespan e.g. 1s, 1m, 1h.")
 	cmd.Flags().Duration(cli.RefreshLifespan, 0, "Set the refresh token lifespan e.g. 1s, 1m, 1h.")
 	cmd.Flags().Duration(cli.ConsentRequestLifespan, 0, "Set the login/consent request lifespan e.g. 1s, 1m, 1h")
-	cmd.Flags().Bool(cli.OnlyRequests, false, "This will only run the cleanup on requests and will skip token and trust relationships cleanup.")
-	cmd.Flags().Bool(cli.OnlyTokens, false, "This will only run the cleanup on tokens and will skip requests and trust relationships cleanup.")
+	cmd.Flags().Duration(cli.DeletedClientRetention, 0, "Set for how long deleted OAuth 2.0 Clients are retained before they are purged e.g. 1h, 168h.")
+	cmd.Flags().Duration(cli.LoginSessionMaxAge, 0, "Set the maximum age of login sessions e.g. 24h, 720h.")
+	cmd.Flags().Bool(cli.OnlyRequests, false, "This will only run the cleanup on requests, expired login sessions and completed back-channel logout deliveries and will skip token and trust relationships cleanup.")
+	cmd.Flags().Bool(cli.OnlyTokens, false, "This will only run the cleanup on tokens, device codes, and pushed authorization requests and will skip requests and trust relationships cleanup.")
 	cmd.Flags().Bool(cli.OnlyGrants, false, "This will only run the cleanup on trust relationships and will skip requests and token cleanup.")
+	cmd.Flags().Bool(cli.OnlyClients, false, "This will only run the cleanup on deleted OAuth 2.0 Clients whose retention period has passed. Their tokens and consent sessions are purged with them.")
 	cmd.Flags().BoolP(cli.ReadFromEnv, "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
 	configx.RegisterFlags(cmd.PersistentFlags())
 	return cmd
@@ -0,0 +1,80 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// loginSession is an OAuth 2.0 Login Session as returned by the admin API. The SDK does not have a model for
+	// it yet.
+	loginSession struct {
+		ID              string     `json:"id"`
+		Subject         string     `json:"subject"`
+		AuthenticatedAt *time.Time `json:"authenticated_at"`
+		Remember        bool       `json:"remember"`
+		ACR             string     `json:"acr,omitempty"`
+		AMR             []string   `json:"amr,omitempty"`
+		ClientIDs       []string   `json:"client_ids"`
+	}
+
+	outputLoginSession           loginSession
+	outputLoginSessionCollection struct {
+		sessions []loginSession
+	}
+)
+
+func (_ outputLoginSession) Header() []string {
+	return []string{"SESSION ID", "SUBJECT", "AUTHENTICATED AT", "REMEMBER", "CLIENT IDS"}
+}
+
+func (i outputLoginSession) Columns() []string {
+	authenticatedAt := ""
+	if i.AuthenticatedAt != nil && !i.AuthenticatedAt.IsZero() {
+		authenticatedAt = i.AuthenticatedAt.Format(time.RFC3339)
+	}
+	data := [5]string{
+		i.ID,
+		i.Subject,
+		authenticatedAt,
+		strconv.FormatBool(i.Remember),
+		strings.Join(i.ClientIDs, ", "),
+	}
+	return data[:]
+}
+
+func (i outputLoginSession) Interface() interface{} {
+	return i
+}
+
+func (_ outputLoginSessionCollection) Header() []string {
+	return outputLoginSession{}.Header()
+}
+
+func (c outputLoginSessionCollection) Table() [][]string {
+	rows := make([][]string, len(c.sessions))
+	for i, session := range c.sessions {
+		rows[i] = outputLoginSession(session).Columns()
+	}
+	return rows
+}
+
+func (c outputLoginSessionCollection) Interface() interface{} {
+	return c.sessions
+}
+
+func (c outputLoginSessionCollection) Len() int {
+	return len(c.sessions)
+}
+
+func (c outputLoginSessionCollection) IDs() []string {
+	ids := make([]string, len(c.sessions))
+	for i, session := range c.sessions {
+		ids[i] = session.ID
+	}
+	return ids
+}
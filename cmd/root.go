@@ -37,6 +37,7 @@ func RegisterCommandRecursive(parent *cobra.Command, slOpts []servicelocatorx.Op
 	getCmd.AddCommand(
 		NewGetClientsCmd(),
 		NewGetJWKSCmd(),
+		NewGetLoginSessionsCmd(),
 	)
 
 	deleteCmd := NewDeleteCmd()
@@ -47,7 +48,10 @@ func RegisterCommandRecursive(parent *cobra.Command, slOpts []servicelocatorx.Op
 	)
 
 	listCmd := NewListCmd()
-	listCmd.AddCommand(NewListClientsCmd())
+	listCmd.AddCommand(
+		NewListClientsCmd(),
+		NewListLoginSessionsCmd(),
+	)
 
 	updateCmd := NewUpdateCmd()
 	updateCmd.AddCommand(NewUpdateClientCmd())
@@ -58,6 +62,9 @@ func RegisterCommandRecursive(parent *cobra.Command, slOpts []servicelocatorx.Op
 		NewKeysImportCmd(),
 	)
 
+	exportCmd := NewExportCmd()
+	exportCmd.AddCommand(NewExportClientsCmd())
+
 	performCmd := NewPerformCmd()
 	performCmd.AddCommand(
 		NewPerformClientCredentialsCmd(),
@@ -65,7 +72,19 @@ func RegisterCommandRecursive(parent *cobra.Command, slOpts []servicelocatorx.Op
 	)
 
 	revokeCmd := NewRevokeCmd()
-	revokeCmd.AddCommand(NewRevokeTokenCmd())
+	revokeCmd.AddCommand(
+		NewRevokeTokenCmd(),
+		NewRevokeLoginSessionsCmd(),
+	)
+
+	disableCmd := NewDisableCmd()
+	disableCmd.AddCommand(NewDisableClientCmd())
+
+	enableCmd := NewEnableCmd()
+	enableCmd.AddCommand(NewEnableClientCmd())
+
+	rotateCmd := NewRotateCmd()
+	rotateCmd.AddCommand(NewRotateClientSecretCmd())
 
 	introspectCmd := NewIntrospectCmd()
 	introspectCmd.AddCommand(NewIntrospectTokenCmd())
@@ -86,9 +105,13 @@ func RegisterCommandRecursive(parent *cobra.Command, slOpts []servicelocatorx.Op
 		listCmd,
 		updateCmd,
 		importCmd,
+		exportCmd,
 		performCmd,
 		introspectCmd,
 		revokeCmd,
+		rotateCmd,
+		disableCmd,
+		enableCmd,
 		migrateCmd,
 		serveCmd,
 		NewJanitorCmd(slOpts, dOpts, cOpts),
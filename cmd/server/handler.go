@@ -294,6 +294,9 @@ func setup(ctx context.Context, d driver.Registry, cmd *cobra.Command) (admin *h
 
 	d.RegisterRoutes(ctx, admin, public)
 
+	// Resumes the back-channel logout deliveries which are pending, including the ones interrupted by a restart.
+	go d.BackChannelLogoutDeliverer().Run(ctx)
+
 	return
 }
 
@@ -318,6 +321,11 @@ func serve(
 	if tc := d.Config().TLS(ctx, iface); tc.Enabled() {
 		// #nosec G402 - This is a false positive because we use graceful.WithDefaults which sets the correct TLS settings.
 		tlsConfig = &tls.Config{GetCertificate: GetOrCreateTLSCertificate(ctx, d, iface, stopReload)}
+		if tc.ClientCertificateEnabled() {
+			// Client certificates are verified by the client authentication strategy, because self-signed
+			// certificates are valid for clients using self_signed_tls_client_auth.
+			tlsConfig.ClientAuth = tls.RequestClientCert
+		}
 	}
 
 	var srv = graceful.WithDefaults(&http.Server{
@@ -0,0 +1,65 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+
+	"github.com/ory/hydra/client"
+)
+
+// ErrUnmetAuthenticationRequirements is returned if the login session does not satisfy the requested Authentication
+// Context Class Reference and the End-User can not be asked to log in again, see
+// https://openid.net/specs/openid-connect-unmet-authentication-requirements-1_0.html.
+var ErrUnmetAuthenticationRequirements = &fosite.RFC6749Error{
+	ErrorField:       "unmet_authentication_requirements",
+	DescriptionField: "The Authorization Server is unable to meet the requirements of the Relying Party for the authentication of the End-User.",
+	CodeField:        http.StatusBadRequest,
+}
+
+// claimsRequest is the part of the OpenID Connect `claims` parameter which requests the `acr` claim.
+type claimsRequest struct {
+	IDToken struct {
+		ACR *struct {
+			Essential bool     `json:"essential"`
+			Value     string   `json:"value"`
+			Values    []string `json:"values"`
+		} `json:"acr"`
+	} `json:"id_token"`
+}
+
+// requestedACRValues returns the Authentication Context Class References of which a login session must have been
+// authenticated with one to be used without logging in again. An essential `acr` claim takes precedence over the
+// `default_acr_values` of the client. The `acr_values` parameter is only a voluntary hint for the login UI and is not
+// enforced. An empty result means that any login session is sufficient.
+func requestedACRValues(ar fosite.AuthorizeRequester) ([]string, error) {
+	if raw := ar.GetRequestForm().Get("claims"); raw != "" {
+		var claims claimsRequest
+		if err := json.Unmarshal([]byte(raw), &claims); err != nil {
+			return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to parse the claims parameter.").WithDebug(err.Error()))
+		}
+
+		if acr := claims.IDToken.ACR; acr != nil && acr.Essential {
+			values := fosite.RemoveEmpty(append(acr.Values, acr.Value))
+			if len(values) > 0 {
+				return values, nil
+			}
+		}
+	}
+
+	if c, ok := ar.GetClient().(*client.Client); ok {
+		return c.DefaultACRValues, nil
+	}
+	return nil, nil
+}
+
+// satisfiesACR reports whether the login session was authenticated with one of the requested ACR values.
+func satisfiesACR(session *LoginSession, requested []string) bool {
+	return len(requested) == 0 || stringslice.Has(requested, session.ACR)
+}
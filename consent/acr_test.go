@@ -0,0 +1,57 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+
+	"github.com/ory/hydra/client"
+)
+
+func TestRequestedACRValues(t *testing.T) {
+	cl := &client.Client{DefaultACRValues: []string{"default"}}
+	for k, tc := range []struct {
+		form     url.Values
+		client   fosite.Client
+		expected []string
+		err      bool
+	}{
+		{form: url.Values{}, client: &client.Client{}},
+		{form: url.Values{}, client: cl, expected: []string{"default"}},
+		{form: url.Values{"acr_values": {"a  b"}}, client: cl, expected: []string{"default"}},
+		{form: url.Values{"acr_values": {"a"}}, client: &client.Client{}},
+		{form: url.Values{"acr_values": {"a"}, "claims": {`{"id_token":{"acr":{"essential":true,"values":["b","c"]}}}`}}, client: cl, expected: []string{"b", "c"}},
+		{form: url.Values{"claims": {`{"id_token":{"acr":{"essential":true,"value":"b"}}}`}}, client: cl, expected: []string{"b"}},
+		{form: url.Values{"claims": {`{"id_token":{"acr":{"values":["b"]}}}`}}, client: cl, expected: []string{"default"}},
+		{form: url.Values{"claims": {`{"id_token":{"acr":null}}`}}, client: cl, expected: []string{"default"}},
+		{form: url.Values{"claims": {`{"userinfo":{"acr":{"essential":true,"value":"b"}}}`}}, client: cl, expected: []string{"default"}},
+		{form: url.Values{"claims": {`not-json`}}, client: cl, err: true},
+	} {
+		ar := fosite.NewAuthorizeRequest()
+		ar.Form = tc.form
+		ar.Client = tc.client
+
+		values, err := requestedACRValues(ar)
+		if tc.err {
+			assert.ErrorIs(t, err, fosite.ErrInvalidRequest, "%d", k)
+			continue
+		}
+		require.NoError(t, err, "%d", k)
+		assert.EqualValues(t, tc.expected, values, "%d", k)
+	}
+}
+
+func TestSatisfiesACR(t *testing.T) {
+	session := &LoginSession{ACR: "silver"}
+	assert.True(t, satisfiesACR(session, nil))
+	assert.True(t, satisfiesACR(session, []string{"gold", "silver"}))
+	assert.False(t, satisfiesACR(session, []string{"gold"}))
+	assert.False(t, satisfiesACR(&LoginSession{}, []string{"gold"}))
+}
@@ -0,0 +1,110 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/errorsx"
+)
+
+// AuthorizationDetail describes a single authorization requirement of a Rich Authorization Request as defined in
+// [RFC 9396](https://www.rfc-editor.org/rfc/rfc9396). Apart from `type`, the members of an authorization detail
+// depend on its type and are passed through as-is.
+//
+// swagger:model authorizationDetail
+type AuthorizationDetail map[string]interface{}
+
+// Type returns the type of the authorization detail.
+func (d AuthorizationDetail) Type() string {
+	t, _ := d["type"].(string)
+	return t
+}
+
+// AuthorizationDetails is a list of authorization details.
+//
+// swagger:model authorizationDetails
+type AuthorizationDetails []AuthorizationDetail
+
+// ParseAuthorizationDetails decodes the JSON value of the `authorization_details` parameter. An empty value
+// yields no authorization details.
+func ParseAuthorizationDetails(raw string) (AuthorizationDetails, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var details AuthorizationDetails
+	if err := json.Unmarshal([]byte(raw), &details); err != nil {
+		return nil, errors.Errorf("authorization_details must be a JSON array of objects: %s", err)
+	}
+
+	return details, details.Validate()
+}
+
+// Validate checks that every authorization detail declares its type.
+func (d AuthorizationDetails) Validate() error {
+	for k, detail := range d {
+		if detail == nil {
+			return errors.Errorf("authorization detail %d must be a JSON object", k)
+		} else if detail.Type() == "" {
+			return errors.Errorf("authorization detail %d must contain a non-empty string member 'type'", k)
+		}
+	}
+	return nil
+}
+
+// Types returns the distinct types of the authorization details.
+func (d AuthorizationDetails) Types() []string {
+	var types []string
+	seen := map[string]bool{}
+	for _, detail := range d {
+		if t := detail.Type(); !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// HasTypes returns true if the authorization details contain an authorization detail of every given type.
+func (d AuthorizationDetails) HasTypes(types ...string) bool {
+	available := map[string]bool{}
+	for _, t := range d.Types() {
+		available[t] = true
+	}
+	for _, t := range types {
+		if !available[t] {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *AuthorizationDetails) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	v := fmt.Sprintf("%s", value)
+	if len(v) == 0 {
+		return nil
+	}
+	return errorsx.WithStack(json.Unmarshal([]byte(v), d))
+}
+
+func (d AuthorizationDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return "[]", nil
+	}
+
+	value, err := json.Marshal(d)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	return string(value), nil
+}
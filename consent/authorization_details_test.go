@@ -0,0 +1,45 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAuthorizationDetails(t *testing.T) {
+	for k, tc := range []struct {
+		raw   string
+		types []string
+		err   bool
+	}{
+		{raw: ""},
+		{raw: "[]"},
+		{raw: `[{"type":"payment_initiation","actions":["initiate"]},{"type":"account_information"},{"type":"payment_initiation"}]`, types: []string{"payment_initiation", "account_information"}},
+		{raw: `{"type":"payment_initiation"}`, err: true},
+		{raw: `[{"actions":["initiate"]}]`, err: true},
+		{raw: `[{"type":1}]`, err: true},
+		{raw: `[null]`, err: true},
+		{raw: `[1]`, err: true},
+		{raw: `not-json`, err: true},
+	} {
+		details, err := ParseAuthorizationDetails(tc.raw)
+		if tc.err {
+			assert.Error(t, err, "%d", k)
+			continue
+		}
+		require.NoError(t, err, "%d", k)
+		assert.Equal(t, tc.types, details.Types(), "%d", k)
+	}
+}
+
+func TestAuthorizationDetailsHasTypes(t *testing.T) {
+	details := AuthorizationDetails{{"type": "a"}, {"type": "b"}}
+	assert.True(t, details.HasTypes())
+	assert.True(t, details.HasTypes("a", "b"))
+	assert.False(t, details.HasTypes("a", "c"))
+	assert.False(t, AuthorizationDetails(nil).HasTypes("a"))
+}
@@ -0,0 +1,190 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/hydra/client"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+const (
+	backchannelAuthenticationRequestDeniedErrorName = "backchannel authentication request denied"
+
+	authReqIDPrefix = "ory_ar_"
+)
+
+// DeriveAuthReqID derives the auth_req_id handed out to the client from the challenge of a backchannel
+// authentication request. Deriving it requires the global secret, which allows sending the auth_req_id to the
+// client notification endpoint in ping mode although only its signature is stored.
+func DeriveAuthReqID(secret []byte, challenge string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte("auth_req_id:" + challenge))
+	return authReqIDPrefix + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// AuthReqIDSignature returns the keyed hash of an auth_req_id under which the backchannel authentication
+// request is stored.
+func AuthReqIDSignature(secret []byte, authReqID string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(authReqID))
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+// BackchannelAuthenticationState is the state of an OpenID Connect CIBA authentication request.
+type BackchannelAuthenticationState int
+
+const (
+	// BackchannelAuthenticationStatePending means that the login app has not yet accepted or rejected the request.
+	BackchannelAuthenticationStatePending BackchannelAuthenticationState = iota + 1
+
+	// BackchannelAuthenticationStateApproved means that the end-user approved the request and the client may
+	// redeem the auth_req_id at the token endpoint.
+	BackchannelAuthenticationStateApproved
+
+	// BackchannelAuthenticationStateDenied means that the end-user or the login app denied the request.
+	BackchannelAuthenticationStateDenied
+
+	// BackchannelAuthenticationStateUsed means that the auth_req_id has already been exchanged for tokens.
+	BackchannelAuthenticationStateUsed
+)
+
+// Contains information on an ongoing OpenID Connect Client-Initiated Backchannel Authentication request.
+//
+// swagger:model oAuth2BackchannelAuthenticationRequest
+type BackchannelAuthenticationRequest struct {
+	// ID is the identifier ("backchannel authentication challenge") of the request.
+	//
+	// required: true
+	ID  string    `json:"challenge" db:"challenge"`
+	NID uuid.UUID `json:"-" db:"nid"`
+
+	// AuthReqIDSignature is the signature of the auth_req_id which is handed out to the client and redeemed at
+	// the token endpoint. The auth_req_id itself is never stored nor exposed to the login app.
+	AuthReqIDSignature string `json:"-" db:"auth_req_id_signature"`
+
+	// RequestedScope contains the OAuth 2.0 Scope requested by the OAuth 2.0 Client.
+	//
+	// required: true
+	RequestedScope sqlxx.StringSliceJSONFormat `json:"requested_scope" db:"requested_scope"`
+
+	// RequestedAudience contains the access token audience as requested by the OAuth 2.0 Client.
+	//
+	// required: true
+	RequestedAudience sqlxx.StringSliceJSONFormat `json:"requested_access_token_audience" db:"requested_at_audience"`
+
+	// LoginHint identifies the end-user for whom authentication is being requested, as sent by the client.
+	LoginHint string `json:"login_hint,omitempty" db:"login_hint"`
+
+	// LoginHintToken is a token containing information identifying the end-user, as sent by the client.
+	LoginHintToken string `json:"login_hint_token,omitempty" db:"login_hint_token"`
+
+	// IDTokenHintClaims are the claims of the previously issued ID Token the client passed as a hint about
+	// the end-user.
+	IDTokenHintClaims sqlxx.MapStringInterface `json:"id_token_hint_claims,omitempty" db:"id_token_hint_claims"`
+
+	// BindingMessage is a human-readable message which should be displayed on both the consumption device
+	// and the authentication device to interlock them.
+	BindingMessage string `json:"binding_message,omitempty" db:"binding_message"`
+
+	// ACRValues are the requested Authentication Context Class Reference values.
+	ACRValues sqlxx.StringSliceJSONFormat `json:"acr_values" db:"acr_values"`
+
+	// Client is the OAuth 2.0 Client that initiated the request.
+	//
+	// required: true
+	Client   *client.Client `json:"client" db:"-"`
+	ClientID string         `json:"-" db:"client_id"`
+
+	// RequestedAt is the time the client initiated the request.
+	RequestedAt time.Time `json:"requested_at" db:"requested_at"`
+
+	// ExpiresAt is the time after which the request can no longer be accepted or redeemed.
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+
+	State                   BackchannelAuthenticationState `json:"-" db:"state"`
+	ClientNotificationToken string                         `json:"-" db:"client_notification_token"`
+	LastPolledAt            sqlxx.NullTime                 `json:"-" db:"last_polled_at"`
+
+	// The fields below are set once the request was handled.
+
+	Subject                string                      `json:"-" db:"subject"`
+	ForceSubjectIdentifier string                      `json:"-" db:"force_subject_identifier"`
+	ACR                    string                      `json:"-" db:"acr"`
+	AMR                    sqlxx.StringSliceJSONFormat `json:"-" db:"amr"`
+	GrantedScope           sqlxx.StringSliceJSONFormat `json:"-" db:"granted_scope"`
+	GrantedAudience        sqlxx.StringSliceJSONFormat `json:"-" db:"granted_at_audience"`
+	SessionAccessToken     sqlxx.MapStringInterface    `json:"-" db:"session_access_token"`
+	SessionIDToken         sqlxx.MapStringInterface    `json:"-" db:"session_id_token"`
+	Error                  *RequestDeniedError         `json:"-" db:"error"`
+	AuthenticatedAt        sqlxx.NullTime              `json:"-" db:"authenticated_at"`
+}
+
+func (_ BackchannelAuthenticationRequest) TableName() string {
+	return "hydra_oauth2_backchannel_authentication_request"
+}
+
+func (r *BackchannelAuthenticationRequest) BeforeSave(_ *pop.Connection) error {
+	if r.Client != nil {
+		r.ClientID = r.Client.GetID()
+	}
+	return nil
+}
+
+func (r *BackchannelAuthenticationRequest) AfterFind(c *pop.Connection) error {
+	r.Client = &client.Client{}
+	return sqlcon.HandleError(c.Where("id = ? AND nid = ?", r.ClientID, r.NID).First(r.Client))
+}
+
+// WasHandled returns true if the login app already accepted or rejected the request.
+func (r *BackchannelAuthenticationRequest) WasHandled() bool {
+	return r.State != BackchannelAuthenticationStatePending
+}
+
+// The request payload used to accept a backchannel authentication request.
+//
+// swagger:model acceptOAuth2BackchannelAuthenticationRequest
+type HandledBackchannelAuthenticationRequest struct {
+	// Subject is the user ID of the end-user that authenticated.
+	//
+	// required: true
+	Subject string `json:"subject"`
+
+	// ForceSubjectIdentifier forces the "pairwise" user ID of the end-user that authenticated. See the
+	// field of the same name in the accept login request for details.
+	ForceSubjectIdentifier string `json:"force_subject_identifier"`
+
+	// ACR sets the Authentication AuthorizationContext Class Reference value for this authentication session.
+	ACR string `json:"acr"`
+
+	// AMR sets the Authentication Methods References value for this authentication session.
+	AMR sqlxx.StringSliceJSONFormat `json:"amr"`
+
+	// GrantScope sets the scope the user authorized the client to use. Should be a subset of `requested_scope`.
+	GrantedScope sqlxx.StringSliceJSONFormat `json:"grant_scope"`
+
+	// GrantedAudience sets the audience the user authorized the client to use. Should be a subset of
+	// `requested_access_token_audience`.
+	GrantedAudience sqlxx.StringSliceJSONFormat `json:"grant_access_token_audience"`
+
+	// Session allows you to set (optional) session data for access and ID tokens.
+	Session *AcceptOAuth2ConsentRequestSession `json:"session"`
+
+	Error           *RequestDeniedError `json:"-"`
+	AuthenticatedAt sqlxx.NullTime      `json:"-"`
+}
+
+// backchannelAuthenticationNotification is sent to the client notification endpoint in ping mode.
+type backchannelAuthenticationNotification struct {
+	AuthReqID string `json:"auth_req_id"`
+}
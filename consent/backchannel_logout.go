@@ -0,0 +1,294 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/x"
+)
+
+// The states of a back-channel logout delivery.
+const (
+	// BackChannelLogoutDeliveryPending means that the logout token was not yet delivered but the delivery will be
+	// attempted (again).
+	BackChannelLogoutDeliveryPending = "pending"
+
+	// BackChannelLogoutDeliveryDelivered means that the client acknowledged the logout token.
+	BackChannelLogoutDeliveryDelivered = "delivered"
+
+	// BackChannelLogoutDeliveryFailed means that all attempts before the deadline failed. Failed deliveries
+	// can be redriven using the admin API.
+	BackChannelLogoutDeliveryFailed = "failed"
+)
+
+// backChannelLogoutLease is how long a delivery is reserved for the Hydra instance which attempts it. If the
+// instance stops during the attempt, the delivery is attempted again once the lease expired.
+const backChannelLogoutLease = 2 * time.Minute
+
+// The delivery of an OpenID Connect Back-Channel Logout request to an OAuth 2.0 Client.
+//
+// swagger:model oAuth2BackChannelLogoutDelivery
+type BackChannelLogoutDelivery struct {
+	// ID is the identifier of the delivery.
+	//
+	// required: true
+	ID  uuid.UUID `json:"id" db:"id"`
+	NID uuid.UUID `json:"-" db:"nid"`
+
+	// ClientID is the ID of the OAuth 2.0 Client the logout token is sent to.
+	//
+	// required: true
+	ClientID string `json:"client_id" db:"client_id"`
+
+	// Subject is the subject which logged out.
+	Subject string `json:"subject" db:"subject"`
+
+	// SessionID is the ID of the login session which ended. It is sent as the `sid` claim of the logout token.
+	SessionID string `json:"session_id" db:"session_id"`
+
+	// URL is the back-channel logout URL of the client at the time of the logout.
+	//
+	// required: true
+	URL string `json:"url" db:"url"`
+
+	// Status is one of `pending`, `delivered` and `failed`.
+	//
+	// required: true
+	Status string `json:"status" db:"status"`
+
+	// Attempts is the number of times the delivery was attempted.
+	//
+	// required: true
+	Attempts int `json:"attempts" db:"attempts"`
+
+	// LastError describes why the last attempt failed.
+	LastError string `json:"last_error,omitempty" db:"last_error"`
+
+	// NextAttemptAt is the time at which a pending delivery is attempted next.
+	NextAttemptAt time.Time `json:"next_attempt_at" db:"next_attempt_at"`
+
+	// ExpiresAt is the deadline after which a pending delivery is no longer attempted and fails.
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (_ BackChannelLogoutDelivery) TableName() string {
+	return "hydra_oauth2_backchannel_logout_delivery"
+}
+
+func (d BackChannelLogoutDelivery) PageToken() string {
+	return x.EncodePageToken(d.CreatedAt.UTC().Format(time.RFC3339Nano), d.ID.String())
+}
+
+// List of OpenID Connect Back-Channel Logout Deliveries
+//
+// swagger:model oAuth2BackChannelLogoutDeliveries
+type oAuth2BackChannelLogoutDeliveries []BackChannelLogoutDelivery
+
+// BackChannelLogoutDeliveryFilter narrows down the deliveries which are listed. Empty fields match all deliveries.
+type BackChannelLogoutDeliveryFilter struct {
+	Status   string
+	ClientID string
+}
+
+// BackChannelLogoutDeliverer sends OpenID Connect Back-Channel Logout requests. Every delivery is persisted before
+// it is attempted and failed attempts are retried with exponential backoff until the deadline passed, so that
+// deliveries are resumed after a restart.
+type BackChannelLogoutDeliverer struct {
+	r InternalRegistry
+	c *config.DefaultProvider
+}
+
+func NewBackChannelLogoutDeliverer(r InternalRegistry, c *config.DefaultProvider) *BackChannelLogoutDeliverer {
+	return &BackChannelLogoutDeliverer{r: r, c: c}
+}
+
+// Enqueue persists the deliveries and attempts them in the background right away.
+func (d *BackChannelLogoutDeliverer) Enqueue(ctx context.Context, deliveries []BackChannelLogoutDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	for k := range deliveries {
+		deliveries[k].Status = BackChannelLogoutDeliveryPending
+		deliveries[k].NextAttemptAt = now
+		deliveries[k].ExpiresAt = now.Add(d.c.BackChannelLogoutDeadline(ctx))
+	}
+
+	if err := d.r.ConsentManager().CreateBackChannelLogoutDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	ctx = detachedContext{ctx}
+	for k := range deliveries {
+		go d.attempt(ctx, deliveries[k])
+	}
+	return nil
+}
+
+// Redrive schedules a failed delivery to be attempted again with a new deadline and attempts it in the
+// background right away.
+func (d *BackChannelLogoutDeliverer) Redrive(ctx context.Context, id uuid.UUID) (*BackChannelLogoutDelivery, error) {
+	delivery, err := d.r.ConsentManager().GetBackChannelLogoutDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	} else if delivery.Status != BackChannelLogoutDeliveryFailed {
+		return nil, errorsx.WithStack(x.ErrConflict.WithHintf("Only failed back-channel logout deliveries can be redriven, but the delivery is %s.", delivery.Status))
+	}
+
+	now := time.Now().UTC()
+	if err := d.r.ConsentManager().RedriveBackChannelLogoutDelivery(ctx, id, now, now.Add(d.c.BackChannelLogoutDeadline(ctx))); err != nil {
+		return nil, err
+	}
+
+	delivery, err = d.r.ConsentManager().GetBackChannelLogoutDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	go d.attempt(detachedContext{ctx}, *delivery)
+	return delivery, nil
+}
+
+// Run attempts the pending deliveries which are due until ctx is canceled. Due deliveries are looked up whenever
+// the minimum retry wait time passed.
+func (d *BackChannelLogoutDeliverer) Run(ctx context.Context) {
+	for {
+		minWait, _ := d.c.BackChannelLogoutRetryWait(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(minWait):
+		}
+
+		d.AttemptDue(ctx)
+	}
+}
+
+// AttemptDue attempts the pending deliveries which are due and waits until all attempts completed.
+func (d *BackChannelLogoutDeliverer) AttemptDue(ctx context.Context) {
+	due, err := d.r.ConsentManager().FindDueBackChannelLogoutDeliveries(ctx, time.Now().UTC(), 100)
+	if err != nil {
+		d.r.Logger().WithError(err).Error("Unable to look up the pending OpenID Connect Back-Channel Logout Requests")
+		return
+	}
+
+	var wg sync.WaitGroup
+	for k := range due {
+		wg.Add(1)
+		go func(delivery BackChannelLogoutDelivery) {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}(due[k])
+	}
+	wg.Wait()
+}
+
+func (d *BackChannelLogoutDeliverer) attempt(ctx context.Context, delivery BackChannelLogoutDelivery) {
+	log := d.r.Logger().
+		WithField("client_id", delivery.ClientID).
+		WithField("backchannel_logout_url", delivery.URL)
+
+	// Leasing the delivery prevents other Hydra instances, which found the same due delivery, from attempting it
+	// at the same time.
+	if err := d.r.ConsentManager().LeaseBackChannelLogoutDelivery(ctx, &delivery, time.Now().UTC().Add(backChannelLogoutLease)); errors.Is(err, sqlcon.ErrNoRows) {
+		return
+	} else if err != nil {
+		log.WithError(err).Error("Unable to lease OpenID Connect Back-Channel Logout Request")
+		return
+	}
+	log = log.WithField("attempt", delivery.Attempts)
+
+	if err := d.send(ctx, &delivery); err != nil {
+		delivery.LastError = err.Error()
+
+		minWait, maxWait := d.c.BackChannelLogoutRetryWait(ctx)
+		wait := minWait
+		for i := 1; i < delivery.Attempts && wait < maxWait; i++ {
+			wait *= 2
+		}
+		if wait > maxWait {
+			wait = maxWait
+		}
+
+		if next := time.Now().UTC().Add(wait); next.After(delivery.ExpiresAt) {
+			delivery.Status = BackChannelLogoutDeliveryFailed
+			log.WithError(err).Error("Unable to execute OpenID Connect Back-Channel Logout Request, giving up because the next attempt would be after the deadline")
+		} else {
+			delivery.NextAttemptAt = next
+			log.WithError(err).WithField("next_attempt_at", next).Warn("Unable to execute OpenID Connect Back-Channel Logout Request, will retry")
+		}
+	} else {
+		delivery.Status = BackChannelLogoutDeliveryDelivered
+		delivery.LastError = ""
+		log.Info("Back-Channel Logout Request")
+	}
+
+	if err := d.r.ConsentManager().UpdateBackChannelLogoutDelivery(ctx, &delivery); err != nil {
+		log.WithError(err).Error("Unable to record the status of OpenID Connect Back-Channel Logout Request")
+	}
+}
+
+func (d *BackChannelLogoutDeliverer) send(ctx context.Context, delivery *BackChannelLogoutDelivery) error {
+	openIDKeyID, err := d.r.OpenIDJWTStrategy().GetPublicKeyID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The logout token is issued for every attempt, so that the client does not reject it as too old when the
+	// delivery succeeds only after several retries.
+	token, _, err := d.r.OpenIDJWTStrategy().Generate(ctx, jwt.MapClaims{
+		"iss":    d.c.IssuerURL(ctx).String(),
+		"aud":    []string{delivery.ClientID},
+		"iat":    time.Now().UTC().Unix(),
+		"jti":    uuid.Must(uuid.NewV4()).String(),
+		"events": map[string]struct{}{"http://schemas.openid.net/event/backchannel-logout": {}},
+		"sid":    delivery.SessionID,
+	}, &jwt.Headers{
+		Extra: map[string]interface{}{"kid": openIDKeyID},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Failed attempts are retried by the deliverer, which persists the delivery status in between.
+	res, err := d.r.HTTPClient(ctx, httpx.ResilientClientWithMaxRetry(0)).PostForm(delivery.URL, url.Values{"logout_token": {token}})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("expected HTTP status code %d but got %d", http.StatusOK, res.StatusCode)
+	}
+	return nil
+}
+
+// detachedContext keeps the values of its parent, such as the network, but is never canceled. It is used for
+// deliveries which are started by a request but outlive it.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
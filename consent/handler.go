@@ -4,22 +4,28 @@
 package consent
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
 	"time"
 
-	"github.com/ory/x/pagination/tokenpagination"
+	"github.com/ory/x/pagination/keysetpagination"
 
 	"github.com/ory/x/httprouterx"
 
+	"github.com/gofrs/uuid"
+	"github.com/hashicorp/go-retryablehttp"
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 
 	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/driver/config"
 	"github.com/ory/hydra/x"
+	"github.com/ory/hydra/x/events"
 	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlcon"
 	"github.com/ory/x/sqlxx"
 	"github.com/ory/x/stringsx"
 	"github.com/ory/x/urlx"
@@ -35,6 +41,8 @@ const (
 	ConsentPath  = "/oauth2/auth/requests/consent"
 	LogoutPath   = "/oauth2/auth/requests/logout"
 	SessionsPath = "/oauth2/auth/sessions"
+
+	BackchannelAuthenticationPath = "/oauth2/auth/requests/backchannel"
 )
 
 func NewHandler(
@@ -56,13 +64,23 @@ func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin) {
 	admin.PUT(ConsentPath+"/accept", h.acceptOAuth2ConsentRequest)
 	admin.PUT(ConsentPath+"/reject", h.rejectOAuth2ConsentRequest)
 
+	admin.GET(SessionsPath+"/login", h.listOAuth2LoginSessions)
 	admin.DELETE(SessionsPath+"/login", h.revokeOAuth2LoginSessions)
+	admin.GET(SessionsPath+"/login/:id", h.getOAuth2LoginSession)
+	admin.DELETE(SessionsPath+"/login/:id", h.revokeOAuth2LoginSession)
+	admin.GET(SessionsPath+"/logout/deliveries", h.listOAuth2BackChannelLogoutDeliveries)
+	admin.GET(SessionsPath+"/logout/deliveries/:id", h.getOAuth2BackChannelLogoutDelivery)
+	admin.POST(SessionsPath+"/logout/deliveries/:id/redrive", h.redriveOAuth2BackChannelLogoutDelivery)
 	admin.GET(SessionsPath+"/consent", h.listOAuth2ConsentSessions)
 	admin.DELETE(SessionsPath+"/consent", h.revokeOAuth2ConsentSessions)
 
 	admin.GET(LogoutPath, h.getOAuth2LogoutRequest)
 	admin.PUT(LogoutPath+"/accept", h.acceptOAuth2LogoutRequest)
 	admin.PUT(LogoutPath+"/reject", h.rejectOAuth2LogoutRequest)
+
+	admin.GET(BackchannelAuthenticationPath, h.getOAuth2BackchannelAuthenticationRequest)
+	admin.PUT(BackchannelAuthenticationPath+"/accept", h.acceptOAuth2BackchannelAuthenticationRequest)
+	admin.PUT(BackchannelAuthenticationPath+"/reject", h.rejectOAuth2BackchannelAuthenticationRequest)
 }
 
 // Revoke OAuth 2.0 Consent Session Parameters
@@ -135,6 +153,11 @@ func (h *Handler) revokeOAuth2ConsentSessions(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Revoking a consent session also revokes the tokens issued based on it.
+	h.r.EventHook().Emit(r.Context(),
+		events.Event{Type: events.ConsentSessionRevoked, Subject: subject, ClientID: client},
+		events.Event{Type: events.TokenRevoked, Subject: subject, ClientID: client},
+	)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -142,7 +165,7 @@ func (h *Handler) revokeOAuth2ConsentSessions(w http.ResponseWriter, r *http.Req
 //
 // swagger:parameters listOAuth2ConsentSessions
 type listOAuth2ConsentSessions struct {
-	tokenpagination.RequestParameters
+	keysetpagination.RequestParameters
 
 	// The subject to list the consent sessions for.
 	//
@@ -177,8 +200,7 @@ func (h *Handler) listOAuth2ConsentSessions(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	page, itemsPerPage := x.ParsePagination(r)
-	s, err := h.r.ConsentManager().FindSubjectsGrantedConsentRequests(r.Context(), subject, itemsPerPage, itemsPerPage*page)
+	s, nextPage, err := h.r.ConsentManager().FindSubjectsGrantedConsentRequests(r.Context(), subject, x.ParseKeysetPagination(r)...)
 	if errors.Is(err, ErrNoPreviousConsentFound) {
 		h.r.Writer().Write(w, r, []OAuth2ConsentSession{})
 		return
@@ -203,7 +225,7 @@ func (h *Handler) listOAuth2ConsentSessions(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	x.PaginationHeader(w, r.URL, int64(n), itemsPerPage, itemsPerPage*page)
+	x.KeysetPaginationHeader(w, r.URL, int64(n), nextPage)
 	h.r.Writer().Write(w, r, a)
 }
 
@@ -251,9 +273,331 @@ func (h *Handler) revokeOAuth2LoginSessions(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	h.r.EventHook().Emit(r.Context(), events.Event{Type: events.LoginSessionRevoked, Subject: subject})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List OAuth 2.0 Login Sessions Parameters
+//
+// swagger:parameters listOAuth2LoginSessions
+type listOAuth2LoginSessions struct {
+	keysetpagination.RequestParameters
+
+	// The subject to list the login sessions for.
+	//
+	// in: query
+	// required: true
+	Subject string `json:"subject"`
+}
+
+// swagger:route GET /admin/oauth2/auth/sessions/login oAuth2 listOAuth2LoginSessions
+//
+// # List OAuth 2.0 Login Sessions of a Subject
+//
+// This endpoint lists the login sessions of a subject, starting with the most recently authenticated one. Each
+// login session includes the IDs of the OAuth 2.0 Clients which used it. If the subject is unknown or has no
+// login sessions, the endpoint returns an empty JSON array with status code 200 OK.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2LoginSessions
+//	  default: errorOAuth2
+func (h *Handler) listOAuth2LoginSessions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	subject := r.URL.Query().Get("subject")
+	if subject == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter 'subject' is not defined but should have been.`)))
+		return
+	}
+
+	sessions, nextPage, err := h.r.ConsentManager().ListSubjectLoginSessions(r.Context(), subject, x.ParseKeysetPagination(r)...)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	ids := make([]string, len(sessions))
+	for k := range sessions {
+		ids[k] = sessions[k].ID
+	}
+	clientIDs, err := h.r.ConsentManager().FindLoginSessionsClientIDs(r.Context(), ids...)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	a := make([]OAuth2LoginSession, len(sessions))
+	for k := range sessions {
+		a[k] = *NewOAuth2LoginSession(&sessions[k], clientIDs[sessions[k].ID])
+	}
+
+	n, err := h.r.ConsentManager().CountSubjectLoginSessions(r.Context(), subject)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	x.KeysetPaginationHeader(w, r.URL, int64(n), nextPage)
+	h.r.Writer().Write(w, r, a)
+}
+
+// Get OAuth 2.0 Login Session Parameters
+//
+// swagger:parameters getOAuth2LoginSession
+type getOAuth2LoginSession struct {
+	// The ID of the login session.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:route GET /admin/oauth2/auth/sessions/login/{id} oAuth2 getOAuth2LoginSession
+//
+// # Get an OAuth 2.0 Login Session
+//
+// This endpoint returns a login session, including the IDs of the OAuth 2.0 Clients which used it.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2LoginSession
+//	  default: errorOAuth2
+func (h *Handler) getOAuth2LoginSession(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	session, err := h.r.ConsentManager().GetLoginSession(r.Context(), ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	clientIDs, err := h.r.ConsentManager().FindLoginSessionsClientIDs(r.Context(), session.ID)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, NewOAuth2LoginSession(session, clientIDs[session.ID]))
+}
+
+// Revoke OAuth 2.0 Login Session Parameters
+//
+// swagger:parameters revokeOAuth2LoginSession
+type revokeOAuth2LoginSession struct {
+	// The ID of the login session.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /admin/oauth2/auth/sessions/login/{id} oAuth2 revokeOAuth2LoginSession
+//
+// # Revoke an OAuth 2.0 Login Session
+//
+// This endpoint invalidates a single login session, while the other login sessions of the subject remain valid.
+// The subject has to re-authenticate when the user-agent which used the session starts the next OAuth 2.0 flow.
+// Like revoking all login sessions of a subject, this endpoint does not invalidate any tokens and does not
+// work with OpenID Connect Front- or Back-channel logout.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  204: emptyResponse
+//	  default: errorOAuth2
+func (h *Handler) revokeOAuth2LoginSession(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	session, err := h.r.ConsentManager().GetLoginSession(r.Context(), ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.r.ConsentManager().DeleteLoginSession(r.Context(), session.ID); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.EventHook().Emit(r.Context(), events.Event{Type: events.LoginSessionRevoked, Subject: session.Subject, SessionID: session.ID})
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// List OpenID Connect Back-Channel Logout Deliveries Parameters
+//
+// swagger:parameters listOAuth2BackChannelLogoutDeliveries
+type listOAuth2BackChannelLogoutDeliveries struct {
+	keysetpagination.RequestParameters
+
+	// Only list the deliveries with this status, which is one of `pending`, `delivered` and `failed`.
+	//
+	// in: query
+	Status string `json:"status"`
+
+	// Only list the deliveries to this OAuth 2.0 Client.
+	//
+	// in: query
+	ClientID string `json:"client_id"`
+}
+
+// swagger:route GET /admin/oauth2/auth/sessions/logout/deliveries oAuth2 listOAuth2BackChannelLogoutDeliveries
+//
+// # List OpenID Connect Back-Channel Logout Deliveries
+//
+// Each OpenID Connect Back-Channel Logout request sent to an OAuth 2.0 Client is recorded as a delivery. Failed
+// attempts are retried until the delivery's deadline passes, after which the delivery fails. This endpoint lists
+// the deliveries, starting with the most recent one. Use `status=failed` to find the deliveries which need to be
+// redriven.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2BackChannelLogoutDeliveries
+//	  default: errorOAuth2
+func (h *Handler) listOAuth2BackChannelLogoutDeliveries(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	filter := BackChannelLogoutDeliveryFilter{
+		Status:   r.URL.Query().Get("status"),
+		ClientID: r.URL.Query().Get("client_id"),
+	}
+	switch filter.Status {
+	case "", BackChannelLogoutDeliveryPending, BackChannelLogoutDeliveryDelivered, BackChannelLogoutDeliveryFailed:
+	default:
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("Query parameter 'status' must be one of '%s', '%s' or '%s'.", BackChannelLogoutDeliveryPending, BackChannelLogoutDeliveryDelivered, BackChannelLogoutDeliveryFailed)))
+		return
+	}
+
+	deliveries, nextPage, err := h.r.ConsentManager().ListBackChannelLogoutDeliveries(r.Context(), filter, x.ParseKeysetPagination(r)...)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	n, err := h.r.ConsentManager().CountBackChannelLogoutDeliveries(r.Context(), filter)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []BackChannelLogoutDelivery{}
+	}
+
+	x.KeysetPaginationHeader(w, r.URL, int64(n), nextPage)
+	h.r.Writer().Write(w, r, deliveries)
+}
+
+// Get OpenID Connect Back-Channel Logout Delivery Parameters
+//
+// swagger:parameters getOAuth2BackChannelLogoutDelivery
+type getOAuth2BackChannelLogoutDelivery struct {
+	// The ID of the delivery.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:route GET /admin/oauth2/auth/sessions/logout/deliveries/{id} oAuth2 getOAuth2BackChannelLogoutDelivery
+//
+// # Get an OpenID Connect Back-Channel Logout Delivery
+//
+// This endpoint returns the status of a back-channel logout delivery, including the number of attempts and the
+// error of the last failed attempt.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2BackChannelLogoutDelivery
+//	  default: errorOAuth2
+func (h *Handler) getOAuth2BackChannelLogoutDelivery(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id, err := uuid.FromString(ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(x.ErrNotFound))
+		return
+	}
+
+	delivery, err := h.r.ConsentManager().GetBackChannelLogoutDelivery(r.Context(), id)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, delivery)
+}
+
+// Redrive OpenID Connect Back-Channel Logout Delivery Parameters
+//
+// swagger:parameters redriveOAuth2BackChannelLogoutDelivery
+type redriveOAuth2BackChannelLogoutDelivery struct {
+	// The ID of the delivery.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:route POST /admin/oauth2/auth/sessions/logout/deliveries/{id}/redrive oAuth2 redriveOAuth2BackChannelLogoutDelivery
+//
+// # Redrive a Failed OpenID Connect Back-Channel Logout Delivery
+//
+// This endpoint attempts a failed back-channel logout delivery again, for example after the OAuth 2.0 Client's
+// back-channel logout endpoint was fixed. The delivery becomes pending with a new deadline and is retried as if
+// the logout just happened. Only failed deliveries can be redriven.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2BackChannelLogoutDelivery
+//	  default: errorOAuth2
+func (h *Handler) redriveOAuth2BackChannelLogoutDelivery(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id, err := uuid.FromString(ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(x.ErrNotFound))
+		return
+	}
+
+	delivery, err := h.r.BackChannelLogoutDeliverer().Redrive(r.Context(), id)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, delivery)
+}
+
 // Get OAuth 2.0 Login Request
 //
 // swagger:parameters getOAuth2LoginRequest
@@ -314,6 +658,18 @@ func (h *Handler) getOAuth2LoginRequest(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if request.Skip && request.SessionID != "" {
+		session, err := h.r.ConsentManager().GetRememberedLoginSession(r.Context(), request.SessionID.String())
+		if err != nil && !errors.Is(err, x.ErrNotFound) {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		} else if err == nil {
+			if expiresAt := LoginSessionExpiresAt(session, h.c.LoginSessionMaxAge(r.Context())); !expiresAt.IsZero() {
+				request.SessionExpiresAt = &expiresAt
+			}
+		}
+	}
+
 	request.Client = sanitizeClient(request.Client)
 	h.r.Writer().Write(w, r, request)
 }
@@ -649,6 +1005,14 @@ func (h *Handler) acceptOAuth2ConsentRequest(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if err := p.GrantedAuthorizationDetails.Validate(); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The granted authorization details are invalid.").WithDebug(err.Error())))
+		return
+	} else if !cr.RequestedAuthorizationDetails.HasTypes(p.GrantedAuthorizationDetails.Types()...) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The granted authorization details must only contain types which were requested by the OAuth 2.0 Client.")))
+		return
+	}
+
 	p.ID = challenge
 	p.RequestedAt = cr.RequestedAt
 	p.HandledAt = sqlxx.NullTime(time.Now().UTC())
@@ -899,3 +1263,235 @@ func (h *Handler) getOAuth2LogoutRequest(w http.ResponseWriter, r *http.Request,
 
 	h.r.Writer().Write(w, r, request)
 }
+
+// Get OpenID Connect Backchannel Authentication Request
+//
+// swagger:parameters getOAuth2BackchannelAuthenticationRequest
+type getOAuth2BackchannelAuthenticationRequest struct {
+	// OpenID Connect Backchannel Authentication Request Challenge
+	//
+	// in: query
+	// required: true
+	Challenge string `json:"backchannel_challenge"`
+}
+
+// swagger:route GET /admin/oauth2/auth/requests/backchannel oAuth2 getOAuth2BackchannelAuthenticationRequest
+//
+// # Get OpenID Connect Backchannel Authentication Request
+//
+// When an OAuth 2.0 Client initiates an OpenID Connect Client-Initiated Backchannel Authentication (CIBA) request,
+// Ory notifies the login provider configured at `urls.backchannel_authentication` of the new challenge. The login
+// provider uses this endpoint to fetch information on the request, such as the login hint and binding message, in
+// order to authenticate the end-user on their authentication device.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2BackchannelAuthenticationRequest
+//	  410: errorOAuth2
+//	  default: errorOAuth2
+func (h *Handler) getOAuth2BackchannelAuthenticationRequest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	challenge := stringsx.Coalesce(
+		r.URL.Query().Get("backchannel_challenge"),
+		r.URL.Query().Get("challenge"),
+	)
+	if challenge == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter 'challenge' is not defined but should have been.`)))
+		return
+	}
+
+	request, err := h.r.ConsentManager().GetBackchannelAuthenticationRequest(r.Context(), challenge)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	if request.WasHandled() {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusGone, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The backchannel authentication request has already been handled.")))
+		return
+	}
+
+	request.Client = sanitizeClient(request.Client)
+	h.r.Writer().Write(w, r, request)
+}
+
+// Accept OpenID Connect Backchannel Authentication Request
+//
+// swagger:parameters acceptOAuth2BackchannelAuthenticationRequest
+type acceptOAuth2BackchannelAuthenticationRequest struct {
+	// OpenID Connect Backchannel Authentication Request Challenge
+	//
+	// in: query
+	// required: true
+	Challenge string `json:"backchannel_challenge"`
+
+	// in: body
+	Body HandledBackchannelAuthenticationRequest
+}
+
+// swagger:route PUT /admin/oauth2/auth/requests/backchannel/accept oAuth2 acceptOAuth2BackchannelAuthenticationRequest
+//
+// # Accept OpenID Connect Backchannel Authentication Request
+//
+// This endpoint tells Ory that the end-user authenticated on their authentication device and approved the
+// backchannel authentication request. It includes the subject's ID as well as the granted scope, audience, and
+// session data. Unlike the login and consent flows, the end-user is not redirected, so the login provider is
+// responsible for asking the end-user for consent.
+//
+// Once accepted, clients using the poll delivery mode receive the tokens on their next token request. Clients
+// using the ping delivery mode are notified at their `backchannel_client_notification_endpoint`.
+//
+//	Consumes:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  204: emptyResponse
+//	  default: errorOAuth2
+func (h *Handler) acceptOAuth2BackchannelAuthenticationRequest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	challenge := stringsx.Coalesce(
+		r.URL.Query().Get("backchannel_challenge"),
+		r.URL.Query().Get("challenge"),
+	)
+	if challenge == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter 'challenge' is not defined but should have been.`)))
+		return
+	}
+
+	var p HandledBackchannelAuthenticationRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&p); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHintf("Unable to decode body because: %s", err)))
+		return
+	}
+
+	if p.Subject == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Field 'subject' must not be empty.")))
+		return
+	}
+
+	// Rounding is important to avoid SQL time synchronization issues in e.g. MySQL!
+	p.AuthenticatedAt = sqlxx.NullTime(time.Now().UTC().Truncate(time.Second))
+	h.handleBackchannelAuthenticationRequest(w, r, challenge, &p)
+}
+
+// Reject OpenID Connect Backchannel Authentication Request
+//
+// swagger:parameters rejectOAuth2BackchannelAuthenticationRequest
+type rejectOAuth2BackchannelAuthenticationRequest struct {
+	// OpenID Connect Backchannel Authentication Request Challenge
+	//
+	// in: query
+	// required: true
+	Challenge string `json:"backchannel_challenge"`
+
+	// in: body
+	Body RequestDeniedError
+}
+
+// swagger:route PUT /admin/oauth2/auth/requests/backchannel/reject oAuth2 rejectOAuth2BackchannelAuthenticationRequest
+//
+// # Reject OpenID Connect Backchannel Authentication Request
+//
+// This endpoint tells Ory that the end-user could not be authenticated or denied the backchannel authentication
+// request. The client receives an `access_denied` error from the token endpoint.
+//
+//	Consumes:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  204: emptyResponse
+//	  default: errorOAuth2
+func (h *Handler) rejectOAuth2BackchannelAuthenticationRequest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	challenge := stringsx.Coalesce(
+		r.URL.Query().Get("backchannel_challenge"),
+		r.URL.Query().Get("challenge"),
+	)
+	if challenge == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter 'challenge' is not defined but should have been.`)))
+		return
+	}
+
+	var p RequestDeniedError
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&p); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHintf("Unable to decode body because: %s", err)))
+		return
+	}
+
+	p.valid = true
+	p.SetDefaults(backchannelAuthenticationRequestDeniedErrorName)
+	h.handleBackchannelAuthenticationRequest(w, r, challenge, &HandledBackchannelAuthenticationRequest{Error: &p})
+}
+
+func (h *Handler) handleBackchannelAuthenticationRequest(w http.ResponseWriter, r *http.Request, challenge string, p *HandledBackchannelAuthenticationRequest) {
+	ctx := r.Context()
+
+	ar, err := h.r.ConsentManager().GetBackchannelAuthenticationRequest(ctx, challenge)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	} else if ar.ExpiresAt.Before(time.Now().UTC()) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The backchannel authentication request has expired.")))
+		return
+	}
+
+	request, err := h.r.ConsentManager().HandleBackchannelAuthenticationRequest(ctx, challenge, p)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The backchannel authentication request has already been handled.")))
+		return
+	} else if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
+		return
+	}
+
+	if request.Client.BackchannelTokenDeliveryMode == client.BackchannelTokenDeliveryModePing {
+		go h.pingBackchannelClient(ctx, request)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pingBackchannelClient notifies a client using the ping delivery mode that the result of its backchannel
+// authentication request can be fetched from the token endpoint. Failures are only logged, because the client
+// can still poll the token endpoint.
+func (h *Handler) pingBackchannelClient(ctx context.Context, request *BackchannelAuthenticationRequest) {
+	log := h.r.Logger().
+		WithField("client_id", request.Client.GetID()).
+		WithField("backchannel_client_notification_endpoint", request.Client.BackchannelClientNotificationEndpoint)
+
+	body, err := json.Marshal(&backchannelAuthenticationNotification{AuthReqID: DeriveAuthReqID(h.c.GetGlobalSecret(ctx), request.ID)})
+	if err != nil {
+		log.WithError(err).Error("Unable to encode OpenID Connect backchannel authentication notification")
+		return
+	}
+
+	req, err := retryablehttp.NewRequest("POST", request.Client.BackchannelClientNotificationEndpoint, body)
+	if err != nil {
+		log.WithError(err).Error("Unable to create OpenID Connect backchannel authentication notification")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+request.ClientNotificationToken)
+
+	res, err := h.r.HTTPClient(ctx).Do(req)
+	if err != nil {
+		log.WithError(err).Error("Unable to send OpenID Connect backchannel authentication notification")
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		log.WithError(errors.Errorf("expected a 2xx HTTP status code but got %d", res.StatusCode)).
+			Error("Unable to send OpenID Connect backchannel authentication notification")
+		return
+	}
+	log.Info("Sent OpenID Connect backchannel authentication notification")
+}
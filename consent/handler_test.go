@@ -6,15 +6,23 @@ package consent_test
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gofrs/uuid"
+	"github.com/tidwall/gjson"
+
 	"github.com/ory/x/pointerx"
 
+	"github.com/ory/hydra/driver/config"
+
 	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/x"
 	"github.com/ory/x/contextx"
@@ -22,6 +30,7 @@ import (
 
 	"github.com/ory/hydra/internal"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	hydra "github.com/ory/hydra-client-go/v2"
@@ -142,6 +151,66 @@ func TestGetLoginRequest(t *testing.T) {
 	}
 }
 
+func TestGetLoginRequestSessionExpiresAt(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	cl := &client.Client{LegacyClientID: "client"}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Round(time.Second)
+	require.NoError(t, reg.ConsentManager().CreateLoginSession(ctx, &LoginSession{
+		ID:              "session",
+		Subject:         "subject",
+		AuthenticatedAt: sqlxx.NullTime(time.Now().UTC().Round(time.Second)),
+		Remember:        true,
+	}))
+	require.NoError(t, reg.ConsentManager().SetLoginSessionExpiry(ctx, "session", 0, expiresAt))
+
+	for _, tc := range []struct {
+		challenge string
+		skip      bool
+		expected  *time.Time
+	}{
+		{challenge: "skip", skip: true, expected: &expiresAt},
+		{challenge: "no-skip", skip: false},
+	} {
+		t.Run("case="+tc.challenge, func(t *testing.T) {
+			require.NoError(t, reg.ConsentManager().CreateLoginRequest(ctx, &LoginRequest{
+				Client:     cl,
+				ID:         tc.challenge,
+				Verifier:   tc.challenge,
+				CSRF:       tc.challenge,
+				RequestURL: "http://192.0.2.1",
+				SessionID:  "session",
+				Subject:    "subject",
+				Skip:       tc.skip,
+			}))
+
+			h := NewHandler(reg, conf)
+			r := x.NewRouterAdmin(conf.AdminURL)
+			h.SetRoutes(r)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			resp, err := http.Get(ts.URL + "/admin" + LoginPath + "?challenge=" + tc.challenge)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.EqualValues(t, http.StatusOK, resp.StatusCode)
+
+			var result LoginRequest
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+			if tc.expected == nil {
+				assert.Nil(t, result.SessionExpiresAt)
+			} else {
+				require.NotNil(t, result.SessionExpiresAt)
+				assert.Equal(t, tc.expected.Unix(), result.SessionExpiresAt.Unix())
+			}
+		})
+	}
+}
+
 func TestGetConsentRequest(t *testing.T) {
 	for k, tc := range []struct {
 		exists  bool
@@ -277,3 +346,187 @@ func TestGetLoginRequestWithDuplicateAccept(t *testing.T) {
 		require.Contains(t, result2.RedirectTo, "login_verifier")
 	})
 }
+
+func TestLoginSessions(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+
+	hookEvents := make(chan gjson.Result, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		payload, err := base64.RawURLEncoding.DecodeString(strings.Split(string(body), ".")[1])
+		require.NoError(t, err)
+		hookEvents <- gjson.GetBytes(payload, "events")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hook.Close()
+	conf.MustSet(ctx, config.KeyEventHookURL, hook.URL)
+
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	cl := &client.Client{LegacyClientID: "login-sessions-client"}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+	for k, id := range []string{"session-1", "session-2"} {
+		session := &LoginSession{ID: id, Subject: "login-sessions-subject", AuthenticatedAt: sqlxx.NullTime(time.Now().Add(time.Duration(k) * time.Minute).UTC()), Remember: true}
+		require.NoError(t, reg.ConsentManager().CreateLoginSession(ctx, session))
+		SaneMockAuthRequest(t, reg.ConsentManager(), session, cl)
+	}
+
+	h := NewHandler(reg, conf)
+	r := x.NewRouterAdmin(conf.AdminURL)
+	h.SetRoutes(r)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	do := func(t *testing.T, method, path string, expectedStatus int, out interface{}) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+"/admin"+SessionsPath+path, nil)
+		require.NoError(t, err)
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.EqualValues(t, expectedStatus, res.StatusCode)
+		if out != nil {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+		}
+		return res
+	}
+
+	t.Run("case=list requires a subject", func(t *testing.T) {
+		do(t, http.MethodGet, "/login", http.StatusBadRequest, nil)
+	})
+
+	t.Run("case=list sessions of an unknown subject", func(t *testing.T) {
+		var sessions []OAuth2LoginSession
+		do(t, http.MethodGet, "/login?subject=unknown", http.StatusOK, &sessions)
+		require.NotNil(t, sessions)
+		require.Empty(t, sessions)
+	})
+
+	t.Run("case=list sessions", func(t *testing.T) {
+		var sessions []OAuth2LoginSession
+		res := do(t, http.MethodGet, "/login?subject=login-sessions-subject&page_size=1", http.StatusOK, &sessions)
+		require.Len(t, sessions, 1)
+		require.Equal(t, "session-2", sessions[0].ID)
+		require.Equal(t, []string{cl.GetID()}, sessions[0].ClientIDs)
+		require.True(t, sessions[0].Remember)
+		require.Equal(t, "2", res.Header.Get("X-Total-Count"))
+		require.Contains(t, strings.Join(res.Header.Values("Link"), ", "), `rel="next"`)
+	})
+
+	t.Run("case=get and revoke a session", func(t *testing.T) {
+		var session OAuth2LoginSession
+		do(t, http.MethodGet, "/login/session-1", http.StatusOK, &session)
+		require.Equal(t, "login-sessions-subject", session.Subject)
+		require.Equal(t, []string{cl.GetID()}, session.ClientIDs)
+
+		do(t, http.MethodDelete, "/login/session-1", http.StatusNoContent, nil)
+		do(t, http.MethodGet, "/login/session-1", http.StatusNotFound, nil)
+
+		select {
+		case e := <-hookEvents:
+			assert.JSONEq(t, `{"login_session.revoked":{"subject":"login-sessions-subject","session_id":"session-1"}}`, e.Raw)
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the revocation to be sent to the event hook")
+		}
+
+		do(t, http.MethodDelete, "/login/session-1", http.StatusNotFound, nil)
+
+		var sessions []OAuth2LoginSession
+		do(t, http.MethodGet, "/login?subject=login-sessions-subject", http.StatusOK, &sessions)
+		require.Len(t, sessions, 1)
+		require.Equal(t, "session-2", sessions[0].ID)
+	})
+}
+
+func TestBackChannelLogoutDeliveries(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	received := make(chan string, 10)
+	rp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		received <- r.PostForm.Get("logout_token")
+	}))
+	defer rp.Close()
+
+	cl := &client.Client{LegacyClientID: "backchannel-logout-deliveries-client", BackChannelLogoutURI: rp.URL}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+
+	deliveries := []BackChannelLogoutDelivery{
+		{ClientID: cl.GetID(), Subject: "logout-subject", SessionID: "logout-sid", URL: rp.URL, Status: BackChannelLogoutDeliveryPending, NextAttemptAt: time.Now().Add(time.Hour), ExpiresAt: time.Now().Add(time.Hour)},
+		{ClientID: cl.GetID(), Subject: "logout-subject", SessionID: "logout-sid", URL: rp.URL, Status: BackChannelLogoutDeliveryPending, NextAttemptAt: time.Now().Add(time.Hour), ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	require.NoError(t, reg.ConsentManager().CreateBackChannelLogoutDeliveries(ctx, deliveries))
+	failed := deliveries[0]
+	failed.Status = BackChannelLogoutDeliveryFailed
+	failed.LastError = "expected HTTP status code 200 but got 503"
+	require.NoError(t, reg.ConsentManager().UpdateBackChannelLogoutDelivery(ctx, &failed))
+
+	h := NewHandler(reg, conf)
+	r := x.NewRouterAdmin(conf.AdminURL)
+	h.SetRoutes(r)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	do := func(t *testing.T, method, path string, expectedStatus int, out interface{}) {
+		req, err := http.NewRequest(method, ts.URL+"/admin"+SessionsPath+"/logout/deliveries"+path, nil)
+		require.NoError(t, err)
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.EqualValues(t, expectedStatus, res.StatusCode)
+		if out != nil {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+		}
+	}
+
+	t.Run("case=list failed deliveries", func(t *testing.T) {
+		var list []BackChannelLogoutDelivery
+		do(t, http.MethodGet, "?status=failed&client_id="+cl.GetID(), http.StatusOK, &list)
+		require.Len(t, list, 1)
+		assert.Equal(t, failed.ID, list[0].ID)
+		assert.Equal(t, failed.LastError, list[0].LastError)
+
+		do(t, http.MethodGet, "?client_id="+cl.GetID(), http.StatusOK, &list)
+		require.Len(t, list, 2)
+
+		do(t, http.MethodGet, "?status=unknown", http.StatusBadRequest, nil)
+	})
+
+	t.Run("case=get a delivery", func(t *testing.T) {
+		var delivery BackChannelLogoutDelivery
+		do(t, http.MethodGet, "/"+failed.ID.String(), http.StatusOK, &delivery)
+		assert.Equal(t, BackChannelLogoutDeliveryFailed, delivery.Status)
+		assert.Equal(t, rp.URL, delivery.URL)
+
+		do(t, http.MethodGet, "/not-a-uuid", http.StatusNotFound, nil)
+		do(t, http.MethodGet, "/"+uuid.Must(uuid.NewV4()).String(), http.StatusNotFound, nil)
+	})
+
+	t.Run("case=redrive a failed delivery", func(t *testing.T) {
+		var delivery BackChannelLogoutDelivery
+		do(t, http.MethodPost, "/"+failed.ID.String()+"/redrive", http.StatusOK, &delivery)
+		assert.Equal(t, BackChannelLogoutDeliveryPending, delivery.Status)
+		assert.True(t, delivery.ExpiresAt.After(time.Now()))
+
+		select {
+		case token := <-received:
+			claims, err := reg.OpenIDJWTStrategy().Decode(ctx, token)
+			require.NoError(t, err)
+			assert.Equal(t, "logout-sid", claims.Claims["sid"])
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the logout token to be sent to the client")
+		}
+
+		require.Eventually(t, func() bool {
+			got, err := reg.ConsentManager().GetBackChannelLogoutDelivery(ctx, failed.ID)
+			require.NoError(t, err)
+			return got.Status == BackChannelLogoutDeliveryDelivered
+		}, 5*time.Second, 50*time.Millisecond)
+
+		do(t, http.MethodPost, "/"+failed.ID.String()+"/redrive", http.StatusConflict, nil)
+		do(t, http.MethodPost, "/"+deliveries[1].ID.String()+"/redrive", http.StatusConflict, nil)
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/gofrs/uuid"
 
 	"github.com/ory/hydra/client"
+	"github.com/ory/x/pagination/keysetpagination"
 )
 
 type ForcedObfuscatedLoginSession struct {
@@ -32,7 +33,7 @@ type Manager interface {
 
 	VerifyAndInvalidateConsentRequest(ctx context.Context, verifier string) (*AcceptOAuth2ConsentRequest, error)
 	FindGrantedAndRememberedConsentRequests(ctx context.Context, client, user string) ([]AcceptOAuth2ConsentRequest, error)
-	FindSubjectsGrantedConsentRequests(ctx context.Context, user string, limit, offset int) ([]AcceptOAuth2ConsentRequest, error)
+	FindSubjectsGrantedConsentRequests(ctx context.Context, user string, pageOpts ...keysetpagination.Option) ([]AcceptOAuth2ConsentRequest, *keysetpagination.Paginator, error)
 	CountSubjectsGrantedConsentRequests(ctx context.Context, user string) (int, error)
 
 	// Cookie management
@@ -40,7 +41,19 @@ type Manager interface {
 	CreateLoginSession(ctx context.Context, session *LoginSession) error
 	DeleteLoginSession(ctx context.Context, id string) error
 	RevokeSubjectLoginSession(ctx context.Context, user string) error
+	GetLoginSession(ctx context.Context, id string) (*LoginSession, error)
+	ListSubjectLoginSessions(ctx context.Context, subject string, pageOpts ...keysetpagination.Option) ([]LoginSession, *keysetpagination.Paginator, error)
+	CountSubjectLoginSessions(ctx context.Context, subject string) (int, error)
+	// FindLoginSessionsClientIDs returns the IDs of the clients which used each of the login sessions.
+	FindLoginSessionsClientIDs(ctx context.Context, ids ...string) (map[string][]string, error)
 	ConfirmLoginSession(ctx context.Context, id string, authTime time.Time, subject string, remember bool) error
+	// SetLoginSessionExpiry sets the number of seconds a login session is remembered for and the time it expires at.
+	// The zero time means that it does not expire.
+	SetLoginSessionExpiry(ctx context.Context, id string, rememberFor int, expiresAt time.Time) error
+	// SetLoginSessionAuthenticationContext stores the ACR and AMR the subject of a login session authenticated with.
+	SetLoginSessionAuthenticationContext(ctx context.Context, id string, acr string, amr []string) error
+	// FlushExpiredLoginSessions deletes login sessions which expired before notAfter or exceed the maximum age.
+	FlushExpiredLoginSessions(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
 
 	CreateLoginRequest(ctx context.Context, req *LoginRequest) error
 	GetLoginRequest(ctx context.Context, challenge string) (*LoginRequest, error)
@@ -53,9 +66,33 @@ type Manager interface {
 	ListUserAuthenticatedClientsWithFrontChannelLogout(ctx context.Context, subject, sid string) ([]client.Client, error)
 	ListUserAuthenticatedClientsWithBackChannelLogout(ctx context.Context, subject, sid string) ([]client.Client, error)
 
+	CreateBackChannelLogoutDeliveries(ctx context.Context, deliveries []BackChannelLogoutDelivery) error
+	GetBackChannelLogoutDelivery(ctx context.Context, id uuid.UUID) (*BackChannelLogoutDelivery, error)
+	ListBackChannelLogoutDeliveries(ctx context.Context, filter BackChannelLogoutDeliveryFilter, pageOpts ...keysetpagination.Option) ([]BackChannelLogoutDelivery, *keysetpagination.Paginator, error)
+	CountBackChannelLogoutDeliveries(ctx context.Context, filter BackChannelLogoutDeliveryFilter) (int, error)
+	// FindDueBackChannelLogoutDeliveries returns pending deliveries whose next attempt is due at now.
+	FindDueBackChannelLogoutDeliveries(ctx context.Context, now time.Time, limit int) ([]BackChannelLogoutDelivery, error)
+	// LeaseBackChannelLogoutDelivery counts an attempt of a pending delivery and postpones its next attempt until
+	// the lease expired. It returns sqlcon.ErrNoRows if the delivery was leased since it was read.
+	LeaseBackChannelLogoutDelivery(ctx context.Context, delivery *BackChannelLogoutDelivery, until time.Time) error
+	UpdateBackChannelLogoutDelivery(ctx context.Context, delivery *BackChannelLogoutDelivery) error
+	// RedriveBackChannelLogoutDelivery makes a failed delivery pending again.
+	RedriveBackChannelLogoutDelivery(ctx context.Context, id uuid.UUID, nextAttemptAt, expiresAt time.Time) error
+	FlushInactiveBackChannelLogoutDeliveries(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
+
 	CreateLogoutRequest(ctx context.Context, request *LogoutRequest) error
 	GetLogoutRequest(ctx context.Context, challenge string) (*LogoutRequest, error)
 	AcceptLogoutRequest(ctx context.Context, challenge string) (*LogoutRequest, error)
 	RejectLogoutRequest(ctx context.Context, challenge string) error
 	VerifyAndInvalidateLogoutRequest(ctx context.Context, verifier string) (*LogoutRequest, error)
+
+	CreateBackchannelAuthenticationRequest(ctx context.Context, req *BackchannelAuthenticationRequest) error
+	GetBackchannelAuthenticationRequest(ctx context.Context, challenge string) (*BackchannelAuthenticationRequest, error)
+	GetBackchannelAuthenticationRequestByAuthReqIDSignature(ctx context.Context, signature string) (*BackchannelAuthenticationRequest, error)
+	// HandleBackchannelAuthenticationRequest approves or, if r.Error is set, denies a pending request.
+	HandleBackchannelAuthenticationRequest(ctx context.Context, challenge string, r *HandledBackchannelAuthenticationRequest) (*BackchannelAuthenticationRequest, error)
+	TouchBackchannelAuthenticationRequest(ctx context.Context, challenge string, polledAt time.Time) error
+	// InvalidateBackchannelAuthenticationRequest marks an approved request as used.
+	InvalidateBackchannelAuthenticationRequest(ctx context.Context, challenge string) error
+	FlushInactiveBackchannelAuthenticationRequests(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
 }
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -17,6 +18,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlcon"
 	"github.com/ory/x/sqlxx"
 
 	"github.com/ory/fosite"
@@ -393,6 +396,200 @@ func ManagerTests(m Manager, clientManager client.Manager, fositeManager x.Fosit
 			}
 		})
 
+		t.Run("case=list-login-sessions", func(t *testing.T) {
+			ctx := context.Background()
+			subject := makeID("list-sessions-subject", network, "1")
+			clients := []*client.Client{
+				{LegacyClientID: makeID("list-sessions-client", network, "a")},
+				{LegacyClientID: makeID("list-sessions-client", network, "b")},
+			}
+			for _, cl := range clients {
+				require.NoError(t, clientManager.CreateClient(ctx, cl))
+			}
+
+			sessions := make([]*LoginSession, 3)
+			for k := range sessions {
+				sessions[k] = &LoginSession{
+					ID:              makeID("list-sessions", network, strconv.Itoa(k)),
+					AuthenticatedAt: sqlxx.NullTime(time.Now().Round(time.Second).Add(time.Duration(k-3) * time.Hour).UTC()),
+					Subject:         subject,
+					Remember:        k == 0,
+				}
+				require.NoError(t, m.CreateLoginSession(ctx, sessions[k]))
+			}
+			SaneMockAuthRequest(t, m, sessions[0], clients[0])
+			SaneMockAuthRequest(t, m, sessions[0], clients[1])
+			SaneMockAuthRequest(t, m, sessions[0], clients[1])
+			SaneMockAuthRequest(t, m, sessions[1], clients[0])
+
+			n, err := m.CountSubjectLoginSessions(ctx, subject)
+			require.NoError(t, err)
+			assert.Equal(t, 3, n)
+
+			var actual []string
+			opts := []keysetpagination.Option{keysetpagination.WithSize(2)}
+			for pages := 1; ; pages++ {
+				require.LessOrEqual(t, pages, 2)
+				page, nextPage, err := m.ListSubjectLoginSessions(ctx, subject, opts...)
+				require.NoError(t, err)
+				for _, s := range page {
+					actual = append(actual, s.ID)
+				}
+				if nextPage.IsLast() {
+					break
+				}
+				opts = nextPage.ToOptions()
+			}
+			assert.Equal(t, []string{sessions[2].ID, sessions[1].ID, sessions[0].ID}, actual, "the most recently authenticated session is listed first")
+
+			clientIDs, err := m.FindLoginSessionsClientIDs(ctx, sessions[0].ID, sessions[1].ID, sessions[2].ID)
+			require.NoError(t, err)
+			assert.Equal(t, map[string][]string{
+				sessions[0].ID: {clients[0].GetID(), clients[1].GetID()},
+				sessions[1].ID: {clients[0].GetID()},
+			}, clientIDs)
+
+			got, err := m.GetLoginSession(ctx, sessions[0].ID)
+			require.NoError(t, err)
+			assert.Equal(t, subject, got.Subject)
+			assert.True(t, got.Remember)
+			assert.Equal(t, time.Time(sessions[0].AuthenticatedAt).Unix(), time.Time(got.AuthenticatedAt).Unix())
+
+			require.NoError(t, m.DeleteLoginSession(ctx, sessions[2].ID))
+			_, err = m.GetLoginSession(ctx, sessions[2].ID)
+			assert.ErrorIs(t, err, x.ErrNotFound)
+			_, err = m.GetLoginSession(ctx, sessions[1].ID)
+			assert.NoError(t, err)
+		})
+
+		t.Run("case=login-session-authentication-context", func(t *testing.T) {
+			ctx := context.Background()
+			s := &LoginSession{
+				ID:              makeID("acr-session", network, "1"),
+				AuthenticatedAt: sqlxx.NullTime(time.Now().Round(time.Second).UTC()),
+				Subject:         makeID("acr-subject", network, "1"),
+				Remember:        true,
+			}
+			require.NoError(t, m.CreateLoginSession(ctx, s))
+
+			got, err := m.GetRememberedLoginSession(ctx, s.ID)
+			require.NoError(t, err)
+			assert.Empty(t, got.ACR)
+			assert.Empty(t, got.AMR)
+
+			require.NoError(t, m.SetLoginSessionAuthenticationContext(ctx, s.ID, "gold", []string{"pwd", "otp"}))
+			got, err = m.GetRememberedLoginSession(ctx, s.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "gold", got.ACR)
+			assert.EqualValues(t, []string{"pwd", "otp"}, got.AMR)
+			assert.Equal(t, s.Subject, got.Subject, "other columns are kept")
+		})
+
+		t.Run("case=login-session-expiry", func(t *testing.T) {
+			ctx := context.Background()
+			expiresAt := map[string]time.Time{
+				"expired":   time.Now().Add(-time.Minute),
+				"valid":     time.Now().Add(time.Hour),
+				"no-expiry": {},
+			}
+			for k, exp := range expiresAt {
+				s := &LoginSession{
+					ID:              makeID("expiry-session", network, k),
+					AuthenticatedAt: sqlxx.NullTime(time.Now().Round(time.Second).Add(-time.Hour).UTC()),
+					Subject:         makeID("expiry-subject", network, k),
+					Remember:        true,
+				}
+				require.NoError(t, m.CreateLoginSession(ctx, s))
+				if !exp.IsZero() {
+					require.NoError(t, m.SetLoginSessionExpiry(ctx, s.ID, 0, exp))
+				}
+			}
+
+			got, err := m.GetLoginSession(ctx, makeID("expiry-session", network, "valid"))
+			require.NoError(t, err)
+			assert.Equal(t, expiresAt["valid"].Unix(), time.Time(got.ExpiresAt).Unix())
+
+			require.NoError(t, m.FlushExpiredLoginSessions(ctx, time.Now(), 100, 1))
+
+			_, err = m.GetLoginSession(ctx, makeID("expiry-session", network, "expired"))
+			assert.ErrorIs(t, err, x.ErrNotFound)
+			for _, k := range []string{"valid", "no-expiry"} {
+				_, err = m.GetLoginSession(ctx, makeID("expiry-session", network, k))
+				assert.NoError(t, err, k)
+			}
+		})
+
+		t.Run("case=backchannel-logout-delivery", func(t *testing.T) {
+			ctx := context.Background()
+			cl := &client.Client{LegacyClientID: uuid.New().String()}
+			require.NoError(t, clientManager.CreateClient(ctx, cl))
+
+			now := time.Now().UTC().Round(time.Second)
+			deliveries := []BackChannelLogoutDelivery{
+				{ClientID: cl.GetID(), Subject: "logout-subject", SessionID: "logout-sid-1", URL: "https://rp.example/logout", Status: BackChannelLogoutDeliveryPending, NextAttemptAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+				{ClientID: cl.GetID(), Subject: "logout-subject", SessionID: "logout-sid-2", URL: "https://rp.example/logout", Status: BackChannelLogoutDeliveryPending, NextAttemptAt: now.Add(time.Hour), ExpiresAt: now.Add(time.Hour)},
+			}
+			require.NoError(t, m.CreateBackChannelLogoutDeliveries(ctx, deliveries))
+			require.NotEqual(t, deliveries[0].ID, deliveries[1].ID)
+
+			got, err := m.GetBackChannelLogoutDelivery(ctx, deliveries[0].ID)
+			require.NoError(t, err)
+			assert.Equal(t, "logout-sid-1", got.SessionID)
+			assert.Equal(t, BackChannelLogoutDeliveryPending, got.Status)
+
+			_, err = m.GetBackChannelLogoutDelivery(ctx, gofrsuuid.Must(gofrsuuid.NewV4()))
+			assert.ErrorIs(t, err, x.ErrNotFound)
+
+			due, err := m.FindDueBackChannelLogoutDeliveries(ctx, now, 100)
+			require.NoError(t, err)
+			var dueIDs []gofrsuuid.UUID
+			for _, d := range due {
+				dueIDs = append(dueIDs, d.ID)
+			}
+			assert.Contains(t, dueIDs, deliveries[0].ID)
+			assert.NotContains(t, dueIDs, deliveries[1].ID)
+
+			// Only the first of two attempts which read the same delivery leases it.
+			first, second := *got, *got
+			require.NoError(t, m.LeaseBackChannelLogoutDelivery(ctx, &first, now.Add(time.Minute)))
+			assert.Equal(t, 1, first.Attempts)
+			assert.ErrorIs(t, m.LeaseBackChannelLogoutDelivery(ctx, &second, now.Add(time.Minute)), sqlcon.ErrNoRows)
+
+			first.Status = BackChannelLogoutDeliveryFailed
+			first.LastError = "connection refused"
+			require.NoError(t, m.UpdateBackChannelLogoutDelivery(ctx, &first))
+
+			failed, _, err := m.ListBackChannelLogoutDeliveries(ctx, BackChannelLogoutDeliveryFilter{ClientID: cl.GetID(), Status: BackChannelLogoutDeliveryFailed})
+			require.NoError(t, err)
+			require.Len(t, failed, 1)
+			assert.Equal(t, first.ID, failed[0].ID)
+			assert.Equal(t, "connection refused", failed[0].LastError)
+			assert.Equal(t, 1, failed[0].Attempts)
+
+			n, err := m.CountBackChannelLogoutDeliveries(ctx, BackChannelLogoutDeliveryFilter{ClientID: cl.GetID()})
+			require.NoError(t, err)
+			assert.Equal(t, 2, n)
+
+			// Pending deliveries can not be redriven.
+			assert.ErrorIs(t, m.RedriveBackChannelLogoutDelivery(ctx, deliveries[1].ID, now, now.Add(time.Hour)), sqlcon.ErrNoRows)
+			require.NoError(t, m.RedriveBackChannelLogoutDelivery(ctx, first.ID, now, now.Add(2*time.Hour)))
+			got, err = m.GetBackChannelLogoutDelivery(ctx, first.ID)
+			require.NoError(t, err)
+			assert.Equal(t, BackChannelLogoutDeliveryPending, got.Status)
+			assert.Equal(t, 0, got.Attempts)
+			assert.Empty(t, got.LastError)
+			assert.Equal(t, now.Add(2*time.Hour).Unix(), got.ExpiresAt.Unix())
+
+			// Completed deliveries are flushed once their deadline passed, pending ones are kept.
+			got.Status = BackChannelLogoutDeliveryDelivered
+			require.NoError(t, m.UpdateBackChannelLogoutDelivery(ctx, got))
+			require.NoError(t, m.FlushInactiveBackChannelLogoutDeliveries(ctx, now.Add(3*time.Hour), 100, 10))
+			_, err = m.GetBackChannelLogoutDelivery(ctx, first.ID)
+			assert.ErrorIs(t, err, x.ErrNotFound)
+			_, err = m.GetBackChannelLogoutDelivery(ctx, deliveries[1].ID)
+			assert.NoError(t, err)
+		})
+
 		t.Run("case=auth-request", func(t *testing.T) {
 			for _, tc := range []struct {
 				key    string
@@ -690,7 +887,7 @@ func ManagerTests(m Manager, clientManager client.Manager, fositeManager x.Fosit
 				},
 			} {
 				t.Run(fmt.Sprintf("case=%d/subject=%s", i, tc.subject), func(t *testing.T) {
-					consents, err := m.FindSubjectsGrantedConsentRequests(context.Background(), tc.subject, 100, 0)
+					consents, _, err := m.FindSubjectsGrantedConsentRequests(context.Background(), tc.subject)
 					assert.Equal(t, len(tc.challenges), len(consents))
 
 					if len(tc.challenges) == 0 {
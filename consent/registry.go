@@ -9,6 +9,7 @@ import (
 	"github.com/ory/fosite/handler/openid"
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/x"
+	"github.com/ory/hydra/x/events"
 )
 
 type InternalRegistry interface {
@@ -18,6 +19,7 @@ type InternalRegistry interface {
 	x.HTTPClientProvider
 	Registry
 	client.Registry
+	events.Registry
 
 	OAuth2Storage() x.FositeStorer
 	OpenIDConnectRequestValidator() *openid.OpenIDConnectRequestValidator
@@ -26,5 +28,6 @@ type InternalRegistry interface {
 type Registry interface {
 	ConsentManager() Manager
 	ConsentStrategy() Strategy
+	BackChannelLogoutDeliverer() *BackChannelLogoutDeliverer
 	SubjectIdentifierAlgorithm(ctx context.Context) map[string]SubjectIdentifierAlgorithm
 }
@@ -6,6 +6,7 @@ package consent
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -61,6 +62,9 @@ func NewStrategy(
 }
 
 var ErrAbortOAuth2Request = errors.New("the OAuth 2.0 Authorization request must be aborted")
+
+// ErrRequestRejected is wrapped by the error returned after the login or consent app rejected a request.
+var ErrRequestRejected = errors.New("the login or consent request was rejected")
 var ErrNoPreviousConsentFound = errors.New("no previous OAuth 2.0 Consent could be found for this access request")
 var ErrNoAuthenticationSessionFound = errors.New("no previous login session was found")
 var ErrHintDoesNotMatchAuthentication = errors.New("subject from hint does not match subject from session")
@@ -114,18 +118,101 @@ func (s *DefaultStrategy) authenticationSession(ctx context.Context, w http.Resp
 		return nil, err
 	}
 
+	if expiresAt := LoginSessionExpiresAt(session, s.c.LoginSessionMaxAge(ctx)); !expiresAt.IsZero() && expiresAt.Before(time.Now()) {
+		s.r.Logger().WithRequest(r).
+			Debug("Authentication session exists but has expired.")
+		return nil, errorsx.WithStack(ErrNoAuthenticationSessionFound)
+	}
+
 	return session, nil
 }
 
-func (s *DefaultStrategy) requestAuthentication(ctx context.Context, w http.ResponseWriter, r *http.Request, ar fosite.AuthorizeRequester) error {
+// LoginSessionExpiresAt returns the time the login session expires at, taking into account the maximum age of login
+// sessions, or the zero time if it does not expire.
+func LoginSessionExpiresAt(session *LoginSession, maxAge time.Duration) time.Time {
+	expiresAt := time.Time(session.ExpiresAt)
+	if maxAge > 0 && !time.Time(session.AuthenticatedAt).IsZero() {
+		if limit := time.Time(session.AuthenticatedAt).Add(maxAge); expiresAt.IsZero() || limit.Before(expiresAt) {
+			expiresAt = limit
+		}
+	}
+	return expiresAt
+}
+
+// loginSessionExpiresAt returns the time a login session which is remembered for rememberFor, or is used now, expires
+// at. It returns the zero time if the session does not expire.
+func (s *DefaultStrategy) loginSessionExpiresAt(ctx context.Context, authenticatedAt time.Time, rememberFor time.Duration) time.Time {
+	var expiresAt time.Time
+	earliest := func(t time.Time) {
+		if expiresAt.IsZero() || t.Before(expiresAt) {
+			expiresAt = t
+		}
+	}
+
+	if rememberFor > 0 {
+		earliest(authenticatedAt.Add(rememberFor))
+	}
+	if idle := s.c.LoginSessionIdleTimeout(ctx); idle > 0 {
+		earliest(time.Now().Add(idle))
+	}
+	if maxAge := s.c.LoginSessionMaxAge(ctx); maxAge > 0 {
+		earliest(authenticatedAt.Add(maxAge))
+	}
+	return expiresAt.UTC()
+}
+
+// extendAuthenticationSession extends a login session which is used to skip the login by the idle timeout, and
+// updates the expiry of the authentication cookie accordingly.
+func (s *DefaultStrategy) extendAuthenticationSession(ctx context.Context, w http.ResponseWriter, r *http.Request, session *LoginSession) error {
+	if s.c.LoginSessionIdleTimeout(ctx) <= 0 {
+		return nil
+	}
+
+	expiresAt := s.loginSessionExpiresAt(ctx, time.Time(session.AuthenticatedAt), time.Duration(session.RememberFor)*time.Second)
+	if err := s.r.ConsentManager().SetLoginSessionExpiry(ctx, session.ID, session.RememberFor, expiresAt); err != nil {
+		return err
+	}
+	session.ExpiresAt = sqlxx.NullTime(expiresAt)
+
+	cookie, _ := s.r.CookieStore(ctx).Get(r, s.c.SessionCookieName(ctx))
+	cookie.Values[CookieAuthenticationSIDName] = session.ID
+	cookie.Options.MaxAge = loginSessionCookieMaxAge(session.RememberFor, expiresAt)
+	cookie.Options.HttpOnly = true
+	cookie.Options.Path = "/"
+	cookie.Options.SameSite = s.c.CookieSameSiteMode(ctx)
+	cookie.Options.Secure = s.c.CookieSecure(ctx)
+	return errorsx.WithStack(cookie.Save(r, w))
+}
+
+// loginSessionCookieMaxAge returns the max age of the authentication cookie of a login session which is remembered for
+// rememberFor seconds and expires at expiresAt. Sessions which are remembered for the browser session (rememberFor is
+// 0) keep using a session cookie, even if the idle timeout or the maximum age of login sessions applies to them.
+func loginSessionCookieMaxAge(rememberFor int, expiresAt time.Time) int {
+	if rememberFor == 0 || expiresAt.IsZero() {
+		return rememberFor
+	}
+	return cookieMaxAge(expiresAt)
+}
+
+// cookieMaxAge returns the max age of a cookie which expires at expiresAt. It rounds up so that the cookie does not
+// expire before the session does.
+func cookieMaxAge(expiresAt time.Time) int {
+	maxAge := int(math.Ceil(time.Until(expiresAt).Seconds()))
+	if maxAge < 1 {
+		return -1
+	}
+	return maxAge
+}
+
+func (s *DefaultStrategy) requestAuthentication(ctx context.Context, w http.ResponseWriter, r *http.Request, ar fosite.AuthorizeRequester, requestURL *url.URL) error {
 	prompt := stringsx.Splitx(ar.GetRequestForm().Get("prompt"), " ")
 	if stringslice.Has(prompt, "login") {
-		return s.forwardAuthenticationRequest(ctx, w, r, ar, "", time.Time{}, nil)
+		return s.forwardAuthenticationRequest(ctx, w, r, ar, requestURL, "", time.Time{}, nil)
 	}
 
 	session, err := s.authenticationSession(ctx, w, r)
 	if errors.Is(err, ErrNoAuthenticationSessionFound) {
-		return s.forwardAuthenticationRequest(ctx, w, r, ar, "", time.Time{}, nil)
+		return s.forwardAuthenticationRequest(ctx, w, r, ar, requestURL, "", time.Time{}, nil)
 	} else if err != nil {
 		return err
 	}
@@ -143,12 +230,25 @@ func (s *DefaultStrategy) requestAuthentication(ctx context.Context, w http.Resp
 		if stringslice.Has(prompt, "none") {
 			return errorsx.WithStack(fosite.ErrLoginRequired.WithHint("Request failed because prompt is set to 'none' and authentication time reached 'max_age'."))
 		}
-		return s.forwardAuthenticationRequest(ctx, w, r, ar, "", time.Time{}, nil)
+		return s.forwardAuthenticationRequest(ctx, w, r, ar, requestURL, "", time.Time{}, nil)
+	}
+
+	// A session which was authenticated with a weaker ACR than requested requires the End-User to log in again
+	// (step-up authentication).
+	acrValues, err := requestedACRValues(ar)
+	if err != nil {
+		return err
+	}
+	if !satisfiesACR(session, acrValues) {
+		if stringslice.Has(prompt, "none") {
+			return errorsx.WithStack(ErrUnmetAuthenticationRequirements.WithHintf("Request failed because prompt is set to 'none' and the authentication session was not authenticated with any of the requested ACR values '%s'.", strings.Join(acrValues, " ")))
+		}
+		return s.forwardAuthenticationRequest(ctx, w, r, ar, requestURL, "", time.Time{}, nil)
 	}
 
 	idTokenHint := ar.GetRequestForm().Get("id_token_hint")
 	if idTokenHint == "" {
-		return s.forwardAuthenticationRequest(ctx, w, r, ar, session.Subject, time.Time(session.AuthenticatedAt), session)
+		return s.forwardAuthenticationRequest(ctx, w, r, ar, requestURL, session.Subject, time.Time(session.AuthenticatedAt), session)
 	}
 
 	hintSub, err := s.getSubjectFromIDTokenHint(r.Context(), idTokenHint)
@@ -160,7 +260,7 @@ func (s *DefaultStrategy) requestAuthentication(ctx context.Context, w http.Resp
 		return errorsx.WithStack(fosite.ErrLoginRequired.WithHint("Request failed because subject claim from id_token_hint does not match subject from authentication session."))
 	}
 
-	return s.forwardAuthenticationRequest(ctx, w, r, ar, session.Subject, time.Time(session.AuthenticatedAt), session)
+	return s.forwardAuthenticationRequest(ctx, w, r, ar, requestURL, session.Subject, time.Time(session.AuthenticatedAt), session)
 }
 
 func (s *DefaultStrategy) getIDTokenHintClaims(ctx context.Context, idTokenHint string) (jwtgo.MapClaims, error) {
@@ -187,7 +287,7 @@ func (s *DefaultStrategy) getSubjectFromIDTokenHint(ctx context.Context, idToken
 	return sub, nil
 }
 
-func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, ar fosite.AuthorizeRequester, subject string, authenticatedAt time.Time, session *LoginSession) error {
+func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, ar fosite.AuthorizeRequester, requestURL *url.URL, subject string, authenticatedAt time.Time, session *LoginSession) error {
 	if (subject != "" && authenticatedAt.IsZero()) || (subject == "" && !authenticatedAt.IsZero()) {
 		return errorsx.WithStack(fosite.ErrServerError.WithHint("Consent strategy returned a non-empty subject with an empty auth date, or an empty subject with a non-empty auth date."))
 	}
@@ -209,7 +309,7 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w ht
 	csrf := strings.Replace(uuid.New(), "-", "", -1)
 
 	// Generate the request URL
-	iu := s.c.OAuth2AuthURL(ctx)
+	iu := urlx.Copy(requestURL)
 	iu.RawQuery = r.URL.RawQuery
 
 	var idTokenHintClaims jwtgo.MapClaims
@@ -225,6 +325,9 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w ht
 	sessionID := uuid.New()
 	if session != nil {
 		sessionID = session.ID
+		if err := s.extendAuthenticationSession(ctx, w, r, session); err != nil {
+			return err
+		}
 	} else {
 		// Create a stub session so that we can later update it.
 		if err := s.r.ConsentManager().CreateLoginSession(r.Context(), &LoginSession{ID: sessionID}); err != nil {
@@ -232,23 +335,29 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w ht
 		}
 	}
 
+	authorizationDetails, err := ParseAuthorizationDetails(ar.GetRequestForm().Get("authorization_details"))
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to parse the authorization details.").WithDebug(err.Error()))
+	}
+
 	// Set the session
 	cl := sanitizeClientFromRequest(ar)
 	if err := s.r.ConsentManager().CreateLoginRequest(
 		r.Context(),
 		&LoginRequest{
-			ID:                challenge,
-			Verifier:          verifier,
-			CSRF:              csrf,
-			Skip:              skip,
-			RequestedScope:    []string(ar.GetRequestedScopes()),
-			RequestedAudience: []string(ar.GetRequestedAudience()),
-			Subject:           subject,
-			Client:            cl,
-			RequestURL:        iu.String(),
-			AuthenticatedAt:   sqlxx.NullTime(authenticatedAt),
-			RequestedAt:       time.Now().Truncate(time.Second).UTC(),
-			SessionID:         sqlxx.NullString(sessionID),
+			ID:                            challenge,
+			Verifier:                      verifier,
+			CSRF:                          csrf,
+			Skip:                          skip,
+			RequestedScope:                []string(ar.GetRequestedScopes()),
+			RequestedAudience:             []string(ar.GetRequestedAudience()),
+			RequestedAuthorizationDetails: authorizationDetails,
+			Subject:                       subject,
+			Client:                        cl,
+			RequestURL:                    iu.String(),
+			AuthenticatedAt:               sqlxx.NullTime(authenticatedAt),
+			RequestedAt:                   time.Now().Truncate(time.Second).UTC(),
+			SessionID:                     sqlxx.NullString(sessionID),
 			OpenIDConnectContext: &OAuth2ConsentRequestOpenIDConnectContext{
 				IDTokenHintClaims: idTokenHintClaims,
 				ACRValues:         stringsx.Splitx(ar.GetRequestForm().Get("acr_values"), " "),
@@ -316,7 +425,7 @@ func (s *DefaultStrategy) verifyAuthentication(w http.ResponseWriter, r *http.Re
 
 	if session.HasError() {
 		session.Error.SetDefaults(loginRequestDeniedErrorName)
-		return nil, errorsx.WithStack(session.Error.toRFCError())
+		return nil, errorsx.WithStack(session.Error.toRFCError().WithWrap(ErrRequestRejected))
 	}
 
 	if session.RequestedAt.Add(s.c.ConsentRequestMaxAge(ctx)).Before(time.Now()) {
@@ -403,6 +512,18 @@ func (s *DefaultStrategy) verifyAuthentication(w http.ResponseWriter, r *http.Re
 		if err := s.r.ConsentManager().ConfirmLoginSession(r.Context(), sessionID, time.Time(session.AuthenticatedAt), session.Subject, session.Remember); err != nil {
 			return nil, err
 		}
+
+		if err := s.r.ConsentManager().SetLoginSessionAuthenticationContext(ctx, sessionID, session.ACR, session.AMR); err != nil {
+			return nil, err
+		}
+	}
+
+	var expiresAt time.Time
+	if session.Remember && !session.LoginRequest.Skip {
+		expiresAt = s.loginSessionExpiresAt(ctx, time.Time(session.AuthenticatedAt), time.Duration(session.RememberFor)*time.Second)
+		if err := s.r.ConsentManager().SetLoginSessionExpiry(ctx, sessionID, session.RememberFor, expiresAt); err != nil {
+			return nil, err
+		}
 	}
 
 	if !session.Remember && !session.LoginRequest.Skip {
@@ -424,7 +545,7 @@ func (s *DefaultStrategy) verifyAuthentication(w http.ResponseWriter, r *http.Re
 	cookie, _ := s.r.CookieStore(ctx).Get(r, s.c.SessionCookieName(ctx))
 	cookie.Values[CookieAuthenticationSIDName] = sessionID
 	if session.RememberFor >= 0 {
-		cookie.Options.MaxAge = session.RememberFor
+		cookie.Options.MaxAge = loginSessionCookieMaxAge(session.RememberFor, expiresAt)
 	}
 	cookie.Options.HttpOnly = true
 	cookie.Options.Path = "/"
@@ -470,7 +591,9 @@ func (s *DefaultStrategy) requestConsent(ctx context.Context, w http.ResponseWri
 		//
 		// This is tracked as issue: https://github.com/ory/hydra/issues/866
 		// This is also tracked as upstream issue: https://github.com/openid-certification/oidctest/issues/97
-		if !(ar.GetRedirectURI().Scheme == "https" || (fosite.IsLocalhost(ar.GetRedirectURI()) && ar.GetRedirectURI().Scheme == "http")) {
+		//
+		// Device authorization requests have no redirect URI which could prove the client's identity.
+		if ar.GetRedirectURI() == nil || !(ar.GetRedirectURI().Scheme == "https" || (fosite.IsLocalhost(ar.GetRedirectURI()) && ar.GetRedirectURI().Scheme == "http")) {
 			return s.forwardConsentRequest(ctx, w, r, ar, authenticationSession, nil)
 		}
 	}
@@ -482,6 +605,14 @@ func (s *DefaultStrategy) requestConsent(ctx context.Context, w http.ResponseWri
 	// 	 return s.forwardConsentRequest(w, r, ar, authenticationSession, nil)
 	// }
 
+	// Remembered consent sessions are only matched by scope, so they can not be used to skip the consent of
+	// requests asking for authorization details.
+	if details, err := ParseAuthorizationDetails(ar.GetRequestForm().Get("authorization_details")); err != nil {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to parse the authorization details.").WithDebug(err.Error()))
+	} else if len(details) > 0 {
+		return s.forwardConsentRequest(ctx, w, r, ar, authenticationSession, nil)
+	}
+
 	consentSessions, err := s.r.ConsentManager().FindGrantedAndRememberedConsentRequests(r.Context(), ar.GetClient().GetID(), authenticationSession.Subject)
 	if errors.Is(err, ErrNoPreviousConsentFound) {
 		return s.forwardConsentRequest(ctx, w, r, ar, authenticationSession, nil)
@@ -568,7 +699,7 @@ func (s *DefaultStrategy) verifyConsent(ctx context.Context, w http.ResponseWrit
 
 	if session.HasError() {
 		session.Error.SetDefaults(consentRequestDeniedErrorName)
-		return nil, errorsx.WithStack(session.Error.toRFCError())
+		return nil, errorsx.WithStack(session.Error.toRFCError().WithWrap(ErrRequestRejected))
 	}
 
 	if time.Time(session.ConsentRequest.AuthenticatedAt).IsZero() {
@@ -618,25 +749,14 @@ func (s *DefaultStrategy) generateFrontChannelLogoutURLs(ctx context.Context, su
 	return urls, nil
 }
 
-func (s *DefaultStrategy) executeBackChannelLogout(ctx context.Context, r *http.Request, subject, sid string) error {
+func (s *DefaultStrategy) executeBackChannelLogout(ctx context.Context, subject, sid string) error {
 	clients, err := s.r.ConsentManager().ListUserAuthenticatedClientsWithBackChannelLogout(ctx, subject, sid)
 	if err != nil {
 		return err
 	}
 
-	openIDKeyID, err := s.r.OpenIDJWTStrategy().GetPublicKeyID(ctx)
-	if err != nil {
-		return err
-	}
-
-	type task struct {
-		url      string
-		token    string
-		clientID string
-	}
-
-	var tasks []task
-	for _, c := range clients {
+	deliveries := make([]BackChannelLogoutDelivery, len(clients))
+	for k, c := range clients {
 		// Getting the forced obfuscated login session is tricky because the user id could be obfuscated with a new
 		// ID every time the algorithm is used. Thus, we would only get the most recent version. It therefore makes
 		// sense to just use the sid.
@@ -644,49 +764,17 @@ func (s *DefaultStrategy) executeBackChannelLogout(ctx context.Context, r *http.
 		// s.r.ConsentManager().GetForcedObfuscatedLoginSession(context.Background(), subject, <missing>)
 		// sub := s.obfuscateSubjectIdentifier(c, subject, )
 
-		t, _, err := s.r.OpenIDJWTStrategy().Generate(ctx, jwtgo.MapClaims{
-			"iss":    s.c.IssuerURL(ctx).String(),
-			"aud":    []string{c.LegacyClientID},
-			"iat":    time.Now().UTC().Unix(),
-			"jti":    uuid.New(),
-			"events": map[string]struct{}{"http://schemas.openid.net/event/backchannel-logout": {}},
-			"sid":    sid,
-		}, &jwt.Headers{
-			Extra: map[string]interface{}{"kid": openIDKeyID},
-		})
-		if err != nil {
-			return err
-		}
-
-		tasks = append(tasks, task{url: c.BackChannelLogoutURI, clientID: c.GetID(), token: t})
-	}
-
-	var execute = func(t task) {
-		log := s.r.Logger().WithRequest(r).
-			WithField("client_id", t.clientID).
-			WithField("backchannel_logout_url", t.url)
-
-		res, err := s.r.HTTPClient(ctx).PostForm(t.url, url.Values{"logout_token": {t.token}})
-		if err != nil {
-			log.WithError(err).Error("Unable to execute OpenID Connect Back-Channel Logout Request")
-			return
-		}
-		defer res.Body.Close()
-
-		if res.StatusCode != http.StatusOK {
-			log.WithError(errors.Errorf("expected HTTP status code %d but got %d", http.StatusOK, res.StatusCode)).
-				Error("Unable to execute OpenID Connect Back-Channel Logout Request")
-			return
-		} else {
-			log.Info("Back-Channel Logout Request")
+		deliveries[k] = BackChannelLogoutDelivery{
+			ClientID:  c.GetID(),
+			Subject:   subject,
+			SessionID: sid,
+			URL:       c.BackChannelLogoutURI,
 		}
 	}
 
-	for _, t := range tasks {
-		go execute(t)
-	}
-
-	return nil
+	// The deliveries are persisted before they are attempted, so that they are retried if a client can not be
+	// reached, also after a restart.
+	return s.r.BackChannelLogoutDeliverer().Enqueue(ctx, deliveries)
 }
 
 func (s *DefaultStrategy) issueLogoutVerifier(ctx context.Context, w http.ResponseWriter, r *http.Request) (*LogoutResult, error) {
@@ -921,7 +1009,7 @@ func (s *DefaultStrategy) completeLogout(ctx context.Context, w http.ResponseWri
 		return nil, err
 	}
 
-	if err := s.executeBackChannelLogout(r.Context(), r, lr.Subject, lr.SessionID); err != nil {
+	if err := s.executeBackChannelLogout(r.Context(), lr.Subject, lr.SessionID); err != nil {
 		return nil, err
 	}
 
@@ -957,11 +1045,19 @@ func (s *DefaultStrategy) HandleOpenIDConnectLogout(ctx context.Context, w http.
 }
 
 func (s *DefaultStrategy) HandleOAuth2AuthorizationRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester) (*AcceptOAuth2ConsentRequest, error) {
+	return s.handleOAuth2Request(ctx, w, r, req, s.c.OAuth2AuthURL(ctx))
+}
+
+func (s *DefaultStrategy) HandleOAuth2DeviceAuthorizationRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester) (*AcceptOAuth2ConsentRequest, error) {
+	return s.handleOAuth2Request(ctx, w, r, req, s.c.OAuth2DeviceVerifyURL(ctx))
+}
+
+func (s *DefaultStrategy) handleOAuth2Request(ctx context.Context, w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester, requestURL *url.URL) (*AcceptOAuth2ConsentRequest, error) {
 	authenticationVerifier := strings.TrimSpace(req.GetRequestForm().Get("login_verifier"))
 	consentVerifier := strings.TrimSpace(req.GetRequestForm().Get("consent_verifier"))
 	if authenticationVerifier == "" && consentVerifier == "" {
 		// ok, we need to process this request and redirect to auth endpoint
-		return nil, s.requestAuthentication(ctx, w, r, req)
+		return nil, s.requestAuthentication(ctx, w, r, req, requestURL)
 	} else if authenticationVerifier != "" {
 		authSession, err := s.verifyAuthentication(w, r, req, authenticationVerifier)
 		if err != nil {
@@ -106,6 +106,26 @@ func newAuthCookieJar(t *testing.T, reg driver.Registry, u, sessionID string) ht
 	return cj
 }
 
+// recordingCookieJar records the cookies which responses set.
+type recordingCookieJar struct {
+	http.CookieJar
+	cookies []*http.Cookie
+}
+
+func (j *recordingCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.cookies = append(j.cookies, cookies...)
+	j.CookieJar.SetCookies(u, cookies)
+}
+
+func (j *recordingCookieJar) named(name string) (cookies []*http.Cookie) {
+	for _, c := range j.cookies {
+		if c.Name == name {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
+
 func genIDToken(t *testing.T, reg driver.Registry, c jwtgo.MapClaims) string {
 	r, _, err := reg.OpenIDJWTStrategy().Generate(context.Background(), c, jwt.NewHeaders())
 	require.NoError(t, err)
@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 
 	hydra "github.com/ory/hydra-client-go/v2"
 	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/driver/config"
 	"github.com/ory/hydra/internal"
 	"github.com/ory/hydra/internal/testhelpers"
@@ -258,6 +260,43 @@ func TestLogoutFlows(t *testing.T) {
 		backChannelWG.Wait() // we want to ensure that all back channels have been called!
 	})
 
+	t.Run("case=should retry backchannel logout until it was delivered", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyBackChannelLogoutMinRetryWait, "10ms")
+
+		acceptLoginAs(t, subject)
+		checkAndAcceptLogout(t, nil, nil)
+
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		t.Cleanup(server.Close)
+		c := createClient(t, reg, &client.Client{
+			BackChannelLogoutURI:   server.URL,
+			RedirectURIs:           []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+			PostLogoutRedirectURIs: []string{customPostLogoutURL}})
+
+		logoutAndExpectPostLogoutPage(t, createBrowserWithSession(t, c), http.MethodGet, url.Values{}, defaultRedirectedMessage)
+
+		var delivery consent.BackChannelLogoutDelivery
+		require.Eventually(t, func() bool {
+			reg.BackChannelLogoutDeliverer().AttemptDue(ctx)
+			deliveries, _, err := reg.ConsentManager().ListBackChannelLogoutDeliveries(ctx, consent.BackChannelLogoutDeliveryFilter{ClientID: c.GetID()})
+			require.NoError(t, err)
+			require.Len(t, deliveries, 1)
+			delivery = deliveries[0]
+			return delivery.Status == consent.BackChannelLogoutDeliveryDelivered
+		}, 10*time.Second, 100*time.Millisecond)
+
+		assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+		assert.Equal(t, 2, delivery.Attempts)
+		assert.Equal(t, subject, delivery.Subject)
+		assert.Equal(t, server.URL, delivery.URL)
+		assert.Empty(t, delivery.LastError)
+	})
+
 	// Only do GET requests from here on out, POST should be tested enough to ensure that it is working fine already.
 
 	t.Run("case=should fail several flows when id_token_hint is invalid", func(t *testing.T) {
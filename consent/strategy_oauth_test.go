@@ -21,6 +21,7 @@ import (
 	"golang.org/x/oauth2"
 
 	"github.com/ory/x/pointerx"
+	"github.com/ory/x/sqlxx"
 
 	"github.com/tidwall/gjson"
 
@@ -37,6 +38,7 @@ import (
 
 	hydra "github.com/ory/hydra-client-go/v2"
 	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/driver/config"
 	"github.com/ory/hydra/internal"
 )
@@ -765,6 +767,163 @@ func TestStrategyLoginConsentNext(t *testing.T) {
 		makeRequestAndExpectError(t, hc, c, url.Values{"prompt": {"none"}}, "The Authorization Server requires End-User authentication.")
 	})
 
+	t.Run("case=should require re-authentication because the login session has expired", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyLoginSessionMaxAge, time.Hour)
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyLoginSessionMaxAge, 0)
+		})
+
+		c := createDefaultClient(t)
+		testhelpers.NewLoginConsentUI(t, reg.Config(), acceptLoginHandler(t, "aeneas-rekkas", nil), acceptConsentHandler(t, nil))
+
+		for _, tc := range []struct {
+			d               string
+			authenticatedAt time.Time
+			expiresAt       time.Time
+		}{
+			{d: "expires_at", authenticatedAt: time.Now().Add(-time.Minute), expiresAt: time.Now().Add(-time.Second)},
+			{d: "max_age", authenticatedAt: time.Now().Add(-2 * time.Hour)},
+		} {
+			t.Run("case="+tc.d, func(t *testing.T) {
+				sessionID := uuid.New()
+				require.NoError(t, reg.ConsentManager().CreateLoginSession(ctx, &consent.LoginSession{
+					ID:              sessionID,
+					Subject:         "aeneas-rekkas",
+					AuthenticatedAt: sqlxx.NullTime(tc.authenticatedAt.UTC()),
+					Remember:        true,
+				}))
+				if !tc.expiresAt.IsZero() {
+					require.NoError(t, reg.ConsentManager().SetLoginSessionExpiry(ctx, sessionID, 0, tc.expiresAt))
+				}
+
+				hc := &http.Client{Jar: newAuthCookieJar(t, reg, publicTS.URL, sessionID)}
+				makeRequestAndExpectError(t, hc, c, url.Values{"prompt": {"none"}},
+					"Prompt 'none' was requested, but no existing login session was found")
+			})
+		}
+	})
+
+	t.Run("case=should require step-up authentication if the login session does not satisfy the requested acr", func(t *testing.T) {
+		var acr string
+		var skipped bool
+		testhelpers.NewLoginConsentUI(t, reg.Config(),
+			checkAndAcceptLoginHandler(t, adminClient, "aeneas-rekkas", func(t *testing.T, res *hydra.OAuth2LoginRequest, err error) hydra.AcceptOAuth2LoginRequest {
+				require.NoError(t, err)
+				skipped = res.Skip
+				return hydra.AcceptOAuth2LoginRequest{Remember: pointerx.Bool(true), Acr: pointerx.String(acr), Amr: []string{acr}}
+			}),
+			acceptConsentHandler(t, &hydra.AcceptOAuth2ConsentRequest{Remember: pointerx.Bool(true)}))
+
+		c := createDefaultClient(t)
+		hc := testhelpers.NewEmptyJarClient(t)
+
+		acr = "silver"
+		makeRequestAndExpectCode(t, hc, c, url.Values{})
+		assert.False(t, skipped)
+
+		makeRequestAndExpectCode(t, hc, c, url.Values{"claims": {`{"id_token":{"acr":{"essential":true,"values":["gold","silver"]}}}`}})
+		assert.True(t, skipped, "the session satisfies one of the requested values")
+
+		makeRequestAndExpectCode(t, hc, c, url.Values{"acr_values": {"gold"}, "prompt": {"none"}})
+		assert.True(t, skipped, "acr_values is only a hint and is not enforced")
+
+		_, res := makeOAuth2Request(t, reg, hc, c, url.Values{"claims": {`{"id_token":{"acr":{"essential":true,"value":"gold"}}}`}, "prompt": {"none"}})
+		assert.Equal(t, "unmet_authentication_requirements", res.Request.URL.Query().Get("error"), "%v", res.Request.URL.Query())
+
+		acr = "gold"
+		makeRequestAndExpectCode(t, hc, c, url.Values{"claims": {`{"id_token":{"acr":{"essential":true,"value":"gold"}}}`}})
+		assert.False(t, skipped, "the End-User has to log in again")
+
+		// The result of the step-up authentication is remembered.
+		makeRequestAndExpectCode(t, hc, c, url.Values{"claims": {`{"id_token":{"acr":{"essential":true,"value":"gold"}}}`}, "prompt": {"none"}})
+		assert.True(t, skipped)
+
+		t.Run("case=client default acr values", func(t *testing.T) {
+			c := createClient(t, reg, &client.Client{
+				RedirectURIs:     []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+				DefaultACRValues: []string{"platinum"},
+			})
+
+			acr = "platinum"
+			makeRequestAndExpectCode(t, hc, c, url.Values{})
+			assert.False(t, skipped)
+
+			makeRequestAndExpectCode(t, hc, c, url.Values{})
+			assert.True(t, skipped)
+		})
+	})
+
+	t.Run("case=should extend the login session while it is used within the idle timeout", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyLoginSessionIdleTimeout, 2*time.Second)
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyLoginSessionIdleTimeout, 0)
+		})
+
+		c := createDefaultClient(t)
+		testhelpers.NewLoginConsentUI(t, reg.Config(), acceptLoginHandler(t, "aeneas-rekkas", &hydra.AcceptOAuth2LoginRequest{Remember: pointerx.Bool(true)}),
+			acceptConsentHandler(t, &hydra.AcceptOAuth2ConsentRequest{Remember: pointerx.Bool(true)}))
+
+		hc := testhelpers.NewEmptyJarClient(t)
+		makeRequestAndExpectCode(t, hc, c, url.Values{})
+
+		// Each use of the session moves its expiry, so it outlives the idle timeout as long as it is used.
+		for i := 0; i < 2; i++ {
+			time.Sleep(time.Second)
+			makeRequestAndExpectCode(t, hc, c, url.Values{"prompt": {"none"}})
+		}
+
+		time.Sleep(2500 * time.Millisecond)
+		makeRequestAndExpectError(t, hc, c, url.Values{"prompt": {"none"}},
+			"Prompt 'none' was requested, but no existing login session was found")
+	})
+
+	t.Run("case=should not extend the login session beyond remember_for", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyLoginSessionIdleTimeout, time.Hour)
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyLoginSessionIdleTimeout, 0)
+		})
+
+		for _, tc := range []struct {
+			d           string
+			rememberFor int64
+		}{
+			{d: "browser session", rememberFor: 0},
+			{d: "remember_for", rememberFor: 60},
+		} {
+			t.Run("case="+tc.d, func(t *testing.T) {
+				subject := uuid.New()
+				c := createDefaultClient(t)
+				testhelpers.NewLoginConsentUI(t, reg.Config(),
+					acceptLoginHandler(t, subject, &hydra.AcceptOAuth2LoginRequest{Remember: pointerx.Bool(true), RememberFor: pointerx.Int64(tc.rememberFor)}),
+					acceptConsentHandler(t, &hydra.AcceptOAuth2ConsentRequest{Remember: pointerx.Bool(true)}))
+
+				jar := &recordingCookieJar{CookieJar: testhelpers.NewEmptyCookieJar(t)}
+				hc := &http.Client{Jar: jar}
+				makeRequestAndExpectCode(t, hc, c, url.Values{})
+				makeRequestAndExpectCode(t, hc, c, url.Values{"prompt": {"none"}})
+
+				cookies := jar.named(reg.Config().SessionCookieName(ctx))
+				require.Len(t, cookies, 2, "the cookie is set when logging in and when the session is extended")
+				for _, cookie := range cookies {
+					if tc.rememberFor == 0 {
+						assert.Zero(t, cookie.MaxAge, "the session cookie must not outlive the browser session")
+					} else {
+						assert.LessOrEqual(t, cookie.MaxAge, int(tc.rememberFor))
+					}
+				}
+
+				sessions, _, err := reg.ConsentManager().ListSubjectLoginSessions(ctx, subject)
+				require.NoError(t, err)
+				require.Len(t, sessions, 1)
+				assert.EqualValues(t, tc.rememberFor, sessions[0].RememberFor)
+				if tc.rememberFor > 0 {
+					assert.False(t, time.Time(sessions[0].ExpiresAt).After(time.Time(sessions[0].AuthenticatedAt).Add(time.Duration(tc.rememberFor)*time.Second)),
+						"the idle timeout must not extend the session beyond remember_for")
+				}
+			})
+		}
+	})
+
 	t.Run("case=should be able to retry accept consent request", func(t *testing.T) {
 		subject := "aeneas-rekkas"
 		c := createDefaultClient(t)
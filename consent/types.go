@@ -18,6 +18,7 @@ import (
 
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/x"
 	"github.com/ory/x/sqlcon"
 	"github.com/ory/x/sqlxx"
 )
@@ -47,12 +48,88 @@ type LoginSession struct {
 	AuthenticatedAt sqlxx.NullTime `db:"authenticated_at"`
 	Subject         string         `db:"subject"`
 	Remember        bool           `db:"remember"`
+
+	// RememberFor is the number of seconds the login session was remembered for. If it is 0, the session is
+	// remembered for the browser session, and its authentication cookie is a session cookie.
+	RememberFor int `db:"remember_for"`
+
+	// ExpiresAt is the time a remembered login session expires at. It is null if the session does not expire.
+	ExpiresAt sqlxx.NullTime `db:"expires_at"`
+
+	// ACR and AMR are the authentication context class and methods the subject was last authenticated with. They
+	// decide whether the session satisfies the ACR requested by a later authorization request.
+	ACR string                      `db:"acr"`
+	AMR sqlxx.StringSliceJSONFormat `db:"amr"`
 }
 
 func (_ LoginSession) TableName() string {
 	return "hydra_oauth2_authentication_session"
 }
 
+// PageToken returns the token of the page which starts after this login session, as login sessions are listed from
+// the most recently authenticated one.
+func (s LoginSession) PageToken() string {
+	return x.EncodePageToken(time.Time(s.AuthenticatedAt).UTC().Format(time.RFC3339Nano), s.ID)
+}
+
+// List of OAuth 2.0 Login Sessions
+//
+// swagger:model oAuth2LoginSessions
+type oAuth2LoginSessions []OAuth2LoginSession
+
+// OAuth 2.0 Login Session
+//
+// A login session is created when a subject authenticates at the login provider and is reused by all OAuth 2.0
+// flows of the same user-agent until it is revoked or, if it is not remembered, the browser is closed.
+//
+// swagger:model oAuth2LoginSession
+type OAuth2LoginSession struct {
+	// ID is the identifier of the login session. It is the `sid` claim of the ID Tokens issued during the session.
+	//
+	// required: true
+	ID string `json:"id"`
+
+	// Subject is the subject which authenticated.
+	//
+	// required: true
+	Subject string `json:"subject"`
+
+	// AuthenticatedAt is the time the subject authenticated at.
+	AuthenticatedAt sqlxx.NullTime `json:"authenticated_at"`
+
+	// Remember is true if the login session is remembered beyond the browser session.
+	//
+	// required: true
+	Remember bool `json:"remember"`
+
+	// ACR is the Authentication Context Class Reference the subject was last authenticated with.
+	ACR string `json:"acr,omitempty"`
+
+	// AMR are the Authentication Methods References the subject was last authenticated with.
+	AMR []string `json:"amr,omitempty"`
+
+	// ClientIDs are the IDs of the OAuth 2.0 Clients which used the login session.
+	//
+	// required: true
+	ClientIDs []string `json:"client_ids"`
+}
+
+// NewOAuth2LoginSession returns the login session as returned by the admin API.
+func NewOAuth2LoginSession(s *LoginSession, clientIDs []string) *OAuth2LoginSession {
+	if clientIDs == nil {
+		clientIDs = []string{}
+	}
+	return &OAuth2LoginSession{
+		ID:              s.ID,
+		Subject:         s.Subject,
+		AuthenticatedAt: s.AuthenticatedAt,
+		Remember:        s.Remember,
+		ACR:             s.ACR,
+		AMR:             s.AMR,
+		ClientIDs:       clientIDs,
+	}
+}
+
 // The request payload used to accept a login or consent request.
 //
 // swagger:model rejectOAuth2Request
@@ -152,6 +229,10 @@ type AcceptOAuth2ConsentRequest struct {
 	// GrantedAudience sets the audience the user authorized the client to use. Should be a subset of `requested_access_token_audience`.
 	GrantedAudience sqlxx.StringSliceJSONFormat `json:"grant_access_token_audience"`
 
+	// GrantedAuthorizationDetails sets the authorization details the user authorized the client to use. Each
+	// authorization detail must be of a type contained in `requested_authorization_details`.
+	GrantedAuthorizationDetails AuthorizationDetails `json:"grant_authorization_details,omitempty" faker:"-"`
+
 	// Session allows you to set (optional) session data for access and ID tokens.
 	Session *AcceptOAuth2ConsentRequestSession `json:"session" faker:"-"`
 
@@ -208,6 +289,11 @@ type OAuth2ConsentSession struct {
 	// GrantedAudience sets the audience the user authorized the client to use. Should be a subset of `requested_access_token_audience`.
 	GrantedAudience sqlxx.StringSliceJSONFormat `json:"grant_access_token_audience" db:"granted_at_audience"`
 
+	// Authorization Details Granted
+	//
+	// GrantedAuthorizationDetails sets the authorization details the user authorized the client to use.
+	GrantedAuthorizationDetails AuthorizationDetails `json:"grant_authorization_details,omitempty" db:"-" faker:"-"`
+
 	// Session Details
 	//
 	// Session allows you to set (optional) session data for access and ID tokens.
@@ -461,6 +547,9 @@ type LoginRequest struct {
 	// required: true
 	RequestedAudience sqlxx.StringSliceJSONFormat `json:"requested_access_token_audience"`
 
+	// RequestedAuthorizationDetails contains the authorization details as requested by the OAuth 2.0 Client.
+	RequestedAuthorizationDetails AuthorizationDetails `json:"requested_authorization_details,omitempty" faker:"-"`
+
 	// Skip, if true, implies that the client has requested the same scopes from the same user previously.
 	// If true, you can skip asking the user to grant the requested scopes, and simply forward the user to the redirect URL.
 	//
@@ -500,6 +589,10 @@ type LoginRequest struct {
 	// channel logout. It's value can generally be used to associate consecutive login requests by a certain user.
 	SessionID sqlxx.NullString `json:"session_id"`
 
+	// SessionExpiresAt is the time the login session expires at if `skip` is true and the session expires. The
+	// subject has to authenticate again afterwards, so it can be used to warn the user.
+	SessionExpiresAt *time.Time `json:"session_expires_at,omitempty" faker:"-"`
+
 	// If set to true means that the request was already handled. This
 	// can happen on form double-submit or other errors. If this is set
 	// we recommend redirecting the user to `request_url` to re-initiate
@@ -530,6 +623,9 @@ type OAuth2ConsentRequest struct {
 	// RequestedAudience contains the access token audience as requested by the OAuth 2.0 Client.
 	RequestedAudience sqlxx.StringSliceJSONFormat `json:"requested_access_token_audience"`
 
+	// RequestedAuthorizationDetails contains the authorization details as requested by the OAuth 2.0 Client.
+	RequestedAuthorizationDetails AuthorizationDetails `json:"requested_authorization_details,omitempty" faker:"-"`
+
 	// Skip, if true, implies that the client has requested the same scopes from the same user previously.
 	// If true, you must not ask the user to grant the requested scopes. You must however either allow or deny the
 	// consent request using the usual API call.
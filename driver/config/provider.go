@@ -42,6 +42,9 @@ const (
 	KeyOAuth2ClientRegistrationURL               = "webfinger.oidc_discovery.client_registration_url"
 	KeyOAuth2TokenURL                            = "webfinger.oidc_discovery.token_url" // #nosec G101
 	KeyOAuth2AuthURL                             = "webfinger.oidc_discovery.auth_url"
+	KeyOAuth2DeviceAuthorisationURL              = "webfinger.oidc_discovery.device_authorization_url"
+	KeyOAuth2PushedAuthorizationRequestURL       = "webfinger.oidc_discovery.par_url"
+	KeyOAuth2BackchannelAuthenticationURL        = "webfinger.oidc_discovery.backchannel_authentication_url"
 	KeyJWKSURL                                   = "webfinger.oidc_discovery.jwks_url"
 	KeyOIDCDiscoverySupportedClaims              = "webfinger.oidc_discovery.supported_claims"
 	KeyOIDCDiscoverySupportedScope               = "webfinger.oidc_discovery.supported_scope"
@@ -66,6 +69,14 @@ const (
 	KeyRefreshTokenLifespan                      = "ttl.refresh_token" // #nosec G101
 	KeyIDTokenLifespan                           = "ttl.id_token"      // #nosec G101
 	KeyAuthCodeLifespan                          = "ttl.auth_code"
+	KeyDeviceAndUserCodeLifespan                 = "ttl.device_user_code"
+	KeyPushedAuthorizeRequestLifespan            = "ttl.pushed_authorization_request"
+	KeyBackchannelAuthenticationRequestLifespan  = "ttl.backchannel_authentication_request"
+	KeyDPoPProofLifespan                         = "ttl.dpop_proof"
+	KeyClientSecretLifespan                      = "ttl.client_secret" // #nosec G101
+	KeyDeletedClientRetention                    = "ttl.deleted_client"
+	KeyLoginSessionIdleTimeout                   = "ttl.login_session_idle"
+	KeyLoginSessionMaxAge                        = "ttl.login_session_max_age"
 	KeyScopeStrategy                             = "strategies.scope"
 	KeyGetCookieSecrets                          = "secrets.cookie"
 	KeyGetSystemSecret                           = "secrets.system"
@@ -74,15 +85,22 @@ const (
 	KeyLogoutURL                                 = "urls.logout"
 	KeyConsentURL                                = "urls.consent"
 	KeyErrorURL                                  = "urls.error"
+	KeyDeviceVerificationURL                     = "urls.device.verification"
+	KeyDeviceDoneURL                             = "urls.device.success"
+	KeyBackchannelAuthenticationLoginURL         = "urls.backchannel_authentication"
 	KeyPublicURL                                 = "urls.self.public"
 	KeyAdminURL                                  = "urls.self.admin"
 	KeyIssuerURL                                 = "urls.self.issuer"
+	KeyMTLSPublicURL                             = "urls.self.mtls_public"
 	KeyAccessTokenStrategy                       = "strategies.access_token"
 	KeyDBIgnoreUnknownTableColumns               = "db.ignore_unknown_table_columns"
 	KeySubjectIdentifierAlgorithmSalt            = "oidc.subject_identifiers.pairwise.salt"
 	KeyPublicAllowDynamicRegistration            = "oidc.dynamic_client_registration.enabled"
+	KeyDynamicRegistrationRequireInitialToken    = "oidc.dynamic_client_registration.require_initial_access_token"
+	KeySoftwareStatementAllowedGrantTypes        = "oidc.dynamic_client_registration.software_statement.allowed_grant_types"
 	KeyPKCEEnforced                              = "oauth2.pkce.enforced"
 	KeyPKCEEnforcedForPublicClients              = "oauth2.pkce.enforced_for_public_clients"
+	KeyPushedAuthorizeRequestsEnforced           = "oauth2.pushed_authorization_requests.enforced"
 	KeyLogLevel                                  = "log.level"
 	KeyCGroupsV1AutoMaxProcsEnabled              = "cgroups.v1.auto_max_procs_enabled"
 	KeyGrantAllClientCredentialsScopesPerDefault = "oauth2.client_credentials.default_grant_allowed_scope" // #nosec G101
@@ -92,7 +110,19 @@ const (
 	KeyOAuth2GrantJWTIDOptional                  = "oauth2.grant.jwt.jti_optional"
 	KeyOAuth2GrantJWTIssuedDateOptional          = "oauth2.grant.jwt.iat_optional"
 	KeyOAuth2GrantJWTMaxDuration                 = "oauth2.grant.jwt.max_ttl"
+	KeyOAuth2GrantPasswordEnabled                = "oauth2.grant.password.enabled"
+	KeyOAuth2GrantPasswordWebhookURL             = "oauth2.grant.password.webhook_url"
 	KeyRefreshTokenHookURL                       = "oauth2.refresh_token_hook" // #nosec G101
+	KeyEventHookURL                              = "oauth2.event_hook.url"
+	KeyEventHookEvents                           = "oauth2.event_hook.events"
+	KeyEventHookMaxRetries                       = "oauth2.event_hook.retry.max_retries"
+	KeyEventHookMinRetryWait                     = "oauth2.event_hook.retry.min_wait"
+	KeyEventHookMaxRetryWait                     = "oauth2.event_hook.retry.max_wait"
+	KeyBackChannelLogoutMinRetryWait             = "oidc.backchannel_logout.retry.min_wait"
+	KeyBackChannelLogoutMaxRetryWait             = "oidc.backchannel_logout.retry.max_wait"
+	KeyBackChannelLogoutDeadline                 = "oidc.backchannel_logout.retry.deadline"
+	KeyDeviceAuthTokenPollingInterval            = "oauth2.device_authorization.token_polling_interval"
+	KeyBackchannelAuthenticationPollingInterval  = "oauth2.backchannel_authentication.token_polling_interval"
 	KeyDevelopmentMode                           = "dev"
 )
 
@@ -192,6 +222,10 @@ func (p *DefaultProvider) WellKnownKeys(ctx context.Context, include ...string)
 		include = append(include, x.OAuth2JWTKeyName)
 	}
 
+	if p.EventHookURL(ctx) != nil {
+		include = append(include, x.EventsKeyName)
+	}
+
 	include = append(include, x.OpenIDConnectKeyName)
 	return stringslice.Unique(append(p.getProvider(ctx).Strings(KeyWellKnownKeys), include...))
 }
@@ -301,6 +335,17 @@ func (p *DefaultProvider) PublicAllowDynamicRegistration(ctx context.Context) bo
 	return p.getProvider(ctx).Bool(KeyPublicAllowDynamicRegistration)
 }
 
+// DynamicRegistrationRequiresInitialAccessToken returns true if dynamic client registration requires an initial
+// access token issued through the admin API.
+func (p *DefaultProvider) DynamicRegistrationRequiresInitialAccessToken(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyDynamicRegistrationRequireInitialToken)
+}
+
+// SoftwareStatementAllowedGrantTypes returns the grant types a client may register with a software statement.
+func (p *DefaultProvider) SoftwareStatementAllowedGrantTypes(ctx context.Context) []string {
+	return p.getProvider(ctx).StringsF(KeySoftwareStatementAllowedGrantTypes, []string{"authorization_code", "refresh_token"})
+}
+
 func (p *DefaultProvider) CookieSameSiteLegacyWorkaround(ctx context.Context) bool {
 	return p.getProvider(ctx).Bool(KeyCookieSameSiteLegacyWorkaround)
 }
@@ -370,10 +415,34 @@ func (p *DefaultProvider) ErrorURL(ctx context.Context) *url.URL {
 	return urlRoot(p.getProvider(ctx).RequestURIF(KeyErrorURL, p.publicFallbackURL(ctx, "oauth2/fallbacks/error")))
 }
 
+func (p *DefaultProvider) DeviceVerificationURL(ctx context.Context) *url.URL {
+	return urlRoot(p.getProvider(ctx).URIF(KeyDeviceVerificationURL, p.publicFallbackURL(ctx, "oauth2/fallbacks/device")))
+}
+
+func (p *DefaultProvider) DeviceDoneURL(ctx context.Context) *url.URL {
+	return urlRoot(p.getProvider(ctx).RequestURIF(KeyDeviceDoneURL, p.publicFallbackURL(ctx, "oauth2/fallbacks/device/done")))
+}
+
+// BackchannelAuthenticationLoginURL returns the endpoint of the login app which is notified of new OpenID Connect
+// CIBA requests, or nil if backchannel authentication is not configured.
+func (p *DefaultProvider) BackchannelAuthenticationLoginURL(ctx context.Context) *url.URL {
+	if len(p.getProvider(ctx).String(KeyBackchannelAuthenticationLoginURL)) == 0 {
+		return nil
+	}
+
+	return p.getProvider(ctx).RequestURIF(KeyBackchannelAuthenticationLoginURL, nil)
+}
+
 func (p *DefaultProvider) PublicURL(ctx context.Context) *url.URL {
 	return urlRoot(p.getProvider(ctx).RequestURIF(KeyPublicURL, p.IssuerURL(ctx)))
 }
 
+// MTLSPublicURL returns the base location of the public endpoints which are reached using mutual TLS. It falls back
+// to the public URL if mutual TLS is handled by the same endpoints.
+func (p *DefaultProvider) MTLSPublicURL(ctx context.Context) *url.URL {
+	return urlRoot(p.getProvider(ctx).RequestURIF(KeyMTLSPublicURL, p.PublicURL(ctx)))
+}
+
 func (p *DefaultProvider) AdminURL(ctx context.Context) *url.URL {
 	return urlRoot(
 		p.getProvider(ctx).RequestURIF(
@@ -400,6 +469,67 @@ func (p *DefaultProvider) OAuth2AuthURL(ctx context.Context) *url.URL {
 	return p.getProvider(ctx).RequestURIF(KeyOAuth2AuthURL, urlx.AppendPaths(p.PublicURL(ctx), "/oauth2/auth"))
 }
 
+func (p *DefaultProvider) OAuth2PushedAuthorizationRequestURL(ctx context.Context) *url.URL {
+	return p.getProvider(ctx).RequestURIF(KeyOAuth2PushedAuthorizationRequestURL, urlx.AppendPaths(p.PublicURL(ctx), "/oauth2/par"))
+}
+
+func (p *DefaultProvider) OAuth2DeviceAuthorisationURL(ctx context.Context) *url.URL {
+	return p.getProvider(ctx).RequestURIF(KeyOAuth2DeviceAuthorisationURL, urlx.AppendPaths(p.PublicURL(ctx), "/oauth2/device/auth"))
+}
+
+// OAuth2DeviceVerifyURL returns the URL of the endpoint which accepts the user code and
+// starts the login and consent flow for a device authorization request.
+func (p *DefaultProvider) OAuth2DeviceVerifyURL(ctx context.Context) *url.URL {
+	return urlx.AppendPaths(p.PublicURL(ctx), "/oauth2/device/verify")
+}
+
+func (p *DefaultProvider) GetDeviceAuthTokenPollingInterval(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyDeviceAuthTokenPollingInterval, time.Second*5)
+}
+
+func (p *DefaultProvider) OAuth2BackchannelAuthenticationURL(ctx context.Context) *url.URL {
+	return p.getProvider(ctx).RequestURIF(KeyOAuth2BackchannelAuthenticationURL, urlx.AppendPaths(p.PublicURL(ctx), "/oauth2/bc-authorize"))
+}
+
+// BackchannelAuthenticationRequestLifespan returns for how long the auth_req_id of an OpenID Connect CIBA request
+// is valid.
+func (p *DefaultProvider) BackchannelAuthenticationRequestLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyBackchannelAuthenticationRequestLifespan, time.Minute*10)
+}
+
+// BackchannelAuthenticationPollingInterval returns the minimum amount of time clients in poll mode must wait
+// between token requests.
+func (p *DefaultProvider) BackchannelAuthenticationPollingInterval(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyBackchannelAuthenticationPollingInterval, time.Second*5)
+}
+
+// DPoPProofLifespan returns for how long a DPoP proof is accepted after it was issued.
+func (p *DefaultProvider) DPoPProofLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyDPoPProofLifespan, time.Minute)
+}
+
+// DeletedClientRetention returns for how long soft-deleted clients are kept before the janitor purges them.
+func (p *DefaultProvider) DeletedClientRetention(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyDeletedClientRetention, time.Hour*24*30)
+}
+
+// LoginSessionIdleTimeout returns for how long a remembered login session stays valid without being used. Zero
+// means that login sessions are not extended when they are used.
+func (p *DefaultProvider) LoginSessionIdleTimeout(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyLoginSessionIdleTimeout, 0)
+}
+
+// LoginSessionMaxAge returns the maximum age of a login session, counted from the time the subject authenticated.
+// Zero means that there is no maximum age.
+func (p *DefaultProvider) LoginSessionMaxAge(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyLoginSessionMaxAge, 0)
+}
+
+// ClientSecretLifespan returns for how long new client secrets are valid. Zero means that they do not expire.
+func (p *DefaultProvider) ClientSecretLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyClientSecretLifespan, 0)
+}
+
 func (p *DefaultProvider) JWKSURL(ctx context.Context) *url.URL {
 	return p.getProvider(ctx).RequestURIF(KeyJWKSURL, urlx.AppendPaths(p.IssuerURL(ctx), "/.well-known/jwks.json"))
 }
@@ -422,6 +552,60 @@ func (p *DefaultProvider) TokenRefreshHookURL(ctx context.Context) *url.URL {
 	return p.getProvider(ctx).RequestURIF(KeyRefreshTokenHookURL, nil)
 }
 
+// EventHookURL returns the endpoint which revocation and deletion events are sent to, or nil if no events are sent.
+func (p *DefaultProvider) EventHookURL(ctx context.Context) *url.URL {
+	if len(p.getProvider(ctx).String(KeyEventHookURL)) == 0 {
+		return nil
+	}
+
+	return p.getProvider(ctx).RequestURIF(KeyEventHookURL, nil)
+}
+
+// EventHookEvents returns the types of the events which are sent to the event hook. All events are sent if it is
+// empty.
+func (p *DefaultProvider) EventHookEvents(ctx context.Context) []string {
+	return p.getProvider(ctx).Strings(KeyEventHookEvents)
+}
+
+// EventHookMaxRetries returns how often the delivery of an event is retried.
+func (p *DefaultProvider) EventHookMaxRetries(ctx context.Context) int {
+	return p.getProvider(ctx).IntF(KeyEventHookMaxRetries, 5)
+}
+
+// EventHookRetryWait returns the minimum and maximum time to wait between two delivery attempts of an event. The
+// time waited grows exponentially from the minimum to the maximum.
+func (p *DefaultProvider) EventHookRetryWait(ctx context.Context) (min time.Duration, max time.Duration) {
+	return p.getProvider(ctx).DurationF(KeyEventHookMinRetryWait, time.Second),
+		p.getProvider(ctx).DurationF(KeyEventHookMaxRetryWait, 30*time.Second)
+}
+
+// BackChannelLogoutRetryWait returns the minimum and maximum time to wait between two attempts to deliver an
+// OpenID Connect Back-Channel Logout request. The time waited doubles with each failed attempt.
+func (p *DefaultProvider) BackChannelLogoutRetryWait(ctx context.Context) (min time.Duration, max time.Duration) {
+	return p.getProvider(ctx).DurationF(KeyBackChannelLogoutMinRetryWait, 10*time.Second),
+		p.getProvider(ctx).DurationF(KeyBackChannelLogoutMaxRetryWait, 10*time.Minute)
+}
+
+// BackChannelLogoutDeadline returns for how long after the logout the delivery of an OpenID Connect Back-Channel
+// Logout request is retried before it is considered failed.
+func (p *DefaultProvider) BackChannelLogoutDeadline(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyBackChannelLogoutDeadline, 24*time.Hour)
+}
+
+// GrantPasswordEnabled returns true if the resource owner password credentials grant is enabled. The grant is
+// disabled unless it is explicitly enabled and a webhook for verifying the credentials is configured.
+func (p *DefaultProvider) GrantPasswordEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyOAuth2GrantPasswordEnabled) && p.GrantPasswordWebhookURL(ctx) != nil
+}
+
+func (p *DefaultProvider) GrantPasswordWebhookURL(ctx context.Context) *url.URL {
+	if len(p.getProvider(ctx).String(KeyOAuth2GrantPasswordWebhookURL)) == 0 {
+		return nil
+	}
+
+	return p.getProvider(ctx).RequestURIF(KeyOAuth2GrantPasswordWebhookURL, nil)
+}
+
 func (p *DefaultProvider) DbIgnoreUnknownTableColumns() bool {
 	return p.p.Bool(KeyDBIgnoreUnknownTableColumns)
 }
@@ -90,6 +90,27 @@ func (p *DefaultProvider) GetAuthorizeCodeLifespan(ctx context.Context) time.Dur
 	return p.getProvider(ctx).DurationF(KeyAuthCodeLifespan, time.Minute*10)
 }
 
+var _ fosite.PushedAuthorizeRequestConfigProvider = (*DefaultProvider)(nil)
+
+// PushedAuthorizeRequestURIPrefix is the prefix of the request_uri returned by the pushed authorization request endpoint.
+const PushedAuthorizeRequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
+func (p *DefaultProvider) GetPushedAuthorizeRequestURIPrefix(ctx context.Context) string {
+	return PushedAuthorizeRequestURIPrefix
+}
+
+func (p *DefaultProvider) GetPushedAuthorizeContextLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyPushedAuthorizeRequestLifespan, time.Minute)
+}
+
+func (p *DefaultProvider) EnforcePushedAuthorize(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyPushedAuthorizeRequestsEnforced)
+}
+
+func (p *DefaultProvider) GetDeviceAndUserCodeLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyDeviceAndUserCodeLifespan, time.Minute*10)
+}
+
 var _ fosite.ScopeStrategyProvider = (*DefaultProvider)(nil)
 
 func (p *DefaultProvider) GetScopeStrategy(ctx context.Context) fosite.ScopeStrategy {
@@ -95,7 +95,7 @@ func TestCORSOptions(t *testing.T) {
 	assert.EqualValues(t, cors.Options{
 		AllowedOrigins:   []string{},
 		AllowedMethods:   []string{"POST", "GET", "PUT", "PATCH", "DELETE", "CONNECT", "HEAD", "OPTIONS", "TRACE"},
-		AllowedHeaders:   []string{"Accept", "Content-Type", "Content-Length", "Accept-Language", "Content-Language", "Authorization"},
+		AllowedHeaders:   []string{"Accept", "Content-Type", "Content-Length", "Accept-Language", "Content-Language", "Authorization", "DPoP"},
 		ExposedHeaders:   []string{"Cache-Control", "Expires", "Last-Modified", "Pragma", "Content-Length", "Content-Language", "Content-Type"},
 		AllowCredentials: true,
 	}, conf)
@@ -377,6 +377,28 @@ func TestLoginConsentURL(t *testing.T) {
 	assert.Equal(t, "http://localhost:3000/#/oauth/consent", p2.ConsentURL(ctx).String())
 }
 
+func TestDeviceAuthorization(t *testing.T) {
+	ctx := context.Background()
+	l := logrusx.New("", "")
+	l.Logrus().SetOutput(io.Discard)
+	p := MustNew(context.Background(), l, configx.WithValue(KeyPublicURL, "https://hydra.localhost/"))
+
+	assert.Equal(t, "https://hydra.localhost/oauth2/device/auth", p.OAuth2DeviceAuthorisationURL(ctx).String())
+	assert.Equal(t, "https://hydra.localhost/oauth2/device/verify", p.OAuth2DeviceVerifyURL(ctx).String())
+	assert.Equal(t, 10*time.Minute, p.GetDeviceAndUserCodeLifespan(ctx))
+	assert.Equal(t, 5*time.Second, p.GetDeviceAuthTokenPollingInterval(ctx))
+
+	p.MustSet(ctx, KeyDeviceVerificationURL, "https://app.localhost/device")
+	p.MustSet(ctx, KeyDeviceDoneURL, "https://app.localhost/device/done")
+	p.MustSet(ctx, KeyDeviceAndUserCodeLifespan, "15m")
+	p.MustSet(ctx, KeyDeviceAuthTokenPollingInterval, "10s")
+
+	assert.Equal(t, "https://app.localhost/device", p.DeviceVerificationURL(ctx).String())
+	assert.Equal(t, "https://app.localhost/device/done", p.DeviceDoneURL(ctx).String())
+	assert.Equal(t, 15*time.Minute, p.GetDeviceAndUserCodeLifespan(ctx))
+	assert.Equal(t, 10*time.Second, p.GetDeviceAuthTokenPollingInterval(ctx))
+}
+
 func TestInfinitRefreshTokenTTL(t *testing.T) {
 	ctx := context.Background()
 	l := logrusx.New("", "")
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ory/x/contextx"
 
@@ -23,6 +24,7 @@ const (
 	KeySuffixSocketGroup            = "socket.group"
 	KeySuffixSocketMode             = "socket.mode"
 	KeySuffixDisableHealthAccessLog = "request_log.disable_for_health"
+	KeySuffixCORSClientOriginsTTL   = "cors.client_origins_cache_ttl"
 )
 
 var (
@@ -90,6 +92,7 @@ func (p *DefaultProvider) CORS(ctx context.Context, iface ServeInterface) (cors.
 			"Accept-Language",
 			"Content-Language",
 			"Authorization",
+			"DPoP",
 		},
 		ExposedHeaders: []string{
 			"Cache-Control",
@@ -104,6 +107,11 @@ func (p *DefaultProvider) CORS(ctx context.Context, iface ServeInterface) (cors.
 	})
 }
 
+// CORSClientOriginsCacheTTL returns for how long the origins allowed by OAuth 2.0 Clients are cached.
+func (p *DefaultProvider) CORSClientOriginsCacheTTL(ctx context.Context, iface ServeInterface) time.Duration {
+	return p.getProvider(ctx).DurationF(iface.Key(KeySuffixCORSClientOriginsTTL), time.Minute)
+}
+
 func (p *DefaultProvider) DisableHealthAccessLog(iface ServeInterface) bool {
 	return p.getProvider(contextx.RootContext).Bool(iface.Key(KeySuffixDisableHealthAccessLog))
 }
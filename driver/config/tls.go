@@ -6,6 +6,9 @@ package config
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"os"
 
 	"github.com/pkg/errors"
 
@@ -21,18 +24,37 @@ const (
 	KeySuffixTLSCertPath             = "tls.cert.path"
 	KeySuffixTLSKeyPath              = "tls.key.path"
 
+	KeySuffixTLSClientCertificateEnabled         = "tls.client_certificate.enabled"
+	KeySuffixTLSClientCertificateForwardedHeader = "tls.client_certificate.forwarded_header"
+	KeySuffixTLSClientCertificateCAString        = "tls.client_certificate.ca.base64"
+	KeySuffixTLSClientCertificateCAPath          = "tls.client_certificate.ca.path"
+
 	KeyTLSAllowTerminationFrom = "serve." + KeySuffixTLSAllowTerminationFrom
 	KeyTLSCertString           = "serve." + KeySuffixTLSCertString
 	KeyTLSKeyString            = "serve." + KeySuffixTLSKeyString
 	KeyTLSCertPath             = "serve." + KeySuffixTLSCertPath
 	KeyTLSKeyPath              = "serve." + KeySuffixTLSKeyPath
 	KeyTLSEnabled              = "serve." + KeySuffixTLSEnabled
+
+	KeyTLSClientCertificateEnabled         = "serve." + KeySuffixTLSClientCertificateEnabled
+	KeyTLSClientCertificateForwardedHeader = "serve." + KeySuffixTLSClientCertificateForwardedHeader
+	KeyTLSClientCertificateCAString        = "serve." + KeySuffixTLSClientCertificateCAString
+	KeyTLSClientCertificateCAPath          = "serve." + KeySuffixTLSClientCertificateCAPath
 )
 
 type TLSConfig interface {
 	Enabled() bool
 	AllowTerminationFrom() []string
 	GetCertificateFunc(stopReload <-chan struct{}, _ *logrusx.Logger) (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error)
+
+	// ClientCertificateEnabled returns true if the TLS listener requests a certificate from the client.
+	ClientCertificateEnabled() bool
+	// ClientCertificateForwardedHeader returns the name of the header in which a TLS terminator forwards the
+	// client certificate, or an empty string if forwarded client certificates are not accepted.
+	ClientCertificateForwardedHeader() string
+	// ClientCertificateAuthorities returns the pool of CAs client certificates are verified against, or nil
+	// if none are configured.
+	ClientCertificateAuthorities() (*x509.CertPool, error)
 }
 
 var _ TLSConfig = (*tlsConfig)(nil)
@@ -45,6 +67,11 @@ type tlsConfig struct {
 	keyString  string
 	certPath   string
 	keyPath    string
+
+	clientCertificateEnabled         bool
+	clientCertificateForwardedHeader string
+	clientCertificateCAString        string
+	clientCertificateCAPath          string
 }
 
 func (c *tlsConfig) Enabled() bool {
@@ -55,6 +82,43 @@ func (c *tlsConfig) AllowTerminationFrom() []string {
 	return c.allowTerminationFrom
 }
 
+func (c *tlsConfig) ClientCertificateEnabled() bool {
+	return c.clientCertificateEnabled
+}
+
+func (c *tlsConfig) ClientCertificateForwardedHeader() string {
+	return c.clientCertificateForwardedHeader
+}
+
+func (c *tlsConfig) ClientCertificateAuthorities() (*x509.CertPool, error) {
+	var encoded []byte
+	switch {
+	case c.clientCertificateCAPath != "":
+		b, err := os.ReadFile(c.clientCertificateCAPath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		encoded = b
+	case c.clientCertificateCAString != "":
+		b, err := base64.StdEncoding.DecodeString(c.clientCertificateCAString)
+		if err != nil {
+			b, err = base64.RawStdEncoding.DecodeString(c.clientCertificateCAString)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		encoded = b
+	default:
+		return nil, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(encoded) {
+		return nil, errors.New("unable to load any client certificate authority from the configured PEM data")
+	}
+	return pool, nil
+}
+
 func (p *DefaultProvider) TLS(ctx context.Context, iface ServeInterface) TLSConfig {
 	return &tlsConfig{
 		enabled:              p.getProvider(ctx).BoolF(iface.Key(KeySuffixTLSEnabled), p.getProvider(ctx).Bool(KeyTLSEnabled)),
@@ -63,6 +127,11 @@ func (p *DefaultProvider) TLS(ctx context.Context, iface ServeInterface) TLSConf
 		keyString:            p.getProvider(ctx).StringF(iface.Key(KeySuffixTLSKeyString), p.getProvider(ctx).String(KeyTLSKeyString)),
 		certPath:             p.getProvider(ctx).StringF(iface.Key(KeySuffixTLSCertPath), p.getProvider(ctx).String(KeyTLSCertPath)),
 		keyPath:              p.getProvider(ctx).StringF(iface.Key(KeySuffixTLSKeyPath), p.getProvider(ctx).String(KeyTLSKeyPath)),
+
+		clientCertificateEnabled:         p.getProvider(ctx).BoolF(iface.Key(KeySuffixTLSClientCertificateEnabled), p.getProvider(ctx).Bool(KeyTLSClientCertificateEnabled)),
+		clientCertificateForwardedHeader: p.getProvider(ctx).StringF(iface.Key(KeySuffixTLSClientCertificateForwardedHeader), p.getProvider(ctx).String(KeyTLSClientCertificateForwardedHeader)),
+		clientCertificateCAString:        p.getProvider(ctx).StringF(iface.Key(KeySuffixTLSClientCertificateCAString), p.getProvider(ctx).String(KeyTLSClientCertificateCAString)),
+		clientCertificateCAPath:          p.getProvider(ctx).StringF(iface.Key(KeySuffixTLSClientCertificateCAPath), p.getProvider(ctx).String(KeyTLSClientCertificateCAPath)),
 	}
 }
 
@@ -35,6 +35,8 @@ import (
 	"github.com/ory/hydra/jwk"
 	"github.com/ory/hydra/oauth2"
 	"github.com/ory/hydra/x"
+	"github.com/ory/hydra/x/events"
+	"github.com/ory/hydra/x/oauth2cors"
 )
 
 type Registry interface {
@@ -54,8 +56,9 @@ type Registry interface {
 	x.RegistryLogger
 	x.RegistryWriter
 	x.RegistryCookieStore
-	client.Registry
+	client.InternalRegistry
 	consent.Registry
+	events.Registry
 	jwk.Registry
 	trust.Registry
 	oauth2.Registry
@@ -73,6 +76,12 @@ type Registry interface {
 	WithOAuth2Provider(f fosite.OAuth2Provider)
 	WithConsentStrategy(c consent.Strategy)
 	WithHsmContext(h hsm.Context)
+
+	// CORSPolicy returns the policy which decides on cross-origin requests to the public OAuth 2.0 endpoints from
+	// origins which are not allowed globally. WithCORSPolicy replaces the default policy, which uses the origins
+	// allowed by the OAuth 2.0 Clients.
+	CORSPolicy() oauth2cors.Policy
+	WithCORSPolicy(p oauth2cors.Policy)
 }
 
 func NewRegistryFromDSN(ctx context.Context, c *config.DefaultProvider, l *logrusx.Logger, skipNetworkInit bool, migrate bool, ctxer contextx.Contextualizer) (Registry, error) {
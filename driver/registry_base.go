@@ -30,6 +30,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ory/hydra/oauth2/trust"
+	"github.com/ory/hydra/x/events"
 	"github.com/ory/hydra/x/oauth2cors"
 	"github.com/ory/x/contextx"
 
@@ -75,6 +76,7 @@ type RegistryBase struct {
 	migrationStatus *popx.MigrationStatuses
 	kc              *jwk.AEAD
 	cos             consent.Strategy
+	bld             *consent.BackChannelLogoutDeliverer
 	writer          herodot.Writer
 	fsc             fosite.ScopeStrategy
 	atjs            jwk.JWTSigner
@@ -102,8 +104,12 @@ type RegistryBase struct {
 	oidcs           jwk.JWTSigner
 	ats             jwk.JWTSigner
 	hmacs           *foauth2.HMACSHAStrategy
+	dcs             oauth2.DeviceCodeStrategy
 	fc              *fositex.Config
 	publicCORS      *cors.Cors
+	corsPolicy      oauth2cors.Policy
+	evs             jwk.JWTSigner
+	evh             *events.Hook
 }
 
 func (m *RegistryBase) GetJWKSFetcherStrategy() fosite.JWKSFetcherStrategy {
@@ -146,6 +152,23 @@ func (m *RegistryBase) OAuth2AwareMiddleware(ctx context.Context) func(h http.Ha
 	return m.oa2mw
 }
 
+func (m *RegistryBase) CORSPolicy() oauth2cors.Policy {
+	if m.corsPolicy == nil {
+		m.corsPolicy = oauth2cors.NewClientPolicy(m.r)
+	}
+	return m.corsPolicy
+}
+
+func (m *RegistryBase) WithCORSPolicy(p oauth2cors.Policy) {
+	m.corsPolicy = p
+}
+
+func (m *RegistryBase) PurgeClientCaches(ctx context.Context) {
+	if p, ok := m.CORSPolicy().(*oauth2cors.ClientPolicy); ok {
+		p.Purge(ctx)
+	}
+}
+
 func (m *RegistryBase) addPublicCORSOnHandler(ctx context.Context) func(http.Handler) http.Handler {
 	corsConfig, corsEnabled := m.Config().CORS(ctx, config.PublicInterface)
 	if !corsEnabled {
@@ -302,6 +325,13 @@ func (m *RegistryBase) ConsentStrategy() consent.Strategy {
 	return m.cos
 }
 
+func (m *RegistryBase) BackChannelLogoutDeliverer() *consent.BackChannelLogoutDeliverer {
+	if m.bld == nil {
+		m.bld = consent.NewBackChannelLogoutDeliverer(m.r, m.Config())
+	}
+	return m.bld
+}
+
 func (m *RegistryBase) KeyCipher() *jwk.AEAD {
 	if m.kc == nil {
 		m.kc = jwk.NewAEAD(m.Config())
@@ -339,10 +369,12 @@ func (m *RegistryBase) CookieStore(ctx context.Context) sessions.Store {
 }
 
 func (m *RegistryBase) HTTPClient(ctx context.Context, opts ...httpx.ResilientOptions) *retryablehttp.Client {
-	opts = append(opts,
+	// The defaults come first so that they can be overridden by the caller.
+	opts = append([]httpx.ResilientOptions{
 		httpx.ResilientClientWithLogger(m.Logger()),
 		httpx.ResilientClientWithMaxRetry(2),
-		httpx.ResilientClientWithConnectionTimeout(30*time.Second))
+		httpx.ResilientClientWithConnectionTimeout(30 * time.Second),
+	}, opts...)
 
 	tracer := m.Tracer(ctx)
 	if tracer.IsLoaded() {
@@ -360,7 +392,7 @@ func (m *RegistryBase) OAuth2Provider() fosite.OAuth2Provider {
 		return m.fop
 	}
 
-	m.fop = fosite.NewOAuth2Provider(m.r.OAuth2Storage(), m.OAuth2ProviderConfig())
+	m.fop = fosite.NewOAuth2Provider(oauth2.NewPushedAuthorizeStorage(m.r.OAuth2Storage()), m.OAuth2ProviderConfig())
 	return m.fop
 }
 
@@ -382,6 +414,22 @@ func (m *RegistryBase) AccessTokenJWTStrategy() jwk.JWTSigner {
 	return m.ats
 }
 
+func (m *RegistryBase) EventsJWTStrategy() jwk.JWTSigner {
+	if m.evs != nil {
+		return m.evs
+	}
+
+	m.evs = jwk.NewDefaultJWTSigner(m.Config(), m.r, x.EventsKeyName)
+	return m.evs
+}
+
+func (m *RegistryBase) EventHook() *events.Hook {
+	if m.evh == nil {
+		m.evh = events.NewHook(m.r)
+	}
+	return m.evh
+}
+
 func (m *RegistryBase) OAuth2HMACStrategy() *foauth2.HMACSHAStrategy {
 	if m.hmacs != nil {
 		return m.hmacs
@@ -391,6 +439,15 @@ func (m *RegistryBase) OAuth2HMACStrategy() *foauth2.HMACSHAStrategy {
 	return m.hmacs
 }
 
+func (m *RegistryBase) DeviceCodeStrategy() oauth2.DeviceCodeStrategy {
+	if m.dcs != nil {
+		return m.dcs
+	}
+
+	m.dcs = oauth2.NewDeviceCodeHMACStrategy(m.OAuth2Config())
+	return m.dcs
+}
+
 func (m *RegistryBase) OAuth2Config() *fositex.Config {
 	if m.fc != nil {
 		return m.fc
@@ -15,6 +15,7 @@ import (
 
 	"github.com/gobuffalo/pop/v6"
 
+	"github.com/ory/hydra/oauth2"
 	"github.com/ory/hydra/oauth2/trust"
 	"github.com/ory/x/errorsx"
 	"github.com/ory/x/networkx"
@@ -208,6 +209,10 @@ func (m *RegistrySQL) OAuth2Storage() x.FositeStorer {
 	return m.Persister()
 }
 
+func (m *RegistrySQL) DeviceCodeStorage() oauth2.DeviceCodeStorage {
+	return m.Persister()
+}
+
 func (m *RegistrySQL) KeyManager() jwk.Manager {
 	return m.defaultKeyManager
 }
@@ -96,6 +96,9 @@ type Flow struct {
 	// required: true
 	RequestedAudience sqlxx.StringSliceJSONFormat `db:"requested_at_audience"`
 
+	// RequestedAuthorizationDetails contains the authorization details as requested by the OAuth 2.0 Client.
+	RequestedAuthorizationDetails consent.AuthorizationDetails `db:"requested_authorization_details" faker:"-"`
+
 	// LoginSkip, if true, implies that the client has requested the same scopes from the same user previously.
 	// If true, you can skip asking the user to grant the requested scopes, and simply forward the user to the redirect URL.
 	//
@@ -213,6 +216,9 @@ type Flow struct {
 	// GrantedAudience sets the audience the user authorized the client to use. Should be a subset of `requested_access_token_audience`.
 	GrantedAudience sqlxx.StringSliceJSONFormat `db:"granted_at_audience"`
 
+	// GrantedAuthorizationDetails sets the authorization details the user authorized the client to use.
+	GrantedAuthorizationDetails consent.AuthorizationDetails `db:"granted_authorization_details" faker:"-"`
+
 	// ConsentRemember, if set to true, tells ORY Hydra to remember this consent authorization and reuse it if the same
 	// client asks the same user for the same, or a subset of, scope.
 	ConsentRemember bool `db:"consent_remember"`
@@ -235,23 +241,24 @@ type Flow struct {
 
 func NewFlow(r *consent.LoginRequest) *Flow {
 	return &Flow{
-		ID:                     r.ID,
-		RequestedScope:         r.RequestedScope,
-		RequestedAudience:      r.RequestedAudience,
-		LoginSkip:              r.Skip,
-		Subject:                r.Subject,
-		OpenIDConnectContext:   r.OpenIDConnectContext,
-		Client:                 r.Client,
-		ClientID:               r.ClientID,
-		RequestURL:             r.RequestURL,
-		SessionID:              r.SessionID,
-		LoginWasUsed:           r.WasHandled,
-		ForceSubjectIdentifier: r.ForceSubjectIdentifier,
-		LoginVerifier:          r.Verifier,
-		LoginCSRF:              r.CSRF,
-		LoginAuthenticatedAt:   r.AuthenticatedAt,
-		RequestedAt:            r.RequestedAt,
-		State:                  FlowStateLoginInitialized,
+		ID:                            r.ID,
+		RequestedScope:                r.RequestedScope,
+		RequestedAudience:             r.RequestedAudience,
+		RequestedAuthorizationDetails: r.RequestedAuthorizationDetails,
+		LoginSkip:                     r.Skip,
+		Subject:                       r.Subject,
+		OpenIDConnectContext:          r.OpenIDConnectContext,
+		Client:                        r.Client,
+		ClientID:                      r.ClientID,
+		RequestURL:                    r.RequestURL,
+		SessionID:                     r.SessionID,
+		LoginWasUsed:                  r.WasHandled,
+		ForceSubjectIdentifier:        r.ForceSubjectIdentifier,
+		LoginVerifier:                 r.Verifier,
+		LoginCSRF:                     r.CSRF,
+		LoginAuthenticatedAt:          r.AuthenticatedAt,
+		RequestedAt:                   r.RequestedAt,
+		State:                         FlowStateLoginInitialized,
 	}
 }
 
@@ -316,22 +323,23 @@ func (f *Flow) GetHandledLoginRequest() consent.HandledLoginRequest {
 
 func (f *Flow) GetLoginRequest() *consent.LoginRequest {
 	return &consent.LoginRequest{
-		ID:                     f.ID,
-		RequestedScope:         f.RequestedScope,
-		RequestedAudience:      f.RequestedAudience,
-		Skip:                   f.LoginSkip,
-		Subject:                f.Subject,
-		OpenIDConnectContext:   f.OpenIDConnectContext,
-		Client:                 f.Client,
-		ClientID:               f.ClientID,
-		RequestURL:             f.RequestURL,
-		SessionID:              f.SessionID,
-		WasHandled:             f.LoginWasUsed,
-		ForceSubjectIdentifier: f.ForceSubjectIdentifier,
-		Verifier:               f.LoginVerifier,
-		CSRF:                   f.LoginCSRF,
-		AuthenticatedAt:        f.LoginAuthenticatedAt,
-		RequestedAt:            f.RequestedAt,
+		ID:                            f.ID,
+		RequestedScope:                f.RequestedScope,
+		RequestedAudience:             f.RequestedAudience,
+		RequestedAuthorizationDetails: f.RequestedAuthorizationDetails,
+		Skip:                          f.LoginSkip,
+		Subject:                       f.Subject,
+		OpenIDConnectContext:          f.OpenIDConnectContext,
+		Client:                        f.Client,
+		ClientID:                      f.ClientID,
+		RequestURL:                    f.RequestURL,
+		SessionID:                     f.SessionID,
+		WasHandled:                    f.LoginWasUsed,
+		ForceSubjectIdentifier:        f.ForceSubjectIdentifier,
+		Verifier:                      f.LoginVerifier,
+		CSRF:                          f.LoginCSRF,
+		AuthenticatedAt:               f.LoginAuthenticatedAt,
+		RequestedAt:                   f.RequestedAt,
 	}
 }
 
@@ -376,6 +384,7 @@ func (f *Flow) HandleConsentRequest(r *consent.AcceptOAuth2ConsentRequest) error
 
 	f.GrantedScope = r.GrantedScope
 	f.GrantedAudience = r.GrantedAudience
+	f.GrantedAuthorizationDetails = r.GrantedAuthorizationDetails
 	f.ConsentRemember = r.Remember
 	f.ConsentRememberFor = &r.RememberFor
 	f.ConsentHandledAt = r.HandledAt
@@ -404,26 +413,27 @@ func (f *Flow) InvalidateConsentRequest() error {
 
 func (f *Flow) GetConsentRequest() *consent.OAuth2ConsentRequest {
 	return &consent.OAuth2ConsentRequest{
-		ID:                     f.ConsentChallengeID.String(),
-		RequestedScope:         f.RequestedScope,
-		RequestedAudience:      f.RequestedAudience,
-		Skip:                   f.ConsentSkip,
-		Subject:                f.Subject,
-		OpenIDConnectContext:   f.OpenIDConnectContext,
-		Client:                 f.Client,
-		ClientID:               f.ClientID,
-		RequestURL:             f.RequestURL,
-		LoginChallenge:         sqlxx.NullString(f.ID),
-		LoginSessionID:         f.SessionID,
-		ACR:                    f.ACR,
-		AMR:                    f.AMR,
-		Context:                f.Context,
-		WasHandled:             f.ConsentWasHandled,
-		ForceSubjectIdentifier: f.ForceSubjectIdentifier,
-		Verifier:               f.ConsentVerifier.String(),
-		CSRF:                   f.ConsentCSRF.String(),
-		AuthenticatedAt:        f.LoginAuthenticatedAt,
-		RequestedAt:            f.RequestedAt,
+		ID:                            f.ConsentChallengeID.String(),
+		RequestedScope:                f.RequestedScope,
+		RequestedAudience:             f.RequestedAudience,
+		RequestedAuthorizationDetails: f.RequestedAuthorizationDetails,
+		Skip:                          f.ConsentSkip,
+		Subject:                       f.Subject,
+		OpenIDConnectContext:          f.OpenIDConnectContext,
+		Client:                        f.Client,
+		ClientID:                      f.ClientID,
+		RequestURL:                    f.RequestURL,
+		LoginChallenge:                sqlxx.NullString(f.ID),
+		LoginSessionID:                f.SessionID,
+		ACR:                           f.ACR,
+		AMR:                           f.AMR,
+		Context:                       f.Context,
+		WasHandled:                    f.ConsentWasHandled,
+		ForceSubjectIdentifier:        f.ForceSubjectIdentifier,
+		Verifier:                      f.ConsentVerifier.String(),
+		CSRF:                          f.ConsentCSRF.String(),
+		AuthenticatedAt:               f.LoginAuthenticatedAt,
+		RequestedAt:                   f.RequestedAt,
 	}
 }
 
@@ -433,20 +443,21 @@ func (f *Flow) GetHandledConsentRequest() *consent.AcceptOAuth2ConsentRequest {
 		crf = *f.ConsentRememberFor
 	}
 	return &consent.AcceptOAuth2ConsentRequest{
-		ID:                 f.ConsentChallengeID.String(),
-		GrantedScope:       f.GrantedScope,
-		GrantedAudience:    f.GrantedAudience,
-		Session:            &consent.AcceptOAuth2ConsentRequestSession{AccessToken: f.SessionAccessToken, IDToken: f.SessionIDToken},
-		Remember:           f.ConsentRemember,
-		RememberFor:        crf,
-		HandledAt:          f.ConsentHandledAt,
-		WasHandled:         f.ConsentWasHandled,
-		ConsentRequest:     f.GetConsentRequest(),
-		Error:              f.ConsentError,
-		RequestedAt:        f.RequestedAt,
-		AuthenticatedAt:    f.LoginAuthenticatedAt,
-		SessionIDToken:     f.SessionIDToken,
-		SessionAccessToken: f.SessionAccessToken,
+		ID:                          f.ConsentChallengeID.String(),
+		GrantedScope:                f.GrantedScope,
+		GrantedAudience:             f.GrantedAudience,
+		GrantedAuthorizationDetails: f.GrantedAuthorizationDetails,
+		Session:                     &consent.AcceptOAuth2ConsentRequestSession{AccessToken: f.SessionAccessToken, IDToken: f.SessionIDToken},
+		Remember:                    f.ConsentRemember,
+		RememberFor:                 crf,
+		HandledAt:                   f.ConsentHandledAt,
+		WasHandled:                  f.ConsentWasHandled,
+		ConsentRequest:              f.GetConsentRequest(),
+		Error:                       f.ConsentError,
+		RequestedAt:                 f.RequestedAt,
+		AuthenticatedAt:             f.LoginAuthenticatedAt,
+		SessionIDToken:              f.SessionIDToken,
+		SessionAccessToken:          f.SessionAccessToken,
 	}
 }
 
@@ -454,6 +465,12 @@ func (_ Flow) TableName() string {
 	return "hydra_oauth2_flow"
 }
 
+// PageToken returns the token of the page which starts after this flow, as flows are listed from the most
+// recently requested one.
+func (f Flow) PageToken() string {
+	return x.EncodePageToken(f.RequestedAt.UTC().Format(time.RFC3339Nano), f.ID)
+}
+
 func (f *Flow) BeforeSave(_ *pop.Connection) error {
 	if f.Client != nil {
 		f.ClientID = f.Client.GetID()
@@ -21,6 +21,7 @@ func (f *Flow) setLoginRequest(r *consent.LoginRequest) {
 	f.ID = r.ID
 	f.RequestedScope = r.RequestedScope
 	f.RequestedAudience = r.RequestedAudience
+	f.RequestedAuthorizationDetails = r.RequestedAuthorizationDetails
 	f.LoginSkip = r.Skip
 	f.Subject = r.Subject
 	f.OpenIDConnectContext = r.OpenIDConnectContext
@@ -55,6 +56,7 @@ func (f *Flow) setConsentRequest(r consent.OAuth2ConsentRequest) {
 	f.ConsentChallengeID = sqlxx.NullString(r.ID)
 	f.RequestedScope = r.RequestedScope
 	f.RequestedAudience = r.RequestedAudience
+	f.RequestedAuthorizationDetails = r.RequestedAuthorizationDetails
 	f.ConsentSkip = r.Skip
 	f.Subject = r.Subject
 	f.OpenIDConnectContext = r.OpenIDConnectContext
@@ -78,6 +80,7 @@ func (f *Flow) setHandledConsentRequest(r consent.AcceptOAuth2ConsentRequest) {
 	f.ConsentChallengeID = sqlxx.NullString(r.ID)
 	f.GrantedScope = r.GrantedScope
 	f.GrantedAudience = r.GrantedAudience
+	f.GrantedAuthorizationDetails = r.GrantedAuthorizationDetails
 	f.ConsentRemember = r.Remember
 	f.ConsentRememberFor = &r.RememberFor
 	f.ConsentHandledAt = r.HandledAt
@@ -17,6 +17,7 @@ import (
 	"github.com/ory/fosite/i18n"
 	"github.com/ory/fosite/token/jwt"
 	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/jwk"
 	"github.com/ory/hydra/oauth2"
 	"github.com/ory/hydra/persistence"
 	"github.com/ory/hydra/x"
@@ -27,8 +28,10 @@ type configDependencies interface {
 	config.Provider
 	persistence.Provider
 	x.HTTPClientProvider
+	x.RegistryLogger
 	GetJWKSFetcherStrategy() fosite.JWKSFetcherStrategy
 	ClientHasher() fosite.Hasher
+	OpenIDJWTStrategy() jwk.JWTSigner
 }
 
 type factory func(config fosite.Configurator, storage interface{}, strategy interface{}) interface{}
@@ -40,11 +43,11 @@ type Config struct {
 	tokenEndpointHandlers      fosite.TokenEndpointHandlers
 	tokenIntrospectionHandlers fosite.TokenIntrospectionHandlers
 	revocationHandlers         fosite.RevocationHandlers
+	pushedAuthorizeHandlers    fosite.PushedAuthorizeEndpointHandlers
 
 	*config.DefaultProvider
 }
 
-var defaultResponseModeHandler = fosite.NewDefaultResponseModeHandler()
 var defaultFactories = []factory{
 	compose.OAuth2AuthorizeExplicitFactory,
 	compose.OAuth2AuthorizeImplicitFactory,
@@ -58,6 +61,11 @@ var defaultFactories = []factory{
 	compose.OAuth2TokenIntrospectionFactory,
 	compose.OAuth2PKCEFactory,
 	compose.RFC7523AssertionGrantFactory,
+	compose.PushedAuthorizeHandlerFactory,
+	oauth2.DeviceCodeGrantFactory,
+	oauth2.TokenExchangeGrantFactory,
+	oauth2.CIBAGrantFactory,
+	oauth2.PasswordGrantFactory,
 }
 
 func NewConfig(deps configDependencies) *Config {
@@ -83,6 +91,9 @@ func (c *Config) LoadDefaultHanlders(strategy interface{}) {
 		if rh, ok := res.(fosite.RevocationHandler); ok {
 			c.revocationHandlers.Append(rh)
 		}
+		if ph, ok := res.(fosite.PushedAuthorizeEndpointHandler); ok {
+			c.pushedAuthorizeHandlers.Append(ph)
+		}
 	}
 }
 
@@ -110,6 +121,10 @@ func (c *Config) GetRevocationHandlers(ctx context.Context) fosite.RevocationHan
 	return c.revocationHandlers
 }
 
+func (c *Config) GetPushedAuthorizeEndpointHandlers(ctx context.Context) fosite.PushedAuthorizeEndpointHandlers {
+	return c.pushedAuthorizeHandlers
+}
+
 func (c *Config) GetGrantTypeJWTBearerCanSkipClientAuth(ctx context.Context) bool {
 	return false
 }
@@ -143,12 +158,15 @@ func (c *Config) GetMinParameterEntropy(_ context.Context) int {
 }
 
 func (c *Config) GetClientAuthenticationStrategy(ctx context.Context) fosite.ClientAuthenticationStrategy {
-	// Fosite falls back to the default fosite.Fosite.DefaultClientAuthenticationStrategy when this is nil.
-	return nil
+	// Clients not using mutual TLS are authenticated by fosite's default strategy.
+	fallback := &fosite.Fosite{Store: c.deps.Persister(), Config: c}
+	return oauth2.NewClientSecretStrategy(c.deps.AuditLogger(),
+		oauth2.NewMutualTLSClientAuthenticationStrategy(c.deps.Config(), c, c.deps.Persister(), fallback.DefaultClientAuthenticationStrategy))
 }
 
 func (c *Config) GetResponseModeHandlerExtension(ctx context.Context) fosite.ResponseModeHandler {
-	return defaultResponseModeHandler
+	// fosite handles the query, fragment, and form_post response modes itself.
+	return oauth2.NewJWTResponseModeHandler(c, c.deps.OpenIDJWTStrategy())
 }
 
 func (c *Config) GetSendDebugMessagesToClients(ctx context.Context) bool {
@@ -0,0 +1,96 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"net/http"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
+)
+
+// ErrInvalidAuthorizationDetails is returned if the `authorization_details` parameter is malformed or
+// asks for more than the client may request, see https://www.rfc-editor.org/rfc/rfc9396#section-5.
+var ErrInvalidAuthorizationDetails = &fosite.RFC6749Error{
+	ErrorField:       "invalid_authorization_details",
+	DescriptionField: "The authorization details are invalid, malformed, or exceed what the OAuth 2.0 Client is allowed to request.",
+	CodeField:        http.StatusBadRequest,
+}
+
+// requestedAuthorizationDetails parses the `authorization_details` parameter of an authorization, pushed
+// authorization, or token request. Only the authorization details types allowed by the client's
+// `authorization_details_types` can be requested.
+func requestedAuthorizationDetails(requester fosite.Requester) (consent.AuthorizationDetails, error) {
+	details, err := consent.ParseAuthorizationDetails(requester.GetRequestForm().Get("authorization_details"))
+	if err != nil {
+		return nil, errorsx.WithStack(ErrInvalidAuthorizationDetails.WithHint("Unable to parse the authorization details.").WithDebug(err.Error()))
+	}
+
+	var allowed []string
+	if c, ok := requester.GetClient().(*client.Client); ok {
+		allowed = c.AuthorizationDetailsTypes
+	}
+
+	for _, t := range details.Types() {
+		if !stringslice.Has(allowed, t) {
+			return nil, errorsx.WithStack(ErrInvalidAuthorizationDetails.WithHintf("The OAuth 2.0 Client is not allowed to request authorization details of type '%s'.", t))
+		}
+	}
+	return details, nil
+}
+
+// grantAuthorizationDetails resolves the authorization details of a token request. The client credentials grant
+// is granted what it requests within the types the client is allowed to request, because the client acts on its
+// own behalf. The password grant webhook decides which authorization details the password grant is granted. The
+// JWT Bearer grant can not request authorization details because no end-user authorizes them. Tokens issued from
+// a consent carry the authorization details of that consent, which the refresh token grant can narrow down to the
+// requested types.
+//
+// The authorization code grant can not narrow the authorization details because fosite reloads the session of the
+// authorization code right before issuing the tokens.
+func grantAuthorizationDetails(request fosite.AccessRequester, session *Session) error {
+	grantTypes := request.GetGrantTypes()
+	if grantTypes.ExactOne(string(fosite.GrantTypePassword)) {
+		// The authorization details were granted by the password grant webhook.
+		return nil
+	}
+
+	requested, err := requestedAuthorizationDetails(request)
+	if err != nil {
+		return err
+	}
+
+	if grantTypes.ExactOne("client_credentials") {
+		session.AuthorizationDetails = requested
+		return nil
+	}
+
+	if len(requested) == 0 {
+		return nil
+	} else if grantTypes.ExactOne("urn:ietf:params:oauth:grant-type:jwt-bearer") {
+		return errorsx.WithStack(ErrInvalidAuthorizationDetails.WithHint("Authorization details can not be requested using the JWT Bearer grant because they can not be authorized by the end-user."))
+	} else if !grantTypes.ExactOne("refresh_token") {
+		return errorsx.WithStack(ErrInvalidAuthorizationDetails.WithHint("The authorization details can only be narrowed down using the refresh token grant."))
+	}
+
+	if !session.AuthorizationDetails.HasTypes(requested.Types()...) {
+		return errorsx.WithStack(ErrInvalidAuthorizationDetails.WithHint("The requested authorization details exceed the authorization details which were granted."))
+	}
+
+	var narrowed consent.AuthorizationDetails
+	for _, detail := range session.AuthorizationDetails {
+		for _, t := range requested.Types() {
+			if detail.Type() == t {
+				narrowed = append(narrowed, detail)
+				break
+			}
+		}
+	}
+	session.AuthorizationDetails = narrowed
+	return nil
+}
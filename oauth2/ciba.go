@@ -0,0 +1,26 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/hydra/consent"
+)
+
+const (
+	// GrantTypeCIBA is the grant type of OpenID Connect Client-Initiated Backchannel Authentication.
+	GrantTypeCIBA = "urn:openid:params:grant-type:ciba"
+
+	// ClaimAuthReqID is the ID token claim containing the auth_req_id the ID token was issued for.
+	ClaimAuthReqID = "urn:openid:params:jwt:claim:auth_req_id"
+)
+
+// BackchannelAuthenticationStorage is the part of the consent.Manager used by the CIBA grant.
+type BackchannelAuthenticationStorage interface {
+	GetBackchannelAuthenticationRequestByAuthReqIDSignature(ctx context.Context, signature string) (*consent.BackchannelAuthenticationRequest, error)
+	TouchBackchannelAuthenticationRequest(ctx context.Context, challenge string, polledAt time.Time) error
+	InvalidateBackchannelAuthenticationRequest(ctx context.Context, challenge string) error
+}
@@ -0,0 +1,252 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/storage"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/hydra/consent"
+)
+
+type cibaGrantConfig interface {
+	fosite.AccessTokenLifespanProvider
+	fosite.RefreshTokenLifespanProvider
+	fosite.IDTokenLifespanProvider
+	fosite.RefreshTokenScopesProvider
+	fosite.GlobalSecretProvider
+	BackchannelAuthenticationPollingInterval(ctx context.Context) time.Duration
+}
+
+var _ fosite.TokenEndpointHandler = (*CIBAGrantHandler)(nil)
+
+// CIBAGrantHandler handles the token endpoint part of OpenID Connect Client-Initiated Backchannel Authentication
+// for the poll and ping delivery modes.
+//
+// The session of the request is populated by the token endpoint from the handled backchannel authentication
+// request, because building the ID token claims requires the consent strategy.
+type CIBAGrantHandler struct {
+	Storage              BackchannelAuthenticationStorage
+	CoreStorage          foauth2.CoreStorage
+	AccessTokenStrategy  foauth2.AccessTokenStrategy
+	RefreshTokenStrategy foauth2.RefreshTokenStrategy
+	*openid.IDTokenHandleHelper
+	Config cibaGrantConfig
+}
+
+// CIBAGrantFactory creates a CIBAGrantHandler. It follows the signature of the factories in
+// github.com/ory/fosite/compose.
+func CIBAGrantFactory(config fosite.Configurator, storage interface{}, strategy interface{}) interface{} {
+	return &CIBAGrantHandler{
+		Storage:              storage.(BackchannelAuthenticationStorage),
+		CoreStorage:          storage.(foauth2.CoreStorage),
+		AccessTokenStrategy:  strategy.(foauth2.AccessTokenStrategy),
+		RefreshTokenStrategy: strategy.(foauth2.RefreshTokenStrategy),
+		IDTokenHandleHelper: &openid.IDTokenHandleHelper{
+			IDTokenStrategy: strategy.(openid.OpenIDConnectTokenStrategy),
+		},
+		Config: config.(cibaGrantConfig),
+	}
+}
+
+func (c *CIBAGrantHandler) HandleTokenEndpointRequest(ctx context.Context, request fosite.AccessRequester) error {
+	if !c.CanHandleTokenEndpointRequest(ctx, request) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	if !request.GetClient().GetGrantTypes().Has(GrantTypeCIBA) {
+		return errorsx.WithStack(fosite.ErrUnauthorizedClient.WithHintf("The OAuth 2.0 Client is not allowed to use authorization grant \"%s\".", GrantTypeCIBA))
+	}
+
+	br, err := c.getBackchannelAuthenticationRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if br.ExpiresAt.Before(now) {
+		return errorsx.WithStack(ErrExpiredToken.WithHint("The auth_req_id has expired."))
+	}
+
+	switch br.State {
+	case consent.BackchannelAuthenticationStateApproved:
+		// continue below
+	case consent.BackchannelAuthenticationStateDenied:
+		return errorsx.WithStack(fosite.ErrAccessDenied.WithHint("The end-user denied the backchannel authentication request."))
+	case consent.BackchannelAuthenticationStateUsed:
+		return errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The auth_req_id has already been used."))
+	default:
+		lastPolledAt := time.Time(br.LastPolledAt)
+		tooFast := !lastPolledAt.IsZero() && now.Sub(lastPolledAt) < c.Config.BackchannelAuthenticationPollingInterval(ctx)
+		if err := c.Storage.TouchBackchannelAuthenticationRequest(ctx, br.ID, now); err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		}
+
+		if tooFast {
+			return errorsx.WithStack(ErrSlowDown)
+		}
+		return errorsx.WithStack(ErrAuthorizationPending)
+	}
+
+	request.SetRequestedScopes(fosite.Arguments(br.RequestedScope))
+	request.SetRequestedAudience(fosite.Arguments(br.RequestedAudience))
+	request.SetID(br.ID)
+
+	atLifespan := fosite.GetEffectiveLifespan(request.GetClient(), GrantTypeCIBA, fosite.AccessToken, c.Config.GetAccessTokenLifespan(ctx))
+	request.GetSession().SetExpiresAt(fosite.AccessToken, now.Add(atLifespan).Round(time.Second))
+
+	rtLifespan := fosite.GetEffectiveLifespan(request.GetClient(), GrantTypeCIBA, fosite.RefreshToken, c.Config.GetRefreshTokenLifespan(ctx))
+	if rtLifespan > -1 {
+		request.GetSession().SetExpiresAt(fosite.RefreshToken, now.Add(rtLifespan).Round(time.Second))
+	}
+
+	return nil
+}
+
+func (c *CIBAGrantHandler) PopulateTokenEndpointResponse(ctx context.Context, requester fosite.AccessRequester, responder fosite.AccessResponder) (err error) {
+	if !c.CanHandleTokenEndpointRequest(ctx, requester) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	br, err := c.getBackchannelAuthenticationRequest(ctx, requester)
+	if err != nil {
+		return err
+	}
+
+	for _, scope := range br.GrantedScope {
+		requester.GrantScope(scope)
+	}
+
+	for _, audience := range br.GrantedAudience {
+		requester.GrantAudience(audience)
+	}
+
+	access, accessSignature, err := c.AccessTokenStrategy.GenerateAccessToken(ctx, requester)
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	var refresh, refreshSignature string
+	if c.canIssueRefreshToken(ctx, requester) {
+		refresh, refreshSignature, err = c.RefreshTokenStrategy.GenerateRefreshToken(ctx, requester)
+		if err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		}
+	}
+
+	ctx, err = storage.MaybeBeginTx(ctx, c.CoreStorage)
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+	defer func() {
+		if err != nil {
+			if rollBackTxnErr := storage.MaybeRollbackTx(ctx, c.CoreStorage); rollBackTxnErr != nil {
+				err = errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebugf("error: %s; rollback error: %s", err, rollBackTxnErr))
+			}
+		}
+	}()
+
+	if err = c.Storage.InvalidateBackchannelAuthenticationRequest(ctx, br.ID); errors.Is(err, sqlcon.ErrNoRows) {
+		return errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The auth_req_id has already been used."))
+	} else if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	} else if err = c.CoreStorage.CreateAccessTokenSession(ctx, accessSignature, requester.Sanitize([]string{})); err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	} else if refreshSignature != "" {
+		if err = c.CoreStorage.CreateRefreshTokenSession(ctx, refreshSignature, requester.Sanitize([]string{})); err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		}
+	}
+
+	responder.SetAccessToken(access)
+	responder.SetTokenType("bearer")
+	atLifespan := fosite.GetEffectiveLifespan(requester.GetClient(), GrantTypeCIBA, fosite.AccessToken, c.Config.GetAccessTokenLifespan(ctx))
+	responder.SetExpiresIn(expiresIn(requester, fosite.AccessToken, atLifespan, time.Now().UTC()))
+	responder.SetScopes(requester.GetGrantedScopes())
+	if refresh != "" {
+		responder.SetExtra("refresh_token", refresh)
+	}
+
+	// Backchannel authentication requests always include the openid scope, but the end-user may not have granted it.
+	if requester.GetGrantedScopes().Has("openid") {
+		sess, ok := requester.GetSession().(openid.Session)
+		if !ok {
+			err = errorsx.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because session must be of type fosite/handler/openid.Session."))
+			return err
+		}
+
+		claims := sess.IDTokenClaims()
+		if claims.Subject == "" {
+			err = errorsx.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because subject is an empty string."))
+			return err
+		}
+		claims.AccessTokenHash = c.GetAccessTokenHash(ctx, requester, responder)
+
+		idTokenLifespan := fosite.GetEffectiveLifespan(requester.GetClient(), GrantTypeCIBA, fosite.IDToken, c.Config.GetIDTokenLifespan(ctx))
+		if err = c.IssueExplicitIDToken(ctx, idTokenLifespan, requester, responder); err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		}
+	}
+
+	if err = storage.MaybeCommitTx(ctx, c.CoreStorage); err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	return nil
+}
+
+func (c *CIBAGrantHandler) getBackchannelAuthenticationRequest(ctx context.Context, requester fosite.AccessRequester) (*consent.BackchannelAuthenticationRequest, error) {
+	br, err := getBackchannelAuthenticationRequest(ctx, c.Storage, c.Config.GetGlobalSecret(ctx), requester)
+	if err != nil {
+		return nil, err
+	}
+
+	// The auth_req_id must have been issued to the client which is authenticated in this request.
+	if br.ClientID != requester.GetClient().GetID() {
+		return nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The OAuth 2.0 Client ID from this request does not match the one from the backchannel authentication request."))
+	}
+
+	return br, nil
+}
+
+func getBackchannelAuthenticationRequest(ctx context.Context, s BackchannelAuthenticationStorage, secret []byte, requester fosite.AccessRequester) (*consent.BackchannelAuthenticationRequest, error) {
+	authReqID := requester.GetRequestForm().Get("auth_req_id")
+	if authReqID == "" {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The \"auth_req_id\" parameter is missing."))
+	}
+
+	br, err := s.GetBackchannelAuthenticationRequestByAuthReqIDSignature(ctx, consent.AuthReqIDSignature(secret, authReqID))
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The auth_req_id is unknown.").WithWrap(err).WithDebug(err.Error()))
+	} else if err != nil {
+		return nil, errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	return br, nil
+}
+
+func (c *CIBAGrantHandler) canIssueRefreshToken(ctx context.Context, requester fosite.Requester) bool {
+	scope := c.Config.GetRefreshTokenScopes(ctx)
+	if len(scope) > 0 && !requester.GetGrantedScopes().HasOneOf(scope...) {
+		return false
+	}
+	return requester.GetClient().GetGrantTypes().Has("refresh_token")
+}
+
+func (c *CIBAGrantHandler) CanSkipClientAuth(ctx context.Context, requester fosite.AccessRequester) bool {
+	return false
+}
+
+func (c *CIBAGrantHandler) CanHandleTokenEndpointRequest(ctx context.Context, requester fosite.AccessRequester) bool {
+	return requester.GetGrantTypes().ExactOne(GrantTypeCIBA)
+}
@@ -0,0 +1,49 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/logrusx"
+
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/x"
+)
+
+// NewClientSecretStrategy wraps a client authentication strategy to reject clients which authenticated with
+// their expired client secret. It writes an audit log entry whenever a client authenticated with a rotated
+// secret which is still within its grace period.
+func NewClientSecretStrategy(l *logrusx.Logger, next fosite.ClientAuthenticationStrategy) fosite.ClientAuthenticationStrategy {
+	return func(ctx context.Context, r *http.Request, form url.Values) (fosite.Client, error) {
+		ctx, matched := x.WithMatchedHashRecorder(ctx)
+		c, err := next(ctx, r, form)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := matched()
+		if len(hash) == 0 {
+			return c, nil
+		}
+
+		if string(hash) == string(c.GetHashedSecret()) {
+			if cl, ok := c.(*client.Client); ok && cl.IsSecretExpired(time.Now()) {
+				return nil, errorsx.WithStack(fosite.ErrInvalidClient.WithHint("The OAuth 2.0 Client secret has expired."))
+			}
+			return c, nil
+		}
+
+		l.WithRequest(r).
+			WithField("client_id", c.GetID()).
+			WithField("secret_fingerprint", client.SecretFingerprint(hash)).
+			Info("OAuth 2.0 Client authenticated using a rotated client secret.")
+		return c, nil
+	}
+}
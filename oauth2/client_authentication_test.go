@@ -0,0 +1,75 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/logrusx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/oauth2"
+)
+
+func TestClientSecretStrategy(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+
+	c := &hc.Client{Secret: "the-original-secret"}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+	_, err := reg.ClientManager().RotateClientSecret(ctx, c.GetID(), "the-rotated-secret", time.Hour)
+	require.NoError(t, err)
+
+	hook := test.Hook{}
+	f := &fosite.Fosite{Store: reg.Persister(), Config: reg.OAuth2ProviderConfig()}
+	strategy := oauth2.NewClientSecretStrategy(logrusx.New("", "", logrusx.WithHook(&hook)), f.DefaultClientAuthenticationStrategy)
+
+	authenticate := func(t *testing.T, secret string) error {
+		r, err := http.NewRequest(http.MethodPost, "/oauth2/token", nil)
+		require.NoError(t, err)
+		r.SetBasicAuth(url.QueryEscape(c.GetID()), url.QueryEscape(secret))
+		_, err = strategy(ctx, r, url.Values{})
+		return err
+	}
+
+	require.NoError(t, authenticate(t, "the-rotated-secret"))
+	assert.Empty(t, hook.AllEntries(), "authenticating with the current secret is not audited")
+
+	require.NoError(t, authenticate(t, "the-original-secret"))
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, c.GetID(), hook.LastEntry().Data["client_id"])
+	stored, err := reg.ClientManager().GetConcreteClient(ctx, c.GetID())
+	require.NoError(t, err)
+	assert.Equal(t, hc.SecretFingerprint([]byte(stored.RotatedSecrets[0].Hash)), hook.LastEntry().Data["secret_fingerprint"])
+
+	hook.Reset()
+	assert.Error(t, authenticate(t, "an-unknown-secret"))
+	assert.Empty(t, hook.AllEntries())
+
+	t.Run("case=rejects an expired client secret", func(t *testing.T) {
+		stored.Secret = "the-rotated-secret"
+		stored.SecretExpiresAt = int(time.Now().Add(-time.Minute).Unix())
+		require.NoError(t, reg.ClientManager().UpdateClient(ctx, stored))
+		stored, err = reg.ClientManager().GetConcreteClient(ctx, c.GetID())
+		require.NoError(t, err)
+		require.NotZero(t, stored.SecretExpiresAt)
+
+		err := authenticate(t, "the-rotated-secret")
+		require.ErrorIs(t, err, fosite.ErrInvalidClient)
+		assert.Contains(t, fosite.ErrorToRFC6749Error(err).HintField, "expired")
+
+		require.NoError(t, authenticate(t, "the-original-secret"), "rotated secrets expire on their own")
+	})
+}
@@ -0,0 +1,182 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ory/fosite"
+	enigma "github.com/ory/fosite/token/hmac"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/randx"
+)
+
+// GrantTypeDeviceCode is the grant type of the OAuth 2.0 Device Authorization Grant (RFC 8628).
+const GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+var (
+	// ErrAuthorizationPending is returned when the end-user has not yet completed the user interaction steps.
+	ErrAuthorizationPending = &fosite.RFC6749Error{
+		ErrorField:       "authorization_pending",
+		DescriptionField: "The authorization request is still pending as the end user hasn't yet completed the user-interaction steps.",
+		CodeField:        http.StatusBadRequest,
+	}
+
+	// ErrSlowDown is returned when the device polls the token endpoint more often than allowed.
+	ErrSlowDown = &fosite.RFC6749Error{
+		ErrorField:       "slow_down",
+		DescriptionField: "The authorization request is still pending and polling should continue, but the interval must be increased.",
+		CodeField:        http.StatusBadRequest,
+	}
+
+	// ErrExpiredToken is returned when the device code has expired.
+	ErrExpiredToken = &fosite.RFC6749Error{
+		ErrorField:       "expired_token",
+		DescriptionField: "The device_code has expired, and the device authorization session has concluded.",
+		CodeField:        http.StatusBadRequest,
+	}
+)
+
+// DeviceCodeState is the state of a device authorization request.
+type DeviceCodeState int
+
+const (
+	// DeviceCodeStatePending means that the end-user has not yet approved or denied the request.
+	DeviceCodeStatePending DeviceCodeState = iota + 1
+
+	// DeviceCodeStateApproved means that the end-user granted the request and the device may exchange the device code.
+	DeviceCodeStateApproved
+
+	// DeviceCodeStateDenied means that the end-user or the login and consent app denied the request.
+	DeviceCodeStateDenied
+
+	// DeviceCodeStateUsed means that the device code has already been exchanged for tokens.
+	DeviceCodeStateUsed
+)
+
+// DeviceCodeSession is a device authorization request as stored by the DeviceCodeStorage.
+type DeviceCodeSession struct {
+	// Signature is the signature of the device code.
+	Signature string
+
+	// Request contains the original authorization request. Once the request was approved,
+	// it also contains the granted scope, granted audience and the session.
+	Request fosite.Requester
+
+	State        DeviceCodeState
+	ExpiresAt    time.Time
+	LastPolledAt time.Time
+
+	// PollingInterval is the minimum time between two token requests of the device. It is increased every time
+	// the device polls too fast. Zero means the configured polling interval applies.
+	PollingInterval time.Duration
+}
+
+// DeviceCodeStorage stores the state of OAuth 2.0 Device Authorization Grant requests.
+type DeviceCodeStorage interface {
+	// CreateDeviceCodeSession stores a new, pending device authorization request.
+	CreateDeviceCodeSession(ctx context.Context, deviceCodeSignature, userCodeSignature string, request fosite.Requester, expiresAt time.Time) error
+
+	// GetDeviceCodeSession returns the device authorization request identified by the device code signature.
+	GetDeviceCodeSession(ctx context.Context, deviceCodeSignature string, session fosite.Session) (*DeviceCodeSession, error)
+
+	// GetDeviceCodeSessionByUserCode returns the device authorization request identified by the user code signature.
+	GetDeviceCodeSessionByUserCode(ctx context.Context, userCodeSignature string, session fosite.Session) (*DeviceCodeSession, error)
+
+	// ApproveDeviceCodeSession marks a pending device authorization request as approved and stores the
+	// granted scope, audience and session of the given request.
+	ApproveDeviceCodeSession(ctx context.Context, deviceCodeSignature string, request fosite.Requester) error
+
+	// DenyDeviceCodeSession marks a pending device authorization request as denied.
+	DenyDeviceCodeSession(ctx context.Context, deviceCodeSignature string) error
+
+	// TouchDeviceCodeSession records the time the device last polled the token endpoint and the polling interval
+	// the device must respect from now on.
+	TouchDeviceCodeSession(ctx context.Context, deviceCodeSignature string, polledAt time.Time, interval time.Duration) error
+
+	// InvalidateDeviceCodeSession marks the device code as used.
+	InvalidateDeviceCodeSession(ctx context.Context, deviceCodeSignature string) error
+
+	// FlushInactiveDeviceCodes removes expired device authorization requests.
+	// No data will be deleted after the 'notAfter' timeframe.
+	FlushInactiveDeviceCodes(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
+}
+
+// DeviceCodeStrategy generates and validates device and user codes.
+type DeviceCodeStrategy interface {
+	DeviceCodeSignature(ctx context.Context, code string) string
+	GenerateDeviceCode(ctx context.Context) (code string, signature string, err error)
+	ValidateDeviceCode(ctx context.Context, code string) error
+
+	UserCodeSignature(ctx context.Context, code string) string
+	GenerateUserCode(ctx context.Context) (code string, signature string, err error)
+}
+
+// userCodeAlphabet contains only consonants to avoid accidentally forming words, as
+// recommended by RFC 8628 Section 6.1.
+var userCodeAlphabet = []rune("BCDFGHJKLMNPQRSTVWXZ")
+
+const (
+	deviceCodePrefix = "ory_dc_"
+	userCodeLength   = 8
+)
+
+var _ DeviceCodeStrategy = (*DeviceCodeHMACStrategy)(nil)
+
+// DeviceCodeHMACStrategy generates device codes in the same format as opaque OAuth 2.0 tokens
+// and short, human-readable user codes.
+type DeviceCodeHMACStrategy struct {
+	Enigma *enigma.HMACStrategy
+	Config enigma.HMACStrategyConfigurator
+}
+
+// NewDeviceCodeHMACStrategy returns a new DeviceCodeHMACStrategy.
+func NewDeviceCodeHMACStrategy(config enigma.HMACStrategyConfigurator) *DeviceCodeHMACStrategy {
+	return &DeviceCodeHMACStrategy{
+		Enigma: &enigma.HMACStrategy{Config: config},
+		Config: config,
+	}
+}
+
+func (s *DeviceCodeHMACStrategy) DeviceCodeSignature(ctx context.Context, code string) string {
+	return s.Enigma.Signature(code)
+}
+
+func (s *DeviceCodeHMACStrategy) GenerateDeviceCode(ctx context.Context) (string, string, error) {
+	code, signature, err := s.Enigma.Generate(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	return deviceCodePrefix + code, signature, nil
+}
+
+func (s *DeviceCodeHMACStrategy) ValidateDeviceCode(ctx context.Context, code string) error {
+	return s.Enigma.Validate(ctx, strings.TrimPrefix(code, deviceCodePrefix))
+}
+
+// UserCodeSignature returns the keyed hash of the normalized user code. User codes are
+// normalized so that the end-user can enter them in lower case and without the dash.
+func (s *DeviceCodeHMACStrategy) UserCodeSignature(ctx context.Context, code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	mac := hmac.New(sha256.New, s.Config.GetGlobalSecret(ctx))
+	_, _ = mac.Write([]byte(normalized))
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+func (s *DeviceCodeHMACStrategy) GenerateUserCode(ctx context.Context) (string, string, error) {
+	seq, err := randx.RuneSequence(userCodeLength, userCodeAlphabet)
+	if err != nil {
+		return "", "", errorsx.WithStack(err)
+	}
+
+	code := string(seq[:userCodeLength/2]) + "-" + string(seq[userCodeLength/2:])
+	return code, s.UserCodeSignature(ctx, code), nil
+}
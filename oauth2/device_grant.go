@@ -0,0 +1,261 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/storage"
+	enigma "github.com/ory/fosite/token/hmac"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlcon"
+)
+
+type deviceCodeGrantConfig interface {
+	fosite.AccessTokenLifespanProvider
+	fosite.RefreshTokenLifespanProvider
+	fosite.IDTokenLifespanProvider
+	fosite.RefreshTokenScopesProvider
+	GetDeviceAuthTokenPollingInterval(ctx context.Context) time.Duration
+}
+
+var _ fosite.TokenEndpointHandler = (*DeviceCodeGrantHandler)(nil)
+
+// DeviceCodeGrantHandler handles the token endpoint part of the OAuth 2.0 Device Authorization
+// Grant (RFC 8628 Section 3.4).
+type DeviceCodeGrantHandler struct {
+	DeviceCodeStorage    DeviceCodeStorage
+	CoreStorage          foauth2.CoreStorage
+	DeviceCodeStrategy   DeviceCodeStrategy
+	AccessTokenStrategy  foauth2.AccessTokenStrategy
+	RefreshTokenStrategy foauth2.RefreshTokenStrategy
+	*openid.IDTokenHandleHelper
+	Config deviceCodeGrantConfig
+}
+
+// DeviceCodeGrantFactory creates a DeviceCodeGrantHandler. It follows the signature of the
+// factories in github.com/ory/fosite/compose.
+func DeviceCodeGrantFactory(config fosite.Configurator, storage interface{}, strategy interface{}) interface{} {
+	return &DeviceCodeGrantHandler{
+		DeviceCodeStorage:    storage.(DeviceCodeStorage),
+		CoreStorage:          storage.(foauth2.CoreStorage),
+		DeviceCodeStrategy:   NewDeviceCodeHMACStrategy(config.(enigma.HMACStrategyConfigurator)),
+		AccessTokenStrategy:  strategy.(foauth2.AccessTokenStrategy),
+		RefreshTokenStrategy: strategy.(foauth2.RefreshTokenStrategy),
+		IDTokenHandleHelper: &openid.IDTokenHandleHelper{
+			IDTokenStrategy: strategy.(openid.OpenIDConnectTokenStrategy),
+		},
+		Config: config.(deviceCodeGrantConfig),
+	}
+}
+
+func (c *DeviceCodeGrantHandler) HandleTokenEndpointRequest(ctx context.Context, request fosite.AccessRequester) error {
+	if !c.CanHandleTokenEndpointRequest(ctx, request) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	if !request.GetClient().GetGrantTypes().Has(GrantTypeDeviceCode) {
+		return errorsx.WithStack(fosite.ErrUnauthorizedClient.WithHintf("The OAuth 2.0 Client is not allowed to use authorization grant \"%s\".", GrantTypeDeviceCode))
+	}
+
+	ds, err := c.getDeviceCodeSession(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	// The device code must have been issued to the client which is authenticated in this request.
+	if ds.Request.GetClient().GetID() != request.GetClient().GetID() {
+		return errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The OAuth 2.0 Client ID from this request does not match the one from the device authorization request."))
+	}
+
+	now := time.Now().UTC()
+	if ds.ExpiresAt.Before(now) {
+		return errorsx.WithStack(ErrExpiredToken)
+	}
+
+	switch ds.State {
+	case DeviceCodeStateApproved:
+		// continue below
+	case DeviceCodeStateDenied:
+		return errorsx.WithStack(fosite.ErrAccessDenied.WithHint("The end-user denied the device authorization request."))
+	case DeviceCodeStateUsed:
+		return errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The device code has already been used."))
+	default:
+		interval := c.Config.GetDeviceAuthTokenPollingInterval(ctx)
+		if ds.PollingInterval > interval {
+			interval = ds.PollingInterval
+		}
+
+		// Devices which poll too fast must wait five seconds longer from now on, see
+		// https://www.rfc-editor.org/rfc/rfc8628#section-3.5
+		tooFast := !ds.LastPolledAt.IsZero() && now.Sub(ds.LastPolledAt) < interval
+		if tooFast {
+			interval += 5 * time.Second
+		}
+
+		if err := c.DeviceCodeStorage.TouchDeviceCodeSession(ctx, ds.Signature, now, interval); err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		}
+
+		if tooFast {
+			return errorsx.WithStack(ErrSlowDown)
+		}
+		return errorsx.WithStack(ErrAuthorizationPending)
+	}
+
+	request.SetRequestedScopes(ds.Request.GetRequestedScopes())
+	request.SetRequestedAudience(ds.Request.GetRequestedAudience())
+	request.SetSession(ds.Request.GetSession())
+	request.SetID(ds.Request.GetID())
+
+	atLifespan := fosite.GetEffectiveLifespan(request.GetClient(), GrantTypeDeviceCode, fosite.AccessToken, c.Config.GetAccessTokenLifespan(ctx))
+	request.GetSession().SetExpiresAt(fosite.AccessToken, now.Add(atLifespan).Round(time.Second))
+
+	rtLifespan := fosite.GetEffectiveLifespan(request.GetClient(), GrantTypeDeviceCode, fosite.RefreshToken, c.Config.GetRefreshTokenLifespan(ctx))
+	if rtLifespan > -1 {
+		request.GetSession().SetExpiresAt(fosite.RefreshToken, now.Add(rtLifespan).Round(time.Second))
+	}
+
+	return nil
+}
+
+func (c *DeviceCodeGrantHandler) PopulateTokenEndpointResponse(ctx context.Context, requester fosite.AccessRequester, responder fosite.AccessResponder) (err error) {
+	if !c.CanHandleTokenEndpointRequest(ctx, requester) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	ds, err := c.getDeviceCodeSession(ctx, requester)
+	if err != nil {
+		return err
+	}
+
+	for _, scope := range ds.Request.GetGrantedScopes() {
+		requester.GrantScope(scope)
+	}
+
+	for _, audience := range ds.Request.GetGrantedAudience() {
+		requester.GrantAudience(audience)
+	}
+
+	access, accessSignature, err := c.AccessTokenStrategy.GenerateAccessToken(ctx, requester)
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	var refresh, refreshSignature string
+	if c.canIssueRefreshToken(ctx, requester) {
+		refresh, refreshSignature, err = c.RefreshTokenStrategy.GenerateRefreshToken(ctx, requester)
+		if err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		}
+	}
+
+	ctx, err = storage.MaybeBeginTx(ctx, c.CoreStorage)
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+	defer func() {
+		if err != nil {
+			if rollBackTxnErr := storage.MaybeRollbackTx(ctx, c.CoreStorage); rollBackTxnErr != nil {
+				err = errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebugf("error: %s; rollback error: %s", err, rollBackTxnErr))
+			}
+		}
+	}()
+
+	if err = c.DeviceCodeStorage.InvalidateDeviceCodeSession(ctx, ds.Signature); errors.Is(err, sqlcon.ErrNoRows) {
+		return errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The device code has already been used."))
+	} else if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	} else if err = c.CoreStorage.CreateAccessTokenSession(ctx, accessSignature, requester.Sanitize([]string{})); err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	} else if refreshSignature != "" {
+		if err = c.CoreStorage.CreateRefreshTokenSession(ctx, refreshSignature, requester.Sanitize([]string{})); err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		}
+	}
+
+	responder.SetAccessToken(access)
+	responder.SetTokenType("bearer")
+	atLifespan := fosite.GetEffectiveLifespan(requester.GetClient(), GrantTypeDeviceCode, fosite.AccessToken, c.Config.GetAccessTokenLifespan(ctx))
+	responder.SetExpiresIn(expiresIn(requester, fosite.AccessToken, atLifespan, time.Now().UTC()))
+	responder.SetScopes(requester.GetGrantedScopes())
+	if refresh != "" {
+		responder.SetExtra("refresh_token", refresh)
+	}
+
+	if requester.GetGrantedScopes().Has("openid") {
+		sess, ok := requester.GetSession().(openid.Session)
+		if !ok {
+			err = errorsx.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because session must be of type fosite/handler/openid.Session."))
+			return err
+		}
+
+		claims := sess.IDTokenClaims()
+		if claims.Subject == "" {
+			err = errorsx.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because subject is an empty string."))
+			return err
+		}
+		claims.AccessTokenHash = c.GetAccessTokenHash(ctx, requester, responder)
+
+		idTokenLifespan := fosite.GetEffectiveLifespan(requester.GetClient(), GrantTypeDeviceCode, fosite.IDToken, c.Config.GetIDTokenLifespan(ctx))
+		if err = c.IssueExplicitIDToken(ctx, idTokenLifespan, requester, responder); err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		}
+	}
+
+	if err = storage.MaybeCommitTx(ctx, c.CoreStorage); err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	return nil
+}
+
+func (c *DeviceCodeGrantHandler) getDeviceCodeSession(ctx context.Context, requester fosite.AccessRequester) (*DeviceCodeSession, error) {
+	code := requester.GetRequestForm().Get("device_code")
+	if code == "" {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The \"device_code\" parameter is missing."))
+	}
+
+	if err := c.DeviceCodeStrategy.ValidateDeviceCode(ctx, code); err != nil {
+		return nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	ds, err := c.DeviceCodeStorage.GetDeviceCodeSession(ctx, c.DeviceCodeStrategy.DeviceCodeSignature(ctx, code), requester.GetSession())
+	if errors.Is(err, fosite.ErrNotFound) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithWrap(err).WithDebug(err.Error()))
+	} else if err != nil {
+		return nil, errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	return ds, nil
+}
+
+func (c *DeviceCodeGrantHandler) canIssueRefreshToken(ctx context.Context, requester fosite.Requester) bool {
+	scope := c.Config.GetRefreshTokenScopes(ctx)
+	if len(scope) > 0 && !requester.GetGrantedScopes().HasOneOf(scope...) {
+		return false
+	}
+	return requester.GetClient().GetGrantTypes().Has("refresh_token")
+}
+
+func (c *DeviceCodeGrantHandler) CanSkipClientAuth(ctx context.Context, requester fosite.AccessRequester) bool {
+	return false
+}
+
+func (c *DeviceCodeGrantHandler) CanHandleTokenEndpointRequest(ctx context.Context, requester fosite.AccessRequester) bool {
+	return requester.GetGrantTypes().ExactOne(GrantTypeDeviceCode)
+}
+
+func expiresIn(r fosite.Requester, key fosite.TokenType, defaultLifespan time.Duration, now time.Time) time.Duration {
+	if r.GetSession().GetExpiresAt(key).IsZero() {
+		return defaultLifespan
+	}
+	return time.Duration(r.GetSession().GetExpiresAt(key).UnixNano() - now.UnixNano())
+}
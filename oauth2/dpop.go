@@ -0,0 +1,202 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+)
+
+const (
+	// DPoPHeader is the HTTP header carrying the DPoP proof (RFC 9449).
+	DPoPHeader = "DPoP"
+
+	// TokenTypeDPoP is the token type of access tokens which are bound to a DPoP key.
+	TokenTypeDPoP = "DPoP"
+
+	dpopProofType = "dpop+jwt"
+)
+
+// DPoPSigningAlgorithms are the JWS algorithms accepted for DPoP proofs.
+var DPoPSigningAlgorithms = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// ErrInvalidDPoPProof is returned when the DPoP proof of a request is missing or invalid.
+var ErrInvalidDPoPProof = &fosite.RFC6749Error{
+	ErrorField:       "invalid_dpop_proof",
+	DescriptionField: "The DPoP proof is invalid.",
+	CodeField:        http.StatusBadRequest,
+}
+
+type dpopProofClaims struct {
+	ID              string           `json:"jti"`
+	Method          string           `json:"htm"`
+	URI             string           `json:"htu"`
+	IssuedAt        *jwt.NumericDate `json:"iat"`
+	AccessTokenHash string           `json:"ath"`
+}
+
+// validateDPoPProof validates the DPoP proof of the request against the expected URL and, if set, the
+// access token presented with the request. It returns the JWK thumbprint of the proof's key, or an empty string
+// if the request does not contain a DPoP proof.
+func (h *Handler) validateDPoPProof(r *http.Request, expectedURL *url.URL, accessToken string) (string, error) {
+	ctx := r.Context()
+
+	values := r.Header.Values(DPoPHeader)
+	if len(values) == 0 {
+		return "", nil
+	} else if len(values) > 1 {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The request must not contain more than one DPoP proof."))
+	}
+
+	proof, err := jose.ParseSigned(values[0])
+	if err != nil {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("Unable to parse the DPoP proof.").WithWrap(err).WithDebug(err.Error()))
+	} else if len(proof.Signatures) != 1 {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The DPoP proof must have exactly one signature."))
+	}
+
+	header := proof.Signatures[0].Protected
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != dpopProofType {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHintf("The DPoP proof must have the \"typ\" header \"%s\".", dpopProofType))
+	} else if !stringslice.Has(DPoPSigningAlgorithms, header.Algorithm) {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHintf("The DPoP proof signing algorithm \"%s\" is not supported.", header.Algorithm))
+	} else if header.JSONWebKey == nil || !header.JSONWebKey.Valid() || !header.JSONWebKey.IsPublic() {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The DPoP proof must contain a public JSON Web Key in the \"jwk\" header."))
+	}
+
+	payload, err := proof.Verify(header.JSONWebKey)
+	if err != nil {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("Unable to verify the signature of the DPoP proof.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	var claims dpopProofClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("Unable to decode the claims of the DPoP proof.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	if claims.ID == "" {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The DPoP proof must contain the \"jti\" claim."))
+	} else if claims.Method != r.Method {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHintf("The \"htm\" claim of the DPoP proof must be \"%s\".", r.Method))
+	} else if !dpopURIMatches(claims.URI, expectedURL) {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHintf("The \"htu\" claim of the DPoP proof must be \"%s\".", expectedURL.String()))
+	}
+
+	lifespan := h.c.DPoPProofLifespan(ctx)
+	now := time.Now().UTC()
+	if claims.IssuedAt == nil {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The DPoP proof must contain the \"iat\" claim."))
+	} else if iat := claims.IssuedAt.Time(); iat.Add(lifespan).Before(now) || iat.After(now.Add(lifespan)) {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The DPoP proof was not issued recently enough."))
+	}
+
+	if accessToken != "" {
+		hash := sha256.Sum256([]byte(accessToken))
+		if claims.AccessTokenHash != base64.RawURLEncoding.EncodeToString(hash[:]) {
+			return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The \"ath\" claim of the DPoP proof does not match the access token."))
+		}
+	}
+
+	thumbprint, err := header.JSONWebKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithWrap(err).WithDebug(err.Error()))
+	}
+	jkt := base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	// Proofs are tracked like client assertions, so each proof can only be used once.
+	if err := h.r.OAuth2Storage().SetClientAssertionJWT(ctx, fmt.Sprintf("dpop:%s:%s", jkt, claims.ID), claims.IssuedAt.Time().Add(lifespan)); errors.Is(err, fosite.ErrJTIKnown) {
+		return "", errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The DPoP proof has already been used."))
+	} else if err != nil {
+		return "", err
+	}
+
+	return jkt, nil
+}
+
+// bindDPoPProof binds the tokens issued by the token endpoint to the key of the request's DPoP proof.
+func (h *Handler) bindDPoPProof(r *http.Request, ar fosite.AccessRequester) error {
+	jkt, err := h.validateDPoPProof(r, h.c.OAuth2TokenURL(r.Context()), "")
+	if err != nil {
+		return err
+	}
+
+	session, ok := ar.GetSession().(*Session)
+	if !ok {
+		return errorsx.WithStack(fosite.ErrServerError.WithHintf("Session must be of type *oauth2.Session but got type: %T", ar.GetSession()))
+	}
+
+	// Refresh tokens of public clients stay bound to the key they were originally issued for. Confidential clients
+	// authenticate themselves when refreshing, so the new tokens are bound to whichever key they present.
+	if bound := session.Confirmation.jwkThumbprint(); bound != "" && ar.GetGrantTypes().ExactOne("refresh_token") && ar.GetClient().IsPublic() {
+		if jkt != bound {
+			return errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The refresh token is bound to a DPoP key and the request must contain a DPoP proof signed with that key."))
+		}
+	}
+
+	cnf := session.Confirmation.copy()
+	cnf.JWKThumbprint = jkt
+	session.Confirmation = cnf.orNil()
+	return nil
+}
+
+// verifyDPoPBinding ensures that an access token which is bound to a DPoP key is only accepted together with a
+// proof signed by that key, and that unbound access tokens are not presented using the DPoP authorization scheme.
+func (h *Handler) verifyDPoPBinding(r *http.Request, ar fosite.Requester, accessToken string, isDPoP bool) error {
+	session, ok := ar.GetSession().(*Session)
+	if !ok || session.Confirmation.jwkThumbprint() == "" {
+		if isDPoP {
+			return errorsx.WithStack(fosite.ErrRequestUnauthorized.WithHint("The access token is not bound to a DPoP key and must be presented using the Bearer authorization scheme."))
+		}
+		return nil
+	} else if !isDPoP {
+		return errorsx.WithStack(fosite.ErrRequestUnauthorized.WithHint("The access token is bound to a DPoP key and must be presented using the DPoP authorization scheme."))
+	}
+
+	jkt, err := h.validateDPoPProof(r, h.c.OIDCDiscoveryUserinfoEndpoint(r.Context()), accessToken)
+	if err != nil {
+		return err
+	} else if jkt == "" {
+		return errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The request must contain a DPoP proof."))
+	} else if jkt != session.Confirmation.JWKThumbprint {
+		return errorsx.WithStack(ErrInvalidDPoPProof.WithHint("The DPoP proof was not signed with the key the access token is bound to."))
+	}
+	return nil
+}
+
+// accessTokenFromRequest returns the access token of the request and whether it was presented using the DPoP
+// authorization scheme.
+func accessTokenFromRequest(r *http.Request) (string, bool) {
+	if parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], TokenTypeDPoP) {
+		return parts[1], true
+	}
+	return fosite.AccessTokenFromRequest(r), false
+}
+
+func dpopURIMatches(raw string, expected *url.URL) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, expected.Scheme) && strings.EqualFold(u.Host, expected.Host) && u.Path == expected.Path
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/ory/fosite/storage"
 	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/urlx"
 
 	gofrsuuid "github.com/gofrs/uuid"
 	"github.com/pkg/errors"
@@ -171,6 +172,7 @@ func TestHelperRunner(t *testing.T, store InternalRegistry, k string) {
 	t.Run(fmt.Sprintf("case=testHelperCreateGetDeleteOpenIDConnectSession/db=%s", k), testHelperCreateGetDeleteOpenIDConnectSession(store))
 	t.Run(fmt.Sprintf("case=testHelperCreateGetDeleteRefreshTokenSession/db=%s", k), testHelperCreateGetDeleteRefreshTokenSession(store))
 	t.Run(fmt.Sprintf("case=testHelperRevokeRefreshToken/db=%s", k), testHelperRevokeRefreshToken(store))
+	t.Run(fmt.Sprintf("case=testHelperRevokeRefreshTokens/db=%s", k), testHelperRevokeRefreshTokens(store))
 	t.Run(fmt.Sprintf("case=testHelperCreateGetDeletePKCERequestSession/db=%s", k), testHelperCreateGetDeletePKCERequestSession(store))
 	t.Run(fmt.Sprintf("case=testHelperFlushTokens/db=%s", k), testHelperFlushTokens(store, time.Hour))
 	t.Run(fmt.Sprintf("case=testHelperFlushTokensWithLimitAndBatchSize/db=%s", k), testHelperFlushTokensWithLimitAndBatchSize(store, 3, 2))
@@ -179,6 +181,8 @@ func TestHelperRunner(t *testing.T, store InternalRegistry, k string) {
 	t.Run(fmt.Sprintf("case=testHelperDeleteAccessTokens/db=%s", k), testHelperDeleteAccessTokens(store))
 	t.Run(fmt.Sprintf("case=testHelperRevokeAccessToken/db=%s", k), testHelperRevokeAccessToken(store))
 	t.Run(fmt.Sprintf("case=testFositeJWTBearerGrantStorage/db=%s", k), testFositeJWTBearerGrantStorage(store))
+	t.Run(fmt.Sprintf("case=testHelperDeviceCodes/db=%s", k), testHelperDeviceCodes(store))
+	t.Run(fmt.Sprintf("case=testHelperPushedAuthorizeRequests/db=%s", k), testHelperPushedAuthorizeRequests(store))
 }
 
 func testHelperRequestIDMultiples(m InternalRegistry, _ string) func(t *testing.T) {
@@ -324,6 +328,112 @@ func testHelperCreateGetDeleteAuthorizeCodes(x InternalRegistry) func(t *testing
 	}
 }
 
+func testHelperDeviceCodes(x InternalRegistry) func(t *testing.T) {
+	return func(t *testing.T) {
+		m := x.DeviceCodeStorage()
+		ctx := context.Background()
+
+		r := defaultRequest
+		r.ID = "device-code-request"
+		r.GrantedScope = fosite.Arguments{}
+		r.GrantedAudience = fosite.Arguments{}
+
+		_, err := m.GetDeviceCodeSession(ctx, "device-1", &Session{})
+		assert.ErrorIs(t, err, fosite.ErrNotFound)
+
+		require.NoError(t, m.CreateDeviceCodeSession(ctx, "device-1", "user-1", &r, time.Now().Add(time.Hour)))
+		require.NoError(t, m.CreateDeviceCodeSession(ctx, "device-2", "user-2", &r, time.Now().Add(-time.Hour)))
+
+		ds, err := m.GetDeviceCodeSessionByUserCode(ctx, "user-1", &Session{})
+		require.NoError(t, err)
+		assert.Equal(t, "device-1", ds.Signature)
+		assert.Equal(t, DeviceCodeStatePending, ds.State)
+		assert.True(t, ds.LastPolledAt.IsZero())
+		AssertObjectKeysEqual(t, &r, ds.Request, "RequestedScope", "RequestedAudience", "Form")
+
+		require.NoError(t, m.TouchDeviceCodeSession(ctx, "device-1", time.Now(), time.Minute))
+		ds, err = m.GetDeviceCodeSession(ctx, "device-1", &Session{})
+		require.NoError(t, err)
+		assert.False(t, ds.LastPolledAt.IsZero())
+		assert.Equal(t, time.Minute, ds.PollingInterval)
+
+		// A pending device code can not be exchanged.
+		assert.ErrorIs(t, m.InvalidateDeviceCodeSession(ctx, "device-1"), sqlcon.ErrNoRows)
+
+		approved := r
+		approved.ID = "device-code-approved"
+		approved.GrantedScope = fosite.Arguments{"fa"}
+		approved.GrantedAudience = fosite.Arguments{"ad1"}
+		require.NoError(t, m.ApproveDeviceCodeSession(ctx, "device-1", &approved))
+		assert.ErrorIs(t, m.ApproveDeviceCodeSession(ctx, "device-1", &approved), sqlcon.ErrNoRows)
+		assert.ErrorIs(t, m.DenyDeviceCodeSession(ctx, "device-1"), sqlcon.ErrNoRows)
+
+		ds, err = m.GetDeviceCodeSession(ctx, "device-1", &Session{})
+		require.NoError(t, err)
+		assert.Equal(t, DeviceCodeStateApproved, ds.State)
+		assert.Equal(t, "device-code-approved", ds.Request.GetID())
+		assert.EqualValues(t, approved.GrantedScope, ds.Request.GetGrantedScopes())
+		assert.EqualValues(t, approved.GrantedAudience, ds.Request.GetGrantedAudience())
+		assert.Equal(t, "bar", ds.Request.GetSession().GetSubject())
+
+		require.NoError(t, m.InvalidateDeviceCodeSession(ctx, "device-1"))
+		assert.ErrorIs(t, m.InvalidateDeviceCodeSession(ctx, "device-1"), sqlcon.ErrNoRows)
+
+		require.NoError(t, m.DenyDeviceCodeSession(ctx, "device-2"))
+		ds, err = m.GetDeviceCodeSession(ctx, "device-2", &Session{})
+		require.NoError(t, err)
+		assert.Equal(t, DeviceCodeStateDenied, ds.State)
+
+		require.NoError(t, m.FlushInactiveDeviceCodes(ctx, time.Now(), 100, 10))
+		_, err = m.GetDeviceCodeSession(ctx, "device-1", &Session{})
+		require.NoError(t, err)
+		_, err = m.GetDeviceCodeSession(ctx, "device-2", &Session{})
+		assert.ErrorIs(t, err, fosite.ErrNotFound)
+	}
+}
+
+func testHelperPushedAuthorizeRequests(x InternalRegistry) func(t *testing.T) {
+	return func(t *testing.T) {
+		m := x.OAuth2Storage()
+		ctx := context.Background()
+
+		r := fosite.NewAuthorizeRequest()
+		r.Merge(&defaultRequest)
+		r.ID = "par-request"
+		r.RedirectURI = urlx.ParseOrPanic("https://example.com/callback")
+		r.ResponseTypes = fosite.Arguments{"code", "id_token"}
+		r.ResponseMode = fosite.ResponseModeFormPost
+		r.State = "some-state"
+
+		_, err := m.GetPARSession(ctx, "urn:par-1")
+		assert.ErrorIs(t, err, fosite.ErrNotFound)
+
+		require.NoError(t, m.CreatePARSession(ctx, "urn:par-1", r))
+		expired := *r
+		expired.Session = &Session{DefaultSession: &openid.DefaultSession{ExpiresAt: map[fosite.TokenType]time.Time{
+			fosite.PushedAuthorizeRequestContext: time.Now().Add(-time.Hour),
+		}}}
+		require.NoError(t, m.CreatePARSession(ctx, "urn:par-2", &expired))
+
+		res, err := m.GetPARSession(ctx, "urn:par-1")
+		require.NoError(t, err)
+		AssertObjectKeysEqual(t, r, res, "ID", "RequestedScope", "RequestedAudience", "Form", "RedirectURI", "ResponseTypes", "ResponseMode", "State")
+		assert.Equal(t, "bar", res.GetSession().GetSubject())
+
+		_, err = m.GetPARSession(ctx, "urn:par-2")
+		assert.ErrorIs(t, err, fosite.ErrNotFound)
+
+		require.NoError(t, m.ResolvePARSession(ctx, "urn:par-1", time.Now().Add(time.Hour)))
+		assert.ErrorIs(t, m.ResolvePARSession(ctx, "urn:par-1", time.Now().Add(time.Hour)), fosite.ErrNotFound, "a pushed authorization request can only be resolved once")
+		assert.ErrorIs(t, m.ResolvePARSession(ctx, "urn:par-2", time.Now().Add(time.Hour)), fosite.ErrNotFound, "expired pushed authorization requests can not be resolved")
+
+		require.NoError(t, m.FlushInactivePARSessions(ctx, time.Now(), 100, 10))
+		require.NoError(t, m.DeletePARSession(ctx, "urn:par-1"))
+		assert.ErrorIs(t, m.DeletePARSession(ctx, "urn:par-1"), fosite.ErrNotFound)
+		assert.ErrorIs(t, m.DeletePARSession(ctx, "urn:par-2"), fosite.ErrNotFound)
+	}
+}
+
 func testHelperNilAccessToken(x InternalRegistry) func(t *testing.T) {
 	return func(t *testing.T) {
 		m := x.OAuth2Storage()
@@ -387,6 +497,30 @@ func testHelperDeleteAccessTokens(x InternalRegistry) func(t *testing.T) {
 	}
 }
 
+func testHelperRevokeRefreshTokens(x InternalRegistry) func(t *testing.T) {
+	return func(t *testing.T) {
+		m := x.OAuth2Storage()
+		ctx := context.Background()
+
+		reqIdOne := uuid.New()
+		reqIdTwo := uuid.New()
+
+		mockRequestForeignKey(t, reqIdOne, x, false)
+		mockRequestForeignKey(t, reqIdTwo, x, false)
+
+		require.NoError(t, m.CreateRefreshTokenSession(ctx, "revoke-by-client-1", &fosite.Request{ID: reqIdOne, Client: &client.Client{LegacyClientID: "foobar"}, RequestedAt: time.Now().UTC().Round(time.Second), Session: &Session{}}))
+		require.NoError(t, m.CreateRefreshTokenSession(ctx, "revoke-by-client-2", &fosite.Request{ID: reqIdTwo, Client: &client.Client{LegacyClientID: "foobar"}, RequestedAt: time.Now().UTC().Round(time.Second), Session: &Session{}}))
+
+		require.NoError(t, m.RevokeRefreshTokens(ctx, "foobar"))
+
+		for _, signature := range []string{"revoke-by-client-1", "revoke-by-client-2"} {
+			req, err := m.GetRefreshTokenSession(ctx, signature, &Session{})
+			assert.NotNil(t, req)
+			assert.EqualError(t, err, fosite.ErrInactiveToken.Error())
+		}
+	}
+}
+
 func testHelperRevokeAccessToken(x InternalRegistry) func(t *testing.T) {
 	return func(t *testing.T) {
 		m := x.OAuth2Storage()
@@ -970,6 +1104,37 @@ func testFositeJWTBearerGrantStorage(x InternalRegistry) func(t *testing.T) {
 			require.NotNil(t, jwks)
 			require.NotEmpty(t, jwks.Keys)
 		})
+
+		t.Run("case=does not return the key of an issuer which allows any subject for another issuer", func(t *testing.T) {
+			keySet, err := jwk.GenerateJWK(context.Background(), jose.RS256, "any-subject-issuer-key", "sig")
+			require.NoError(t, err)
+
+			publicKey := keySet.Keys[0].Public()
+			issuer := "any-subject-issuer"
+			grant := trust.Grant{
+				ID:              uuid.New(),
+				Issuer:          issuer,
+				Subject:         "",
+				AllowAnySubject: true,
+				Scope:           []string{"openid", "offline"},
+				PublicKey:       trust.PublicKey{Set: issuer, KeyID: publicKey.KeyID},
+				CreatedAt:       time.Now().UTC().Round(time.Second),
+				ExpiresAt:       time.Now().UTC().Round(time.Second).AddDate(1, 0, 0),
+			}
+
+			err = grantManager.CreateGrant(context.TODO(), grant, publicKey)
+			require.NoError(t, err)
+
+			_, err = grantStorage.GetPublicKey(context.TODO(), "untrusted-issuer", "any-subject", publicKey.KeyID)
+			require.Error(t, err)
+
+			_, err = grantStorage.GetPublicKeyScopes(context.TODO(), "untrusted-issuer", "any-subject", publicKey.KeyID)
+			require.Error(t, err)
+
+			jwks, err := grantStorage.GetPublicKeys(context.TODO(), "untrusted-issuer", "any-subject")
+			require.NoError(t, err)
+			assert.Empty(t, jwks.Keys)
+		})
 	}
 }
 
@@ -34,6 +34,7 @@ import (
 	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/driver/config"
 	"github.com/ory/hydra/x"
+	"github.com/ory/hydra/x/events"
 )
 
 const (
@@ -69,6 +70,8 @@ func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin, public *httprouterx.
 	public.Handler("OPTIONS", TokenPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
 	public.Handler("POST", TokenPath, corsMiddleware(http.HandlerFunc(h.oauth2TokenExchange)))
 
+	public.Handler("OPTIONS", PushedAuthorizationRequestPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
+	public.Handler("POST", PushedAuthorizationRequestPath, corsMiddleware(http.HandlerFunc(h.oAuth2PushedAuthorize)))
 	public.GET(AuthPath, h.oAuth2Authorize)
 	public.POST(AuthPath, h.oAuth2Authorize)
 	public.GET(LogoutPath, h.performOidcFrontOrBackChannelLogout)
@@ -85,6 +88,20 @@ func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin, public *httprouterx.
 	))
 	public.GET(DefaultErrorPath, h.DefaultErrorHandler)
 
+	public.Handler("OPTIONS", BackchannelAuthenticationPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
+	public.Handler("POST", BackchannelAuthenticationPath, corsMiddleware(http.HandlerFunc(h.oAuth2BackchannelAuthorize)))
+
+	public.Handler("OPTIONS", DeviceAuthPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
+	public.Handler("POST", DeviceAuthPath, corsMiddleware(http.HandlerFunc(h.oAuth2DeviceAuthorize)))
+	public.GET(DeviceVerifyPath, h.oAuth2DeviceVerify)
+	public.GET(DefaultDeviceVerifyPath, h.fallbackHandler("", "", http.StatusOK, config.KeyDeviceVerificationURL))
+	public.GET(DefaultDeviceDonePath, h.fallbackHandler(
+		"Your device has been authorized!",
+		"The Default Device Done URL is not set which is why you are seeing this fallback page. Your device authorization request however succeeded.",
+		http.StatusOK,
+		config.KeyDeviceDoneURL,
+	))
+
 	public.Handler("OPTIONS", RevocationPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
 	public.Handler("POST", RevocationPath, corsMiddleware(http.HandlerFunc(h.revokeOAuth2Token)))
 	public.Handler("OPTIONS", WellKnownPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
@@ -346,6 +363,69 @@ type oidcConfiguration struct {
 	// URL of the authorization server's OAuth 2.0 revocation endpoint.
 	RevocationEndpoint string `json:"revocation_endpoint"`
 
+	// OAuth 2.0 Device Authorization Endpoint URL
+	//
+	// URL of the authorization server's OAuth 2.0 device authorization endpoint.
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+
+	// OAuth 2.0 Pushed Authorization Request Endpoint URL
+	//
+	// URL of the authorization server's OAuth 2.0 pushed authorization request endpoint.
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint"`
+
+	// OAuth 2.0 Pushed Authorization Requests Required
+	//
+	// Boolean value specifying whether the authorization server accepts authorization requests only via
+	// the pushed authorization request endpoint.
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests"`
+
+	// OAuth 2.0 DPoP Signing Algorithms Supported
+	//
+	// JSON array containing a list of the JWS signing algorithms supported for DPoP proof JWTs.
+	DPoPSigningAlgValuesSupported []string `json:"dpop_signing_alg_values_supported"`
+
+	// OAuth 2.0 Mutual-TLS Endpoint Aliases
+	//
+	// Endpoints clients use when authenticating with mutual TLS or requesting certificate-bound access tokens.
+	MTLSEndpointAliases *mtlsEndpointAliases `json:"mtls_endpoint_aliases"`
+
+	// OAuth 2.0 Mutual-TLS Certificate-Bound Access Tokens Supported
+	//
+	// Boolean value indicating support for mutual TLS client certificate-bound access tokens.
+	TLSClientCertificateBoundAccessTokens bool `json:"tls_client_certificate_bound_access_tokens"`
+
+	// OpenID Connect Backchannel Authentication Endpoint URL
+	//
+	// URL of the OP's Client-Initiated Backchannel Authentication endpoint.
+	BackchannelAuthenticationEndpoint string `json:"backchannel_authentication_endpoint"`
+
+	// OpenID Connect Backchannel Token Delivery Modes Supported
+	//
+	// JSON array containing the Client-Initiated Backchannel Authentication token delivery modes supported by the OP.
+	BackchannelTokenDeliveryModesSupported []string `json:"backchannel_token_delivery_modes_supported"`
+
+	// OpenID Connect Backchannel User Code Parameter Supported
+	//
+	// Boolean value specifying whether the OP supports the user_code parameter in backchannel authentication requests.
+	BackchannelUserCodeParameterSupported bool `json:"backchannel_user_code_parameter_supported"`
+
+	// JARM Authorization Signing Algorithms Supported
+	//
+	// JSON array containing a list of the JWS signing algorithms supported for JWT secured authorization responses.
+	AuthorizationSigningAlgValuesSupported []string `json:"authorization_signing_alg_values_supported"`
+
+	// JARM Authorization Encryption Algorithms Supported
+	//
+	// JSON array containing a list of the JWE key management algorithms supported for JWT secured authorization
+	// responses.
+	AuthorizationEncryptionAlgValuesSupported []string `json:"authorization_encryption_alg_values_supported"`
+
+	// JARM Authorization Encryption Encodings Supported
+	//
+	// JSON array containing a list of the JWE content encryption algorithms supported for JWT secured
+	// authorization responses.
+	AuthorizationEncryptionEncValuesSupported []string `json:"authorization_encryption_enc_values_supported"`
+
 	// OpenID Connect Back-Channel Logout Supported
 	//
 	// Boolean value specifying whether the OP supports back-channel logout, with true indicating support.
@@ -412,38 +492,88 @@ func (h *Handler) discoverOidcConfiguration(w http.ResponseWriter, r *http.Reque
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
+	grantTypes := []string{"authorization_code", "implicit", "client_credentials", "refresh_token", GrantTypeDeviceCode, GrantTypeTokenExchange, GrantTypeCIBA}
+	if h.c.GrantPasswordEnabled(r.Context()) {
+		grantTypes = append(grantTypes, string(fosite.GrantTypePassword))
+	}
+
+	mtlsURL := h.c.MTLSPublicURL(r.Context())
+	mtlsAliases := &mtlsEndpointAliases{
+		TokenURL:                           urlx.AppendPaths(mtlsURL, TokenPath).String(),
+		RevocationEndpoint:                 urlx.AppendPaths(mtlsURL, RevocationPath).String(),
+		UserinfoEndpoint:                   urlx.AppendPaths(mtlsURL, UserinfoPath).String(),
+		DeviceAuthorizationEndpoint:        urlx.AppendPaths(mtlsURL, DeviceAuthPath).String(),
+		PushedAuthorizationRequestEndpoint: urlx.AppendPaths(mtlsURL, PushedAuthorizationRequestPath).String(),
+		BackchannelAuthenticationEndpoint:  urlx.AppendPaths(mtlsURL, BackchannelAuthenticationPath).String(),
+	}
+
 	h.r.Writer().Write(w, r, &oidcConfiguration{
-		Issuer:                                 h.c.IssuerURL(r.Context()).String(),
-		AuthURL:                                h.c.OAuth2AuthURL(r.Context()).String(),
-		TokenURL:                               h.c.OAuth2TokenURL(r.Context()).String(),
-		JWKsURI:                                h.c.JWKSURL(r.Context()).String(),
-		RevocationEndpoint:                     urlx.AppendPaths(h.c.IssuerURL(r.Context()), RevocationPath).String(),
-		RegistrationEndpoint:                   h.c.OAuth2ClientRegistrationURL(r.Context()).String(),
-		SubjectTypes:                           h.c.SubjectTypesSupported(r.Context()),
-		ResponseTypes:                          []string{"code", "code id_token", "id_token", "token id_token", "token", "token id_token code"},
-		ClaimsSupported:                        h.c.OIDCDiscoverySupportedClaims(r.Context()),
-		ScopesSupported:                        h.c.OIDCDiscoverySupportedScope(r.Context()),
-		UserinfoEndpoint:                       h.c.OIDCDiscoveryUserinfoEndpoint(r.Context()).String(),
-		TokenEndpointAuthMethodsSupported:      []string{"client_secret_post", "client_secret_basic", "private_key_jwt", "none"},
-		IDTokenSigningAlgValuesSupported:       []string{key.Algorithm},
-		IDTokenSignedResponseAlg:               []string{key.Algorithm},
-		UserinfoSignedResponseAlg:              []string{key.Algorithm},
-		GrantTypesSupported:                    []string{"authorization_code", "implicit", "client_credentials", "refresh_token"},
-		ResponseModesSupported:                 []string{"query", "fragment"},
-		UserinfoSigningAlgValuesSupported:      []string{"none", key.Algorithm},
-		RequestParameterSupported:              true,
-		RequestURIParameterSupported:           true,
-		RequireRequestURIRegistration:          true,
-		BackChannelLogoutSupported:             true,
-		BackChannelLogoutSessionSupported:      true,
-		FrontChannelLogoutSupported:            true,
-		FrontChannelLogoutSessionSupported:     true,
-		EndSessionEndpoint:                     urlx.AppendPaths(h.c.IssuerURL(r.Context()), LogoutPath).String(),
-		RequestObjectSigningAlgValuesSupported: []string{"none", string(jose.RS256), string(jose.ES256)},
-		CodeChallengeMethodsSupported:          []string{"plain", "S256"},
+		Issuer:                                    h.c.IssuerURL(r.Context()).String(),
+		AuthURL:                                   h.c.OAuth2AuthURL(r.Context()).String(),
+		TokenURL:                                  h.c.OAuth2TokenURL(r.Context()).String(),
+		JWKsURI:                                   h.c.JWKSURL(r.Context()).String(),
+		RevocationEndpoint:                        urlx.AppendPaths(h.c.IssuerURL(r.Context()), RevocationPath).String(),
+		RegistrationEndpoint:                      h.c.OAuth2ClientRegistrationURL(r.Context()).String(),
+		DeviceAuthorizationEndpoint:               h.c.OAuth2DeviceAuthorisationURL(r.Context()).String(),
+		PushedAuthorizationRequestEndpoint:        h.c.OAuth2PushedAuthorizationRequestURL(r.Context()).String(),
+		RequirePushedAuthorizationRequests:        h.c.EnforcePushedAuthorize(r.Context()),
+		DPoPSigningAlgValuesSupported:             DPoPSigningAlgorithms,
+		MTLSEndpointAliases:                       mtlsAliases,
+		TLSClientCertificateBoundAccessTokens:     true,
+		BackchannelAuthenticationEndpoint:         h.c.OAuth2BackchannelAuthenticationURL(r.Context()).String(),
+		BackchannelTokenDeliveryModesSupported:    []string{client.BackchannelTokenDeliveryModePoll, client.BackchannelTokenDeliveryModePing},
+		BackchannelUserCodeParameterSupported:     false,
+		AuthorizationSigningAlgValuesSupported:    []string{key.Algorithm},
+		AuthorizationEncryptionAlgValuesSupported: client.AuthorizationEncryptionAlgValuesSupported,
+		AuthorizationEncryptionEncValuesSupported: client.AuthorizationEncryptionEncValuesSupported,
+		SubjectTypes:                              h.c.SubjectTypesSupported(r.Context()),
+		ResponseTypes:                             []string{"code", "code id_token", "id_token", "token id_token", "token", "token id_token code"},
+		ClaimsSupported:                           h.c.OIDCDiscoverySupportedClaims(r.Context()),
+		ScopesSupported:                           h.c.OIDCDiscoverySupportedScope(r.Context()),
+		UserinfoEndpoint:                          h.c.OIDCDiscoveryUserinfoEndpoint(r.Context()).String(),
+		TokenEndpointAuthMethodsSupported:         []string{"client_secret_post", "client_secret_basic", "private_key_jwt", "none", ClientAuthMethodTLSClientAuth, ClientAuthMethodSelfSignedTLSClientAuth},
+		IDTokenSigningAlgValuesSupported:          []string{key.Algorithm},
+		IDTokenSignedResponseAlg:                  []string{key.Algorithm},
+		UserinfoSignedResponseAlg:                 []string{key.Algorithm},
+		GrantTypesSupported:                       grantTypes,
+		ResponseModesSupported:                    []string{"query", "fragment", string(client.ResponseModeJWT), string(client.ResponseModeQueryJWT), string(client.ResponseModeFragmentJWT), string(client.ResponseModeFormPostJWT)},
+		UserinfoSigningAlgValuesSupported:         []string{"none", key.Algorithm},
+		RequestParameterSupported:                 true,
+		RequestURIParameterSupported:              true,
+		RequireRequestURIRegistration:             true,
+		BackChannelLogoutSupported:                true,
+		BackChannelLogoutSessionSupported:         true,
+		FrontChannelLogoutSupported:               true,
+		FrontChannelLogoutSessionSupported:        true,
+		EndSessionEndpoint:                        urlx.AppendPaths(h.c.IssuerURL(r.Context()), LogoutPath).String(),
+		RequestObjectSigningAlgValuesSupported:    []string{"none", string(jose.RS256), string(jose.ES256)},
+		CodeChallengeMethodsSupported:             []string{"plain", "S256"},
 	})
 }
 
+// OAuth 2.0 Mutual-TLS Endpoint Aliases
+//
+// swagger:model oidcConfigurationMTLSEndpointAliases
+type mtlsEndpointAliases struct {
+	// OAuth 2.0 Token Endpoint URL
+	TokenURL string `json:"token_endpoint"`
+
+	// OAuth 2.0 Token Revocation URL
+	RevocationEndpoint string `json:"revocation_endpoint"`
+
+	// OpenID Connect Userinfo URL
+	UserinfoEndpoint string `json:"userinfo_endpoint"`
+
+	// OAuth 2.0 Device Authorization Endpoint URL
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+
+	// OAuth 2.0 Pushed Authorization Request Endpoint URL
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint"`
+
+	// OpenID Connect Backchannel Authentication Endpoint URL
+	BackchannelAuthenticationEndpoint string `json:"backchannel_authentication_endpoint"`
+}
+
 // OpenID Connect Userinfo
 //
 // swagger:model oidcUserInfo
@@ -531,7 +661,8 @@ type oidcUserInfo struct {
 func (h *Handler) getOidcUserInfo(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	session := NewSessionWithCustomClaims("", h.c.AllowedTopLevelClaims(ctx))
-	tokenType, ar, err := h.r.OAuth2Provider().IntrospectToken(ctx, fosite.AccessTokenFromRequest(r), fosite.AccessToken, session)
+	accessToken, isDPoP := accessTokenFromRequest(r)
+	tokenType, ar, err := h.r.OAuth2Provider().IntrospectToken(ctx, accessToken, fosite.AccessToken, session)
 	if err != nil {
 		rfcerr := fosite.ErrorToRFC6749Error(err)
 		if rfcerr.StatusCode() == http.StatusUnauthorized {
@@ -548,6 +679,24 @@ func (h *Handler) getOidcUserInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.verifyDPoPBinding(r, ar, accessToken, isDPoP); err != nil {
+		rfcerr := fosite.ErrorToRFC6749Error(err)
+		errorField := "invalid_token"
+		if rfcerr.ErrorField == ErrInvalidDPoPProof.ErrorField {
+			errorField = rfcerr.ErrorField
+		}
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`DPoP algs="%s",error="%s",error_description="%s"`, strings.Join(DPoPSigningAlgorithms, " "), errorField, rfcerr.GetDescription()))
+		h.r.Writer().WriteErrorCode(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.verifyClientCertificateBinding(r, ar); err != nil {
+		rfcerr := fosite.ErrorToRFC6749Error(err)
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token",error_description="%s"`, rfcerr.GetDescription()))
+		h.r.Writer().WriteErrorCode(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
 	c, ok := ar.GetClient().(*client.Client)
 	if !ok {
 		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrServerError.WithHint("Unable to type assert to *client.Client.")))
@@ -640,14 +789,49 @@ type revokeOAuth2Token struct {
 func (h *Handler) revokeOAuth2Token(w http.ResponseWriter, r *http.Request) {
 	var ctx = r.Context()
 
+	// The token has to be looked up before it is revoked, so that the event can tell whose token was revoked.
+	event := h.revokedTokenEvent(r)
+
 	err := h.r.OAuth2Provider().NewRevocationRequest(ctx, r)
 	if err != nil {
 		x.LogError(r, err, h.r.Logger())
+	} else if event != nil {
+		h.r.EventHook().Emit(ctx, *event)
 	}
 
 	h.r.OAuth2Provider().WriteRevocationResponse(ctx, w, err)
 }
 
+// revokedTokenEvent returns the event which announces the revocation of the token of a revocation request. It
+// returns nil if no events are sent or the token is not active, in which case revoking it has no effect.
+func (h *Handler) revokedTokenEvent(r *http.Request) *events.Event {
+	ctx := r.Context()
+	if h.c.EventHookURL(ctx) == nil {
+		return nil
+	}
+
+	if err := r.ParseMultipartForm(1 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		return nil
+	}
+
+	session := NewSessionWithCustomClaims("", h.c.AllowedTopLevelClaims(ctx))
+	_, ar, err := h.r.OAuth2Provider().IntrospectToken(ctx, token, fosite.TokenType(r.PostForm.Get("token_type_hint")), session)
+	if err != nil {
+		return nil
+	}
+
+	event := &events.Event{Type: events.TokenRevoked, Subject: session.GetSubject(), ClientID: ar.GetClient().GetID()}
+	if claims := session.IDTokenClaims(); claims != nil {
+		event.SessionID, _ = claims.Extra["sid"].(string)
+	}
+	return event
+}
+
 // Introspect OAuth 2.0 Access or Refresh Token Request
 //
 // swagger:parameters introspectOAuth2Token
@@ -744,6 +928,10 @@ func (h *Handler) introspectOAuth2Token(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if session.Confirmation.jwkThumbprint() != "" {
+		resp.AccessTokenType = TokenTypeDPoP
+	}
+
 	var obfuscated string
 	if len(session.Claims.Subject) > 0 && session.Claims.Subject != session.Subject {
 		obfuscated = session.Claims.Subject
@@ -757,20 +945,22 @@ func (h *Handler) introspectOAuth2Token(w http.ResponseWriter, r *http.Request,
 
 	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
 	if err = json.NewEncoder(w).Encode(&Introspection{
-		Active:            resp.IsActive(),
-		ClientID:          resp.GetAccessRequester().GetClient().GetID(),
-		Scope:             strings.Join(resp.GetAccessRequester().GetGrantedScopes(), " "),
-		ExpiresAt:         exp.Unix(),
-		IssuedAt:          resp.GetAccessRequester().GetRequestedAt().Unix(),
-		Subject:           session.GetSubject(),
-		Username:          session.GetUsername(),
-		Extra:             session.Extra,
-		Audience:          audience,
-		Issuer:            h.c.IssuerURL(ctx).String(),
-		ObfuscatedSubject: obfuscated,
-		TokenType:         resp.GetAccessTokenType(),
-		TokenUse:          string(resp.GetTokenUse()),
-		NotBefore:         resp.GetAccessRequester().GetRequestedAt().Unix(),
+		Active:               resp.IsActive(),
+		ClientID:             resp.GetAccessRequester().GetClient().GetID(),
+		Scope:                strings.Join(resp.GetAccessRequester().GetGrantedScopes(), " "),
+		ExpiresAt:            exp.Unix(),
+		IssuedAt:             resp.GetAccessRequester().GetRequestedAt().Unix(),
+		Subject:              session.GetSubject(),
+		Username:             session.GetUsername(),
+		Extra:                session.Extra,
+		Audience:             audience,
+		Issuer:               h.c.IssuerURL(ctx).String(),
+		ObfuscatedSubject:    obfuscated,
+		TokenType:            resp.GetAccessTokenType(),
+		TokenUse:             string(resp.GetTokenUse()),
+		NotBefore:            resp.GetAccessRequester().GetRequestedAt().Unix(),
+		Confirmation:         session.Confirmation,
+		AuthorizationDetails: session.AuthorizationDetails,
 	}); err != nil {
 		x.LogError(r, errorsx.WithStack(err), h.r.Logger())
 	}
@@ -851,14 +1041,55 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 	var session = NewSessionWithCustomClaims("", h.c.AllowedTopLevelClaims(r.Context()))
 	var ctx = r.Context()
 
+	if err := resourceIndicatorsToAudience(r); err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WriteAccessError(ctx, w, fosite.NewAccessRequest(session), err)
+		return
+	}
+
 	accessRequest, err := h.r.OAuth2Provider().NewAccessRequest(ctx, r, session)
 	if err != nil {
+		err = asInvalidTarget(h.r.AudienceStrategy(), accessRequest, err)
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
+		return
+	}
+
+	if err := narrowRefreshedAudience(accessRequest); err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
+		return
+	}
+
+	if err := h.bindDPoPProof(r, accessRequest); err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
+		return
+	}
+
+	if err := h.bindClientCertificate(r, accessRequest); err != nil {
 		h.logOrAudit(err, r)
 		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
 		return
 	}
 
-	if accessRequest.GetGrantTypes().ExactOne("client_credentials") || accessRequest.GetGrantTypes().ExactOne("urn:ietf:params:oauth:grant-type:jwt-bearer") {
+	if err := h.verifyExchangedTokenBindings(r, accessRequest); err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
+		return
+	}
+
+	if accessRequest.GetGrantTypes().ExactOne(GrantTypeCIBA) {
+		if err := h.setBackchannelAuthenticationSession(ctx, accessRequest, session); err != nil {
+			h.logOrAudit(err, r)
+			h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
+			return
+		}
+	}
+
+	isTokenExchange := accessRequest.GetGrantTypes().ExactOne(GrantTypeTokenExchange)
+	isPassword := accessRequest.GetGrantTypes().ExactOne(string(fosite.GrantTypePassword))
+	if accessRequest.GetGrantTypes().ExactOne("client_credentials") || accessRequest.GetGrantTypes().ExactOne("urn:ietf:params:oauth:grant-type:jwt-bearer") || isTokenExchange || isPassword {
 		var accessTokenKeyID string
 		if h.c.AccessTokenStrategy(ctx) == "jwt" {
 			accessTokenKeyID, err = h.r.AccessTokenJWTStrategy().GetPublicKeyID(ctx)
@@ -877,7 +1108,10 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 		session.KID = accessTokenKeyID
 		session.DefaultSession.Claims.Issuer = h.c.IssuerURL(r.Context()).String()
 		session.DefaultSession.Claims.IssuedAt = time.Now().UTC()
+	}
 
+	// The token exchange handler grants the scopes and audiences itself, because they are limited by the subject token.
+	if accessRequest.GetGrantTypes().ExactOne("client_credentials") || accessRequest.GetGrantTypes().ExactOne("urn:ietf:params:oauth:grant-type:jwt-bearer") || isPassword {
 		var scopes = accessRequest.GetRequestedScopes()
 
 		// Added for compatibility with MITREid
@@ -900,6 +1134,14 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if s, ok := accessRequest.GetSession().(*Session); ok {
+		if err := grantAuthorizationDetails(accessRequest, s); err != nil {
+			h.logOrAudit(err, r)
+			h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
+			return
+		}
+	}
+
 	for _, hook := range h.r.AccessRequestHooks() {
 		if err := hook(ctx, accessRequest); err != nil {
 			h.logOrAudit(err, r)
@@ -915,6 +1157,15 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s, ok := accessRequest.GetSession().(*Session); ok {
+		if s.Confirmation.jwkThumbprint() != "" {
+			accessResponse.SetTokenType(TokenTypeDPoP)
+		}
+		if len(s.AuthorizationDetails) > 0 {
+			accessResponse.SetExtra("authorization_details", s.AuthorizationDetails)
+		}
+	}
+
 	h.r.OAuth2Provider().WriteAccessResponse(ctx, w, accessRequest, accessResponse)
 }
 
@@ -938,11 +1189,45 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) oAuth2Authorize(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	var ctx = r.Context()
 
+	// Invalid resource indicators are reported once the redirect URI is known to be valid.
+	resourceErr := resourceIndicatorsToAudience(r)
+
 	authorizeRequest, err := h.r.OAuth2Provider().NewAuthorizeRequest(ctx, r)
 	if err != nil {
+		err = asInvalidTarget(h.r.AudienceStrategy(), authorizeRequest, err)
 		x.LogError(r, err, h.r.Logger())
 		h.writeAuthorizeError(w, r, authorizeRequest, err)
 		return
+	} else if resourceErr != nil {
+		x.LogAudit(r, resourceErr, h.r.AuditLogger())
+		h.writeAuthorizeError(w, r, authorizeRequest, resourceErr)
+		return
+	}
+
+	if _, err := requestedAuthorizationDetails(authorizeRequest); err != nil {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.writeAuthorizeError(w, r, authorizeRequest, err)
+		return
+	}
+
+	if err := validateJWTResponseMode(authorizeRequest); err != nil {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.writeAuthorizeError(w, r, authorizeRequest, err)
+		return
+	}
+
+	isPushedAuthorizeRequest := strings.HasPrefix(authorizeRequest.GetRequestForm().Get("request_uri"), h.c.GetPushedAuthorizeRequestURIPrefix(ctx))
+	if c, ok := authorizeRequest.GetClient().(*client.Client); ok && c.RequirePushedAuthorizationRequests && !isPushedAuthorizeRequest {
+		err := errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The OAuth 2.0 Client must use a pushed authorization request."))
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.writeAuthorizeError(w, r, authorizeRequest, err)
+		return
+	}
+
+	if err := h.resolvePushedAuthorizeRequest(r); err != nil {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.writeAuthorizeError(w, r, authorizeRequest, err)
+		return
 	}
 
 	session, err := h.r.ConsentStrategy().HandleOAuth2AuthorizationRequest(ctx, w, r, authorizeRequest)
@@ -1016,6 +1301,14 @@ func (h *Handler) oAuth2Authorize(w http.ResponseWriter, r *http.Request, _ http
 	}
 	claims.Add("sid", session.ConsentRequest.LoginSessionID)
 
+	if isPushedAuthorizeRequest {
+		if err := h.consumePushedAuthorizeRequest(r, authorizeRequest); err != nil {
+			x.LogError(r, err, h.r.Logger())
+			h.writeAuthorizeError(w, r, authorizeRequest, err)
+			return
+		}
+	}
+
 	// done
 	response, err := h.r.OAuth2Provider().NewAuthorizeResponse(ctx, authorizeRequest, &Session{
 		DefaultSession: &openid.DefaultSession{
@@ -1030,6 +1323,7 @@ func (h *Handler) oAuth2Authorize(w http.ResponseWriter, r *http.Request, _ http
 		KID:                   accessTokenKeyID,
 		ClientID:              authorizeRequest.GetClient().GetID(),
 		ConsentChallenge:      session.ID,
+		AuthorizationDetails:  session.GrantedAuthorizationDetails,
 		ExcludeNotBeforeClaim: h.c.ExcludeNotBeforeClaim(ctx),
 		AllowedTopLevelClaims: h.c.AllowedTopLevelClaims(ctx),
 	})
@@ -1079,6 +1373,7 @@ func (h *Handler) deleteOAuth2Token(w http.ResponseWriter, r *http.Request, _ ht
 		return
 	}
 
+	h.r.EventHook().Emit(r.Context(), events.Event{Type: events.TokenRevoked, ClientID: clientID})
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -0,0 +1,343 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
+)
+
+const BackchannelAuthenticationPath = "/oauth2/bc-authorize"
+
+// OpenID Connect Backchannel Authentication Request
+//
+// swagger:parameters oAuth2BackchannelAuthorize
+type oAuth2BackchannelAuthorizeParameters struct {
+	// in: formData
+	// required: true
+	Scope string `json:"scope"`
+
+	// in: formData
+	ClientNotificationToken string `json:"client_notification_token"`
+
+	// in: formData
+	ACRValues string `json:"acr_values"`
+
+	// in: formData
+	LoginHintToken string `json:"login_hint_token"`
+
+	// in: formData
+	IDTokenHint string `json:"id_token_hint"`
+
+	// in: formData
+	LoginHint string `json:"login_hint"`
+
+	// in: formData
+	BindingMessage string `json:"binding_message"`
+
+	// in: formData
+	RequestedExpiry int64 `json:"requested_expiry"`
+
+	// in: formData
+	Audience string `json:"audience"`
+}
+
+// OpenID Connect Backchannel Authentication Response
+//
+// swagger:model backchannelAuthentication
+type backchannelAuthentication struct {
+	// The identifier of the authentication request, which the client uses at the token endpoint.
+	//
+	// required: true
+	AuthReqID string `json:"auth_req_id"`
+
+	// The lifetime in seconds of the "auth_req_id".
+	//
+	// required: true
+	ExpiresIn int64 `json:"expires_in"`
+
+	// The minimum amount of time in seconds that the client should wait between polling requests to the token
+	// endpoint. Only set for clients using the poll delivery mode.
+	Interval int64 `json:"interval,omitempty"`
+}
+
+// backchannelAuthenticationLoginNotification is sent to the login app for every new backchannel authentication
+// request.
+type backchannelAuthenticationLoginNotification struct {
+	Challenge string `json:"backchannel_authentication_challenge"`
+}
+
+// swagger:route POST /oauth2/bc-authorize oidc oAuth2BackchannelAuthorize
+//
+// # OpenID Connect Backchannel Authentication Endpoint
+//
+// This endpoint implements the authentication request of OpenID Connect Client-Initiated Backchannel
+// Authentication (CIBA). The end-user is identified by one of the hints and authenticated out of band by the
+// login app configured at `urls.backchannel_authentication`. The client then obtains the tokens from the token
+// endpoint using the "urn:openid:params:grant-type:ciba" grant type, either by polling or once it was notified.
+//
+//	Consumes:
+//	- application/x-www-form-urlencoded
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  basic:
+//
+//	Responses:
+//	  200: backchannelAuthentication
+//	  default: errorOAuth2
+func (h *Handler) oAuth2BackchannelAuthorize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to parse HTTP body, make sure to send a properly formatted form request body.").WithWrap(err).WithDebug(err.Error())))
+		return
+	}
+
+	authenticator, ok := h.r.OAuth2Provider().(clientAuthenticator)
+	if !ok {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrServerError.WithDebug("The OAuth 2.0 provider does not support client authentication.")))
+		return
+	}
+
+	fc, err := authenticator.AuthenticateClient(ctx, r, r.PostForm)
+	if err != nil {
+		h.logOrAudit(err, r)
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	c, ok := fc.(*client.Client)
+	if !ok {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrServerError.WithDebugf("The client must be of type *client.Client but got type: %T", fc)))
+		return
+	} else if c.IsPublic() {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrUnauthorizedClient.WithHint("Backchannel authentication requests must be made by confidential OAuth 2.0 Clients.")))
+		return
+	} else if !c.GetGrantTypes().Has(GrantTypeCIBA) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrUnauthorizedClient.WithHintf("The OAuth 2.0 Client is not allowed to use authorization grant \"%s\".", GrantTypeCIBA)))
+		return
+	}
+
+	request, err := h.newBackchannelAuthenticationRequest(ctx, r, c)
+	if err != nil {
+		h.logOrAudit(err, r)
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	loginURL := h.c.BackchannelAuthenticationLoginURL(ctx)
+	if loginURL == nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrServerError.WithHint("Backchannel authentication is not enabled because no login endpoint is configured.")))
+		return
+	}
+
+	if err := h.r.ConsentManager().CreateBackchannelAuthenticationRequest(ctx, request); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.notifyBackchannelLogin(ctx, loginURL.String(), request.ID); err != nil {
+		h.logOrAudit(err, r)
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	var interval int64
+	if c.BackchannelTokenDeliveryMode != client.BackchannelTokenDeliveryModePing {
+		interval = int64(h.c.BackchannelAuthenticationPollingInterval(ctx) / time.Second)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	h.r.Writer().Write(w, r, &backchannelAuthentication{
+		AuthReqID: consent.DeriveAuthReqID(h.c.GetGlobalSecret(ctx), request.ID),
+		ExpiresIn: int64(request.ExpiresAt.Sub(request.RequestedAt) / time.Second),
+		Interval:  interval,
+	})
+}
+
+func (h *Handler) newBackchannelAuthenticationRequest(ctx context.Context, r *http.Request, c *client.Client) (*consent.BackchannelAuthenticationRequest, error) {
+	form := r.PostForm
+
+	if form.Get("request") != "" {
+		return nil, errorsx.WithStack(fosite.ErrRequestNotSupported.WithHint("Signed backchannel authentication requests are not supported."))
+	}
+
+	scopes := fosite.RemoveEmpty(strings.Split(form.Get("scope"), " "))
+	if !fosite.Arguments(scopes).Has("openid") {
+		return nil, errorsx.WithStack(fosite.ErrInvalidScope.WithHint("Backchannel authentication requests must include the 'openid' scope."))
+	}
+	for _, scope := range scopes {
+		if !h.r.Config().GetScopeStrategy(ctx)(c.GetScopes(), scope) {
+			return nil, errorsx.WithStack(fosite.ErrInvalidScope.WithHintf("The OAuth 2.0 Client is not allowed to request scope '%s'.", scope))
+		}
+	}
+
+	audience := fosite.GetAudiences(form)
+	if err := h.r.AudienceStrategy()(c.GetAudience(), audience); err != nil {
+		return nil, err
+	}
+
+	var hints int
+	for _, k := range []string{"login_hint", "login_hint_token", "id_token_hint"} {
+		if form.Get(k) != "" {
+			hints++
+		}
+	}
+	if hints != 1 {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Exactly one of the parameters 'login_hint', 'login_hint_token', or 'id_token_hint' must be set."))
+	}
+
+	var idTokenHintClaims sqlxx.MapStringInterface
+	if hint := form.Get("id_token_hint"); hint != "" {
+		token, err := h.r.OpenIDJWTStrategy().Decode(ctx, hint)
+		if ve := new(jwt.ValidationError); errors.As(err, &ve) && ve.Errors == jwt.ValidationErrorExpired {
+			// Expired is ok
+		} else if err != nil {
+			return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to validate the 'id_token_hint'.").WithWrap(err).WithDebug(err.Error()))
+		}
+		idTokenHintClaims = sqlxx.MapStringInterface(token.Claims)
+	}
+
+	var notificationToken string
+	if c.BackchannelTokenDeliveryMode == client.BackchannelTokenDeliveryModePing {
+		notificationToken = form.Get("client_notification_token")
+		if notificationToken == "" {
+			return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The parameter 'client_notification_token' is required for OAuth 2.0 Clients using the ping delivery mode."))
+		}
+	}
+
+	lifespan := h.c.BackchannelAuthenticationRequestLifespan(ctx)
+	if raw := form.Get("requested_expiry"); raw != "" {
+		seconds, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || seconds <= 0 {
+			return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The parameter 'requested_expiry' must be a positive integer."))
+		} else if requested := time.Duration(seconds) * time.Second; requested < lifespan {
+			lifespan = requested
+		}
+	}
+
+	challenge := strings.Replace(uuid.New(), "-", "", -1)
+	secret := h.c.GetGlobalSecret(ctx)
+
+	now := time.Now().UTC().Round(time.Second)
+	return &consent.BackchannelAuthenticationRequest{
+		ID:                      challenge,
+		AuthReqIDSignature:      consent.AuthReqIDSignature(secret, consent.DeriveAuthReqID(secret, challenge)),
+		Client:                  c,
+		RequestedScope:          scopes,
+		RequestedAudience:       audience,
+		LoginHint:               form.Get("login_hint"),
+		LoginHintToken:          form.Get("login_hint_token"),
+		IDTokenHintClaims:       idTokenHintClaims,
+		BindingMessage:          form.Get("binding_message"),
+		ACRValues:               fosite.RemoveEmpty(strings.Split(form.Get("acr_values"), " ")),
+		ClientNotificationToken: notificationToken,
+		RequestedAt:             now,
+		ExpiresAt:               now.Add(lifespan),
+	}, nil
+}
+
+// notifyBackchannelLogin hands the backchannel authentication request off to the login app.
+func (h *Handler) notifyBackchannelLogin(ctx context.Context, loginURL, challenge string) error {
+	body, err := json.Marshal(&backchannelAuthenticationLoginNotification{Challenge: challenge})
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, loginURL, bytes.NewReader(body))
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	res, err := h.r.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrTemporarilyUnavailable.WithHint("Unable to reach the backchannel authentication login endpoint.").WithWrap(err).WithDebug(err.Error()))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errorsx.WithStack(fosite.ErrTemporarilyUnavailable.WithHint("The backchannel authentication login endpoint responded with an error.").WithDebugf("The login endpoint responded with HTTP status code: %s", res.Status))
+	}
+	return nil
+}
+
+// setBackchannelAuthenticationSession populates the session of a token request using the CIBA grant from the
+// accepted backchannel authentication request. The grant handler already ensured that the request was accepted.
+func (h *Handler) setBackchannelAuthenticationSession(ctx context.Context, ar fosite.AccessRequester, session *Session) error {
+	br, err := getBackchannelAuthenticationRequest(ctx, h.r.ConsentManager(), h.c.GetGlobalSecret(ctx), ar)
+	if err != nil {
+		return err
+	}
+
+	openIDKeyID, err := h.r.OpenIDJWTStrategy().GetPublicKeyID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var accessTokenKeyID string
+	if h.c.AccessTokenStrategy(ctx) == "jwt" {
+		accessTokenKeyID, err = h.r.AccessTokenJWTStrategy().GetPublicKeyID(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	obfuscatedSubject, err := h.r.ConsentStrategy().ObfuscateSubjectIdentifier(ctx, ar.GetClient(), br.Subject, br.ForceSubjectIdentifier)
+	if err != nil {
+		return err
+	}
+
+	claims := &jwt.IDTokenClaims{
+		Subject:                             obfuscatedSubject,
+		Issuer:                              h.c.IssuerURL(ctx).String(),
+		AuthTime:                            time.Time(br.AuthenticatedAt),
+		RequestedAt:                         br.RequestedAt,
+		Extra:                               br.SessionIDToken,
+		AuthenticationContextClassReference: br.ACR,
+		AuthenticationMethodsReferences:     br.AMR,
+		Audience:                            []string{ar.GetClient().GetID()},
+		IssuedAt:                            time.Now().Truncate(time.Second).UTC(),
+	}
+	claims.Add(ClaimAuthReqID, ar.GetRequestForm().Get("auth_req_id"))
+
+	session.DefaultSession = &openid.DefaultSession{
+		Claims: claims,
+		Headers: &jwt.Headers{Extra: map[string]interface{}{
+			// required for lookup on jwk endpoint
+			"kid": openIDKeyID,
+		}},
+		Subject:   br.Subject,
+		ExpiresAt: session.DefaultSession.ExpiresAt,
+	}
+	session.Extra = br.SessionAccessToken
+	session.KID = accessTokenKeyID
+	session.ClientID = ar.GetClient().GetID()
+	session.ExcludeNotBeforeClaim = h.c.ExcludeNotBeforeClaim(ctx)
+	return nil
+}
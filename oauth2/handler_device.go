@@ -0,0 +1,336 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/hydra/consent"
+	"github.com/ory/hydra/x"
+)
+
+const (
+	DeviceAuthPath          = "/oauth2/device/auth"
+	DeviceVerifyPath        = "/oauth2/device/verify"
+	DefaultDeviceVerifyPath = "/oauth2/fallbacks/device"
+	DefaultDeviceDonePath   = "/oauth2/fallbacks/device/done"
+)
+
+type clientAuthenticator interface {
+	AuthenticateClient(ctx context.Context, r *http.Request, form url.Values) (fosite.Client, error)
+}
+
+// OAuth 2.0 Device Authorization Request
+//
+// swagger:parameters oAuth2DeviceAuthorize
+type oAuth2DeviceAuthorizeParameters struct {
+	// in: formData
+	// required: true
+	ClientID string `json:"client_id"`
+
+	// in: formData
+	Scope string `json:"scope"`
+
+	// in: formData
+	Audience string `json:"audience"`
+}
+
+// OAuth 2.0 Device Authorization Response
+//
+// swagger:model deviceAuthorization
+type deviceAuthorization struct {
+	// The device verification code.
+	//
+	// required: true
+	DeviceCode string `json:"device_code"`
+
+	// The end-user verification code.
+	//
+	// required: true
+	// example: WDJB-MJHT
+	UserCode string `json:"user_code"`
+
+	// The end-user verification URI on the authorization server.
+	//
+	// required: true
+	VerificationURI string `json:"verification_uri"`
+
+	// A verification URI that includes the "user_code", designed for non-textual transmission.
+	VerificationURIComplete string `json:"verification_uri_complete"`
+
+	// The lifetime in seconds of the "device_code" and "user_code".
+	//
+	// required: true
+	ExpiresIn int64 `json:"expires_in"`
+
+	// The minimum amount of time in seconds that the client should wait between polling requests to the token endpoint.
+	Interval int64 `json:"interval"`
+}
+
+// swagger:route POST /oauth2/device/auth oAuth2 oAuth2DeviceAuthorize
+//
+// # OAuth 2.0 Device Authorization Endpoint
+//
+// This endpoint implements the device authorization request of the OAuth 2.0 Device Authorization Grant
+// (RFC 8628). The device displays the returned user code and verification URI to the end-user and polls
+// the token endpoint using the "urn:ietf:params:oauth:grant-type:device_code" grant type.
+//
+//	Consumes:
+//	- application/x-www-form-urlencoded
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  basic:
+//
+//	Responses:
+//	  200: deviceAuthorization
+//	  default: errorOAuth2
+func (h *Handler) oAuth2DeviceAuthorize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to parse HTTP body, make sure to send a properly formatted form request body.").WithWrap(err).WithDebug(err.Error())))
+		return
+	}
+
+	authenticator, ok := h.r.OAuth2Provider().(clientAuthenticator)
+	if !ok {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrServerError.WithDebug("The OAuth 2.0 provider does not support client authentication.")))
+		return
+	}
+
+	c, err := authenticator.AuthenticateClient(ctx, r, r.PostForm)
+	if err != nil {
+		h.logOrAudit(err, r)
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if !c.GetGrantTypes().Has(GrantTypeDeviceCode) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrUnauthorizedClient.WithHintf("The OAuth 2.0 Client is not allowed to use authorization grant \"%s\".", GrantTypeDeviceCode)))
+		return
+	}
+
+	request := fosite.NewRequest()
+	request.ID = uuid.New()
+	request.Client = c
+	// Client credentials which may be part of the form must not be persisted.
+	for _, k := range []string{"client_id", "scope", "audience"} {
+		if v, ok := r.PostForm[k]; ok {
+			request.Form[k] = v
+		}
+	}
+	request.Session = NewSession("")
+
+	for _, scope := range fosite.RemoveEmpty(strings.Split(r.PostForm.Get("scope"), " ")) {
+		if !h.r.Config().GetScopeStrategy(ctx)(c.GetScopes(), scope) {
+			h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidScope.WithHintf("The OAuth 2.0 Client is not allowed to request scope '%s'.", scope)))
+			return
+		}
+		request.AppendRequestedScope(scope)
+	}
+
+	audience := fosite.GetAudiences(r.PostForm)
+	if err := h.r.AudienceStrategy()(c.GetAudience(), audience); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	request.SetRequestedAudience(audience)
+
+	deviceCode, deviceCodeSignature, err := h.r.DeviceCodeStrategy().GenerateDeviceCode(ctx)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error())))
+		return
+	}
+
+	userCode, userCodeSignature, err := h.r.DeviceCodeStrategy().GenerateUserCode(ctx)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error())))
+		return
+	}
+
+	lifespan := h.c.GetDeviceAndUserCodeLifespan(ctx)
+	if err := h.r.DeviceCodeStorage().CreateDeviceCodeSession(ctx, deviceCodeSignature, userCodeSignature, request, time.Now().UTC().Add(lifespan)); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	h.r.Writer().Write(w, r, &deviceAuthorization{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         h.c.DeviceVerificationURL(ctx).String(),
+		VerificationURIComplete: urlx.CopyWithQuery(h.c.OAuth2DeviceVerifyURL(ctx), url.Values{"user_code": {userCode}}).String(),
+		ExpiresIn:               int64(lifespan / time.Second),
+		Interval:                int64(h.c.GetDeviceAuthTokenPollingInterval(ctx) / time.Second),
+	})
+}
+
+// swagger:route GET /oauth2/device/verify oAuth2 oAuth2DeviceVerify
+//
+// # OAuth 2.0 Device Verification Endpoint
+//
+// The end-user is sent to this endpoint with the user code displayed on the device. The endpoint
+// starts the login and consent flow and, once the end-user granted the request, redirects the
+// end-user to the device done URL.
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  302: emptyResponse
+func (h *Handler) oAuth2DeviceVerify(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	userCode := r.URL.Query().Get("user_code")
+	if userCode == "" {
+		http.Redirect(w, r, h.c.DeviceVerificationURL(ctx).String(), http.StatusFound)
+		return
+	}
+
+	ds, err := h.r.DeviceCodeStorage().GetDeviceCodeSessionByUserCode(ctx, h.r.DeviceCodeStrategy().UserCodeSignature(ctx, userCode), NewSession(""))
+	if errors.Is(err, fosite.ErrNotFound) {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.forwardError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The user code is unknown.")))
+		return
+	} else if err != nil {
+		x.LogError(r, err, h.r.Logger())
+		h.forwardError(w, r, err)
+		return
+	}
+
+	if ds.State != DeviceCodeStatePending || ds.ExpiresAt.Before(time.Now().UTC()) {
+		h.forwardError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The user code has expired or has already been used.")))
+		return
+	}
+
+	form := url.Values{}
+	for k, v := range ds.Request.GetRequestForm() {
+		form[k] = v
+	}
+	// Only the verifiers are taken from the query, all other parameters were set by the device.
+	for _, k := range []string{"login_verifier", "consent_verifier"} {
+		if v := r.URL.Query().Get(k); v != "" {
+			form.Set(k, v)
+		}
+	}
+	form.Del("prompt")
+
+	authorizeRequest := fosite.NewAuthorizeRequest()
+	authorizeRequest.Merge(ds.Request)
+	authorizeRequest.ID = ds.Request.GetID()
+	authorizeRequest.Form = form
+
+	session, err := h.r.ConsentStrategy().HandleOAuth2DeviceAuthorizationRequest(ctx, w, r, authorizeRequest)
+	if errors.Is(err, consent.ErrAbortOAuth2Request) {
+		x.LogAudit(r, nil, h.r.AuditLogger())
+		return
+	} else if errors.Is(err, consent.ErrRequestRejected) {
+		// Only an explicit rejection by the login or consent app denies the device code. Other errors, such as
+		// an invalid verifier, must not allow anyone who knows the user code to end the device flow.
+		x.LogAudit(r, err, h.r.AuditLogger())
+		if err := h.r.DeviceCodeStorage().DenyDeviceCodeSession(ctx, ds.Signature); err != nil {
+			x.LogError(r, err, h.r.Logger())
+		}
+		h.forwardError(w, r, err)
+		return
+	} else if e := &(fosite.RFC6749Error{}); errors.As(err, &e) && e.CodeField != http.StatusInternalServerError {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.forwardError(w, r, err)
+		return
+	} else if err != nil {
+		x.LogError(r, err, h.r.Logger())
+		h.forwardError(w, r, err)
+		return
+	}
+
+	for _, scope := range session.GrantedScope {
+		authorizeRequest.GrantScope(scope)
+	}
+
+	for _, audience := range session.GrantedAudience {
+		authorizeRequest.GrantAudience(audience)
+	}
+
+	openIDKeyID, err := h.r.OpenIDJWTStrategy().GetPublicKeyID(ctx)
+	if err != nil {
+		x.LogError(r, err, h.r.Logger())
+		h.forwardError(w, r, err)
+		return
+	}
+
+	var accessTokenKeyID string
+	if h.c.AccessTokenStrategy(ctx) == "jwt" {
+		accessTokenKeyID, err = h.r.AccessTokenJWTStrategy().GetPublicKeyID(ctx)
+		if err != nil {
+			x.LogError(r, err, h.r.Logger())
+			h.forwardError(w, r, err)
+			return
+		}
+	}
+
+	obfuscatedSubject, err := h.r.ConsentStrategy().ObfuscateSubjectIdentifier(ctx, authorizeRequest.GetClient(), session.ConsentRequest.Subject, session.ConsentRequest.ForceSubjectIdentifier)
+	if err != nil {
+		h.logOrAudit(err, r)
+		h.forwardError(w, r, err)
+		return
+	}
+
+	authorizeRequest.SetID(session.ID)
+	claims := &jwt.IDTokenClaims{
+		Subject:                             obfuscatedSubject,
+		Issuer:                              h.c.IssuerURL(ctx).String(),
+		AuthTime:                            time.Time(session.AuthenticatedAt),
+		RequestedAt:                         session.RequestedAt,
+		Extra:                               session.Session.IDToken,
+		AuthenticationContextClassReference: session.ConsentRequest.ACR,
+		AuthenticationMethodsReferences:     session.ConsentRequest.AMR,
+		Audience:                            []string{authorizeRequest.GetClient().GetID()},
+		IssuedAt:                            time.Now().Truncate(time.Second).UTC(),
+	}
+	claims.Add("sid", session.ConsentRequest.LoginSessionID)
+
+	authorizeRequest.SetSession(&Session{
+		DefaultSession: &openid.DefaultSession{
+			Claims: claims,
+			Headers: &jwt.Headers{Extra: map[string]interface{}{
+				// required for lookup on jwk endpoint
+				"kid": openIDKeyID,
+			}},
+			Subject: session.ConsentRequest.Subject,
+		},
+		Extra:                 session.Session.AccessToken,
+		KID:                   accessTokenKeyID,
+		ClientID:              authorizeRequest.GetClient().GetID(),
+		ConsentChallenge:      session.ID,
+		ExcludeNotBeforeClaim: h.c.ExcludeNotBeforeClaim(ctx),
+		AllowedTopLevelClaims: h.c.AllowedTopLevelClaims(ctx),
+	})
+
+	if err := h.r.DeviceCodeStorage().ApproveDeviceCodeSession(ctx, ds.Signature, authorizeRequest); err != nil {
+		x.LogError(r, err, h.r.Logger())
+		h.forwardError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, h.c.DeviceDoneURL(ctx).String(), http.StatusFound)
+}
@@ -0,0 +1,149 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+)
+
+const PushedAuthorizationRequestPath = "/oauth2/par"
+
+// OAuth 2.0 Pushed Authorization Request
+//
+// swagger:parameters oAuth2PushedAuthorize
+type oAuth2PushedAuthorizeParameters struct {
+	// in: formData
+	// required: true
+	ClientID string `json:"client_id"`
+
+	// in: formData
+	// required: true
+	ResponseType string `json:"response_type"`
+
+	// in: formData
+	RedirectURI string `json:"redirect_uri"`
+
+	// in: formData
+	Scope string `json:"scope"`
+
+	// in: formData
+	State string `json:"state"`
+}
+
+// OAuth 2.0 Pushed Authorization Response
+//
+// swagger:model pushedAuthorization
+type pushedAuthorization struct {
+	// The request URI which the client passes to the authorization endpoint.
+	//
+	// required: true
+	// example: urn:ietf:params:oauth:request_uri:6esc_11ACC5bwc014ltc14eY22c
+	RequestURI string `json:"request_uri"`
+
+	// The lifetime of the request URI in seconds.
+	//
+	// required: true
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// swagger:route POST /oauth2/par oAuth2 oAuth2PushedAuthorize
+//
+// # OAuth 2.0 Pushed Authorization Request Endpoint
+//
+// This endpoint implements Pushed Authorization Requests (RFC 9126). The client pushes the
+// parameters of an authorization request and receives a "request_uri" which it then passes
+// together with its "client_id" to the OAuth 2.0 Authorize Endpoint.
+//
+//	Consumes:
+//	- application/x-www-form-urlencoded
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  basic:
+//
+//	Responses:
+//	  201: pushedAuthorization
+//	  default: errorOAuth2
+func (h *Handler) oAuth2PushedAuthorize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := resourceIndicatorsToAudience(r); err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WritePushedAuthorizeError(ctx, w, fosite.NewAuthorizeRequest(), err)
+		return
+	}
+
+	ar, err := h.r.OAuth2Provider().NewPushedAuthorizeRequest(ctx, r)
+	if err != nil {
+		err = asInvalidTarget(h.r.AudienceStrategy(), ar, err)
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WritePushedAuthorizeError(ctx, w, ar, err)
+		return
+	}
+
+	if _, err := requestedAuthorizationDetails(ar); err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WritePushedAuthorizeError(ctx, w, ar, err)
+		return
+	}
+
+	// Client credentials which may be part of the form must not be persisted.
+	for _, k := range []string{"client_secret", "client_assertion", "client_assertion_type"} {
+		ar.GetRequestForm().Del(k)
+	}
+
+	resp, err := h.r.OAuth2Provider().NewPushedAuthorizeResponse(ctx, ar, NewSession(""))
+	if err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WritePushedAuthorizeError(ctx, w, ar, err)
+		return
+	}
+
+	h.r.OAuth2Provider().WritePushedAuthorizeResponse(ctx, w, ar, resp)
+}
+
+// resolvePushedAuthorizeRequest marks the pushed authorization request referenced by the request_uri as resolved
+// when the login and consent flow starts. This extends its lifetime to the maximum age of the login and consent
+// flow, which may take longer than the lifespan of the request_uri, and rejects any further attempt to start a
+// flow with the same request_uri. It must only be called once the authorization request was validated, so that an
+// invalid request, for example one with a client_id which does not match the pushed authorization request, does not
+// use up the request_uri.
+func (h *Handler) resolvePushedAuthorizeRequest(r *http.Request) error {
+	ctx := r.Context()
+	requestURI := r.Form.Get("request_uri")
+	if !strings.HasPrefix(requestURI, h.c.GetPushedAuthorizeRequestURIPrefix(ctx)) ||
+		r.Form.Get("login_verifier") != "" || r.Form.Get("consent_verifier") != "" {
+		return nil
+	}
+
+	if err := h.r.OAuth2Storage().ResolvePARSession(ctx, requestURI, time.Now().Add(h.c.ConsentRequestMaxAge(ctx))); errors.Is(err, fosite.ErrNotFound) {
+		return errorsx.WithStack(fosite.ErrInvalidRequestURI.WithHint("The pushed authorization request has expired or has already been used."))
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// consumePushedAuthorizeRequest deletes the pushed authorization request referenced by the
+// authorization request, ensuring that a request_uri is only used for a single authorization
+// response.
+func (h *Handler) consumePushedAuthorizeRequest(r *http.Request, ar fosite.AuthorizeRequester) error {
+	if err := h.r.OAuth2Storage().DeletePARSession(r.Context(), ar.GetRequestForm().Get("request_uri")); errors.Is(err, fosite.ErrNotFound) {
+		return errorsx.WithStack(fosite.ErrInvalidRequestURI.WithHint("The pushed authorization request has already been used."))
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
@@ -3,6 +3,8 @@
 
 package oauth2
 
+import "github.com/ory/hydra/consent"
+
 // Introspection contains an access token's session data as specified by
 // [IETF RFC 7662](https://tools.ietf.org/html/rfc7662)
 //
@@ -62,7 +64,7 @@ type Introspection struct {
 	// IssuerURL is a string representing the issuer of this token
 	Issuer string `json:"iss"`
 
-	// TokenType is the introspected token's type, typically `Bearer`.
+	// TokenType is the introspected token's type, typically `Bearer`, or `DPoP` if the token is bound to a DPoP key.
 	TokenType string `json:"token_type"`
 
 	// TokenUse is the introspected token's use, for example `access_token` or `refresh_token`.
@@ -70,4 +72,11 @@ type Introspection struct {
 
 	// Extra is arbitrary data set by the session.
 	Extra map[string]interface{} `json:"ext,omitempty"`
+
+	// Confirmation contains the thumbprint of the DPoP key or client certificate the token is bound to.
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+
+	// AuthorizationDetails contains the authorization details granted to the token, see
+	// [RFC 9396](https://www.rfc-editor.org/rfc/rfc9396).
+	AuthorizationDetails consent.AuthorizationDetails `json:"authorization_details,omitempty"`
 }
@@ -0,0 +1,263 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/x/errorsx"
+
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/jwk"
+)
+
+// jwtResponseLifespan is the lifetime of JWT secured authorization responses. The response is consumed by the
+// client right after the redirect, so the JARM specification recommends a short lifetime.
+const jwtResponseLifespan = 10 * time.Minute
+
+const defaultAuthorizationEncryptedResponseEnc = "A128CBC-HS256"
+
+type jwtResponseModeConfig interface {
+	fosite.FormPostHTMLTemplateProvider
+	fosite.JWKSFetcherStrategyProvider
+	fosite.SendDebugMessagesToClientsProvider
+	fosite.UseLegacyErrorFormatProvider
+	IssuerURL(ctx context.Context) *url.URL
+}
+
+var _ fosite.ResponseModeHandler = (*JWTResponseModeHandler)(nil)
+
+// JWTResponseModeHandler implements JWT Secured Authorization Response Mode for OAuth 2.0 (JARM). Authorization
+// responses and errors are wrapped in a JWT signed with the OpenID Connect key set, which is encrypted as well
+// if the client has set `authorization_encrypted_response_alg`.
+type JWTResponseModeHandler struct {
+	Config jwtResponseModeConfig
+	Signer jwk.JWTSigner
+}
+
+func NewJWTResponseModeHandler(config jwtResponseModeConfig, signer jwk.JWTSigner) *JWTResponseModeHandler {
+	return &JWTResponseModeHandler{Config: config, Signer: signer}
+}
+
+func (h *JWTResponseModeHandler) ResponseModes() fosite.ResponseModeTypes {
+	return fosite.ResponseModeTypes{
+		client.ResponseModeJWT,
+		client.ResponseModeQueryJWT,
+		client.ResponseModeFragmentJWT,
+		client.ResponseModeFormPostJWT,
+	}
+}
+
+func (h *JWTResponseModeHandler) WriteAuthorizeResponse(ctx context.Context, rw http.ResponseWriter, ar fosite.AuthorizeRequester, resp fosite.AuthorizeResponder) {
+	if err := validateJWTResponseMode(ar); err != nil {
+		h.writeJSONError(ctx, rw, err)
+		return
+	}
+
+	token, err := h.generate(ctx, ar, resp.GetParameters())
+	if err != nil {
+		h.writeJSONError(ctx, rw, err)
+		return
+	}
+
+	h.write(ctx, rw, ar, token)
+}
+
+func (h *JWTResponseModeHandler) WriteAuthorizeError(ctx context.Context, rw http.ResponseWriter, ar fosite.AuthorizeRequester, err error) {
+	if !ar.IsRedirectURIValid() {
+		h.writeJSONError(ctx, rw, err)
+		return
+	}
+
+	rfcerr := fosite.ErrorToRFC6749Error(err).
+		WithLegacyFormat(h.Config.GetUseLegacyErrorFormat(ctx)).
+		WithExposeDebug(h.Config.GetSendDebugMessagesToClients(ctx))
+	parameters := rfcerr.ToValues()
+	parameters.Set("state", ar.GetState())
+
+	token, err := h.generate(ctx, ar, parameters)
+	if err != nil {
+		h.writeJSONError(ctx, rw, err)
+		return
+	}
+
+	h.write(ctx, rw, ar, token)
+}
+
+func (h *JWTResponseModeHandler) write(ctx context.Context, rw http.ResponseWriter, ar fosite.AuthorizeRequester, token string) {
+	redirectURI := ar.GetRedirectURI()
+	parameters := url.Values{"response": {token}}
+
+	switch responseMode(ar) {
+	case client.ResponseModeFormPostJWT:
+		rw.Header().Set("Content-Type", "text/html;charset=UTF-8")
+		fosite.WriteAuthorizeFormPostResponse(redirectURI.String(), parameters, h.Config.GetFormPostHTMLTemplate(ctx), rw)
+		return
+	case client.ResponseModeFragmentJWT:
+		// The endpoint URI MUST NOT include a fragment component.
+		redirectURI.Fragment = ""
+		rw.Header().Set("Location", redirectURI.String()+"#"+parameters.Encode())
+	default:
+		q := redirectURI.Query()
+		q.Set("response", token)
+		redirectURI.RawQuery = q.Encode()
+		rw.Header().Set("Location", redirectURI.String())
+	}
+	rw.WriteHeader(http.StatusSeeOther)
+}
+
+// responseMode resolves the "jwt" response mode to the default mode of the response type. Responses containing
+// tokens must never be sent in the query.
+func responseMode(ar fosite.AuthorizeRequester) fosite.ResponseModeType {
+	if rm := ar.GetResponseMode(); rm != client.ResponseModeJWT {
+		return rm
+	} else if ar.GetResponseTypes().ExactOne("code") {
+		return client.ResponseModeQueryJWT
+	}
+	return client.ResponseModeFragmentJWT
+}
+
+// validateJWTResponseMode rejects the "query.jwt" response mode for response types which return tokens from the
+// authorization endpoint, unless the response is encrypted (JARM Section 2.3.1).
+func validateJWTResponseMode(ar fosite.AuthorizeRequester) error {
+	if responseMode(ar) != client.ResponseModeQueryJWT || !ar.GetResponseTypes().HasOneOf("token", "id_token") {
+		return nil
+	} else if c, ok := ar.GetClient().(*client.Client); ok && c.AuthorizationEncryptedResponseAlg != "" {
+		return nil
+	}
+	return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Response mode 'query.jwt' can only be used with response types which return tokens if the OAuth 2.0 Client encrypts authorization responses."))
+}
+
+func (h *JWTResponseModeHandler) generate(ctx context.Context, ar fosite.AuthorizeRequester, parameters url.Values) (string, error) {
+	c, ok := ar.GetClient().(*client.Client)
+	if !ok {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithDebugf("The client must be of type *client.Client but got type: %T", ar.GetClient()))
+	}
+
+	key, err := h.Signer.GetPublicKey(ctx)
+	if err != nil {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	} else if c.AuthorizationSignedResponseAlg != "" && c.AuthorizationSignedResponseAlg != key.Algorithm {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithHintf("The OAuth 2.0 Client requires authorization responses signed with '%s' but the signing key uses '%s'.", c.AuthorizationSignedResponseAlg, key.Algorithm))
+	}
+
+	now := time.Now().UTC()
+	claims := jwt.MapClaims{
+		"iss": h.Config.IssuerURL(ctx).String(),
+		"aud": c.GetID(),
+		"exp": now.Add(jwtResponseLifespan).Unix(),
+		"iat": now.Unix(),
+		"jti": uuid.New(),
+	}
+	for k := range parameters {
+		claims[k] = parameters.Get(k)
+	}
+
+	token, _, err := h.Signer.Generate(ctx, claims, &jwt.Headers{
+		Extra: map[string]interface{}{"kid": key.KeyID},
+	})
+	if err != nil {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	if c.AuthorizationEncryptedResponseAlg == "" {
+		return token, nil
+	}
+	return h.encrypt(ctx, c, token)
+}
+
+func (h *JWTResponseModeHandler) encrypt(ctx context.Context, c *client.Client, token string) (string, error) {
+	keys := c.GetJSONWebKeys()
+	if c.GetJSONWebKeysURI() != "" {
+		var err error
+		keys, err = h.Config.GetJWKSFetcherStrategy(ctx).Resolve(ctx, c.GetJSONWebKeysURI(), false)
+		if err != nil {
+			return "", errorsx.WithStack(fosite.ErrServerError.WithHint("Unable to fetch the OAuth 2.0 Client's JSON Web Keys to encrypt the authorization response.").WithWrap(err).WithDebug(err.Error()))
+		}
+	}
+
+	alg := c.AuthorizationEncryptedResponseAlg
+	key := findEncryptionKey(keys, alg)
+	if key == nil {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithHintf("The OAuth 2.0 Client has no JSON Web Key suitable for encryption algorithm '%s'.", alg))
+	}
+
+	enc := c.AuthorizationEncryptedResponseEnc
+	if enc == "" {
+		enc = defaultAuthorizationEncryptedResponseEnc
+	}
+
+	encrypter, err := jose.NewEncrypter(
+		jose.ContentEncryption(enc),
+		jose.Recipient{Algorithm: jose.KeyAlgorithm(alg), Key: key.Key, KeyID: key.KeyID},
+		(&jose.EncrypterOptions{}).WithType("JWT").WithContentType("JWT"),
+	)
+	if err != nil {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	object, err := encrypter.Encrypt([]byte(token))
+	if err != nil {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	return object.CompactSerialize()
+}
+
+// findEncryptionKey returns the first public key of the set which may be used for encryption with the given
+// key management algorithm.
+func findEncryptionKey(keys *jose.JSONWebKeySet, alg string) *jose.JSONWebKey {
+	if keys == nil {
+		return nil
+	}
+
+	for k := range keys.Keys {
+		key := keys.Keys[k].Public()
+		if key.Use != "" && key.Use != "enc" {
+			continue
+		} else if key.Algorithm != "" && key.Algorithm != alg {
+			continue
+		}
+
+		switch key.Key.(type) {
+		case *rsa.PublicKey:
+			if strings.HasPrefix(alg, "RSA-OAEP") {
+				return &key
+			}
+		case *ecdsa.PublicKey:
+			if strings.HasPrefix(alg, "ECDH-ES") {
+				return &key
+			}
+		}
+	}
+	return nil
+}
+
+func (h *JWTResponseModeHandler) writeJSONError(ctx context.Context, rw http.ResponseWriter, err error) {
+	rfcerr := fosite.ErrorToRFC6749Error(err).
+		WithLegacyFormat(h.Config.GetUseLegacyErrorFormat(ctx)).
+		WithExposeDebug(h.Config.GetSendDebugMessagesToClients(ctx))
+
+	js, err := json.Marshal(rfcerr)
+	if err != nil {
+		http.Error(rw, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	rw.WriteHeader(rfcerr.CodeField)
+	_, _ = rw.Write(js)
+}
@@ -0,0 +1,222 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/contextx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	"github.com/ory/hydra/x"
+)
+
+func TestJWTResponseMode(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+
+	publicKey, err := reg.OpenIDJWTStrategy().GetPublicKey(ctx)
+	require.NoError(t, err)
+
+	encryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	newClient := func(t *testing.T, c *hc.Client) *hc.Client {
+		c.RedirectURIs = []string{"https://client.example.com/callback"}
+		c.ResponseTypes = []string{"code", "token"}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+		return c
+	}
+
+	authorize := func(t *testing.T, c *hc.Client, responseType, responseMode string, err error) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/oauth2/auth?"+url.Values{
+			"client_id":     {c.GetID()},
+			"redirect_uri":  {c.RedirectURIs[0]},
+			"response_type": {responseType},
+			"response_mode": {responseMode},
+			"state":         {"state-1234567890"},
+		}.Encode(), nil)
+
+		ar, parseErr := reg.OAuth2Provider().NewAuthorizeRequest(ctx, r)
+		require.NoError(t, parseErr)
+
+		rec := httptest.NewRecorder()
+		if err != nil {
+			reg.OAuth2Provider().WriteAuthorizeError(ctx, rec, ar, err)
+		} else {
+			reg.OAuth2Provider().WriteAuthorizeResponse(ctx, rec, ar, &fosite.AuthorizeResponse{
+				Header:     http.Header{},
+				Parameters: url.Values{"code": {"some-code"}, "state": {"state-1234567890"}},
+			})
+		}
+		return rec
+	}
+
+	verify := func(t *testing.T, token string) gjson.Result {
+		sig, err := jose.ParseSigned(token)
+		require.NoError(t, err)
+		assert.Equal(t, publicKey.KeyID, sig.Signatures[0].Header.KeyID)
+		payload, err := sig.Verify(publicKey.Key)
+		require.NoError(t, err)
+		return gjson.ParseBytes(payload)
+	}
+
+	location := func(t *testing.T, rec *httptest.ResponseRecorder) *url.URL {
+		require.Equal(t, http.StatusSeeOther, rec.Code, rec.Body.String())
+		u, err := url.Parse(rec.Header().Get("Location"))
+		require.NoError(t, err)
+		return u
+	}
+
+	t.Run("case=signs the response in the query", func(t *testing.T) {
+		c := newClient(t, &hc.Client{})
+		for _, mode := range []string{"query.jwt", "jwt"} {
+			t.Run("mode="+mode, func(t *testing.T) {
+				u := location(t, authorize(t, c, "code", mode, nil))
+				assert.Empty(t, u.Query().Get("code"))
+
+				claims := verify(t, u.Query().Get("response"))
+				assert.Equal(t, "some-code", claims.Get("code").String(), claims.Raw)
+				assert.Equal(t, "state-1234567890", claims.Get("state").String(), claims.Raw)
+				assert.Equal(t, c.GetID(), claims.Get("aud").String(), claims.Raw)
+				assert.Equal(t, reg.Config().IssuerURL(ctx).String(), claims.Get("iss").String(), claims.Raw)
+				assert.NotZero(t, claims.Get("exp").Int(), claims.Raw)
+			})
+		}
+	})
+
+	t.Run("case=uses the fragment for token responses", func(t *testing.T) {
+		c := newClient(t, &hc.Client{})
+		u := location(t, authorize(t, c, "token", "jwt", nil))
+		assert.Empty(t, u.RawQuery)
+
+		fragment, err := url.ParseQuery(u.Fragment)
+		require.NoError(t, err)
+		claims := verify(t, fragment.Get("response"))
+		assert.Equal(t, "some-code", claims.Get("code").String(), claims.Raw)
+	})
+
+	t.Run("case=posts the response", func(t *testing.T) {
+		c := newClient(t, &hc.Client{})
+		rec := authorize(t, c, "code", "form_post.jwt", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		matches := regexp.MustCompile(`name="response" value="([^"]+)"`).FindStringSubmatch(rec.Body.String())
+		require.Len(t, matches, 2, rec.Body.String())
+		claims := verify(t, matches[1])
+		assert.Equal(t, "some-code", claims.Get("code").String(), claims.Raw)
+	})
+
+	t.Run("case=signs errors", func(t *testing.T) {
+		c := newClient(t, &hc.Client{})
+		u := location(t, authorize(t, c, "code", "query.jwt", fosite.ErrAccessDenied))
+		assert.Empty(t, u.Query().Get("error"))
+
+		claims := verify(t, u.Query().Get("response"))
+		assert.Equal(t, "access_denied", claims.Get("error").String(), claims.Raw)
+		assert.Equal(t, "state-1234567890", claims.Get("state").String(), claims.Raw)
+	})
+
+	t.Run("case=encrypts the response", func(t *testing.T) {
+		c := newClient(t, &hc.Client{
+			AuthorizationEncryptedResponseAlg: "RSA-OAEP-256",
+			JSONWebKeys: &x.JoseJSONWebKeySet{JSONWebKeySet: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{Key: &encryptionKey.PublicKey, KeyID: "enc-key", Use: "enc", Algorithm: "RSA-OAEP-256"},
+			}}},
+		})
+		u := location(t, authorize(t, c, "code", "query.jwt", nil))
+
+		object, err := jose.ParseEncrypted(u.Query().Get("response"))
+		require.NoError(t, err)
+		assert.Equal(t, "enc-key", object.Header.KeyID)
+		assert.EqualValues(t, "A128CBC-HS256", object.Header.ExtraHeaders["enc"])
+
+		token, err := object.Decrypt(encryptionKey)
+		require.NoError(t, err)
+		claims := verify(t, string(token))
+		assert.Equal(t, "some-code", claims.Get("code").String(), claims.Raw)
+	})
+
+	t.Run("case=fails if the signing algorithm does not match", func(t *testing.T) {
+		c := newClient(t, &hc.Client{AuthorizationSignedResponseAlg: "ES256"})
+		rec := authorize(t, c, "code", "query.jwt", nil)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		assert.Equal(t, "server_error", body["error"])
+	})
+
+	t.Run("case=rejects tokens in the query unless the response is encrypted", func(t *testing.T) {
+		rec := authorize(t, newClient(t, &hc.Client{}), "token", "query.jwt", nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		assert.Equal(t, "invalid_request", body["error"])
+
+		c := newClient(t, &hc.Client{
+			AuthorizationEncryptedResponseAlg: "RSA-OAEP-256",
+			JSONWebKeys: &x.JoseJSONWebKeySet{JSONWebKeySet: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{Key: &encryptionKey.PublicKey, KeyID: "enc-key", Use: "enc", Algorithm: "RSA-OAEP-256"},
+			}}},
+		})
+		u := location(t, authorize(t, c, "token", "query.jwt", nil))
+		_, err := jose.ParseEncrypted(u.Query().Get("response"))
+		require.NoError(t, err)
+	})
+
+	t.Run("case=authorization endpoint rejects tokens in the query", func(t *testing.T) {
+		publicTS, _ := testhelpers.NewOAuth2Server(ctx, t, reg)
+		c := newClient(t, &hc.Client{GrantTypes: []string{"implicit"}})
+
+		httpClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		res, err := httpClient.Get(publicTS.URL + "/oauth2/auth?" + url.Values{
+			"client_id":     {c.GetID()},
+			"redirect_uri":  {c.RedirectURIs[0]},
+			"response_type": {"token"},
+			"response_mode": {"query.jwt"},
+			"state":         {"state-1234567890"},
+		}.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusSeeOther, res.StatusCode)
+		u, err := url.Parse(res.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Empty(t, u.Query().Get("access_token"))
+
+		claims := verify(t, u.Query().Get("response"))
+		assert.Equal(t, "invalid_request", claims.Get("error").String(), claims.Raw)
+	})
+
+	t.Run("case=rejects unknown response modes", func(t *testing.T) {
+		c := newClient(t, &hc.Client{})
+		r := httptest.NewRequest(http.MethodGet, "/oauth2/auth?"+url.Values{
+			"client_id":     {c.GetID()},
+			"redirect_uri":  {c.RedirectURIs[0]},
+			"response_type": {"code"},
+			"response_mode": {"foo.jwt"},
+			"state":         {"state-1234567890"},
+		}.Encode(), nil)
+		_, err := reg.OAuth2Provider().NewAuthorizeRequest(ctx, r)
+		require.ErrorIs(t, err, fosite.ErrUnsupportedResponseMode)
+	})
+}
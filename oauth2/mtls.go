@@ -0,0 +1,217 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+)
+
+const (
+	// ClientAuthMethodTLSClientAuth authenticates clients using a certificate issued by a trusted CA (RFC 8705).
+	ClientAuthMethodTLSClientAuth = "tls_client_auth"
+
+	// ClientAuthMethodSelfSignedTLSClientAuth authenticates clients using a self-signed certificate whose key is
+	// registered in the client's JSON Web Key Set (RFC 8705).
+	ClientAuthMethodSelfSignedTLSClientAuth = "self_signed_tls_client_auth"
+)
+
+// NewMutualTLSClientAuthenticationStrategy returns a client authentication strategy which authenticates clients
+// using tls_client_auth or self_signed_tls_client_auth. All other clients are authenticated by fallback.
+func NewMutualTLSClientAuthenticationStrategy(
+	c *config.DefaultProvider,
+	jwks fosite.JWKSFetcherStrategyProvider,
+	clients fosite.ClientManager,
+	fallback fosite.ClientAuthenticationStrategy,
+) fosite.ClientAuthenticationStrategy {
+	return func(ctx context.Context, r *http.Request, form url.Values) (fosite.Client, error) {
+		// Requests using basic auth or client assertions are never mutual TLS authenticated.
+		clientID := form.Get("client_id")
+		if clientID == "" || form.Get("client_assertion_type") != "" || r.Header.Get("Authorization") != "" {
+			return fallback(ctx, r, form)
+		}
+
+		fc, err := clients.GetClient(ctx, clientID)
+		if err != nil {
+			return nil, errorsx.WithStack(fosite.ErrInvalidClient.WithWrap(err).WithDebug(err.Error()))
+		}
+
+		cl, ok := fc.(*client.Client)
+		if !ok {
+			return fallback(ctx, r, form)
+		}
+
+		method := cl.GetTokenEndpointAuthMethod()
+		if method != ClientAuthMethodTLSClientAuth && method != ClientAuthMethodSelfSignedTLSClientAuth {
+			return fallback(ctx, r, form)
+		} else if form.Get("client_secret") != "" {
+			return nil, errorsx.WithStack(fosite.ErrInvalidClient.WithHintf("The OAuth 2.0 Client supports client authentication method '%s', but method 'client_secret_post' was requested. You must configure the OAuth 2.0 client's 'token_endpoint_auth_method' value to accept 'client_secret_post'.", method))
+		}
+
+		tc := c.TLS(ctx, config.PublicInterface)
+		chain, err := x.ClientCertificate(r, tc)
+		if err != nil {
+			return nil, errorsx.WithStack(fosite.ErrInvalidClient.WithHint("Unable to read the client certificate of the request.").WithWrap(err).WithDebug(err.Error()))
+		} else if len(chain) == 0 {
+			return nil, errorsx.WithStack(fosite.ErrInvalidClient.WithHintf("The OAuth 2.0 Client supports client authentication method '%s' and must present a client certificate.", method))
+		}
+
+		if method == ClientAuthMethodTLSClientAuth {
+			if err := verifyTLSClientAuth(cl, chain, tc); err != nil {
+				return nil, err
+			}
+		} else if err := verifySelfSignedTLSClientAuth(ctx, cl, chain[0], jwks); err != nil {
+			return nil, err
+		}
+
+		return cl, nil
+	}
+}
+
+// verifyTLSClientAuth verifies that the certificate chains to a trusted CA and matches the subject registered for
+// the client. The chain is verified even if it was forwarded by a TLS terminator, because the terminator may be
+// configured to accept any client certificate.
+func verifyTLSClientAuth(cl *client.Client, chain []*x509.Certificate, tc config.TLSConfig) error {
+	roots, err := tc.ClientCertificateAuthorities()
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithHint("Unable to load the client certificate authorities.").WithWrap(err).WithDebug(err.Error()))
+	} else if roots == nil {
+		return errorsx.WithStack(fosite.ErrInvalidClient.WithHint("The client certificate can not be verified because no client certificate authorities are configured."))
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return errorsx.WithStack(fosite.ErrInvalidClient.WithHint("The client certificate was not issued by a trusted certificate authority.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	leaf := chain[0]
+	var matches bool
+	switch {
+	case cl.TLSClientAuthSubjectDN != "":
+		matches = leaf.Subject.String() == cl.TLSClientAuthSubjectDN
+	case cl.TLSClientAuthSANDNS != "":
+		matches = stringslice.Has(leaf.DNSNames, cl.TLSClientAuthSANDNS)
+	case cl.TLSClientAuthSANURI != "":
+		for _, u := range leaf.URIs {
+			matches = matches || u.String() == cl.TLSClientAuthSANURI
+		}
+	case cl.TLSClientAuthSANIP != "":
+		ip := net.ParseIP(cl.TLSClientAuthSANIP)
+		for _, addr := range leaf.IPAddresses {
+			matches = matches || addr.Equal(ip)
+		}
+	case cl.TLSClientAuthSANEmail != "":
+		matches = stringslice.Has(leaf.EmailAddresses, cl.TLSClientAuthSANEmail)
+	}
+
+	if !matches {
+		return errorsx.WithStack(fosite.ErrInvalidClient.WithHint("The client certificate does not match the certificate subject registered for the OAuth 2.0 Client."))
+	}
+	return nil
+}
+
+// verifySelfSignedTLSClientAuth verifies that the public key of the certificate is registered in the client's
+// JSON Web Key Set. The key set at jwks_uri is fetched again if no key matches, in case the client rotated its keys.
+func verifySelfSignedTLSClientAuth(ctx context.Context, cl *client.Client, cert *x509.Certificate, jwks fosite.JWKSFetcherStrategyProvider) error {
+	if cl.JSONWebKeys != nil && cl.JSONWebKeys.JSONWebKeySet != nil {
+		if certificateKeyRegistered(cert, cl.JSONWebKeys.JSONWebKeySet) {
+			return nil
+		}
+	} else if cl.JSONWebKeysURI != "" {
+		for _, forceRefresh := range []bool{false, true} {
+			keys, err := jwks.GetJWKSFetcherStrategy(ctx).Resolve(ctx, cl.JSONWebKeysURI, forceRefresh)
+			if err != nil {
+				return err
+			} else if certificateKeyRegistered(cert, keys) {
+				return nil
+			}
+		}
+	}
+
+	return errorsx.WithStack(fosite.ErrInvalidClient.WithHint("The public key of the client certificate is not registered in the JSON Web Key Set of the OAuth 2.0 Client."))
+}
+
+func certificateKeyRegistered(cert *x509.Certificate, keys *jose.JSONWebKeySet) bool {
+	certKey, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return false
+	}
+
+	for _, key := range keys.Keys {
+		if certKey.Equal(key.Public().Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindClientCertificate binds the tokens issued by the token endpoint to the client certificate of the request if
+// the client requested certificate-bound access tokens.
+func (h *Handler) bindClientCertificate(r *http.Request, ar fosite.AccessRequester) error {
+	session, ok := ar.GetSession().(*Session)
+	if !ok {
+		return errorsx.WithStack(fosite.ErrServerError.WithHintf("Session must be of type *oauth2.Session but got type: %T", ar.GetSession()))
+	}
+
+	var thumbprint string
+	if cl, ok := ar.GetClient().(*client.Client); ok && cl.TLSClientCertificateBoundAccessTokens {
+		chain, err := x.ClientCertificate(r, h.c.TLS(r.Context(), config.PublicInterface))
+		if err != nil {
+			return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to read the client certificate of the request.").WithWrap(err).WithDebug(err.Error()))
+		} else if len(chain) == 0 {
+			return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The OAuth 2.0 Client requires certificate-bound access tokens and must present a client certificate."))
+		}
+		thumbprint = x.CertificateThumbprint(chain[0])
+	}
+
+	// As with DPoP, refresh tokens of public clients stay bound to the certificate they were issued for.
+	if bound := session.Confirmation.certificateThumbprint(); bound != "" && ar.GetGrantTypes().ExactOne("refresh_token") && ar.GetClient().IsPublic() {
+		if thumbprint != bound {
+			return errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The refresh token is bound to a client certificate and the request must be made using that certificate."))
+		}
+	}
+
+	cnf := session.Confirmation.copy()
+	cnf.X509CertificateThumbprint = thumbprint
+	session.Confirmation = cnf.orNil()
+	return nil
+}
+
+// verifyClientCertificateBinding ensures that an access token which is bound to a client certificate is only
+// accepted over a connection using that certificate.
+func (h *Handler) verifyClientCertificateBinding(r *http.Request, ar fosite.Requester) error {
+	session, ok := ar.GetSession().(*Session)
+	if !ok || session.Confirmation.certificateThumbprint() == "" {
+		return nil
+	}
+
+	chain, err := x.ClientCertificate(r, h.c.TLS(r.Context(), config.PublicInterface))
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrRequestUnauthorized.WithHint("Unable to read the client certificate of the request.").WithWrap(err).WithDebug(err.Error()))
+	} else if len(chain) == 0 || x.CertificateThumbprint(chain[0]) != session.Confirmation.X509CertificateThumbprint {
+		return errorsx.WithStack(fosite.ErrRequestUnauthorized.WithHint("The access token is bound to a client certificate and must be presented using that certificate."))
+	}
+	return nil
+}
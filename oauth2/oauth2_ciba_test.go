@@ -0,0 +1,243 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	hydraoauth2 "github.com/ory/hydra/oauth2"
+	"github.com/ory/hydra/x"
+)
+
+func TestBackchannelAuthentication(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	reg.Config().MustSet(ctx, config.KeyBackchannelAuthenticationPollingInterval, "1h")
+	publicTS, adminTS := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	challenges := make(chan string, 1)
+	loginTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		challenges <- gjson.ParseBytes(ioutilx.MustReadAll(r.Body)).Get("backchannel_authentication_challenge").String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(loginTS.Close)
+	reg.Config().MustSet(ctx, config.KeyBackchannelAuthenticationLoginURL, loginTS.URL)
+
+	type notification struct {
+		authorization string
+		authReqID     string
+	}
+	notifications := make(chan notification, 1)
+	notificationTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notifications <- notification{
+			authorization: r.Header.Get("Authorization"),
+			authReqID:     gjson.ParseBytes(ioutilx.MustReadAll(r.Body)).Get("auth_req_id").String(),
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(notificationTS.Close)
+
+	newClient := func(t *testing.T, mode, endpoint string) *hc.Client {
+		c := &hc.Client{
+			Secret:                                "secret",
+			GrantTypes:                            []string{hydraoauth2.GrantTypeCIBA, "refresh_token"},
+			Scope:                                 "hydra offline openid",
+			BackchannelTokenDeliveryMode:          mode,
+			BackchannelClientNotificationEndpoint: endpoint,
+		}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+		return c
+	}
+
+	post := func(t *testing.T, c *hc.Client, path string, form url.Values) (*http.Response, gjson.Result) {
+		req, err := http.NewRequest(http.MethodPost, publicTS.URL+path, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(c.GetID(), "secret")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	authorize := func(t *testing.T, c *hc.Client, form url.Values) (*http.Response, gjson.Result) {
+		return post(t, c, hydraoauth2.BackchannelAuthenticationPath, form)
+	}
+
+	pollToken := func(t *testing.T, c *hc.Client, authReqID string) (*http.Response, gjson.Result) {
+		return post(t, c, hydraoauth2.TokenPath, url.Values{
+			"grant_type":  {hydraoauth2.GrantTypeCIBA},
+			"auth_req_id": {authReqID},
+		})
+	}
+
+	handle := func(t *testing.T, action, challenge string, body interface{}) {
+		var b bytes.Buffer
+		require.NoError(t, json.NewEncoder(&b).Encode(body))
+		req, err := http.NewRequest(http.MethodPut, adminTS.URL+"/admin"+consent.BackchannelAuthenticationPath+"/"+action+"?challenge="+challenge, &b)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusNoContent, res.StatusCode, string(ioutilx.MustReadAll(res.Body)))
+	}
+
+	t.Run("case=rejects clients without the ciba grant", func(t *testing.T) {
+		c := &hc.Client{Secret: "secret", GrantTypes: []string{"client_credentials"}}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+
+		res, body := authorize(t, c, url.Values{"scope": {"openid"}, "login_hint": {"foo"}})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "unauthorized_client", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=rejects invalid requests", func(t *testing.T) {
+		c := newClient(t, hc.BackchannelTokenDeliveryModePoll, "")
+		for k, form := range []url.Values{
+			{"login_hint": {"foo"}},
+			{"scope": {"openid"}},
+			{"scope": {"openid"}, "login_hint": {"foo"}, "login_hint_token": {"bar"}},
+			{"scope": {"openid"}, "login_hint": {"foo"}, "requested_expiry": {"-1"}},
+		} {
+			res, body := authorize(t, c, form)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%d: %s", k, body.Raw)
+		}
+	})
+
+	t.Run("case=performs the flow in poll mode", func(t *testing.T) {
+		c := newClient(t, hc.BackchannelTokenDeliveryModePoll, "")
+
+		res, auth := authorize(t, c, url.Values{
+			"scope":            {"hydra offline openid"},
+			"login_hint":       {"aeneas-rekkas"},
+			"binding_message":  {"W4SCT"},
+			"requested_expiry": {"120"},
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode, auth.Raw)
+		assert.Equal(t, int64(120), auth.Get("expires_in").Int(), auth.Raw)
+		assert.Equal(t, int64(time.Hour/time.Second), auth.Get("interval").Int(), auth.Raw)
+		authReqID := auth.Get("auth_req_id").String()
+		require.NotEmpty(t, authReqID)
+
+		challenge := <-challenges
+		br, err := reg.ConsentManager().GetBackchannelAuthenticationRequest(ctx, challenge)
+		require.NoError(t, err)
+		assert.Equal(t, "aeneas-rekkas", br.LoginHint)
+		assert.Equal(t, "W4SCT", br.BindingMessage)
+		assert.Equal(t, c.GetID(), br.Client.GetID())
+		assert.NotEqual(t, authReqID, br.AuthReqIDSignature, "the auth_req_id must not be stored in plaintext")
+		assert.Equal(t, consent.AuthReqIDSignature(reg.Config().GetGlobalSecret(ctx), authReqID), br.AuthReqIDSignature)
+
+		res, body := pollToken(t, c, authReqID)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "authorization_pending", body.Get("error").String(), body.Raw)
+
+		res, body = pollToken(t, c, authReqID)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "slow_down", body.Get("error").String(), body.Raw)
+
+		handle(t, "accept", challenge, map[string]interface{}{
+			"subject":     "aeneas-rekkas",
+			"grant_scope": []string{"hydra", "offline", "openid"},
+			"session":     map[string]interface{}{"access_token": map[string]interface{}{"foo": "bar"}},
+		})
+
+		res, body = pollToken(t, c, authReqID)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.NotEmpty(t, body.Get("access_token").String(), body.Raw)
+		assert.NotEmpty(t, body.Get("refresh_token").String(), body.Raw)
+		assert.Equal(t, "hydra offline openid", body.Get("scope").String(), body.Raw)
+
+		payload, err := x.DecodeSegment(strings.Split(body.Get("id_token").String(), ".")[1])
+		require.NoError(t, err)
+		idToken := gjson.ParseBytes(payload)
+		assert.Equal(t, "aeneas-rekkas", idToken.Get("sub").String(), idToken.Raw)
+		assert.Equal(t, authReqID, idToken.Get(strings.ReplaceAll(hydraoauth2.ClaimAuthReqID, ".", `\.`)).String(), idToken.Raw)
+
+		introspection := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID()}, body.Get("access_token").String(), adminTS)
+		assert.True(t, introspection.Get("active").Bool(), introspection.Raw)
+		assert.Equal(t, "aeneas-rekkas", introspection.Get("sub").String(), introspection.Raw)
+		assert.Equal(t, "bar", introspection.Get("ext.foo").String(), introspection.Raw)
+
+		res, body = pollToken(t, c, authReqID)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "invalid_grant", body.Get("error").String(), body.Raw)
+
+		other := newClient(t, hc.BackchannelTokenDeliveryModePoll, "")
+		res, body = pollToken(t, other, authReqID)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "invalid_grant", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=notifies the client in ping mode", func(t *testing.T) {
+		c := newClient(t, hc.BackchannelTokenDeliveryModePing, notificationTS.URL)
+
+		res, body := authorize(t, c, url.Values{"scope": {"openid"}, "login_hint": {"foo"}})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_request", body.Get("error").String(), body.Raw)
+
+		res, auth := authorize(t, c, url.Values{
+			"scope":                     {"openid"},
+			"login_hint":                {"foo"},
+			"client_notification_token": {"notification-token"},
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode, auth.Raw)
+		assert.False(t, auth.Get("interval").Exists(), auth.Raw)
+		authReqID := auth.Get("auth_req_id").String()
+
+		handle(t, "accept", <-challenges, map[string]interface{}{"subject": "foo", "grant_scope": []string{"openid"}})
+
+		n := <-notifications
+		assert.Equal(t, "Bearer notification-token", n.authorization)
+		assert.Equal(t, authReqID, n.authReqID)
+
+		res, body = pollToken(t, c, authReqID)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.NotEmpty(t, body.Get("id_token").String(), body.Raw)
+	})
+
+	t.Run("case=rejects denied requests", func(t *testing.T) {
+		c := newClient(t, hc.BackchannelTokenDeliveryModePoll, "")
+
+		res, auth := authorize(t, c, url.Values{"scope": {"openid"}, "login_hint": {"foo"}})
+		require.Equal(t, http.StatusOK, res.StatusCode, auth.Raw)
+
+		challenge := <-challenges
+		handle(t, "reject", challenge, map[string]interface{}{"error": "access_denied"})
+
+		res, body := pollToken(t, c, auth.Get("auth_req_id").String())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		assert.Equal(t, "access_denied", body.Get("error").String(), body.Raw)
+
+		req, err := http.NewRequest(http.MethodPut, adminTS.URL+"/admin"+consent.BackchannelAuthenticationPath+"/accept?challenge="+challenge, strings.NewReader(`{"subject":"foo"}`))
+		require.NoError(t, err)
+		res, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
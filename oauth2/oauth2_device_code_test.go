@@ -0,0 +1,203 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+	"github.com/ory/x/pointerx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	hydraoauth2 "github.com/ory/hydra/oauth2"
+)
+
+func TestDeviceCodeHMACStrategy(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	s := reg.DeviceCodeStrategy()
+
+	code, signature, err := s.GenerateDeviceCode(ctx)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(code, "ory_dc_"), code)
+	assert.Equal(t, signature, s.DeviceCodeSignature(ctx, code))
+	require.NoError(t, s.ValidateDeviceCode(ctx, code))
+	require.Error(t, s.ValidateDeviceCode(ctx, code+"x"))
+
+	userCode, userSignature, err := s.GenerateUserCode(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, userSignature, s.UserCodeSignature(ctx, strings.ToLower(strings.ReplaceAll(userCode, "-", ""))))
+	assert.NotEqual(t, userSignature, s.UserCodeSignature(ctx, "BCDF-GHJK"))
+}
+
+func TestDeviceCode(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	reg.Config().MustSet(ctx, config.KeyDeviceAuthTokenPollingInterval, "1h")
+	publicTS, adminTS := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	adminClient := hydra.NewAPIClient(hydra.NewConfiguration())
+	adminClient.GetConfig().Servers = hydra.ServerConfigurations{{URL: adminTS.URL}}
+
+	doneURL := testhelpers.NewCallbackURL(t, "done", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("done"))
+	})
+	reg.Config().MustSet(ctx, config.KeyDeviceDoneURL, doneURL)
+
+	subject := "aeneas-rekkas"
+	testhelpers.NewLoginConsentUI(t, reg.Config(),
+		func(w http.ResponseWriter, r *http.Request) {
+			rr, _, err := adminClient.OAuth2Api.GetOAuth2LoginRequest(ctx).LoginChallenge(r.URL.Query().Get("login_challenge")).Execute()
+			require.NoError(t, err)
+			assert.Contains(t, rr.RequestUrl, reg.Config().OAuth2DeviceVerifyURL(ctx).String())
+
+			v, _, err := adminClient.OAuth2Api.AcceptOAuth2LoginRequest(ctx).
+				LoginChallenge(r.URL.Query().Get("login_challenge")).
+				AcceptOAuth2LoginRequest(hydra.AcceptOAuth2LoginRequest{Subject: subject}).
+				Execute()
+			require.NoError(t, err)
+			http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			rr, _, err := adminClient.OAuth2Api.GetOAuth2ConsentRequest(ctx).ConsentChallenge(r.URL.Query().Get("consent_challenge")).Execute()
+			require.NoError(t, err)
+			assert.EqualValues(t, []string{"hydra", "offline", "openid"}, rr.RequestedScope)
+
+			v, _, err := adminClient.OAuth2Api.AcceptOAuth2ConsentRequest(ctx).
+				ConsentChallenge(r.URL.Query().Get("consent_challenge")).
+				AcceptOAuth2ConsentRequest(hydra.AcceptOAuth2ConsentRequest{
+					GrantScope: rr.RequestedScope,
+					Session: &hydra.AcceptOAuth2ConsentRequestSession{
+						AccessToken: map[string]interface{}{"foo": "bar"},
+					},
+					RememberFor: pointerx.Int64(0),
+				}).
+				Execute()
+			require.NoError(t, err)
+			http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+		},
+	)
+
+	c := &hc.Client{
+		GrantTypes:              []string{hydraoauth2.GrantTypeDeviceCode, "refresh_token"},
+		Scope:                   "hydra offline openid",
+		TokenEndpointAuthMethod: "none",
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+
+	deviceAuthorize := func(t *testing.T, clientID string) (*http.Response, gjson.Result) {
+		res, err := http.PostForm(publicTS.URL+hydraoauth2.DeviceAuthPath, url.Values{"client_id": {clientID}, "scope": {"hydra offline openid"}})
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	pollToken := func(t *testing.T, deviceCode string) (*http.Response, gjson.Result) {
+		res, err := http.PostForm(publicTS.URL+hydraoauth2.TokenPath, url.Values{
+			"grant_type":  {hydraoauth2.GrantTypeDeviceCode},
+			"device_code": {deviceCode},
+			"client_id":   {c.GetID()},
+		})
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	t.Run("case=rejects clients without the device code grant", func(t *testing.T) {
+		cl := &hc.Client{GrantTypes: []string{"authorization_code"}, TokenEndpointAuthMethod: "none"}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+
+		res, body := deviceAuthorize(t, cl.GetID())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "unauthorized_client", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=performs the device flow", func(t *testing.T) {
+		res, auth := deviceAuthorize(t, c.GetID())
+		require.Equal(t, http.StatusOK, res.StatusCode, auth.Raw)
+		assert.Equal(t, int64(time.Hour/time.Second), auth.Get("interval").Int(), auth.Raw)
+		assert.Equal(t, int64(reg.Config().GetDeviceAndUserCodeLifespan(ctx)/time.Second), auth.Get("expires_in").Int(), auth.Raw)
+		assert.Regexp(t, "^[A-Z]{4}-[A-Z]{4}$", auth.Get("user_code").String())
+		deviceCode := auth.Get("device_code").String()
+		require.NotEmpty(t, deviceCode)
+
+		res, body := pollToken(t, deviceCode)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "authorization_pending", body.Get("error").String(), body.Raw)
+
+		res, body = pollToken(t, deviceCode)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "slow_down", body.Get("error").String(), body.Raw)
+
+		ds, err := reg.DeviceCodeStorage().GetDeviceCodeSession(ctx, reg.DeviceCodeStrategy().DeviceCodeSignature(ctx, deviceCode), hydraoauth2.NewSession(""))
+		require.NoError(t, err)
+		assert.Equal(t, time.Hour+5*time.Second, ds.PollingInterval, "slow_down increases the polling interval by five seconds")
+
+		res, err = testhelpers.NewEmptyJarClient(t).Get(auth.Get("verification_uri_complete").String())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, doneURL, res.Request.URL.String())
+
+		res, body = pollToken(t, deviceCode)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.NotEmpty(t, body.Get("access_token").String(), body.Raw)
+		assert.NotEmpty(t, body.Get("refresh_token").String(), body.Raw)
+		assert.NotEmpty(t, body.Get("id_token").String(), body.Raw)
+		assert.Equal(t, "hydra offline openid", body.Get("scope").String(), body.Raw)
+
+		introspection := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID()}, body.Get("access_token").String(), adminTS)
+		assert.True(t, introspection.Get("active").Bool(), introspection.Raw)
+		assert.Equal(t, subject, introspection.Get("sub").String(), introspection.Raw)
+		assert.Equal(t, "bar", introspection.Get("ext.foo").String(), introspection.Raw)
+
+		res, body = pollToken(t, deviceCode)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "invalid_grant", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=invalid verifiers do not deny the device code", func(t *testing.T) {
+		res, auth := deviceAuthorize(t, c.GetID())
+		require.Equal(t, http.StatusOK, res.StatusCode, auth.Raw)
+
+		for _, verifier := range []string{"login_verifier", "consent_verifier"} {
+			res, err := testhelpers.NewEmptyJarClient(t).Get(auth.Get("verification_uri_complete").String() + "&" + verifier + "=not-a-verifier")
+			require.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, reg.Config().ErrorURL(ctx).Path, res.Request.URL.Path, verifier)
+		}
+
+		ds, err := reg.DeviceCodeStorage().GetDeviceCodeSession(ctx, reg.DeviceCodeStrategy().DeviceCodeSignature(ctx, auth.Get("device_code").String()), hydraoauth2.NewSession(""))
+		require.NoError(t, err)
+		assert.Equal(t, hydraoauth2.DeviceCodeStatePending, ds.State)
+
+		res, err = testhelpers.NewEmptyJarClient(t).Get(auth.Get("verification_uri_complete").String())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, doneURL, res.Request.URL.String())
+	})
+
+	t.Run("case=rejects unknown user codes", func(t *testing.T) {
+		res, err := testhelpers.NewEmptyJarClient(t).Get(publicTS.URL + hydraoauth2.DeviceVerifyPath + "?user_code=BCDF-GHJK")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, reg.Config().ErrorURL(ctx).Path, res.Request.URL.Path)
+		assert.Equal(t, "invalid_request", res.Request.URL.Query().Get("error"))
+	})
+}
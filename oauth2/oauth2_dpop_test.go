@@ -0,0 +1,340 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+	"gopkg.in/square/go-jose.v2"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+	"github.com/ory/x/pointerx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	hydraoauth2 "github.com/ory/hydra/oauth2"
+	"github.com/ory/hydra/x"
+)
+
+func TestDPoP(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	_, adminTS := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	tokenURL := reg.Config().OAuth2TokenURL(ctx).String()
+	userinfoURL := reg.Config().OIDCDiscoveryUserinfoEndpoint(ctx).String()
+
+	newKey := func(t *testing.T) *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		return key
+	}
+
+	thumbprint := func(t *testing.T, key *ecdsa.PrivateKey) string {
+		tp, err := (&jose.JSONWebKey{Key: key.Public()}).Thumbprint(crypto.SHA256)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(tp)
+	}
+
+	newProof := func(t *testing.T, key *ecdsa.PrivateKey, method, htu, accessToken string) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{EmbedJWK: true}).WithType("dpop+jwt"))
+		require.NoError(t, err)
+
+		claims := map[string]interface{}{"jti": uuid.NewString(), "htm": method, "htu": htu, "iat": time.Now().Unix()}
+		if accessToken != "" {
+			hash := sha256.Sum256([]byte(accessToken))
+			claims["ath"] = base64.RawURLEncoding.EncodeToString(hash[:])
+		}
+		payload, err := json.Marshal(claims)
+		require.NoError(t, err)
+
+		signed, err := signer.Sign(payload)
+		require.NoError(t, err)
+		proof, err := signed.CompactSerialize()
+		require.NoError(t, err)
+		return proof
+	}
+
+	secret := uuid.NewString()
+	cl := &hc.Client{
+		Secret:     secret,
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "foo",
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+
+	requestToken := func(t *testing.T, c *hc.Client, form url.Values, proof string) (int, gjson.Result) {
+		req, err := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if c.TokenEndpointAuthMethod != "none" {
+			req.SetBasicAuth(url.QueryEscape(c.GetID()), url.QueryEscape(secret))
+		}
+		if proof != "" {
+			req.Header.Set(hydraoauth2.DPoPHeader, proof)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.StatusCode, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	userinfo := func(t *testing.T, scheme, token, proof string) (*http.Response, gjson.Result) {
+		req, err := http.NewRequest("GET", userinfoURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", scheme+" "+token)
+		if proof != "" {
+			req.Header.Set(hydraoauth2.DPoPHeader, proof)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	introspect := func(t *testing.T, token string) gjson.Result {
+		return testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: cl.GetID(), ClientSecret: secret}, token, adminTS)
+	}
+
+	clientCredentials := url.Values{"grant_type": {"client_credentials"}, "scope": {"foo"}}
+
+	t.Run("case=issues bearer tokens without a proof", func(t *testing.T) {
+		code, body := requestToken(t, cl, clientCredentials, "")
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		assert.Equal(t, "bearer", body.Get("token_type").String(), body.Raw)
+
+		i := introspect(t, body.Get("access_token").String())
+		assert.Equal(t, "Bearer", i.Get("token_type").String(), i.Raw)
+		assert.False(t, i.Get("cnf").Exists(), i.Raw)
+
+		res, _ := userinfo(t, "DPoP", body.Get("access_token").String(), newProof(t, newKey(t), "GET", userinfoURL, body.Get("access_token").String()))
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("case=binds the access token to the proof key", func(t *testing.T) {
+		key := newKey(t)
+		code, body := requestToken(t, cl, clientCredentials, newProof(t, key, "POST", tokenURL, ""))
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		assert.Equal(t, "DPoP", body.Get("token_type").String(), body.Raw)
+		token := body.Get("access_token").String()
+
+		i := introspect(t, token)
+		assert.True(t, i.Get("active").Bool(), i.Raw)
+		assert.Equal(t, "DPoP", i.Get("token_type").String(), i.Raw)
+		assert.Equal(t, thumbprint(t, key), i.Get("cnf.jkt").String(), i.Raw)
+
+		t.Run("case=userinfo accepts a proof signed with the bound key", func(t *testing.T) {
+			res, body := userinfo(t, "DPoP", token, newProof(t, key, "GET", userinfoURL, token))
+			require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+			assert.Equal(t, cl.GetID(), body.Get("aud.0").String(), body.Raw)
+		})
+
+		t.Run("case=userinfo rejects the bearer scheme", func(t *testing.T) {
+			res, body := userinfo(t, "Bearer", token, "")
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+			assert.Contains(t, res.Header.Get("WWW-Authenticate"), `error="invalid_token"`)
+		})
+
+		t.Run("case=userinfo rejects a proof signed with another key", func(t *testing.T) {
+			res, body := userinfo(t, "DPoP", token, newProof(t, newKey(t), "GET", userinfoURL, token))
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+			assert.Contains(t, res.Header.Get("WWW-Authenticate"), `error="invalid_dpop_proof"`)
+		})
+
+		t.Run("case=userinfo rejects a proof without the access token hash", func(t *testing.T) {
+			res, body := userinfo(t, "DPoP", token, newProof(t, key, "GET", userinfoURL, ""))
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		})
+
+		t.Run("case=userinfo rejects a replayed proof", func(t *testing.T) {
+			proof := newProof(t, key, "GET", userinfoURL, token)
+			res, body := userinfo(t, "DPoP", token, proof)
+			require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+
+			res, body = userinfo(t, "DPoP", token, proof)
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+			assert.Contains(t, res.Header.Get("WWW-Authenticate"), `error="invalid_dpop_proof"`)
+		})
+	})
+
+	t.Run("case=adds the confirmation claim to JWT access tokens", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "jwt")
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque") })
+
+		key := newKey(t)
+		code, body := requestToken(t, cl, clientCredentials, newProof(t, key, "POST", tokenURL, ""))
+		require.Equal(t, http.StatusOK, code, body.Raw)
+
+		payload, err := x.DecodeSegment(strings.Split(body.Get("access_token").String(), ".")[1])
+		require.NoError(t, err)
+		claims := gjson.ParseBytes(payload)
+		assert.Equal(t, thumbprint(t, key), claims.Get("cnf.jkt").String(), claims.Raw)
+	})
+
+	t.Run("case=rejects invalid proofs at the token endpoint", func(t *testing.T) {
+		key := newKey(t)
+		replayed := newProof(t, key, "POST", tokenURL, "")
+		code, body := requestToken(t, cl, clientCredentials, replayed)
+		require.Equal(t, http.StatusOK, code, body.Raw)
+
+		for k, proof := range map[string]string{
+			"replayed":     replayed,
+			"wrong method": newProof(t, key, "GET", tokenURL, ""),
+			"wrong uri":    newProof(t, key, "POST", userinfoURL, ""),
+			"not a jwt":    "not-a-jwt",
+			"missing typ": func() string {
+				signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, &jose.SignerOptions{EmbedJWK: true})
+				require.NoError(t, err)
+				payload, err := json.Marshal(map[string]interface{}{"jti": uuid.NewString(), "htm": "POST", "htu": tokenURL, "iat": time.Now().Unix()})
+				require.NoError(t, err)
+				signed, err := signer.Sign(payload)
+				require.NoError(t, err)
+				proof, err := signed.CompactSerialize()
+				require.NoError(t, err)
+				return proof
+			}(),
+		} {
+			t.Run("case="+k, func(t *testing.T) {
+				code, body := requestToken(t, cl, clientCredentials, proof)
+				assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+				assert.Equal(t, "invalid_dpop_proof", body.Get("error").String(), body.Raw)
+			})
+		}
+	})
+
+	t.Run("case=keeps refresh tokens of public clients bound to the proof key", func(t *testing.T) {
+		adminClient := hydra.NewAPIClient(hydra.NewConfiguration())
+		adminClient.GetConfig().Servers = hydra.ServerConfigurations{{URL: adminTS.URL}}
+		testhelpers.NewLoginConsentUI(t, reg.Config(),
+			func(w http.ResponseWriter, r *http.Request) {
+				v, _, err := adminClient.OAuth2Api.AcceptOAuth2LoginRequest(ctx).
+					LoginChallenge(r.URL.Query().Get("login_challenge")).
+					AcceptOAuth2LoginRequest(hydra.AcceptOAuth2LoginRequest{Subject: "aeneas-rekkas"}).
+					Execute()
+				require.NoError(t, err)
+				http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+			},
+			func(w http.ResponseWriter, r *http.Request) {
+				v, _, err := adminClient.OAuth2Api.AcceptOAuth2ConsentRequest(ctx).
+					ConsentChallenge(r.URL.Query().Get("consent_challenge")).
+					AcceptOAuth2ConsentRequest(hydra.AcceptOAuth2ConsentRequest{
+						GrantScope:  []string{"offline_access"},
+						RememberFor: pointerx.Int64(0),
+					}).
+					Execute()
+				require.NoError(t, err)
+				http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+			},
+		)
+
+		public := &hc.Client{
+			RedirectURIs:            []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+			ResponseTypes:           []string{"code"},
+			GrantTypes:              []string{"authorization_code", "refresh_token"},
+			Scope:                   "offline_access",
+			TokenEndpointAuthMethod: "none",
+		}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, public))
+
+		res, err := testhelpers.NewEmptyJarClient(t).Get(reg.Config().OAuth2AuthURL(ctx).String() + "?" + url.Values{
+			"client_id":     {public.GetID()},
+			"response_type": {"code"},
+			"redirect_uri":  {public.RedirectURIs[0]},
+			"scope":         {"offline_access"},
+			"state":         {uuid.NewString()},
+		}.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		authCode := res.Request.URL.Query().Get("code")
+		require.NotEmpty(t, authCode, res.Request.URL.String())
+
+		key := newKey(t)
+		code, body := requestToken(t, public, url.Values{
+			"grant_type":   {"authorization_code"},
+			"code":         {authCode},
+			"redirect_uri": {public.RedirectURIs[0]},
+			"client_id":    {public.GetID()},
+		}, newProof(t, key, "POST", tokenURL, ""))
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		assert.Equal(t, "DPoP", body.Get("token_type").String(), body.Raw)
+		refreshToken := body.Get("refresh_token").String()
+		require.NotEmpty(t, refreshToken, body.Raw)
+
+		refresh := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}, "client_id": {public.GetID()}}
+
+		code, body = requestToken(t, public, refresh, newProof(t, newKey(t), "POST", tokenURL, ""))
+		assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+		assert.Equal(t, "invalid_dpop_proof", body.Get("error").String(), body.Raw)
+
+		code, body = requestToken(t, public, refresh, newProof(t, key, "POST", tokenURL, ""))
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		assert.Equal(t, "DPoP", body.Get("token_type").String(), body.Raw)
+	})
+
+	t.Run("case=requires a proof signed with the key of bound tokens to exchange them", func(t *testing.T) {
+		exchanger := &hc.Client{
+			Secret:     secret,
+			GrantTypes: []string{"client_credentials", hydraoauth2.GrantTypeTokenExchange},
+			Scope:      "foo",
+		}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, exchanger))
+
+		key := newKey(t)
+		code, body := requestToken(t, exchanger, clientCredentials, newProof(t, key, "POST", tokenURL, ""))
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		bound := body.Get("access_token").String()
+		code, body = requestToken(t, exchanger, clientCredentials, "")
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		unbound := body.Get("access_token").String()
+
+		for _, tc := range []struct {
+			d    string
+			form url.Values
+		}{
+			{d: "subject token", form: url.Values{"subject_token": {bound}}},
+			{d: "actor token", form: url.Values{"subject_token": {unbound}, "actor_token": {bound}, "actor_token_type": {hydraoauth2.TokenTypeAccessToken}}},
+		} {
+			t.Run("token="+tc.d, func(t *testing.T) {
+				tc.form.Set("grant_type", hydraoauth2.GrantTypeTokenExchange)
+				tc.form.Set("subject_token_type", hydraoauth2.TokenTypeAccessToken)
+
+				code, body := requestToken(t, exchanger, tc.form, "")
+				assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+				assert.Equal(t, "invalid_dpop_proof", body.Get("error").String(), body.Raw)
+
+				code, body = requestToken(t, exchanger, tc.form, newProof(t, newKey(t), "POST", tokenURL, ""))
+				assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+				assert.Equal(t, "invalid_dpop_proof", body.Get("error").String(), body.Raw)
+
+				code, body = requestToken(t, exchanger, tc.form, newProof(t, key, "POST", tokenURL, ""))
+				require.Equal(t, http.StatusOK, code, body.Raw)
+				assert.Equal(t, thumbprint(t, key), introspect(t, body.Get("access_token").String()).Get("cnf.jkt").String())
+			})
+		}
+	})
+}
@@ -45,6 +45,10 @@ func (c *consentMock) HandleOAuth2AuthorizationRequest(ctx context.Context, w ht
 	}, nil
 }
 
+func (c *consentMock) HandleOAuth2DeviceAuthorizationRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester) (*consent.AcceptOAuth2ConsentRequest, error) {
+	return c.HandleOAuth2AuthorizationRequest(ctx, w, r, req)
+}
+
 func (c *consentMock) HandleOpenIDConnectLogout(ctx context.Context, w http.ResponseWriter, r *http.Request) (*consent.LogoutResult, error) {
 	panic("not implemented")
 }
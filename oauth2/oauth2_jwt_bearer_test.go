@@ -184,6 +184,36 @@ func TestJWTBearer(t *testing.T) {
 		assert.Contains(t, err.Error(), "i_am_not_allowed")
 	})
 
+	t.Run("case=unable to request authorization details", func(t *testing.T) {
+		client := &hc.Client{
+			Secret:                    secret,
+			GrantTypes:                []string{"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			Scope:                     "offline_access",
+			AuthorizationDetailsTypes: []string{"account_information"},
+		}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, client))
+
+		token, _, err := signer.Generate(ctx, jwt.MapClaims{
+			"jti": uuid.NewString(),
+			"iss": trustGrant.Issuer,
+			"sub": trustGrant.Subject,
+			"aud": reg.Config().OAuth2TokenURL(ctx).String(),
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"iat": time.Now().Add(-time.Minute).Unix(),
+		}, &jwt.Headers{Extra: map[string]interface{}{"kid": kid}})
+		require.NoError(t, err)
+
+		conf := newConf(client)
+		conf.EndpointParams = url.Values{
+			"grant_type":            {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":             {token},
+			"authorization_details": {`[{"type":"account_information"}]`},
+		}
+		_, err = getToken(t, conf)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid_authorization_details")
+	})
+
 	t.Run("case=unable to exchange token with an unknown kid", func(t *testing.T) {
 		token, _, err := signer.Generate(ctx, jwt.MapClaims{
 			"jti": uuid.NewString(),
@@ -0,0 +1,308 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	hydraoauth2 "github.com/ory/hydra/oauth2"
+	"github.com/ory/hydra/x"
+)
+
+func TestMutualTLS(t *testing.T) {
+	const certificateHeader = "X-SSL-Client-Cert"
+
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	reg.Config().MustSet(ctx, config.KeyTLSAllowTerminationFrom, []string{"127.0.0.1/32", "::1/128"})
+	reg.Config().MustSet(ctx, config.KeyTLSClientCertificateForwardedHeader, certificateHeader)
+	publicTS, adminTS := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	tokenURL := reg.Config().OAuth2TokenURL(ctx).String()
+	userinfoURL := reg.Config().OIDCDiscoveryUserinfoEndpoint(ctx).String()
+
+	type certificate struct {
+		cert *x509.Certificate
+		key  *ecdsa.PrivateKey
+	}
+
+	newCertificate := func(t *testing.T, cn string, issuer *certificate) *certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber: serial,
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			DNSNames:     []string{cn + ".example.com"},
+		}
+
+		parent, signer := template, key
+		if issuer != nil {
+			parent, signer = issuer.cert, issuer.key
+		} else {
+			template.IsCA = true
+			template.BasicConstraintsValid = true
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+		require.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return &certificate{cert: cert, key: key}
+	}
+
+	encode := func(c *certificate) string {
+		return url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})))
+	}
+
+	thumbprint := func(c *certificate) string {
+		hash := sha256.Sum256(c.cert.Raw)
+		return base64.RawURLEncoding.EncodeToString(hash[:])
+	}
+
+	ca := newCertificate(t, "ca", nil)
+	reg.Config().MustSet(ctx, config.KeyTLSClientCertificateCAString, base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})))
+
+	selfSigned := newCertificate(t, "self-signed", nil)
+
+	pkiClient := &hc.Client{
+		GrantTypes:                            []string{"client_credentials"},
+		Scope:                                 "foo",
+		TokenEndpointAuthMethod:               hydraoauth2.ClientAuthMethodTLSClientAuth,
+		TLSClientAuthSubjectDN:                "CN=pki-client",
+		TLSClientCertificateBoundAccessTokens: true,
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, pkiClient))
+
+	selfSignedClient := &hc.Client{
+		GrantTypes:              []string{"client_credentials"},
+		Scope:                   "foo",
+		TokenEndpointAuthMethod: hydraoauth2.ClientAuthMethodSelfSignedTLSClientAuth,
+		JSONWebKeys: &x.JoseJSONWebKeySet{JSONWebKeySet: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: selfSigned.key.Public(), KeyID: "self-signed", Use: "sig", Algorithm: string(jose.ES256)},
+		}}},
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, selfSignedClient))
+
+	requestToken := func(t *testing.T, c *hc.Client, cert *certificate, headers ...string) (int, gjson.Result) {
+		form := url.Values{"grant_type": {"client_credentials"}, "scope": {"foo"}, "client_id": {c.GetID()}}
+		req, err := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cert != nil {
+			req.Header.Set(certificateHeader, encode(cert))
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.StatusCode, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	userinfo := func(t *testing.T, token string, cert *certificate) (*http.Response, gjson.Result) {
+		req, err := http.NewRequest("GET", userinfoURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if cert != nil {
+			req.Header.Set(certificateHeader, encode(cert))
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	t.Run("case=authenticates tls_client_auth clients and binds the token to the certificate", func(t *testing.T) {
+		cert := newCertificate(t, "pki-client", ca)
+		code, body := requestToken(t, pkiClient, cert)
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		assert.Equal(t, "bearer", body.Get("token_type").String(), body.Raw)
+		token := body.Get("access_token").String()
+
+		i := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: pkiClient.GetID()}, token, adminTS)
+		assert.True(t, i.Get("active").Bool(), i.Raw)
+		assert.Equal(t, thumbprint(cert), i.Get(`cnf.x5t#S256`).String(), i.Raw)
+		assert.False(t, i.Get("cnf.jkt").Exists(), i.Raw)
+
+		t.Run("case=userinfo accepts the bound certificate", func(t *testing.T) {
+			res, body := userinfo(t, token, cert)
+			assert.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		})
+
+		t.Run("case=userinfo rejects requests without a certificate", func(t *testing.T) {
+			res, body := userinfo(t, token, nil)
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+			assert.Contains(t, res.Header.Get("WWW-Authenticate"), `error="invalid_token"`)
+		})
+
+		t.Run("case=userinfo rejects another certificate", func(t *testing.T) {
+			res, body := userinfo(t, token, newCertificate(t, "pki-client", ca))
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		})
+	})
+
+	t.Run("case=authenticates self_signed_tls_client_auth clients", func(t *testing.T) {
+		code, body := requestToken(t, selfSignedClient, selfSigned)
+		require.Equal(t, http.StatusOK, code, body.Raw)
+
+		i := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: selfSignedClient.GetID()}, body.Get("access_token").String(), adminTS)
+		assert.True(t, i.Get("active").Bool(), i.Raw)
+		assert.False(t, i.Get("cnf").Exists(), i.Raw)
+	})
+
+	for _, tc := range []struct {
+		d    string
+		c    *hc.Client
+		cert func(t *testing.T) *certificate
+	}{
+		{d: "without a certificate", c: pkiClient, cert: func(t *testing.T) *certificate { return nil }},
+		{d: "with a certificate for another subject", c: pkiClient, cert: func(t *testing.T) *certificate { return newCertificate(t, "other-client", ca) }},
+		{d: "with a certificate from an untrusted issuer", c: pkiClient, cert: func(t *testing.T) *certificate { return newCertificate(t, "pki-client", nil) }},
+		{d: "with a self-signed certificate for an unregistered key", c: selfSignedClient, cert: func(t *testing.T) *certificate { return newCertificate(t, "self-signed", nil) }},
+	} {
+		t.Run("case=rejects authentication "+tc.d, func(t *testing.T) {
+			code, body := requestToken(t, tc.c, tc.cert(t))
+			assert.Equal(t, http.StatusUnauthorized, code, body.Raw)
+			assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+		})
+	}
+
+	t.Run("case=ignores forwarded certificates from untrusted sources", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyTLSAllowTerminationFrom, []string{"10.0.0.0/8"})
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyTLSAllowTerminationFrom, []string{"127.0.0.1/32", "::1/128"})
+		})
+
+		code, body := requestToken(t, selfSignedClient, selfSigned)
+		assert.Equal(t, http.StatusUnauthorized, code, body.Raw)
+		assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=ignores forwarded certificates if only X-Forwarded-For matches the trusted sources", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyTLSAllowTerminationFrom, []string{"10.0.0.0/8"})
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyTLSAllowTerminationFrom, []string{"127.0.0.1/32", "::1/128"})
+		})
+
+		for _, c := range []struct {
+			cl   *hc.Client
+			cert *certificate
+		}{
+			{cl: selfSignedClient, cert: selfSigned},
+			{cl: pkiClient, cert: newCertificate(t, "pki-client", ca)},
+		} {
+			code, body := requestToken(t, c.cl, c.cert, "X-Forwarded-For", "10.0.0.1")
+			assert.Equal(t, http.StatusUnauthorized, code, body.Raw)
+			assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+		}
+	})
+
+	t.Run("case=rejects tls_client_auth if no certificate authorities are configured", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyTLSClientCertificateCAString, "")
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyTLSClientCertificateCAString, base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})))
+		})
+
+		code, body := requestToken(t, pkiClient, newCertificate(t, "pki-client", ca))
+		assert.Equal(t, http.StatusUnauthorized, code, body.Raw)
+		assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+
+		code, body = requestToken(t, pkiClient, newCertificate(t, "pki-client", nil))
+		assert.Equal(t, http.StatusUnauthorized, code, body.Raw)
+		assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=requires the bound certificate to exchange certificate-bound tokens", func(t *testing.T) {
+		exchanger := &hc.Client{
+			GrantTypes:                            []string{"client_credentials", hydraoauth2.GrantTypeTokenExchange},
+			Scope:                                 "foo",
+			TokenEndpointAuthMethod:               hydraoauth2.ClientAuthMethodTLSClientAuth,
+			TLSClientAuthSubjectDN:                "CN=pki-exchanger",
+			TLSClientCertificateBoundAccessTokens: true,
+		}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, exchanger))
+
+		cert := newCertificate(t, "pki-exchanger", ca)
+		code, body := requestToken(t, exchanger, cert)
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		subjectToken := body.Get("access_token").String()
+
+		exchange := func(t *testing.T, cert *certificate) (int, gjson.Result) {
+			form := url.Values{
+				"grant_type":         {hydraoauth2.GrantTypeTokenExchange},
+				"client_id":          {exchanger.GetID()},
+				"subject_token":      {subjectToken},
+				"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+			}
+			req, err := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set(certificateHeader, encode(cert))
+
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			return res.StatusCode, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+		}
+
+		code, body = exchange(t, newCertificate(t, "pki-exchanger", ca))
+		assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+		assert.Equal(t, "invalid_grant", body.Get("error").String(), body.Raw)
+
+		code, body = exchange(t, cert)
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		i := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: exchanger.GetID()}, body.Get("access_token").String(), adminTS)
+		assert.Equal(t, thumbprint(cert), i.Get(`cnf.x5t#S256`).String(), i.Raw)
+	})
+
+	t.Run("case=advertises mutual TLS in the discovery document", func(t *testing.T) {
+		res, err := http.Get(publicTS.URL + hydraoauth2.WellKnownPath)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body := gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+
+		assert.Equal(t, tokenURL, body.Get("mtls_endpoint_aliases.token_endpoint").String(), body.Raw)
+		assert.True(t, body.Get("tls_client_certificate_bound_access_tokens").Bool(), body.Raw)
+		assert.Contains(t, body.Get("token_endpoint_auth_methods_supported").String(), hydraoauth2.ClientAuthMethodSelfSignedTLSClientAuth)
+	})
+}
@@ -0,0 +1,213 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+	"github.com/ory/x/pointerx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	hydraoauth2 "github.com/ory/hydra/oauth2"
+)
+
+func TestPushedAuthorizationRequest(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	publicTS, adminTS := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	adminClient := hydra.NewAPIClient(hydra.NewConfiguration())
+	adminClient.GetConfig().Servers = hydra.ServerConfigurations{{URL: adminTS.URL}}
+
+	subject := "aeneas-rekkas"
+	var loginDelay time.Duration
+	testhelpers.NewLoginConsentUI(t, reg.Config(),
+		func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(loginDelay)
+			v, _, err := adminClient.OAuth2Api.AcceptOAuth2LoginRequest(ctx).
+				LoginChallenge(r.URL.Query().Get("login_challenge")).
+				AcceptOAuth2LoginRequest(hydra.AcceptOAuth2LoginRequest{Subject: subject}).
+				Execute()
+			require.NoError(t, err)
+			http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			rr, _, err := adminClient.OAuth2Api.GetOAuth2ConsentRequest(ctx).ConsentChallenge(r.URL.Query().Get("consent_challenge")).Execute()
+			require.NoError(t, err)
+
+			v, _, err := adminClient.OAuth2Api.AcceptOAuth2ConsentRequest(ctx).
+				ConsentChallenge(r.URL.Query().Get("consent_challenge")).
+				AcceptOAuth2ConsentRequest(hydra.AcceptOAuth2ConsentRequest{
+					GrantScope:  rr.RequestedScope,
+					RememberFor: pointerx.Int64(0),
+				}).
+				Execute()
+			require.NoError(t, err)
+			http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+		},
+	)
+
+	newClient := func(t *testing.T, requirePAR bool) (*hc.Client, *goauth2.Config) {
+		secret := uuid.New()
+		c := &hc.Client{
+			Secret:                             secret,
+			RedirectURIs:                       []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+			ResponseTypes:                      []string{"code"},
+			GrantTypes:                         []string{"authorization_code"},
+			Scope:                              "hydra openid",
+			RequirePushedAuthorizationRequests: requirePAR,
+		}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+		return c, &goauth2.Config{
+			ClientID:     c.GetID(),
+			ClientSecret: secret,
+			RedirectURL:  c.RedirectURIs[0],
+			Endpoint: goauth2.Endpoint{
+				AuthURL:   reg.Config().OAuth2AuthURL(ctx).String(),
+				TokenURL:  reg.Config().OAuth2TokenURL(ctx).String(),
+				AuthStyle: goauth2.AuthStyleInHeader,
+			},
+			Scopes: strings.Split(c.Scope, " "),
+		}
+	}
+
+	pushAuthorizationRequest := func(t *testing.T, conf *goauth2.Config, state string) (*http.Response, gjson.Result) {
+		req, err := http.NewRequest("POST", publicTS.URL+hydraoauth2.PushedAuthorizationRequestPath, strings.NewReader(url.Values{
+			"response_type": {"code"},
+			"redirect_uri":  {conf.RedirectURL},
+			"scope":         {strings.Join(conf.Scopes, " ")},
+			"state":         {state},
+			"nonce":         {uuid.New()},
+		}.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(url.QueryEscape(conf.ClientID), url.QueryEscape(conf.ClientSecret))
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	authorize := func(t *testing.T, conf *goauth2.Config, query url.Values) url.Values {
+		query.Set("client_id", conf.ClientID)
+		res, err := testhelpers.NewEmptyJarClient(t).Get(conf.Endpoint.AuthURL + "?" + query.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.Request.URL.Query()
+	}
+
+	t.Run("case=performs the authorization code flow with a pushed authorization request", func(t *testing.T) {
+		_, conf := newClient(t, true)
+		state := uuid.New()
+
+		res, body := pushAuthorizationRequest(t, conf, state)
+		require.Equal(t, http.StatusCreated, res.StatusCode, body.Raw)
+		requestURI := body.Get("request_uri").String()
+		assert.True(t, strings.HasPrefix(requestURI, config.PushedAuthorizeRequestURIPrefix), body.Raw)
+		assert.Equal(t, int64(60), body.Get("expires_in").Int(), body.Raw)
+
+		q := authorize(t, conf, url.Values{"request_uri": {requestURI}})
+		require.Empty(t, q.Get("error"), q.Get("error_description"))
+		assert.Equal(t, state, q.Get("state"))
+		require.NotEmpty(t, q.Get("code"))
+
+		token, err := conf.Exchange(ctx, q.Get("code"))
+		require.NoError(t, err)
+		assert.NotEmpty(t, token.AccessToken)
+		assert.NotEmpty(t, token.Extra("id_token"))
+
+		t.Run("case=request_uri can not be used twice", func(t *testing.T) {
+			q := authorize(t, conf, url.Values{"request_uri": {requestURI}})
+			assert.Equal(t, "invalid_request_uri", q.Get("error"))
+		})
+	})
+
+	t.Run("case=login and consent may take longer than the lifespan of the request_uri", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyPushedAuthorizeRequestLifespan, "1s")
+		loginDelay = 2 * time.Second
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyPushedAuthorizeRequestLifespan, "1m")
+			loginDelay = 0
+		})
+
+		_, conf := newClient(t, true)
+		res, body := pushAuthorizationRequest(t, conf, uuid.New())
+		require.Equal(t, http.StatusCreated, res.StatusCode, body.Raw)
+
+		q := authorize(t, conf, url.Values{"request_uri": {body.Get("request_uri").String()}})
+		require.Empty(t, q.Get("error"), q.Get("error_description"))
+		require.NotEmpty(t, q.Get("code"))
+	})
+
+	t.Run("case=request_uri can not be replayed while the login and consent flow is running", func(t *testing.T) {
+		_, conf := newClient(t, true)
+		res, body := pushAuthorizationRequest(t, conf, uuid.New())
+		require.Equal(t, http.StatusCreated, res.StatusCode, body.Raw)
+		requestURI := body.Get("request_uri").String()
+
+		// Resolving the request_uri is what the authorization endpoint does when the login and consent flow starts.
+		require.NoError(t, reg.OAuth2Storage().ResolvePARSession(ctx, requestURI, time.Now().Add(time.Hour)))
+
+		q := authorize(t, conf, url.Values{"request_uri": {requestURI}})
+		assert.Equal(t, "invalid_request_uri", q.Get("error"), q.Get("error_description"))
+	})
+
+	t.Run("case=rejects authorization requests which were not pushed if the client requires it", func(t *testing.T) {
+		_, conf := newClient(t, true)
+
+		q := authorize(t, conf, url.Values{
+			"response_type": {"code"},
+			"redirect_uri":  {conf.RedirectURL},
+			"scope":         {"hydra"},
+			"state":         {uuid.New()},
+		})
+		assert.Equal(t, "invalid_request", q.Get("error"))
+		assert.Contains(t, q.Get("error_description"), "pushed authorization request")
+	})
+
+	t.Run("case=rejects unauthenticated clients", func(t *testing.T) {
+		_, conf := newClient(t, false)
+		conf.ClientSecret = "wrong"
+
+		res, body := pushAuthorizationRequest(t, conf, uuid.New())
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=rejects request_uri from another client", func(t *testing.T) {
+		_, conf := newClient(t, false)
+		_, other := newClient(t, false)
+
+		res, body := pushAuthorizationRequest(t, conf, uuid.New())
+		require.Equal(t, http.StatusCreated, res.StatusCode, body.Raw)
+
+		requestURI := body.Get("request_uri").String()
+		q := authorize(t, other, url.Values{"request_uri": {requestURI}})
+		assert.Equal(t, "invalid_request", q.Get("error"))
+
+		// The rejected request must not use up the request_uri of the client which pushed it.
+		q = authorize(t, conf, url.Values{"request_uri": {requestURI}})
+		require.Empty(t, q.Get("error"), q.Get("error_description"))
+		assert.NotEmpty(t, q.Get("code"))
+	})
+}
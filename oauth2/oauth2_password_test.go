@@ -0,0 +1,193 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	hydraoauth2 "github.com/ory/hydra/oauth2"
+)
+
+func TestPasswordGrant(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	publicTS, adminTS := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	var received hydraoauth2.PasswordGrantWebhookRequest
+	var grantedDetails interface{}
+	var calls int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		switch received.Password {
+		case "correct":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"subject":                     "user-" + received.Username,
+				"session":                     map[string]interface{}{"access_token": map[string]interface{}{"foo": "bar"}},
+				"grant_authorization_details": grantedDetails,
+			})
+		case "broken":
+			w.WriteHeader(http.StatusBadRequest)
+		case "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(webhook.Close)
+
+	c := &hc.Client{
+		Secret:                    "secret",
+		GrantTypes:                []string{"password", "refresh_token"},
+		Scope:                     "offline read",
+		AuthorizationDetailsTypes: []string{"account_information", "payment_initiation"},
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+
+	requestToken := func(t *testing.T, cl *hc.Client, form url.Values) (int, gjson.Result) {
+		form.Set("grant_type", "password")
+		req, err := http.NewRequest(http.MethodPost, publicTS.URL+hydraoauth2.TokenPath, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(cl.GetID(), "secret")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.StatusCode, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	credentials := func(password string) url.Values {
+		return url.Values{"username": {"alice"}, "password": {password}, "scope": {"offline read"}}
+	}
+
+	t.Run("case=is disabled by default", func(t *testing.T) {
+		code, body := requestToken(t, c, credentials("correct"))
+		assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+		assert.NotEqual(t, "", body.Get("error").String(), body.Raw)
+		assert.False(t, body.Get("access_token").Exists(), body.Raw)
+	})
+
+	t.Run("case=is disabled without a webhook", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyOAuth2GrantPasswordEnabled, true)
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOAuth2GrantPasswordEnabled, false) })
+
+		code, body := requestToken(t, c, credentials("correct"))
+		assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+		assert.False(t, body.Get("access_token").Exists(), body.Raw)
+	})
+
+	t.Run("case=enabled", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyOAuth2GrantPasswordEnabled, true)
+		reg.Config().MustSet(ctx, config.KeyOAuth2GrantPasswordWebhookURL, webhook.URL)
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOAuth2GrantPasswordEnabled, false) })
+
+		t.Run("case=issues tokens for valid credentials", func(t *testing.T) {
+			code, body := requestToken(t, c, credentials("correct"))
+			require.Equal(t, http.StatusOK, code, body.Raw)
+			assert.NotEmpty(t, body.Get("access_token").String(), body.Raw)
+			assert.NotEmpty(t, body.Get("refresh_token").String(), body.Raw)
+			assert.Equal(t, "offline read", body.Get("scope").String(), body.Raw)
+
+			assert.Equal(t, "alice", received.Username)
+			assert.Equal(t, c.GetID(), received.ClientID)
+			assert.EqualValues(t, []string{"offline", "read"}, received.RequestedScope)
+
+			introspection := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID()}, body.Get("access_token").String(), adminTS)
+			assert.True(t, introspection.Get("active").Bool(), introspection.Raw)
+			assert.Equal(t, "user-alice", introspection.Get("sub").String(), introspection.Raw)
+			assert.Equal(t, "bar", introspection.Get("ext.foo").String(), introspection.Raw)
+		})
+
+		t.Run("case=grants the authorization details granted by the webhook", func(t *testing.T) {
+			grantedDetails = []map[string]interface{}{{"type": "account_information", "actions": []string{"list_accounts"}}}
+			t.Cleanup(func() { grantedDetails = nil })
+
+			form := credentials("correct")
+			form.Set("authorization_details", `[{"type":"account_information"},{"type":"payment_initiation"}]`)
+			code, body := requestToken(t, c, form)
+			require.Equal(t, http.StatusOK, code, body.Raw)
+			assert.Len(t, received.RequestedAuthorizationDetails, 2)
+
+			introspection := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID()}, body.Get("access_token").String(), adminTS)
+			assert.Len(t, introspection.Get("authorization_details").Array(), 1, introspection.Raw)
+			assert.Equal(t, "list_accounts", introspection.Get("authorization_details.0.actions.0").String(), introspection.Raw)
+		})
+
+		t.Run("case=does not grant authorization details the webhook did not grant", func(t *testing.T) {
+			form := credentials("correct")
+			form.Set("authorization_details", `[{"type":"account_information"}]`)
+			code, body := requestToken(t, c, form)
+			require.Equal(t, http.StatusOK, code, body.Raw)
+
+			introspection := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID()}, body.Get("access_token").String(), adminTS)
+			assert.False(t, introspection.Get("authorization_details").Exists(), introspection.Raw)
+		})
+
+		t.Run("case=fails if the webhook grants authorization details which were not requested", func(t *testing.T) {
+			grantedDetails = []map[string]interface{}{{"type": "payment_initiation"}}
+			t.Cleanup(func() { grantedDetails = nil })
+
+			form := credentials("correct")
+			form.Set("authorization_details", `[{"type":"account_information"}]`)
+			code, body := requestToken(t, c, form)
+			assert.Equal(t, http.StatusInternalServerError, code, body.Raw)
+			assert.Equal(t, "server_error", body.Get("error").String(), body.Raw)
+		})
+
+		t.Run("case=rejects invalid credentials", func(t *testing.T) {
+			code, body := requestToken(t, c, credentials("wrong"))
+			assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+			assert.Equal(t, "invalid_grant", body.Get("error").String(), body.Raw)
+		})
+
+		t.Run("case=fails if the webhook fails", func(t *testing.T) {
+			code, body := requestToken(t, c, credentials("broken"))
+			assert.Equal(t, http.StatusInternalServerError, code, body.Raw)
+			assert.Equal(t, "server_error", body.Get("error").String(), body.Raw)
+		})
+
+		t.Run("case=does not retry the webhook", func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			code, body := requestToken(t, c, credentials("unavailable"))
+			assert.Equal(t, http.StatusInternalServerError, code, body.Raw)
+			assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+		})
+
+		t.Run("case=requires username and password", func(t *testing.T) {
+			code, body := requestToken(t, c, url.Values{"username": {"alice"}})
+			assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+			assert.Equal(t, "invalid_request", body.Get("error").String(), body.Raw)
+		})
+
+		t.Run("case=rejects clients without the password grant", func(t *testing.T) {
+			other := &hc.Client{Secret: "secret", GrantTypes: []string{"client_credentials"}}
+			require.NoError(t, reg.ClientManager().CreateClient(ctx, other))
+
+			code, body := requestToken(t, other, credentials("correct"))
+			assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+			assert.Equal(t, "unauthorized_client", body.Get("error").String(), body.Raw)
+		})
+	})
+}
@@ -0,0 +1,308 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	hydraoauth2 "github.com/ory/hydra/oauth2"
+	"github.com/ory/hydra/x"
+)
+
+func TestRichAuthorizationRequests(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "jwt")
+	publicTS, adminTS := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	adminClient := hydra.NewAPIClient(hydra.NewConfiguration())
+	adminClient.GetConfig().Servers = hydra.ServerConfigurations{{URL: adminTS.URL}}
+
+	payment := map[string]interface{}{
+		"type":             "payment_initiation",
+		"actions":          []string{"initiate"},
+		"instructedAmount": map[string]interface{}{"currency": "EUR", "amount": "100.00"},
+		"creditorAccount":  map[string]interface{}{"iban": "DE02100100109307118603"},
+	}
+	accounts := map[string]interface{}{
+		"type":    "account_information",
+		"actions": []string{"list_accounts"},
+	}
+
+	var requested gjson.Result
+	var grant func(requested gjson.Result) interface{}
+	var remember bool
+	testhelpers.NewLoginConsentUI(t, reg.Config(),
+		func(w http.ResponseWriter, r *http.Request) {
+			v, _, err := adminClient.OAuth2Api.AcceptOAuth2LoginRequest(ctx).
+				LoginChallenge(r.URL.Query().Get("login_challenge")).
+				AcceptOAuth2LoginRequest(hydra.AcceptOAuth2LoginRequest{Subject: "foo@bar.com"}).
+				Execute()
+			require.NoError(t, err)
+			http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			challenge := r.URL.Query().Get("consent_challenge")
+			res, err := http.Get(adminTS.URL + "/admin/oauth2/auth/requests/consent?consent_challenge=" + challenge)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			requested = gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+
+			body, err := json.Marshal(map[string]interface{}{
+				"grant_scope":                 []string{"openid", "offline"},
+				"grant_authorization_details": grant(requested.Get("requested_authorization_details")),
+				"remember":                    remember,
+			})
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPut, adminTS.URL+"/admin/oauth2/auth/requests/consent/accept?consent_challenge="+challenge, bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			res, err = http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			result := gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+			if res.StatusCode != http.StatusOK {
+				http.Redirect(w, r, testhelpers.NewCallbackURL(t, "error", testhelpers.HTTPServerNotImplementedHandler)+"?error="+url.QueryEscape(result.Get("error").String()), http.StatusFound)
+				return
+			}
+			http.Redirect(w, r, result.Get("redirect_to").String(), http.StatusFound)
+		},
+	)
+
+	secret := uuid.New()
+	c := &hc.Client{
+		Secret:                    secret,
+		RedirectURIs:              []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+		ResponseTypes:             []string{"code"},
+		GrantTypes:                []string{"authorization_code", "refresh_token", "client_credentials"},
+		Scope:                     "openid offline",
+		AuthorizationDetailsTypes: []string{"payment_initiation", "account_information"},
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+	conf := &goauth2.Config{
+		ClientID:     c.GetID(),
+		ClientSecret: secret,
+		RedirectURL:  c.RedirectURIs[0],
+		Endpoint: goauth2.Endpoint{
+			AuthURL:   reg.Config().OAuth2AuthURL(ctx).String(),
+			TokenURL:  reg.Config().OAuth2TokenURL(ctx).String(),
+			AuthStyle: goauth2.AuthStyleInHeader,
+		},
+		Scopes: []string{"openid", "offline"},
+	}
+
+	encode := func(t *testing.T, details ...interface{}) string {
+		out, err := json.Marshal(details)
+		require.NoError(t, err)
+		return string(out)
+	}
+
+	authorize := func(t *testing.T, details string) url.Values {
+		res, err := testhelpers.NewEmptyJarClient(t).Get(conf.AuthCodeURL(uuid.New(), goauth2.SetAuthURLParam("authorization_details", details)))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.Request.URL.Query()
+	}
+
+	tokenRequestAs := func(t *testing.T, clientID string, form url.Values) gjson.Result {
+		req, err := http.NewRequest(http.MethodPost, publicTS.URL+hydraoauth2.TokenPath, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(clientID, conf.ClientSecret)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	tokenRequest := func(t *testing.T, form url.Values) gjson.Result {
+		return tokenRequestAs(t, conf.ClientID, form)
+	}
+
+	accessTokenClaims := func(t *testing.T, token string) gjson.Result {
+		parts := strings.Split(token, ".")
+		require.Len(t, parts, 3)
+		payload, err := x.DecodeSegment(parts[1])
+		require.NoError(t, err)
+		return gjson.ParseBytes(payload)
+	}
+
+	t.Run("case=grants a subset of the requested authorization details", func(t *testing.T) {
+		grant = func(requested gjson.Result) interface{} {
+			return []interface{}{requested.Array()[0].Value()}
+		}
+
+		q := authorize(t, encode(t, payment, accounts))
+		require.Empty(t, q.Get("error"), q.Get("error_description"))
+		assert.Len(t, requested.Get("requested_authorization_details").Array(), 2, requested.Raw)
+		assert.Equal(t, "100.00", requested.Get("requested_authorization_details.0.instructedAmount.amount").String(), requested.Raw)
+
+		token := tokenRequest(t, url.Values{"grant_type": {"authorization_code"}, "code": {q.Get("code")}, "redirect_uri": {conf.RedirectURL}})
+		require.NotEmpty(t, token.Get("access_token").String(), token.Raw)
+		assert.Len(t, token.Get("authorization_details").Array(), 1, token.Raw)
+		assert.Equal(t, "payment_initiation", token.Get("authorization_details.0.type").String(), token.Raw)
+
+		claims := accessTokenClaims(t, token.Get("access_token").String())
+		assert.Equal(t, "DE02100100109307118603", claims.Get("authorization_details.0.creditorAccount.iban").String(), claims.Raw)
+
+		introspection := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID()}, token.Get("access_token").String(), adminTS)
+		assert.True(t, introspection.Get("active").Bool(), introspection.Raw)
+		assert.Equal(t, "EUR", introspection.Get("authorization_details.0.instructedAmount.currency").String(), introspection.Raw)
+
+		t.Run("case=refresh keeps the authorization details", func(t *testing.T) {
+			refreshed := tokenRequest(t, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {token.Get("refresh_token").String()}})
+			require.NotEmpty(t, refreshed.Get("access_token").String(), refreshed.Raw)
+			assert.Equal(t, "payment_initiation", refreshed.Get("authorization_details.0.type").String(), refreshed.Raw)
+
+			t.Run("case=refresh can not widen the authorization details", func(t *testing.T) {
+				widened := tokenRequest(t, url.Values{
+					"grant_type":            {"refresh_token"},
+					"refresh_token":         {refreshed.Get("refresh_token").String()},
+					"authorization_details": {encode(t, accounts)},
+				})
+				assert.Equal(t, "invalid_authorization_details", widened.Get("error").String(), widened.Raw)
+			})
+		})
+	})
+
+	t.Run("case=narrows the authorization details when refreshing", func(t *testing.T) {
+		grant = func(requested gjson.Result) interface{} {
+			return requested.Value()
+		}
+
+		t.Run("case=the authorization code grant can not narrow", func(t *testing.T) {
+			q := authorize(t, encode(t, payment, accounts))
+			require.Empty(t, q.Get("error"), q.Get("error_description"))
+
+			token := tokenRequest(t, url.Values{
+				"grant_type":            {"authorization_code"},
+				"code":                  {q.Get("code")},
+				"redirect_uri":          {conf.RedirectURL},
+				"authorization_details": {encode(t, map[string]interface{}{"type": "account_information"})},
+			})
+			assert.Equal(t, "invalid_authorization_details", token.Get("error").String(), token.Raw)
+		})
+
+		q := authorize(t, encode(t, payment, accounts))
+		require.Empty(t, q.Get("error"), q.Get("error_description"))
+
+		token := tokenRequest(t, url.Values{"grant_type": {"authorization_code"}, "code": {q.Get("code")}, "redirect_uri": {conf.RedirectURL}})
+		require.NotEmpty(t, token.Get("refresh_token").String(), token.Raw)
+		require.Len(t, token.Get("authorization_details").Array(), 2, token.Raw)
+
+		refreshed := tokenRequest(t, url.Values{
+			"grant_type":            {"refresh_token"},
+			"refresh_token":         {token.Get("refresh_token").String()},
+			"authorization_details": {encode(t, map[string]interface{}{"type": "account_information"})},
+		})
+		require.NotEmpty(t, refreshed.Get("access_token").String(), refreshed.Raw)
+		require.Len(t, refreshed.Get("authorization_details").Array(), 1, refreshed.Raw)
+		assert.Equal(t, "list_accounts", refreshed.Get("authorization_details.0.actions.0").String(), refreshed.Raw)
+
+		introspection := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID()}, refreshed.Get("access_token").String(), adminTS)
+		assert.Len(t, introspection.Get("authorization_details").Array(), 1, introspection.Raw)
+	})
+
+	t.Run("case=rejects granting authorization details which were not requested", func(t *testing.T) {
+		grant = func(gjson.Result) interface{} {
+			return []interface{}{accounts}
+		}
+
+		q := authorize(t, encode(t, payment))
+		assert.Equal(t, "invalid_request", q.Get("error"))
+	})
+
+	t.Run("case=rejects malformed authorization details", func(t *testing.T) {
+		for _, details := range []string{`{"type":"payment_initiation"}`, `[{"actions":["initiate"]}]`, `[1]`} {
+			t.Run("details="+details, func(t *testing.T) {
+				q := authorize(t, details)
+				assert.Equal(t, "invalid_authorization_details", q.Get("error"))
+			})
+		}
+	})
+
+	t.Run("case=rejects malformed authorization details in pushed authorization requests", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, publicTS.URL+hydraoauth2.PushedAuthorizationRequestPath, strings.NewReader(url.Values{
+			"response_type":         {"code"},
+			"redirect_uri":          {conf.RedirectURL},
+			"scope":                 {"openid"},
+			"state":                 {uuid.New()},
+			"authorization_details": {`[{}]`},
+		}.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(conf.ClientID, conf.ClientSecret)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body := gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_authorization_details", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=grants the requested authorization details to the client credentials grant", func(t *testing.T) {
+		token := tokenRequest(t, url.Values{"grant_type": {"client_credentials"}, "authorization_details": {encode(t, accounts)}})
+		require.NotEmpty(t, token.Get("access_token").String(), token.Raw)
+		assert.Equal(t, "account_information", token.Get("authorization_details.0.type").String(), token.Raw)
+
+		claims := accessTokenClaims(t, token.Get("access_token").String())
+		assert.Equal(t, "account_information", claims.Get("authorization_details.0.type").String(), claims.Raw)
+	})
+
+	t.Run("case=rejects authorization details types the client may not request", func(t *testing.T) {
+		token := tokenRequest(t, url.Values{"grant_type": {"client_credentials"}, "authorization_details": {encode(t, map[string]interface{}{"type": "customer_information"})}})
+		assert.Equal(t, "invalid_authorization_details", token.Get("error").String(), token.Raw)
+
+		q := authorize(t, encode(t, payment, map[string]interface{}{"type": "customer_information"}))
+		assert.Equal(t, "invalid_authorization_details", q.Get("error"))
+
+		other := &hc.Client{Secret: secret, GrantTypes: []string{"client_credentials"}}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, other))
+		token = tokenRequestAs(t, other.GetID(), url.Values{"grant_type": {"client_credentials"}, "authorization_details": {encode(t, accounts)}})
+		assert.Equal(t, "invalid_authorization_details", token.Get("error").String(), token.Raw)
+	})
+
+	t.Run("case=does not skip the consent of requests with authorization details", func(t *testing.T) {
+		grant = func(requested gjson.Result) interface{} {
+			return requested.Value()
+		}
+		remember = true
+		t.Cleanup(func() { remember = false })
+
+		q := authorize(t, encode(t, payment))
+		require.Empty(t, q.Get("error"), q.Get("error_description"))
+		assert.False(t, requested.Get("skip").Bool(), requested.Raw)
+
+		q = authorize(t, "")
+		require.Empty(t, q.Get("error"), q.Get("error_description"))
+		assert.True(t, requested.Get("skip").Bool(), "the remembered consent is used without authorization details: %s", requested.Raw)
+
+		q = authorize(t, encode(t, payment))
+		require.Empty(t, q.Get("error"), q.Get("error_description"))
+		assert.False(t, requested.Get("skip").Bool(), requested.Raw)
+		assert.Len(t, requested.Get("requested_authorization_details").Array(), 1, requested.Raw)
+	})
+}
@@ -0,0 +1,234 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+	"github.com/ory/x/pointerx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	hydraoauth2 "github.com/ory/hydra/oauth2"
+)
+
+func TestResourceIndicators(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	publicTS, adminTS := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	adminClient := hydra.NewAPIClient(hydra.NewConfiguration())
+	adminClient.GetConfig().Servers = hydra.ServerConfigurations{{URL: adminTS.URL}}
+
+	const api, reports = "https://api.example.com", "https://reports.example.com/v1"
+
+	var requestedAudience []string
+	testhelpers.NewLoginConsentUI(t, reg.Config(),
+		func(w http.ResponseWriter, r *http.Request) {
+			v, _, err := adminClient.OAuth2Api.AcceptOAuth2LoginRequest(ctx).
+				LoginChallenge(r.URL.Query().Get("login_challenge")).
+				AcceptOAuth2LoginRequest(hydra.AcceptOAuth2LoginRequest{Subject: "foo@bar.com"}).
+				Execute()
+			require.NoError(t, err)
+			http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			rr, _, err := adminClient.OAuth2Api.GetOAuth2ConsentRequest(ctx).ConsentChallenge(r.URL.Query().Get("consent_challenge")).Execute()
+			require.NoError(t, err)
+			requestedAudience = rr.RequestedAccessTokenAudience
+
+			v, _, err := adminClient.OAuth2Api.AcceptOAuth2ConsentRequest(ctx).
+				ConsentChallenge(r.URL.Query().Get("consent_challenge")).
+				AcceptOAuth2ConsentRequest(hydra.AcceptOAuth2ConsentRequest{
+					GrantScope:               rr.RequestedScope,
+					GrantAccessTokenAudience: rr.RequestedAccessTokenAudience,
+					RememberFor:              pointerx.Int64(0),
+				}).
+				Execute()
+			require.NoError(t, err)
+			http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+		},
+	)
+
+	secret := uuid.New()
+	c := &hc.Client{
+		Secret:        secret,
+		RedirectURIs:  []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+		ResponseTypes: []string{"code"},
+		GrantTypes:    []string{"authorization_code", "refresh_token", "client_credentials"},
+		Scope:         "offline",
+		Audience:      []string{api, reports},
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+	conf := &goauth2.Config{
+		ClientID:     c.GetID(),
+		ClientSecret: secret,
+		RedirectURL:  c.RedirectURIs[0],
+		Endpoint: goauth2.Endpoint{
+			AuthURL:   reg.Config().OAuth2AuthURL(ctx).String(),
+			TokenURL:  reg.Config().OAuth2TokenURL(ctx).String(),
+			AuthStyle: goauth2.AuthStyleInHeader,
+		},
+		Scopes: []string{"offline"},
+	}
+
+	authorize := func(t *testing.T, resources ...string) url.Values {
+		query := url.Values{
+			"client_id":     {conf.ClientID},
+			"response_type": {"code"},
+			"redirect_uri":  {conf.RedirectURL},
+			"scope":         {"offline"},
+			"state":         {uuid.New()},
+			"resource":      resources,
+		}
+		res, err := testhelpers.NewEmptyJarClient(t).Get(conf.Endpoint.AuthURL + "?" + query.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.Request.URL.Query()
+	}
+
+	post := func(t *testing.T, path string, form url.Values) (int, gjson.Result) {
+		req, err := http.NewRequest(http.MethodPost, publicTS.URL+path, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(conf.ClientID, conf.ClientSecret)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.StatusCode, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	audienceOf := func(t *testing.T, token string) []string {
+		introspection := testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID()}, token, adminTS)
+		require.True(t, introspection.Get("active").Bool(), introspection.Raw)
+		var audience []string
+		for _, a := range introspection.Get("aud").Array() {
+			audience = append(audience, a.String())
+		}
+		return audience
+	}
+
+	t.Run("case=maps resource indicators to the audience", func(t *testing.T) {
+		q := authorize(t, api, reports)
+		require.Empty(t, q.Get("error"), q.Get("error_description"))
+		assert.ElementsMatch(t, []string{api, reports}, requestedAudience)
+
+		code, token := post(t, hydraoauth2.TokenPath, url.Values{"grant_type": {"authorization_code"}, "code": {q.Get("code")}, "redirect_uri": {conf.RedirectURL}})
+		require.Equal(t, http.StatusOK, code, token.Raw)
+		assert.ElementsMatch(t, []string{api, reports}, audienceOf(t, token.Get("access_token").String()))
+
+		t.Run("case=refresh narrows the resource", func(t *testing.T) {
+			code, refreshed := post(t, hydraoauth2.TokenPath, url.Values{
+				"grant_type":    {"refresh_token"},
+				"refresh_token": {token.Get("refresh_token").String()},
+				"resource":      {reports},
+			})
+			require.Equal(t, http.StatusOK, code, refreshed.Raw)
+			assert.Equal(t, []string{reports}, audienceOf(t, refreshed.Get("access_token").String()))
+
+			t.Run("case=refresh keeps the resources granted originally", func(t *testing.T) {
+				code, widened := post(t, hydraoauth2.TokenPath, url.Values{
+					"grant_type":    {"refresh_token"},
+					"refresh_token": {refreshed.Get("refresh_token").String()},
+					"resource":      {api},
+				})
+				require.Equal(t, http.StatusOK, code, widened.Raw)
+				assert.Equal(t, []string{api}, audienceOf(t, widened.Get("access_token").String()))
+
+				code, unnarrowed := post(t, hydraoauth2.TokenPath, url.Values{
+					"grant_type":    {"refresh_token"},
+					"refresh_token": {widened.Get("refresh_token").String()},
+				})
+				require.Equal(t, http.StatusOK, code, unnarrowed.Raw)
+				assert.ElementsMatch(t, []string{api, reports}, audienceOf(t, unnarrowed.Get("access_token").String()))
+
+				t.Run("case=refresh can not widen the resource", func(t *testing.T) {
+					code, widened := post(t, hydraoauth2.TokenPath, url.Values{
+						"grant_type":    {"refresh_token"},
+						"refresh_token": {unnarrowed.Get("refresh_token").String()},
+						"resource":      {"https://unknown.example.com"},
+					})
+					assert.Equal(t, http.StatusBadRequest, code, widened.Raw)
+					assert.Equal(t, "invalid_target", widened.Get("error").String(), widened.Raw)
+				})
+			})
+		})
+	})
+
+	t.Run("case=rejects resources the client may not request", func(t *testing.T) {
+		q := authorize(t, "https://unknown.example.com")
+		assert.Equal(t, "invalid_target", q.Get("error"), q.Get("error_description"))
+
+		code, body := post(t, hydraoauth2.TokenPath, url.Values{"grant_type": {"client_credentials"}, "resource": {"https://unknown.example.com"}})
+		assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+		assert.Equal(t, "invalid_target", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=rejects malformed resource indicators", func(t *testing.T) {
+		for _, resource := range []string{"/relative", "https://api.example.com#fragment"} {
+			t.Run("resource="+resource, func(t *testing.T) {
+				q := authorize(t, resource)
+				assert.Equal(t, "invalid_target", q.Get("error"), q.Get("error_description"))
+
+				code, body := post(t, hydraoauth2.TokenPath, url.Values{"grant_type": {"client_credentials"}, "resource": {resource}})
+				assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+				assert.Equal(t, "invalid_target", body.Get("error").String(), body.Raw)
+
+				code, body = post(t, hydraoauth2.PushedAuthorizationRequestPath, url.Values{
+					"response_type": {"code"},
+					"redirect_uri":  {conf.RedirectURL},
+					"state":         {uuid.New()},
+					"resource":      {resource},
+				})
+				assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+				assert.Equal(t, "invalid_target", body.Get("error").String(), body.Raw)
+			})
+		}
+	})
+
+	t.Run("case=accepts resource indicators in pushed authorization requests", func(t *testing.T) {
+		code, body := post(t, hydraoauth2.PushedAuthorizationRequestPath, url.Values{
+			"response_type": {"code"},
+			"redirect_uri":  {conf.RedirectURL},
+			"scope":         {"offline"},
+			"state":         {uuid.New()},
+			"resource":      {api},
+		})
+		require.Equal(t, http.StatusCreated, code, body.Raw)
+
+		res, err := testhelpers.NewEmptyJarClient(t).Get(conf.Endpoint.AuthURL + "?" + url.Values{
+			"client_id":   {conf.ClientID},
+			"request_uri": {body.Get("request_uri").String()},
+		}.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Empty(t, res.Request.URL.Query().Get("error"), res.Request.URL.Query().Get("error_description"))
+		assert.Equal(t, []string{api}, requestedAudience)
+	})
+
+	t.Run("case=grants the resource to the client credentials grant", func(t *testing.T) {
+		code, token := post(t, hydraoauth2.TokenPath, url.Values{"grant_type": {"client_credentials"}, "resource": {api}})
+		require.Equal(t, http.StatusOK, code, token.Raw)
+		assert.Equal(t, []string{api}, audienceOf(t, token.Get("access_token").String()))
+
+		code, body := post(t, hydraoauth2.TokenPath, url.Values{"grant_type": {"client_credentials"}, "resource": {"https://unknown.example.com"}})
+		assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+	})
+}
@@ -0,0 +1,338 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/internal/testhelpers"
+	"github.com/ory/hydra/jwk"
+	hydraoauth2 "github.com/ory/hydra/oauth2"
+	"github.com/ory/hydra/oauth2/trust"
+	"github.com/ory/hydra/x"
+)
+
+func TestTokenExchange(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	_, admin := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	secret := uuid.NewString()
+	newClient := func(t *testing.T, c *hc.Client) *hc.Client {
+		c.Secret = secret
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+		return c
+	}
+
+	getToken := func(t *testing.T, c *hc.Client, scopes ...string) string {
+		token, err := (&clientcredentials.Config{
+			ClientID:     c.GetID(),
+			ClientSecret: secret,
+			TokenURL:     reg.Config().OAuth2TokenURL(ctx).String(),
+			Scopes:       scopes,
+			AuthStyle:    goauth2.AuthStyleInHeader,
+		}).Token(ctx)
+		require.NoError(t, err)
+		return token.AccessToken
+	}
+
+	exchange := func(t *testing.T, c *hc.Client, params url.Values) (int, gjson.Result) {
+		params.Set("grant_type", hydraoauth2.GrantTypeTokenExchange)
+		req, err := http.NewRequest("POST", reg.Config().OAuth2TokenURL(ctx).String(), strings.NewReader(params.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(url.QueryEscape(c.GetID()), url.QueryEscape(secret))
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.StatusCode, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	introspect := func(t *testing.T, c *hc.Client, token string) gjson.Result {
+		return testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID(), ClientSecret: secret}, token, admin)
+	}
+
+	service := newClient(t, &hc.Client{
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "read write",
+	})
+	exchanger := newClient(t, &hc.Client{
+		GrantTypes: []string{"client_credentials", hydraoauth2.GrantTypeTokenExchange},
+		Scope:      "read write",
+		Audience:   []string{"https://api.example.com"},
+		TokenExchangePolicy: &hc.TokenExchangePolicy{
+			AllowedSubjectClients: []string{service.GetID()},
+		},
+	})
+
+	t.Run("case=exchanges a hydra access token for a downscoped token", func(t *testing.T) {
+		subjectToken := getToken(t, service, "read", "write")
+
+		code, body := exchange(t, exchanger, url.Values{
+			"subject_token":      {subjectToken},
+			"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+			"scope":              {"read"},
+			"audience":           {"https://api.example.com"},
+		})
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		assert.Equal(t, hydraoauth2.TokenTypeAccessToken, body.Get("issued_token_type").String(), body.Raw)
+		assert.Equal(t, "read", body.Get("scope").String(), body.Raw)
+		assert.Empty(t, body.Get("refresh_token").String(), body.Raw)
+
+		i := introspect(t, exchanger, body.Get("access_token").String())
+		assert.True(t, i.Get("active").Bool(), i.Raw)
+		assert.Equal(t, service.GetID(), i.Get("sub").String(), i.Raw)
+		assert.Equal(t, exchanger.GetID(), i.Get("client_id").String(), i.Raw)
+		assert.Equal(t, "read", i.Get("scope").String(), i.Raw)
+		assert.Equal(t, `["https://api.example.com"]`, i.Get("aud").Raw, i.Raw)
+		assert.False(t, i.Get("ext.act").Exists(), i.Raw)
+
+		subject := introspect(t, service, subjectToken)
+		assert.LessOrEqual(t, i.Get("exp").Int(), subject.Get("exp").Int(), "%s\n%s", i.Raw, subject.Raw)
+	})
+
+	t.Run("case=defaults to the scopes of the subject token", func(t *testing.T) {
+		code, body := exchange(t, exchanger, url.Values{
+			"subject_token":      {getToken(t, service, "write")},
+			"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+		})
+		require.Equal(t, http.StatusOK, code, body.Raw)
+		assert.Equal(t, "write", body.Get("scope").String(), body.Raw)
+	})
+
+	t.Run("case=adds the actor to the act claim", func(t *testing.T) {
+		code, body := exchange(t, exchanger, url.Values{
+			"subject_token":      {getToken(t, service, "read")},
+			"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+			"actor_token":        {getToken(t, exchanger)},
+			"actor_token_type":   {hydraoauth2.TokenTypeAccessToken},
+		})
+		require.Equal(t, http.StatusOK, code, body.Raw)
+
+		i := introspect(t, exchanger, body.Get("access_token").String())
+		assert.Equal(t, service.GetID(), i.Get("sub").String(), i.Raw)
+		assert.Equal(t, exchanger.GetID(), i.Get("ext.act.sub").String(), i.Raw)
+		assert.Equal(t, exchanger.GetID(), i.Get("ext.act.client_id").String(), i.Raw)
+
+		t.Run("case=nests the previous actor", func(t *testing.T) {
+			code, next := exchange(t, exchanger, url.Values{
+				"subject_token":      {body.Get("access_token").String()},
+				"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+				"actor_token":        {getToken(t, exchanger)},
+				"actor_token_type":   {hydraoauth2.TokenTypeAccessToken},
+			})
+			require.Equal(t, http.StatusOK, code, next.Raw)
+
+			i := introspect(t, exchanger, next.Get("access_token").String())
+			assert.Equal(t, service.GetID(), i.Get("sub").String(), i.Raw)
+			assert.Equal(t, exchanger.GetID(), i.Get("ext.act.act.sub").String(), i.Raw)
+		})
+	})
+
+	t.Run("case=mirrors the act claim in JWT access tokens", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "jwt")
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque") })
+
+		code, body := exchange(t, exchanger, url.Values{
+			"subject_token":      {getToken(t, service, "read")},
+			"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+			"actor_token":        {getToken(t, exchanger)},
+			"actor_token_type":   {hydraoauth2.TokenTypeAccessToken},
+		})
+		require.Equal(t, http.StatusOK, code, body.Raw)
+
+		payload, err := x.DecodeSegment(strings.Split(body.Get("access_token").String(), ".")[1])
+		require.NoError(t, err)
+		claims := gjson.ParseBytes(payload)
+		assert.Equal(t, service.GetID(), claims.Get("sub").String(), claims.Raw)
+		assert.Equal(t, exchanger.GetID(), claims.Get("act.sub").String(), claims.Raw)
+	})
+
+	t.Run("case=rejects invalid exchanges", func(t *testing.T) {
+		other := newClient(t, &hc.Client{
+			GrantTypes: []string{"client_credentials"},
+			Scope:      "read",
+		})
+
+		for k, tc := range []struct {
+			d         string
+			client    *hc.Client
+			params    url.Values
+			expectErr string
+		}{
+			{
+				d:      "client is not allowed to use the grant",
+				client: service,
+				params: url.Values{
+					"subject_token":      {getToken(t, service, "read")},
+					"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+				},
+				expectErr: "unauthorized_client",
+			},
+			{
+				d:      "subject token is missing",
+				client: exchanger,
+				params: url.Values{
+					"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+				},
+				expectErr: "invalid_request",
+			},
+			{
+				d:      "subject token is not active",
+				client: exchanger,
+				params: url.Values{
+					"subject_token":      {"ory_at_not-a-token.invalid"},
+					"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+				},
+				expectErr: "invalid_request",
+			},
+			{
+				d:      "subject token was issued to a client which is not allowed by the policy",
+				client: exchanger,
+				params: url.Values{
+					"subject_token":      {getToken(t, other, "read")},
+					"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+				},
+				expectErr: "invalid_request",
+			},
+			{
+				d:      "actor token was issued to a client which is not allowed by the policy",
+				client: exchanger,
+				params: url.Values{
+					"subject_token":      {getToken(t, service, "read")},
+					"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+					"actor_token":        {getToken(t, other, "read")},
+					"actor_token_type":   {hydraoauth2.TokenTypeAccessToken},
+				},
+				expectErr: "invalid_request",
+			},
+			{
+				d:      "requested scope was not granted to the subject token",
+				client: exchanger,
+				params: url.Values{
+					"subject_token":      {getToken(t, service, "read")},
+					"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+					"scope":              {"write"},
+				},
+				expectErr: "invalid_scope",
+			},
+			{
+				d:      "requested audience is not allowed",
+				client: exchanger,
+				params: url.Values{
+					"subject_token":      {getToken(t, service, "read")},
+					"subject_token_type": {hydraoauth2.TokenTypeAccessToken},
+					"audience":           {"https://not-allowed.example.com"},
+				},
+				expectErr: "invalid_target",
+			},
+			{
+				d:      "requested token type is not supported",
+				client: exchanger,
+				params: url.Values{
+					"subject_token":        {getToken(t, service, "read")},
+					"subject_token_type":   {hydraoauth2.TokenTypeAccessToken},
+					"requested_token_type": {"urn:ietf:params:oauth:token-type:refresh_token"},
+				},
+				expectErr: "invalid_request",
+			},
+		} {
+			t.Run("case="+tc.d, func(t *testing.T) {
+				code, body := exchange(t, tc.client, tc.params)
+				assert.Equal(t, http.StatusBadRequest, code, "%d: %s", k, body.Raw)
+				assert.Equal(t, tc.expectErr, body.Get("error").String(), "%d: %s", k, body.Raw)
+			})
+		}
+	})
+
+	t.Run("case=exchanges a JWT of a trusted issuer", func(t *testing.T) {
+		issuer, kid := "https://"+uuid.NewString()+".example.com", uuid.NewString()
+		keys, err := jwk.GenerateJWK(ctx, jose.RS256, kid, "sig")
+		require.NoError(t, err)
+		grant := trust.Grant{
+			ID:        uuid.NewString(),
+			Issuer:    issuer,
+			Subject:   uuid.NewString(),
+			Scope:     []string{"read"},
+			ExpiresAt: time.Now().Add(time.Hour),
+			PublicKey: trust.PublicKey{Set: issuer, KeyID: kid},
+		}
+		require.NoError(t, reg.GrantManager().CreateGrant(ctx, grant, keys.Keys[0].Public()))
+		signer := jwk.NewDefaultJWTSigner(reg.Config(), reg, issuer)
+		signer.GetPrivateKey = func(ctx context.Context) (interface{}, error) {
+			return keys.Keys[0], nil
+		}
+
+		subjectToken, _, err := signer.Generate(ctx, jwt.MapClaims{
+			"iss": issuer,
+			"sub": grant.Subject,
+			"aud": reg.Config().OAuth2TokenURL(ctx).String(),
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"iat": time.Now().Add(-time.Minute).Unix(),
+		}, &jwt.Headers{Extra: map[string]interface{}{"kid": kid}})
+		require.NoError(t, err)
+
+		params := url.Values{
+			"subject_token":      {subjectToken},
+			"subject_token_type": {hydraoauth2.TokenTypeJWT},
+			"scope":              {"read"},
+		}
+
+		t.Run("case=rejects issuers which are not allowed by the policy", func(t *testing.T) {
+			code, body := exchange(t, exchanger, params)
+			assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+			assert.Equal(t, "invalid_request", body.Get("error").String(), body.Raw)
+		})
+
+		trusting := newClient(t, &hc.Client{
+			GrantTypes: []string{hydraoauth2.GrantTypeTokenExchange},
+			Scope:      "read write",
+			TokenExchangePolicy: &hc.TokenExchangePolicy{
+				AllowedSubjectIssuers: []string{issuer},
+			},
+		})
+
+		code, body := exchange(t, trusting, params)
+		require.Equal(t, http.StatusOK, code, body.Raw)
+
+		i := introspect(t, trusting, body.Get("access_token").String())
+		assert.True(t, i.Get("active").Bool(), i.Raw)
+		assert.Equal(t, grant.Subject, i.Get("sub").String(), i.Raw)
+		assert.Equal(t, trusting.GetID(), i.Get("client_id").String(), i.Raw)
+		assert.Equal(t, "read", i.Get("scope").String(), i.Raw)
+
+		t.Run("case=rejects scopes which were not granted to the issuer", func(t *testing.T) {
+			params := url.Values{
+				"subject_token":      {subjectToken},
+				"subject_token_type": {hydraoauth2.TokenTypeJWT},
+				"scope":              {"write"},
+			}
+			code, body := exchange(t, trusting, params)
+			assert.Equal(t, http.StatusBadRequest, code, body.Raw)
+			assert.Equal(t, "invalid_scope", body.Get("error").String(), body.Raw)
+		})
+	})
+}
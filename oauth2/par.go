@@ -0,0 +1,29 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+
+	"github.com/ory/hydra/x"
+)
+
+// PushedAuthorizeStorage is the storage passed to the OAuth 2.0 provider.
+//
+// Fosite deletes a pushed authorization request as soon as the authorization endpoint
+// resolved its request_uri. Hydra however resolves the request_uri again whenever the
+// user agent returns from the login and consent UI, so the deletion is skipped here.
+// Instead, the handler marks the pushed authorization request as resolved when the
+// login and consent flow starts, and consumes it once the end-user granted the request.
+type PushedAuthorizeStorage struct {
+	x.FositeStorer
+}
+
+func NewPushedAuthorizeStorage(s x.FositeStorer) *PushedAuthorizeStorage {
+	return &PushedAuthorizeStorage{FositeStorer: s}
+}
+
+func (s *PushedAuthorizeStorage) DeletePARSession(_ context.Context, _ string) error {
+	return nil
+}
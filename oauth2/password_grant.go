@@ -0,0 +1,284 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/x/errorsx"
+
+	"github.com/ory/hydra/consent"
+)
+
+// PasswordGrantWebhookRequest is the request body sent to the password grant webhook.
+//
+// swagger:ignore
+type PasswordGrantWebhookRequest struct {
+	// Username is the username the end-user entered in the client.
+	Username string `json:"username"`
+	// Password is the password the end-user entered in the client.
+	Password string `json:"password"`
+	// ClientID is the identifier of the OAuth 2.0 client.
+	ClientID string `json:"client_id"`
+	// RequestedScope is the list of scopes requested by the OAuth 2.0 client.
+	RequestedScope []string `json:"requested_scope"`
+	// RequestedAudience is the list of audiences requested by the OAuth 2.0 client.
+	RequestedAudience []string `json:"requested_audience"`
+	// RequestedAuthorizationDetails contains the authorization details as requested by the OAuth 2.0 client.
+	RequestedAuthorizationDetails consent.AuthorizationDetails `json:"requested_authorization_details,omitempty"`
+}
+
+// PasswordGrantWebhookResponse is the response body received from the password grant webhook.
+//
+// swagger:ignore
+type PasswordGrantWebhookResponse struct {
+	// Subject is the identifier of the authenticated end-user.
+	Subject string `json:"subject"`
+	// Session is the session data to include in the access token.
+	Session consent.AcceptOAuth2ConsentRequestSession `json:"session"`
+	// GrantedAuthorizationDetails are the authorization details the end-user authorized the client to use. Only
+	// the types of the requested authorization details can be granted.
+	GrantedAuthorizationDetails consent.AuthorizationDetails `json:"grant_authorization_details,omitempty"`
+}
+
+type passwordGrantConfig interface {
+	fosite.ScopeStrategyProvider
+	fosite.AudienceStrategyProvider
+	fosite.AccessTokenLifespanProvider
+	fosite.RefreshTokenLifespanProvider
+	fosite.RefreshTokenScopesProvider
+	fosite.HTTPClientProvider
+	GrantPasswordEnabled(ctx context.Context) bool
+	GrantPasswordWebhookURL(ctx context.Context) *url.URL
+}
+
+var _ fosite.TokenEndpointHandler = (*PasswordGrantHandler)(nil)
+
+// PasswordGrantHandler handles the OAuth 2.0 Resource Owner Password Credentials Grant. Ory Hydra never sees a
+// password database: the credentials are verified by the configured webhook, which responds with the subject
+// of the end-user.
+type PasswordGrantHandler struct {
+	*foauth2.HandleHelper
+	CoreStorage          foauth2.CoreStorage
+	RefreshTokenStrategy foauth2.RefreshTokenStrategy
+	Config               passwordGrantConfig
+}
+
+// PasswordGrantFactory creates a PasswordGrantHandler. It follows the signature of the factories in
+// github.com/ory/fosite/compose.
+func PasswordGrantFactory(config fosite.Configurator, storage interface{}, strategy interface{}) interface{} {
+	return &PasswordGrantHandler{
+		HandleHelper: &foauth2.HandleHelper{
+			AccessTokenStrategy: strategy.(foauth2.AccessTokenStrategy),
+			AccessTokenStorage:  storage.(foauth2.AccessTokenStorage),
+			Config:              config,
+		},
+		CoreStorage:          storage.(foauth2.CoreStorage),
+		RefreshTokenStrategy: strategy.(foauth2.RefreshTokenStrategy),
+		Config:               config.(passwordGrantConfig),
+	}
+}
+
+func (c *PasswordGrantHandler) HandleTokenEndpointRequest(ctx context.Context, request fosite.AccessRequester) error {
+	if !c.CanHandleTokenEndpointRequest(ctx, request) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	if !request.GetClient().GetGrantTypes().Has(string(fosite.GrantTypePassword)) {
+		return errorsx.WithStack(fosite.ErrUnauthorizedClient.WithHint("The client is not allowed to use authorization grant 'password'."))
+	}
+
+	client := request.GetClient()
+	for _, scope := range request.GetRequestedScopes() {
+		if !c.Config.GetScopeStrategy(ctx)(client.GetScopes(), scope) {
+			return errorsx.WithStack(fosite.ErrInvalidScope.WithHintf("The OAuth 2.0 Client is not allowed to request scope '%s'.", scope))
+		}
+	}
+
+	if err := c.Config.GetAudienceStrategy(ctx)(client.GetAudience(), request.GetRequestedAudience()); err != nil {
+		return err
+	}
+
+	requestedDetails, err := requestedAuthorizationDetails(request)
+	if err != nil {
+		return err
+	}
+
+	username := request.GetRequestForm().Get("username")
+	password := request.GetRequestForm().Get("password")
+	if username == "" || password == "" {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Username or password are missing from the POST body."))
+	}
+
+	res, err := c.authenticate(ctx, request, username, password, requestedDetails)
+	if err != nil {
+		return err
+	}
+
+	// Credentials must not be passed around, potentially leaking to the database!
+	delete(request.GetRequestForm(), "password")
+
+	session, ok := request.GetSession().(*Session)
+	if !ok {
+		return errorsx.WithStack(fosite.ErrServerError.WithDebugf("The session must be of type *oauth2.Session but got type: %T", request.GetSession()))
+	}
+	session.Subject = res.Subject
+	session.DefaultSession.Claims.Subject = res.Subject
+	session.Extra = res.Session.AccessToken
+	session.AuthorizationDetails = res.GrantedAuthorizationDetails
+
+	now := time.Now().UTC()
+	atLifespan := fosite.GetEffectiveLifespan(request.GetClient(), fosite.GrantTypePassword, fosite.AccessToken, c.Config.GetAccessTokenLifespan(ctx))
+	request.GetSession().SetExpiresAt(fosite.AccessToken, now.Add(atLifespan).Round(time.Second))
+
+	rtLifespan := fosite.GetEffectiveLifespan(request.GetClient(), fosite.GrantTypePassword, fosite.RefreshToken, c.Config.GetRefreshTokenLifespan(ctx))
+	if rtLifespan > -1 {
+		request.GetSession().SetExpiresAt(fosite.RefreshToken, now.Add(rtLifespan).Round(time.Second))
+	}
+
+	return nil
+}
+
+// authenticate asks the webhook to verify the end-user's credentials.
+func (c *PasswordGrantHandler) authenticate(ctx context.Context, request fosite.AccessRequester, username, password string, requestedDetails consent.AuthorizationDetails) (*PasswordGrantWebhookResponse, error) {
+	body, err := json.Marshal(&PasswordGrantWebhookRequest{
+		Username:                      username,
+		Password:                      password,
+		ClientID:                      request.GetClient().GetID(),
+		RequestedScope:                request.GetRequestedScopes(),
+		RequestedAudience:             request.GetRequestedAudience(),
+		RequestedAuthorizationDetails: requestedDetails,
+	})
+	if err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while encoding the password grant webhook.").
+				WithDebugf("Unable to encode the password grant webhook body: %s", err),
+		)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Config.GrantPasswordWebhookURL(ctx).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while preparing the password grant webhook.").
+				WithDebugf("Unable to prepare the HTTP Request: %s", err),
+		)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	// The credentials must be checked exactly once, so the request is sent without the retries of the resilient
+	// client. Retrying could otherwise count a single login attempt multiple times against lockout policies.
+	resp, err := c.Config.GetHTTPClient(ctx).HTTPClient.Do(req)
+	if err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while executing the password grant webhook.").
+				WithDebugf("Unable to execute HTTP Request: %s", err),
+		)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The credentials are valid
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return nil, errorsx.WithStack(
+			fosite.ErrInvalidGrant.
+				WithHint("Unable to authenticate the provided username and password credentials.").
+				WithDebugf("Password grant webhook responded with HTTP status code: %s", resp.Status),
+		)
+	default:
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The password grant webhook target responded with an error.").
+				WithDebugf("Password grant webhook responded with HTTP status code: %s", resp.Status),
+		)
+	}
+
+	var res PasswordGrantWebhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("The password grant webhook target responded with an error.").
+				WithDebugf("Response from password grant webhook could not be decoded: %s", err),
+		)
+	} else if res.Subject == "" {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The password grant webhook target responded with an error.").
+				WithDebug("Response from password grant webhook did not contain a subject."),
+		)
+	} else if err := res.GrantedAuthorizationDetails.Validate(); err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The password grant webhook target responded with an error.").
+				WithDebugf("Response from password grant webhook contained invalid authorization details: %s", err),
+		)
+	} else if !requestedDetails.HasTypes(res.GrantedAuthorizationDetails.Types()...) {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The password grant webhook target responded with an error.").
+				WithDebug("Response from password grant webhook granted authorization details which were not requested."),
+		)
+	}
+
+	return &res, nil
+}
+
+func (c *PasswordGrantHandler) PopulateTokenEndpointResponse(ctx context.Context, requester fosite.AccessRequester, responder fosite.AccessResponder) error {
+	if !c.CanHandleTokenEndpointRequest(ctx, requester) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	var refresh, refreshSignature string
+	if c.canIssueRefreshToken(ctx, requester) {
+		var err error
+		refresh, refreshSignature, err = c.RefreshTokenStrategy.GenerateRefreshToken(ctx, requester)
+		if err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		} else if err := c.CoreStorage.CreateRefreshTokenSession(ctx, refreshSignature, requester.Sanitize([]string{})); err != nil {
+			return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+		}
+	}
+
+	atLifespan := fosite.GetEffectiveLifespan(requester.GetClient(), fosite.GrantTypePassword, fosite.AccessToken, c.Config.GetAccessTokenLifespan(ctx))
+	if err := c.IssueAccessToken(ctx, atLifespan, requester, responder); err != nil {
+		return err
+	}
+
+	if refresh != "" {
+		responder.SetExtra("refresh_token", refresh)
+	}
+
+	return nil
+}
+
+func (c *PasswordGrantHandler) canIssueRefreshToken(ctx context.Context, requester fosite.Requester) bool {
+	scope := c.Config.GetRefreshTokenScopes(ctx)
+	if len(scope) > 0 && !requester.GetGrantedScopes().HasOneOf(scope...) {
+		return false
+	}
+	return requester.GetClient().GetGrantTypes().Has("refresh_token")
+}
+
+func (c *PasswordGrantHandler) CanSkipClientAuth(ctx context.Context, requester fosite.AccessRequester) bool {
+	return false
+}
+
+func (c *PasswordGrantHandler) CanHandleTokenEndpointRequest(ctx context.Context, requester fosite.AccessRequester) bool {
+	// The grant behaves as if it was not registered at all unless it was enabled explicitly.
+	return c.Config.GrantPasswordEnabled(ctx) && requester.GetGrantTypes().ExactOne(string(fosite.GrantTypePassword))
+}
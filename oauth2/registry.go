@@ -11,6 +11,7 @@ import (
 	"github.com/ory/hydra/jwk"
 	"github.com/ory/hydra/oauth2/trust"
 	"github.com/ory/hydra/x"
+	"github.com/ory/hydra/x/events"
 )
 
 type InternalRegistry interface {
@@ -19,12 +20,16 @@ type InternalRegistry interface {
 	trust.Registry
 	x.RegistryWriter
 	x.RegistryLogger
+	x.HTTPClientProvider
 	consent.Registry
+	events.Registry
 	Registry
 }
 
 type Registry interface {
 	OAuth2Storage() x.FositeStorer
+	DeviceCodeStorage() DeviceCodeStorage
+	DeviceCodeStrategy() DeviceCodeStrategy
 	OAuth2Provider() fosite.OAuth2Provider
 	AudienceStrategy() fosite.AudienceMatchingStrategy
 	AccessTokenJWTStrategy() jwk.JWTSigner
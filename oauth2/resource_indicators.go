@@ -0,0 +1,104 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+)
+
+// resourceIndicatorsToAudience maps the `resource` parameter of
+// [RFC 8707](https://www.rfc-editor.org/rfc/rfc8707) onto the `audience` parameter, so that resource indicators
+// are validated against the audience of the OAuth 2.0 Client and flow through the consent app like any other
+// requested audience. It must be called before the request is parsed by fosite.
+func resourceIndicatorsToAudience(r *http.Request) error {
+	if err := r.ParseMultipartForm(1 << 20); err != nil && err != http.ErrNotMultipart {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to parse HTTP body, make sure to send a properly formatted form request body.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	for _, form := range []url.Values{r.Form, r.PostForm} {
+		resources := form["resource"]
+		if len(resources) == 0 {
+			continue
+		}
+
+		for _, resource := range resources {
+			if err := validateResourceIndicator(resource); err != nil {
+				return err
+			}
+		}
+
+		form.Set("audience", strings.Join(stringslice.Unique(append(fosite.GetAudiences(form), resources...)), " "))
+	}
+
+	return nil
+}
+
+func validateResourceIndicator(resource string) error {
+	u, err := url.Parse(resource)
+	if err != nil {
+		return errorsx.WithStack(ErrInvalidTarget.WithHintf("Resource indicator '%s' is not a valid URI.", resource).WithWrap(err).WithDebug(err.Error()))
+	} else if !u.IsAbs() {
+		return errorsx.WithStack(ErrInvalidTarget.WithHintf("Resource indicator '%s' must be an absolute URI.", resource))
+	} else if u.Fragment != "" {
+		return errorsx.WithStack(ErrInvalidTarget.WithHintf("Resource indicator '%s' must not contain a fragment component.", resource))
+	}
+	return nil
+}
+
+// asInvalidTarget reports an audience rejected by fosite as `invalid_target` if it was requested through a
+// resource indicator the OAuth 2.0 Client may not request.
+func asInvalidTarget(strategy fosite.AudienceMatchingStrategy, requester fosite.Requester, err error) error {
+	if requester == nil || requester.GetClient() == nil || !errors.Is(err, fosite.ErrInvalidRequest) {
+		return err
+	}
+
+	for _, resource := range requester.GetRequestForm()["resource"] {
+		if strategy(requester.GetClient().GetAudience(), []string{resource}) != nil {
+			return errorsx.WithStack(ErrInvalidTarget.WithHintf("Resource indicator '%s' is not allowed for the OAuth 2.0 Client.", resource).WithWrap(err).WithDebug(err.Error()))
+		}
+	}
+	return err
+}
+
+// narrowRefreshedAudience limits the audience of an access token issued by the refresh token grant to the
+// requested resource indicators, which must have been granted originally. The new refresh token keeps the
+// audience granted originally, see Session.RefreshTokenGrantedAudience.
+func narrowRefreshedAudience(request fosite.AccessRequester) error {
+	if !request.GetGrantTypes().ExactOne("refresh_token") {
+		return nil
+	}
+
+	session, ok := request.GetSession().(*Session)
+	if !ok {
+		return errorsx.WithStack(fosite.ErrServerError.WithDebugf("The session must be of type *oauth2.Session but got type: %T", request.GetSession()))
+	}
+	session.RefreshTokenGrantedAudience = nil
+
+	resources := request.GetRequestForm()["resource"]
+	if len(resources) == 0 {
+		return nil
+	}
+
+	for _, resource := range resources {
+		if !request.GetGrantedAudience().Has(resource) {
+			return errorsx.WithStack(ErrInvalidTarget.WithHintf("Resource indicator '%s' was not granted to the refresh token.", resource))
+		}
+	}
+
+	ar, ok := request.(*fosite.AccessRequest)
+	if !ok {
+		return errorsx.WithStack(fosite.ErrServerError.WithDebugf("The access request must be of type *fosite.AccessRequest but got type: %T", request))
+	}
+	session.RefreshTokenGrantedAudience = ar.GrantedAudience
+	ar.GrantedAudience = stringslice.Unique(resources)
+	return nil
+}
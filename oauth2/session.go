@@ -18,17 +18,73 @@ import (
 	"github.com/ory/fosite/token/jwt"
 
 	"github.com/ory/x/stringslice"
+
+	"github.com/ory/hydra/consent"
 )
 
 // swagger:ignore
 type Session struct {
 	*openid.DefaultSession `json:"id_token"`
-	Extra                  map[string]interface{} `json:"extra"`
-	KID                    string                 `json:"kid"`
-	ClientID               string                 `json:"client_id"`
-	ConsentChallenge       string                 `json:"consent_challenge"`
-	ExcludeNotBeforeClaim  bool                   `json:"exclude_not_before_claim"`
-	AllowedTopLevelClaims  []string               `json:"allowed_top_level_claims"`
+	Extra                  map[string]interface{}       `json:"extra"`
+	KID                    string                       `json:"kid"`
+	ClientID               string                       `json:"client_id"`
+	ConsentChallenge       string                       `json:"consent_challenge"`
+	ExcludeNotBeforeClaim  bool                         `json:"exclude_not_before_claim"`
+	AllowedTopLevelClaims  []string                     `json:"allowed_top_level_claims"`
+	Confirmation           *Confirmation                `json:"cnf,omitempty"`
+	AuthorizationDetails   consent.AuthorizationDetails `json:"authorization_details,omitempty"`
+
+	// RefreshTokenGrantedAudience is the audience granted to the refresh token if the audience of the access
+	// token was narrowed by resource indicators. It is stored in place of the granted audience of the access
+	// token when the refresh token is persisted.
+	RefreshTokenGrantedAudience []string `json:"refresh_token_granted_audience,omitempty"`
+
+	// exchangedConfirmations are the confirmations of the subject and actor tokens of a token exchange by request
+	// parameter. They are only used to check the proof of possession of the token request and are never stored.
+	exchangedConfirmations map[string]*Confirmation
+}
+
+// Confirmation binds the tokens of a session to a proof-of-possession key, see
+// [RFC 7800](https://www.rfc-editor.org/rfc/rfc7800), [RFC 8705](https://www.rfc-editor.org/rfc/rfc8705), and
+// [RFC 9449](https://www.rfc-editor.org/rfc/rfc9449).
+//
+// swagger:model tokenConfirmation
+type Confirmation struct {
+	// JWKThumbprint is the base64url-encoded SHA-256 JWK thumbprint of the DPoP key the token is bound to.
+	JWKThumbprint string `json:"jkt,omitempty"`
+
+	// X509CertificateThumbprint is the base64url-encoded SHA-256 thumbprint of the mutual TLS client certificate
+	// the token is bound to.
+	X509CertificateThumbprint string `json:"x5t#S256,omitempty"`
+}
+
+func (c *Confirmation) jwkThumbprint() string {
+	if c == nil {
+		return ""
+	}
+	return c.JWKThumbprint
+}
+
+func (c *Confirmation) certificateThumbprint() string {
+	if c == nil {
+		return ""
+	}
+	return c.X509CertificateThumbprint
+}
+
+// orNil returns nil if the confirmation does not bind the token to any key.
+func (c Confirmation) orNil() *Confirmation {
+	if c == (Confirmation{}) {
+		return nil
+	}
+	return &c
+}
+
+func (c *Confirmation) copy() Confirmation {
+	if c == nil {
+		return Confirmation{}
+	}
+	return *c
 }
 
 func NewSession(subject string) *Session {
@@ -49,7 +105,7 @@ func NewSessionWithCustomClaims(subject string, allowedTopLevelClaims []string)
 
 func (s *Session) GetJWTClaims() jwt.JWTClaimsContainer {
 	//a slice of claims that are reserved and should not be overridden
-	var reservedClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "client_id", "scp", "ext"}
+	var reservedClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "client_id", "scp", "ext", "cnf", "authorization_details"}
 
 	//remove any reserved claims from the custom claims
 	allowedClaimsFromConfigWithoutReserved := stringslice.Filter(s.AllowedTopLevelClaims, func(s string) bool {
@@ -99,6 +155,12 @@ func (s *Session) GetJWTClaims() jwt.JWTClaimsContainer {
 	}
 
 	claims.Extra["client_id"] = s.ClientID
+	if s.Confirmation != nil {
+		claims.Extra["cnf"] = s.Confirmation
+	}
+	if len(s.AuthorizationDetails) > 0 {
+		claims.Extra["authorization_details"] = s.AuthorizationDetails
+	}
 	return claims
 }
 
@@ -0,0 +1,364 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/fosite/handler/rfc7523"
+	"github.com/ory/x/errorsx"
+
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/x"
+)
+
+const (
+	// GrantTypeTokenExchange is the grant type of the OAuth 2.0 Token Exchange (RFC 8693).
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// TokenTypeAccessToken indicates that a token is an OAuth 2.0 access token issued by Ory Hydra.
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	// TokenTypeJWT indicates that a token is a JSON Web Token issued by a trusted issuer.
+	TokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// ErrInvalidTarget is returned when the requested audience of a token exchange or a resource indicator
+// (see https://www.rfc-editor.org/rfc/rfc8707#section-2) is not acceptable.
+var ErrInvalidTarget = &fosite.RFC6749Error{
+	ErrorField:       "invalid_target",
+	DescriptionField: "The requested audience is invalid, unknown, or malformed.",
+	CodeField:        http.StatusBadRequest,
+}
+
+type tokenExchangeConfig interface {
+	fosite.AccessTokenLifespanProvider
+	fosite.TokenURLProvider
+	fosite.ScopeStrategyProvider
+	fosite.AudienceStrategyProvider
+}
+
+var _ fosite.TokenEndpointHandler = (*TokenExchangeHandler)(nil)
+
+// TokenExchangeHandler handles the OAuth 2.0 Token Exchange grant (RFC 8693). Subject tokens are
+// either access tokens issued by Ory Hydra or JSON Web Tokens of a trusted issuer, actor tokens
+// are always access tokens issued by Ory Hydra.
+type TokenExchangeHandler struct {
+	CoreStorage         foauth2.CoreStorage
+	TrustStorage        rfc7523.RFC7523KeyStorage
+	AccessTokenStrategy foauth2.AccessTokenStrategy
+	Config              tokenExchangeConfig
+}
+
+// TokenExchangeGrantFactory creates a TokenExchangeHandler. It follows the signature of the
+// factories in github.com/ory/fosite/compose.
+func TokenExchangeGrantFactory(config fosite.Configurator, storage interface{}, strategy interface{}) interface{} {
+	return &TokenExchangeHandler{
+		CoreStorage:         storage.(foauth2.CoreStorage),
+		TrustStorage:        storage.(rfc7523.RFC7523KeyStorage),
+		AccessTokenStrategy: strategy.(foauth2.AccessTokenStrategy),
+		Config:              config.(tokenExchangeConfig),
+	}
+}
+
+// exchangedToken is a validated subject or actor token.
+type exchangedToken struct {
+	subject   string
+	clientID  string
+	scopes    fosite.Arguments
+	audience  fosite.Arguments
+	expiresAt time.Time
+	act       interface{}
+	cnf       *Confirmation
+}
+
+func (c *TokenExchangeHandler) HandleTokenEndpointRequest(ctx context.Context, request fosite.AccessRequester) error {
+	if !c.CanHandleTokenEndpointRequest(ctx, request) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	if !request.GetClient().GetGrantTypes().Has(GrantTypeTokenExchange) {
+		return errorsx.WithStack(fosite.ErrUnauthorizedClient.WithHintf("The OAuth 2.0 Client is not allowed to use authorization grant \"%s\".", GrantTypeTokenExchange))
+	}
+
+	form := request.GetRequestForm()
+	if tt := form.Get("requested_token_type"); tt != "" && tt != TokenTypeAccessToken {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The requested token type \"%s\" is not supported, only \"%s\" can be issued.", tt, TokenTypeAccessToken))
+	}
+
+	var policy *client.TokenExchangePolicy
+	if cl, ok := request.GetClient().(*client.Client); ok {
+		policy = cl.TokenExchangePolicy
+	}
+
+	subject, err := c.validateSubjectToken(ctx, request, policy)
+	if err != nil {
+		return err
+	}
+
+	session, ok := request.GetSession().(*Session)
+	if !ok {
+		return errorsx.WithStack(fosite.ErrServerError.WithHintf("Session must be of type *oauth2.Session but got type: %T", request.GetSession()))
+	}
+	session.exchangedConfirmations = map[string]*Confirmation{"subject_token": subject.cnf}
+
+	if actorToken := form.Get("actor_token"); actorToken != "" {
+		if tt := form.Get("actor_token_type"); tt != TokenTypeAccessToken {
+			return errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The \"actor_token_type\" parameter must be \"%s\".", TokenTypeAccessToken))
+		}
+
+		actor, err := c.validateAccessToken(ctx, actorToken, "actor_token")
+		if err != nil {
+			return err
+		}
+		session.exchangedConfirmations["actor_token"] = actor.cnf
+
+		if actor.clientID != request.GetClient().GetID() && !policy.AllowsActorClient(actor.clientID) {
+			return errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The OAuth 2.0 Client is not allowed to use actor tokens issued to OAuth 2.0 Client \"%s\".", actor.clientID))
+		}
+
+		act := map[string]interface{}{"sub": actor.subject, "client_id": actor.clientID}
+		if subject.act != nil {
+			act["act"] = subject.act
+		}
+		session.Extra["act"] = act
+		session.AllowedTopLevelClaims = append(session.AllowedTopLevelClaims, "act")
+	} else if form.Get("actor_token_type") != "" {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The \"actor_token_type\" parameter must not be set without the \"actor_token\" parameter."))
+	} else if subject.act != nil {
+		session.Extra["act"] = subject.act
+		session.AllowedTopLevelClaims = append(session.AllowedTopLevelClaims, "act")
+	}
+
+	scopeStrategy := c.Config.GetScopeStrategy(ctx)
+	if len(request.GetRequestedScopes()) == 0 {
+		for _, scope := range subject.scopes {
+			if scopeStrategy(request.GetClient().GetScopes(), scope) {
+				request.GrantScope(scope)
+			}
+		}
+	}
+	for _, scope := range request.GetRequestedScopes() {
+		if !scopeStrategy(subject.scopes, scope) {
+			return errorsx.WithStack(fosite.ErrInvalidScope.WithHintf("The subject token was not granted scope \"%s\".", scope))
+		} else if !scopeStrategy(request.GetClient().GetScopes(), scope) {
+			return errorsx.WithStack(fosite.ErrInvalidScope.WithHintf("The OAuth 2.0 Client is not allowed to request scope \"%s\".", scope))
+		}
+		request.GrantScope(scope)
+	}
+
+	if err := c.Config.GetAudienceStrategy(ctx)(request.GetClient().GetAudience(), request.GetRequestedAudience()); err != nil {
+		return errorsx.WithStack(ErrInvalidTarget.WithWrap(err).WithDebug(err.Error()))
+	}
+	for _, audience := range request.GetRequestedAudience() {
+		request.GrantAudience(audience)
+	}
+
+	// The issued token must not outlive the token it was exchanged for.
+	atLifespan := fosite.GetEffectiveLifespan(request.GetClient(), GrantTypeTokenExchange, fosite.AccessToken, c.Config.GetAccessTokenLifespan(ctx))
+	expiresAt := time.Now().UTC().Add(atLifespan)
+	if !subject.expiresAt.IsZero() && subject.expiresAt.Before(expiresAt) {
+		expiresAt = subject.expiresAt
+	}
+	session.SetExpiresAt(fosite.AccessToken, expiresAt.Truncate(time.Second))
+	session.SetSubject(subject.subject)
+
+	return nil
+}
+
+func (c *TokenExchangeHandler) PopulateTokenEndpointResponse(ctx context.Context, requester fosite.AccessRequester, responder fosite.AccessResponder) error {
+	if !c.CanHandleTokenEndpointRequest(ctx, requester) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	token, signature, err := c.AccessTokenStrategy.GenerateAccessToken(ctx, requester)
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	} else if err := c.CoreStorage.CreateAccessTokenSession(ctx, signature, requester.Sanitize([]string{})); err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	atLifespan := fosite.GetEffectiveLifespan(requester.GetClient(), GrantTypeTokenExchange, fosite.AccessToken, c.Config.GetAccessTokenLifespan(ctx))
+	responder.SetAccessToken(token)
+	responder.SetTokenType("bearer")
+	responder.SetExpiresIn(expiresIn(requester, fosite.AccessToken, atLifespan, time.Now().UTC()))
+	responder.SetScopes(requester.GetGrantedScopes())
+	responder.SetExtra("issued_token_type", TokenTypeAccessToken)
+	return nil
+}
+
+func (c *TokenExchangeHandler) CanSkipClientAuth(ctx context.Context, requester fosite.AccessRequester) bool {
+	return false
+}
+
+func (c *TokenExchangeHandler) CanHandleTokenEndpointRequest(ctx context.Context, requester fosite.AccessRequester) bool {
+	return requester.GetGrantTypes().ExactOne(GrantTypeTokenExchange)
+}
+
+func (c *TokenExchangeHandler) validateSubjectToken(ctx context.Context, request fosite.AccessRequester, policy *client.TokenExchangePolicy) (*exchangedToken, error) {
+	form := request.GetRequestForm()
+	token := form.Get("subject_token")
+	if token == "" {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The \"subject_token\" parameter is missing."))
+	}
+
+	switch tt := form.Get("subject_token_type"); tt {
+	case TokenTypeAccessToken:
+		subject, err := c.validateAccessToken(ctx, token, "subject_token")
+		if err != nil {
+			return nil, err
+		}
+
+		id := request.GetClient().GetID()
+		if subject.clientID != id && !subject.audience.Has(id) && !policy.AllowsSubjectClient(subject.clientID) {
+			return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The OAuth 2.0 Client is not allowed to exchange access tokens issued to OAuth 2.0 Client \"%s\".", subject.clientID))
+		}
+		return subject, nil
+	case TokenTypeJWT:
+		return c.validateJWT(ctx, token, policy)
+	case "":
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The \"subject_token_type\" parameter is missing."))
+	default:
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The subject token type \"%s\" is not supported.", tt))
+	}
+}
+
+func (c *TokenExchangeHandler) validateAccessToken(ctx context.Context, token string, parameter string) (*exchangedToken, error) {
+	or, err := c.CoreStorage.GetAccessTokenSession(ctx, c.AccessTokenStrategy.AccessTokenSignature(ctx, token), NewSession(""))
+	if errors.Is(err, fosite.ErrNotFound) || errors.Is(err, fosite.ErrInactiveToken) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The \"%s\" is not an active access token.", parameter).WithWrap(err).WithDebug(err.Error()))
+	} else if err != nil {
+		return nil, errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	if err := c.AccessTokenStrategy.ValidateAccessToken(ctx, or, token); err != nil {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The \"%s\" is not an active access token.", parameter).WithWrap(err).WithDebug(err.Error()))
+	}
+
+	t := &exchangedToken{
+		subject:   or.GetSession().GetSubject(),
+		clientID:  or.GetClient().GetID(),
+		scopes:    or.GetGrantedScopes(),
+		audience:  or.GetGrantedAudience(),
+		expiresAt: or.GetSession().GetExpiresAt(fosite.AccessToken),
+	}
+	if s, ok := or.GetSession().(*Session); ok {
+		t.act = s.Extra["act"]
+		t.cnf = s.Confirmation
+	}
+	return t, nil
+}
+
+// verifyExchangedTokenBindings ensures that subject and actor tokens which are bound to a DPoP key or a client
+// certificate are only exchanged by the holder of that key or certificate. It must be called after the DPoP proof
+// of the token request was bound to the session.
+func (h *Handler) verifyExchangedTokenBindings(r *http.Request, ar fosite.AccessRequester) error {
+	session, ok := ar.GetSession().(*Session)
+	if !ok {
+		return nil
+	}
+
+	for _, parameter := range []string{"subject_token", "actor_token"} {
+		cnf := session.exchangedConfirmations[parameter]
+		if jkt := cnf.jwkThumbprint(); jkt != "" && jkt != session.Confirmation.jwkThumbprint() {
+			return errorsx.WithStack(ErrInvalidDPoPProof.WithHintf("The \"%s\" is bound to a DPoP key and the request must contain a DPoP proof signed with that key.", parameter))
+		}
+
+		if x5t := cnf.certificateThumbprint(); x5t != "" {
+			chain, err := x.ClientCertificate(r, h.c.TLS(r.Context(), config.PublicInterface))
+			if err != nil {
+				return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to read the client certificate of the request.").WithWrap(err).WithDebug(err.Error()))
+			} else if len(chain) == 0 || x.CertificateThumbprint(chain[0]) != x5t {
+				return errorsx.WithStack(fosite.ErrInvalidGrant.WithHintf("The \"%s\" is bound to a client certificate and the request must be made using that certificate.", parameter))
+			}
+		}
+	}
+	return nil
+}
+
+func (c *TokenExchangeHandler) validateJWT(ctx context.Context, token string, policy *client.TokenExchangePolicy) (*exchangedToken, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to parse the JSON Web Token passed in the \"subject_token\" parameter.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	var unverified jwt.Claims
+	if err := parsed.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithDebug(err.Error()))
+	} else if unverified.Issuer == "" || unverified.Subject == "" {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The JSON Web Token passed in the \"subject_token\" parameter must contain the \"iss\" and \"sub\" claims."))
+	} else if !policy.AllowsSubjectIssuer(unverified.Issuer) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The OAuth 2.0 Client is not allowed to exchange JSON Web Tokens issued by \"%s\".", unverified.Issuer))
+	}
+
+	key, err := c.findPublicKey(ctx, parsed, unverified)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims jwt.Claims
+	if err := parsed.Claims(key, &claims); err != nil {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to verify the integrity of the \"subject_token\" value.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	now := time.Now().UTC()
+	if !claims.Audience.Contains(c.Config.GetTokenURL(ctx)) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The JSON Web Token passed in the \"subject_token\" parameter must contain an \"aud\" (audience) claim containing \"%s\".", c.Config.GetTokenURL(ctx)))
+	} else if claims.Expiry == nil {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The JSON Web Token passed in the \"subject_token\" parameter must contain an \"exp\" (expiration time) claim."))
+	} else if claims.Expiry.Time().Before(now) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The JSON Web Token passed in the \"subject_token\" parameter expired."))
+	} else if claims.NotBefore != nil && claims.NotBefore.Time().After(now) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The JSON Web Token passed in the \"subject_token\" parameter is not valid yet."))
+	}
+
+	scopes, err := c.TrustStorage.GetPublicKeyScopes(ctx, claims.Issuer, claims.Subject, key.KeyID)
+	if err != nil {
+		return nil, errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	return &exchangedToken{
+		subject:   claims.Subject,
+		scopes:    scopes,
+		expiresAt: claims.Expiry.Time(),
+	}, nil
+}
+
+func (c *TokenExchangeHandler) findPublicKey(ctx context.Context, token *jwt.JSONWebToken, claims jwt.Claims) (*jose.JSONWebKey, error) {
+	notFound := fosite.ErrInvalidRequest.WithHintf("No public JSON Web Key was registered for issuer \"%s\" and subject \"%s\".", claims.Issuer, claims.Subject)
+
+	for _, header := range token.Headers {
+		if header.KeyID == "" {
+			continue
+		}
+		key, err := c.TrustStorage.GetPublicKey(ctx, claims.Issuer, claims.Subject, header.KeyID)
+		if err != nil {
+			return nil, errorsx.WithStack(notFound.WithWrap(err).WithDebug(err.Error()))
+		}
+		return key, nil
+	}
+
+	keys, err := c.TrustStorage.GetPublicKeys(ctx, claims.Issuer, claims.Subject)
+	if err != nil {
+		return nil, errorsx.WithStack(notFound.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	for _, key := range keys.Keys {
+		key := key
+		if err := token.Claims(key, new(jwt.Claims)); err == nil {
+			return &key, nil
+		}
+	}
+
+	return nil, errorsx.WithStack(notFound)
+}
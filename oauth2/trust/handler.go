@@ -8,7 +8,7 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/ory/x/pagination/tokenpagination"
+	"github.com/ory/x/pagination/keysetpagination"
 
 	"github.com/ory/hydra/x"
 
@@ -230,7 +230,7 @@ type listTrustedOAuth2JwtGrantIssuers struct {
 	// required: false
 	Issuer string `json:"issuer"`
 
-	tokenpagination.TokenPaginator
+	keysetpagination.RequestParameters
 }
 
 // swagger:route GET /admin/trust/grants/jwt-bearer/issuers oAuth2 listTrustedOAuth2JwtGrantIssuers
@@ -251,10 +251,9 @@ type listTrustedOAuth2JwtGrantIssuers struct {
 //	  200: trustedOAuth2JwtGrantIssuers
 //	  default: genericError
 func (h *Handler) adminListTrustedOAuth2JwtGrantIssuers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	page, itemsPerPage := x.ParsePagination(r)
 	optionalIssuer := r.URL.Query().Get("issuer")
 
-	grants, err := h.registry.GrantManager().GetGrants(r.Context(), itemsPerPage, page*itemsPerPage, optionalIssuer)
+	grants, nextPage, err := h.registry.GrantManager().GetGrants(r.Context(), optionalIssuer, x.ParseKeysetPagination(r)...)
 	if err != nil {
 		h.registry.Writer().WriteError(w, r, err)
 		return
@@ -266,7 +265,7 @@ func (h *Handler) adminListTrustedOAuth2JwtGrantIssuers(w http.ResponseWriter, r
 		return
 	}
 
-	x.PaginationHeader(w, r.URL, int64(n), page, itemsPerPage)
+	x.KeysetPaginationHeader(w, r.URL, int64(n), nextPage)
 	if grants == nil {
 		grants = []Grant{}
 	}
@@ -17,6 +17,7 @@ import (
 	"gopkg.in/square/go-jose.v2"
 
 	"github.com/ory/x/pointerx"
+	"github.com/tomnomnom/linkheader"
 
 	"github.com/stretchr/testify/assert"
 
@@ -256,6 +257,47 @@ func (s *HandlerTestSuite) TestGrantListCanBeFetched() {
 	s.Equal(createRequestParams2.Issuer, *getResult[0].Issuer, "issuer must match")
 }
 
+func (s *HandlerTestSuite) TestGrantListIsPaginated() {
+	for _, issuer := range []string{"ory", "ory2", "ory3"} {
+		_, _, err := s.hydraClient.OAuth2Api.TrustOAuth2JwtGrantIssuer(context.Background()).TrustOAuth2JwtGrantIssuer(
+			s.newCreateJwtBearerGrantParams(issuer, "hackerman@example.com", false, []string{"openid"}, time.Now().Add(time.Hour)),
+		).Execute()
+		s.Require().NoError(err, "no errors expected on grant creation")
+	}
+
+	seen := map[string]bool{}
+	next := s.server.URL + "/admin/trust/grants/jwt-bearer/issuers?page_size=2"
+	for pages := 1; next != ""; pages++ {
+		s.Require().LessOrEqual(pages, 2)
+
+		res, err := s.server.Client().Get(next)
+		s.Require().NoError(err)
+		var grants []trust.Grant
+		s.Require().NoError(json.NewDecoder(res.Body).Decode(&grants))
+		s.Require().NoError(res.Body.Close())
+		s.Require().Equal(http.StatusOK, res.StatusCode)
+		s.Equal("3", res.Header.Get("X-Total-Count"))
+
+		for _, grant := range grants {
+			s.False(seen[grant.ID], "grant %s was listed twice", grant.ID)
+			seen[grant.ID] = true
+		}
+
+		next = ""
+		for _, link := range linkheader.ParseMultiple(res.Header.Values("Link")) {
+			if link.Rel == "next" {
+				next = s.server.URL + link.URL
+			}
+		}
+	}
+	s.Len(seen, 3)
+
+	res, err := s.server.Client().Get(s.server.URL + "/admin/trust/grants/jwt-bearer/issuers?page_token=not-a-page-token")
+	s.Require().NoError(err)
+	s.Require().NoError(res.Body.Close())
+	s.Equal(http.StatusBadRequest, res.StatusCode)
+}
+
 func (s *HandlerTestSuite) TestGrantCanBeDeleted() {
 	createRequestParams := s.newCreateJwtBearerGrantParams(
 		"ory",
@@ -9,13 +9,16 @@ import (
 
 	"github.com/gofrs/uuid"
 	"gopkg.in/square/go-jose.v2"
+
+	"github.com/ory/hydra/x"
+	"github.com/ory/x/pagination/keysetpagination"
 )
 
 type GrantManager interface {
 	CreateGrant(ctx context.Context, g Grant, publicKey jose.JSONWebKey) error
 	GetConcreteGrant(ctx context.Context, id string) (Grant, error)
 	DeleteGrant(ctx context.Context, id string) error
-	GetGrants(ctx context.Context, limit, offset int, optionalIssuer string) ([]Grant, error)
+	GetGrants(ctx context.Context, optionalIssuer string, pageOpts ...keysetpagination.Option) ([]Grant, *keysetpagination.Paginator, error)
 	CountGrants(ctx context.Context) (int, error)
 	FlushInactiveGrants(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
 }
@@ -36,3 +39,8 @@ type SQLData struct {
 func (SQLData) TableName() string {
 	return "hydra_oauth2_trusted_jwt_bearer_issuer"
 }
+
+// PageToken returns the token of the page which starts after this grant.
+func (d SQLData) PageToken() string {
+	return x.EncodePageToken(d.ID)
+}
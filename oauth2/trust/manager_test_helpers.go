@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/ory/x/josex"
+	"github.com/ory/x/pagination/keysetpagination"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -44,7 +45,7 @@ func TestHelperGrantManagerCreateGetDeleteGrant(t1 GrantManager, km jwk.Manager,
 		require.NoError(t, err)
 		mikePubKey = josex.ToPublicKey(&keySet.Keys[0])
 
-		storedGrants, err := t1.GetGrants(context.TODO(), 100, 0, "")
+		storedGrants, _, err := t1.GetGrants(context.TODO(), "")
 		require.NoError(t, err)
 		assert.Len(t, storedGrants, 0)
 
@@ -115,7 +116,7 @@ func TestHelperGrantManagerCreateGetDeleteGrant(t1 GrantManager, km jwk.Manager,
 		require.NoError(t, err)
 		assert.Equal(t, 3, count)
 
-		storedGrants, err = t1.GetGrants(context.TODO(), 100, 0, "")
+		storedGrants, _, err = t1.GetGrants(context.TODO(), "")
 		sort.Slice(storedGrants, func(i, j int) bool {
 			return storedGrants[i].CreatedAt.Before(storedGrants[j].CreatedAt)
 		})
@@ -125,7 +126,17 @@ func TestHelperGrantManagerCreateGetDeleteGrant(t1 GrantManager, km jwk.Manager,
 		assert.Equal(t, grant2.ID, storedGrants[1].ID)
 		assert.Equal(t, grant3.ID, storedGrants[2].ID)
 
-		storedGrants, err = t1.GetGrants(context.TODO(), 100, 0, set)
+		firstPage, nextPage, err := t1.GetGrants(context.TODO(), "", keysetpagination.WithSize(2))
+		require.NoError(t, err)
+		require.Len(t, firstPage, 2)
+		require.False(t, nextPage.IsLast())
+		lastPage, nextPage, err := t1.GetGrants(context.TODO(), "", nextPage.ToOptions()...)
+		require.NoError(t, err)
+		require.Len(t, lastPage, 1)
+		assert.True(t, nextPage.IsLast())
+		assert.NotContains(t, []string{firstPage[0].ID, firstPage[1].ID}, lastPage[0].ID)
+
+		storedGrants, _, err = t1.GetGrants(context.TODO(), set)
 		sort.Slice(storedGrants, func(i, j int) bool {
 			return storedGrants[i].CreatedAt.Before(storedGrants[j].CreatedAt)
 		})
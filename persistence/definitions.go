@@ -7,10 +7,12 @@ import (
 	"context"
 
 	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
 
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/oauth2"
 	"github.com/ory/hydra/oauth2/trust"
 	"github.com/ory/hydra/x"
 	"github.com/ory/x/popx"
@@ -23,12 +25,14 @@ type (
 		x.FositeStorer
 		jwk.Manager
 		trust.GrantManager
+		oauth2.DeviceCodeStorage
 
 		MigrationStatus(ctx context.Context) (popx.MigrationStatuses, error)
 		MigrateDown(context.Context, int) error
 		MigrateUp(context.Context) error
 		PrepareMigration(context.Context) error
 		Connection(context.Context) *pop.Connection
+		NetworkID(context.Context) uuid.UUID
 		Ping() error
 	}
 	Provider interface {
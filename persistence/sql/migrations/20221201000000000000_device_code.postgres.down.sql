@@ -0,0 +1 @@
+DROP TABLE IF EXISTS hydra_oauth2_device_code;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS hydra_oauth2_device_code
+(
+    signature           VARCHAR(255)            NOT NULL PRIMARY KEY,
+    user_code_signature VARCHAR(255)            NOT NULL,
+    request_id          VARCHAR(40)             NOT NULL,
+    requested_at        TIMESTAMP DEFAULT NOW() NOT NULL,
+    client_id           VARCHAR(255)            NOT NULL,
+    scope               TEXT                    NOT NULL,
+    granted_scope       TEXT                    NOT NULL,
+    requested_audience  TEXT                    NOT NULL DEFAULT '',
+    granted_audience    TEXT                    NOT NULL DEFAULT '',
+    form_data           TEXT                    NOT NULL,
+    subject             VARCHAR(255)            NOT NULL DEFAULT '',
+    session_data        TEXT                    NOT NULL,
+    state               INTEGER                 NOT NULL,
+    expires_at          TIMESTAMP DEFAULT NOW() NOT NULL,
+    last_polled_at      TIMESTAMP               NULL,
+    nid                 UUID                    NOT NULL,
+    UNIQUE (user_code_signature, nid),
+    FOREIGN KEY (client_id, nid) REFERENCES hydra_client (id, nid) ON DELETE CASCADE,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX hydra_oauth2_device_code_expires_at_idx ON hydra_oauth2_device_code (expires_at, nid);
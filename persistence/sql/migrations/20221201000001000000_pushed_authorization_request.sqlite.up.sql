@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS hydra_oauth2_par
+(
+    request_uri        VARCHAR(255)            NOT NULL PRIMARY KEY,
+    request_id         VARCHAR(40)             NOT NULL,
+    requested_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    client_id          VARCHAR(255)            NOT NULL,
+    scope              TEXT                    NOT NULL,
+    granted_scope      TEXT                    NOT NULL,
+    requested_audience TEXT                    NOT NULL DEFAULT '',
+    granted_audience   TEXT                    NOT NULL DEFAULT '',
+    form_data          TEXT                    NOT NULL,
+    subject            VARCHAR(255)            NOT NULL DEFAULT '',
+    session_data       TEXT                    NOT NULL,
+    redirect_uri       TEXT                    NOT NULL,
+    response_type      TEXT                    NOT NULL,
+    response_mode      VARCHAR(64)             NOT NULL DEFAULT '',
+    state              TEXT                    NOT NULL,
+    expires_at         TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    nid                CHAR(36)                NOT NULL,
+    FOREIGN KEY (client_id, nid) REFERENCES hydra_client (id, nid) ON DELETE CASCADE,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX hydra_oauth2_par_expires_at_idx ON hydra_oauth2_par (expires_at, nid);
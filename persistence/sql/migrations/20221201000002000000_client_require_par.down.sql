@@ -0,0 +1 @@
+ALTER TABLE hydra_client DROP COLUMN require_pushed_authorization_requests;
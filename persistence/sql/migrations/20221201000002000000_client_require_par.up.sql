@@ -0,0 +1 @@
+ALTER TABLE hydra_client ADD COLUMN require_pushed_authorization_requests BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+ALTER TABLE hydra_client ADD COLUMN token_exchange_policy TEXT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE hydra_client DROP COLUMN tls_client_certificate_bound_access_tokens;
+ALTER TABLE hydra_client DROP COLUMN tls_client_auth_san_email;
+ALTER TABLE hydra_client DROP COLUMN tls_client_auth_san_ip;
+ALTER TABLE hydra_client DROP COLUMN tls_client_auth_san_uri;
+ALTER TABLE hydra_client DROP COLUMN tls_client_auth_san_dns;
+ALTER TABLE hydra_client DROP COLUMN tls_client_auth_subject_dn;
@@ -0,0 +1,21 @@
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_subject_dn TEXT NULL;
+UPDATE hydra_client SET tls_client_auth_subject_dn='';
+ALTER TABLE hydra_client MODIFY tls_client_auth_subject_dn TEXT NOT NULL;
+
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_san_dns TEXT NULL;
+UPDATE hydra_client SET tls_client_auth_san_dns='';
+ALTER TABLE hydra_client MODIFY tls_client_auth_san_dns TEXT NOT NULL;
+
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_san_uri TEXT NULL;
+UPDATE hydra_client SET tls_client_auth_san_uri='';
+ALTER TABLE hydra_client MODIFY tls_client_auth_san_uri TEXT NOT NULL;
+
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_san_ip TEXT NULL;
+UPDATE hydra_client SET tls_client_auth_san_ip='';
+ALTER TABLE hydra_client MODIFY tls_client_auth_san_ip TEXT NOT NULL;
+
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_san_email TEXT NULL;
+UPDATE hydra_client SET tls_client_auth_san_email='';
+ALTER TABLE hydra_client MODIFY tls_client_auth_san_email TEXT NOT NULL;
+
+ALTER TABLE hydra_client ADD COLUMN tls_client_certificate_bound_access_tokens BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_subject_dn TEXT NOT NULL DEFAULT '';
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_san_dns TEXT NOT NULL DEFAULT '';
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_san_uri TEXT NOT NULL DEFAULT '';
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_san_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE hydra_client ADD COLUMN tls_client_auth_san_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE hydra_client ADD COLUMN tls_client_certificate_bound_access_tokens BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE hydra_client DROP COLUMN backchannel_client_notification_endpoint;
+ALTER TABLE hydra_client DROP COLUMN backchannel_token_delivery_mode;
@@ -0,0 +1,5 @@
+ALTER TABLE hydra_client ADD COLUMN backchannel_token_delivery_mode VARCHAR(10) NOT NULL DEFAULT '';
+
+ALTER TABLE hydra_client ADD COLUMN backchannel_client_notification_endpoint TEXT NULL;
+UPDATE hydra_client SET backchannel_client_notification_endpoint='';
+ALTER TABLE hydra_client MODIFY backchannel_client_notification_endpoint TEXT NOT NULL;
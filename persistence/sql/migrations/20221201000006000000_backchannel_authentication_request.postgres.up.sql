@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS hydra_oauth2_backchannel_authentication_request
+(
+    challenge                 VARCHAR(40)  NOT NULL PRIMARY KEY,
+    auth_req_id_signature     VARCHAR(255) NOT NULL,
+    client_id                 VARCHAR(255) NOT NULL,
+    requested_scope           TEXT         NOT NULL,
+    requested_at_audience     TEXT         NULL,
+    login_hint                TEXT         NULL,
+    login_hint_token          TEXT         NULL,
+    id_token_hint_claims      TEXT         NULL,
+    binding_message           TEXT         NULL,
+    acr_values                TEXT         NULL,
+    client_notification_token TEXT         NULL,
+    requested_at              TIMESTAMP DEFAULT NOW() NOT NULL,
+    expires_at                TIMESTAMP DEFAULT NOW() NOT NULL,
+    state                     INTEGER      NOT NULL,
+    last_polled_at            TIMESTAMP    NULL,
+    subject                   VARCHAR(255) NOT NULL DEFAULT '',
+    force_subject_identifier  VARCHAR(255) NOT NULL DEFAULT '',
+    acr                       TEXT         NULL,
+    amr                       TEXT         NULL,
+    granted_scope             TEXT         NULL,
+    granted_at_audience       TEXT         NULL,
+    session_access_token      TEXT         NULL,
+    session_id_token          TEXT         NULL,
+    error                     TEXT         NULL,
+    authenticated_at          TIMESTAMP    NULL,
+    nid                       UUID         NOT NULL,
+    UNIQUE (auth_req_id_signature, nid),
+    FOREIGN KEY (client_id, nid) REFERENCES hydra_client (id, nid) ON DELETE CASCADE,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX hydra_oauth2_backchannel_authentication_request_expires_at_idx ON hydra_oauth2_backchannel_authentication_request (expires_at, nid);
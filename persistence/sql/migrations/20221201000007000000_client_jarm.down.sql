@@ -0,0 +1,3 @@
+ALTER TABLE hydra_client DROP COLUMN authorization_encrypted_response_enc;
+ALTER TABLE hydra_client DROP COLUMN authorization_encrypted_response_alg;
+ALTER TABLE hydra_client DROP COLUMN authorization_signed_response_alg;
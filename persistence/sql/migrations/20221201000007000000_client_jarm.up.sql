@@ -0,0 +1,3 @@
+ALTER TABLE hydra_client ADD COLUMN authorization_signed_response_alg VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE hydra_client ADD COLUMN authorization_encrypted_response_alg VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE hydra_client ADD COLUMN authorization_encrypted_response_enc VARCHAR(20) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE hydra_oauth2_flow DROP COLUMN granted_authorization_details;
+ALTER TABLE hydra_oauth2_flow DROP COLUMN requested_authorization_details;
@@ -0,0 +1,2 @@
+ALTER TABLE hydra_oauth2_flow ADD COLUMN requested_authorization_details TEXT NULL;
+ALTER TABLE hydra_oauth2_flow ADD COLUMN granted_authorization_details TEXT NULL;
@@ -0,0 +1 @@
+ALTER TABLE hydra_client ADD COLUMN rotated_secrets TEXT NULL;
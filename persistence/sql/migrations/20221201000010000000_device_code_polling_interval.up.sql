@@ -0,0 +1 @@
+ALTER TABLE hydra_oauth2_device_code ADD COLUMN polling_interval INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE hydra_oauth2_par DROP COLUMN resolved;
@@ -0,0 +1 @@
+ALTER TABLE hydra_oauth2_par ADD COLUMN resolved BOOLEAN NOT NULL DEFAULT false;
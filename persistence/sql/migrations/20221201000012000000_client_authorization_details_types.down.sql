@@ -0,0 +1 @@
+ALTER TABLE hydra_client DROP COLUMN authorization_details_types;
@@ -0,0 +1,3 @@
+ALTER TABLE hydra_client ADD COLUMN authorization_details_types TEXT NULL;
+UPDATE hydra_client SET authorization_details_types='[]';
+ALTER TABLE hydra_client MODIFY authorization_details_types TEXT NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE hydra_client ADD COLUMN authorization_details_types TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,4 @@
+DROP INDEX hydra_client_nid_token_endpoint_auth_method_idx ON hydra_client;
+DROP INDEX hydra_client_nid_client_name_idx ON hydra_client;
+DROP INDEX hydra_client_nid_updated_at_idx ON hydra_client;
+DROP INDEX hydra_client_nid_created_at_idx ON hydra_client;
@@ -0,0 +1,4 @@
+CREATE INDEX hydra_client_nid_created_at_idx ON hydra_client (nid, created_at, pk);
+CREATE INDEX hydra_client_nid_updated_at_idx ON hydra_client (nid, updated_at, pk);
+CREATE INDEX hydra_client_nid_client_name_idx ON hydra_client (nid, client_name, pk);
+CREATE INDEX hydra_client_nid_token_endpoint_auth_method_idx ON hydra_client (nid, token_endpoint_auth_method);
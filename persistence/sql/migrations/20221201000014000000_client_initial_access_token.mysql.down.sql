@@ -0,0 +1 @@
+DROP TABLE IF EXISTS hydra_client_initial_access_token;
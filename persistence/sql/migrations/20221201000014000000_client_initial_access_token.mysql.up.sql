@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS hydra_client_initial_access_token
+(
+    id                CHAR(36)     NOT NULL PRIMARY KEY,
+    signature         VARCHAR(255) NOT NULL,
+    created_at        TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_at        TIMESTAMP    NULL,
+    max_registrations INTEGER      NOT NULL DEFAULT 0,
+    registrations     INTEGER      NOT NULL DEFAULT 0,
+    nid               CHAR(36)     NOT NULL,
+    UNIQUE (signature, nid),
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS hydra_client_initial_access_token
+(
+    id                UUID         NOT NULL PRIMARY KEY,
+    signature         VARCHAR(255) NOT NULL,
+    created_at        TIMESTAMP DEFAULT NOW() NOT NULL,
+    expires_at        TIMESTAMP    NULL,
+    max_registrations INTEGER      NOT NULL DEFAULT 0,
+    registrations     INTEGER      NOT NULL DEFAULT 0,
+    nid               UUID         NOT NULL,
+    UNIQUE (signature, nid),
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS hydra_client_template
+(
+    id                                              UUID NOT NULL,
+    name                                            VARCHAR(255) NOT NULL,
+    grant_types                                     TEXT NOT NULL,
+    response_types                                  TEXT NOT NULL,
+    token_endpoint_auth_method                      VARCHAR(25) NOT NULL DEFAULT '',
+    metadata                                        TEXT NOT NULL,
+    enforce                                         BOOL NOT NULL DEFAULT FALSE,
+    created_at                                      TIMESTAMP DEFAULT NOW() NOT NULL,
+    updated_at                                      TIMESTAMP DEFAULT NOW() NOT NULL,
+    authorization_code_grant_access_token_lifespan  BIGINT NULL DEFAULT NULL,
+    authorization_code_grant_id_token_lifespan      BIGINT NULL DEFAULT NULL,
+    authorization_code_grant_refresh_token_lifespan BIGINT NULL DEFAULT NULL,
+    client_credentials_grant_access_token_lifespan  BIGINT NULL DEFAULT NULL,
+    implicit_grant_access_token_lifespan            BIGINT NULL DEFAULT NULL,
+    implicit_grant_id_token_lifespan                BIGINT NULL DEFAULT NULL,
+    jwt_bearer_grant_access_token_lifespan          BIGINT NULL DEFAULT NULL,
+    password_grant_access_token_lifespan            BIGINT NULL DEFAULT NULL,
+    password_grant_refresh_token_lifespan           BIGINT NULL DEFAULT NULL,
+    refresh_token_grant_id_token_lifespan           BIGINT NULL DEFAULT NULL,
+    refresh_token_grant_access_token_lifespan       BIGINT NULL DEFAULT NULL,
+    refresh_token_grant_refresh_token_lifespan      BIGINT NULL DEFAULT NULL,
+    nid                                             UUID NOT NULL,
+    UNIQUE (name, nid),
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE,
+    CONSTRAINT "primary" PRIMARY KEY (id ASC)
+);
@@ -0,0 +1 @@
+ALTER TABLE hydra_client ADD COLUMN template VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS hydra_client@hydra_client_deleted_at_idx;
+
+ALTER TABLE hydra_client DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+DROP INDEX hydra_client_deleted_at_idx ON hydra_client;
+
+ALTER TABLE hydra_client DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE hydra_client ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX hydra_client_deleted_at_idx ON hydra_client (nid, deleted_at);
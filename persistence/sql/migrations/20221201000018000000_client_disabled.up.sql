@@ -0,0 +1 @@
+ALTER TABLE hydra_client ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS hydra_oauth2_authentication_session_expires_at_idx;
+
+ALTER TABLE hydra_oauth2_authentication_session DROP COLUMN expires_at;
@@ -0,0 +1,3 @@
+DROP INDEX hydra_oauth2_authentication_session_expires_at_idx ON hydra_oauth2_authentication_session;
+
+ALTER TABLE hydra_oauth2_authentication_session DROP COLUMN expires_at;
@@ -0,0 +1,3 @@
+ALTER TABLE hydra_oauth2_authentication_session ADD COLUMN expires_at TIMESTAMP NULL;
+
+CREATE INDEX hydra_oauth2_authentication_session_expires_at_idx ON hydra_oauth2_authentication_session (nid, expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE hydra_client DROP COLUMN default_acr_values;
+ALTER TABLE hydra_oauth2_authentication_session DROP COLUMN acr;
+ALTER TABLE hydra_oauth2_authentication_session DROP COLUMN amr;
@@ -0,0 +1,8 @@
+ALTER TABLE hydra_client ADD COLUMN default_acr_values TEXT NULL;
+UPDATE hydra_client SET default_acr_values='[]';
+ALTER TABLE hydra_client MODIFY default_acr_values TEXT NOT NULL;
+ALTER TABLE hydra_oauth2_authentication_session ADD COLUMN acr TEXT NULL;
+ALTER TABLE hydra_oauth2_authentication_session ADD COLUMN amr TEXT NULL;
+UPDATE hydra_oauth2_authentication_session SET acr='', amr='[]';
+ALTER TABLE hydra_oauth2_authentication_session MODIFY acr TEXT NOT NULL;
+ALTER TABLE hydra_oauth2_authentication_session MODIFY amr TEXT NOT NULL;
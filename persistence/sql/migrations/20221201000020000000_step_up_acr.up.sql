@@ -0,0 +1,3 @@
+ALTER TABLE hydra_client ADD COLUMN default_acr_values TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE hydra_oauth2_authentication_session ADD COLUMN acr TEXT NOT NULL DEFAULT '';
+ALTER TABLE hydra_oauth2_authentication_session ADD COLUMN amr TEXT NOT NULL DEFAULT '[]';
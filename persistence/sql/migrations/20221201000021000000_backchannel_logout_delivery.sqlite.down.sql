@@ -0,0 +1 @@
+DROP TABLE IF EXISTS hydra_oauth2_backchannel_logout_delivery;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS hydra_oauth2_backchannel_logout_delivery
+(
+    id              CHAR(36)     NOT NULL PRIMARY KEY,
+    client_id       VARCHAR(255) NOT NULL,
+    subject         VARCHAR(255) NOT NULL DEFAULT '',
+    session_id      VARCHAR(40)  NOT NULL DEFAULT '',
+    url             TEXT         NOT NULL,
+    status          VARCHAR(10)  NOT NULL,
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    last_error      TEXT         NOT NULL,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    nid             CHAR(36)     NOT NULL,
+    FOREIGN KEY (client_id, nid) REFERENCES hydra_client (id, nid) ON DELETE CASCADE,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX hydra_oauth2_backchannel_logout_delivery_due_idx ON hydra_oauth2_backchannel_logout_delivery (nid, status, next_attempt_at);
+CREATE INDEX hydra_oauth2_backchannel_logout_delivery_created_at_idx ON hydra_oauth2_backchannel_logout_delivery (nid, created_at, id);
@@ -0,0 +1 @@
+ALTER TABLE hydra_oauth2_authentication_session ADD COLUMN remember_for INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,146 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/hydra/consent"
+)
+
+func (p *Persister) CreateBackchannelAuthenticationRequest(ctx context.Context, req *consent.BackchannelAuthenticationRequest) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateBackchannelAuthenticationRequest")
+	defer span.End()
+
+	req.State = consent.BackchannelAuthenticationStatePending
+	req.RequestedAt = req.RequestedAt.UTC().Round(time.Second)
+	req.ExpiresAt = req.ExpiresAt.UTC().Round(time.Second)
+	return sqlcon.HandleError(p.CreateWithNetwork(ctx, req))
+}
+
+func (p *Persister) GetBackchannelAuthenticationRequest(ctx context.Context, challenge string) (*consent.BackchannelAuthenticationRequest, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetBackchannelAuthenticationRequest")
+	defer span.End()
+
+	var r consent.BackchannelAuthenticationRequest
+	return &r, sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("challenge = ?", challenge).First(&r))
+}
+
+func (p *Persister) GetBackchannelAuthenticationRequestByAuthReqIDSignature(ctx context.Context, signature string) (*consent.BackchannelAuthenticationRequest, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetBackchannelAuthenticationRequestByAuthReqIDSignature")
+	defer span.End()
+
+	var r consent.BackchannelAuthenticationRequest
+	return &r, sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("auth_req_id_signature = ?", signature).First(&r))
+}
+
+func (p *Persister) HandleBackchannelAuthenticationRequest(ctx context.Context, challenge string, h *consent.HandledBackchannelAuthenticationRequest) (*consent.BackchannelAuthenticationRequest, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.HandleBackchannelAuthenticationRequest")
+	defer span.End()
+
+	var err error
+	if h.Error.IsError() {
+		err = p.updateBackchannelAuthenticationState(ctx, "state=?, error=?",
+			[]interface{}{consent.BackchannelAuthenticationStateDenied, h.Error},
+			challenge, consent.BackchannelAuthenticationStatePending)
+	} else {
+		session := consent.NewConsentRequestSessionData()
+		if h.Session != nil {
+			session = h.Session
+		}
+
+		err = p.updateBackchannelAuthenticationState(ctx,
+			"state=?, subject=?, force_subject_identifier=?, acr=?, amr=?, granted_scope=?, granted_at_audience=?, session_access_token=?, session_id_token=?, authenticated_at=?",
+			[]interface{}{
+				consent.BackchannelAuthenticationStateApproved, h.Subject, h.ForceSubjectIdentifier, h.ACR, h.AMR,
+				h.GrantedScope, h.GrantedAudience, sqlxx.MapStringInterface(session.AccessToken), sqlxx.MapStringInterface(session.IDToken), h.AuthenticatedAt,
+			},
+			challenge, consent.BackchannelAuthenticationStatePending)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p.GetBackchannelAuthenticationRequest(ctx, challenge)
+}
+
+func (p *Persister) TouchBackchannelAuthenticationRequest(ctx context.Context, challenge string, polledAt time.Time) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.TouchBackchannelAuthenticationRequest")
+	defer span.End()
+
+	/* #nosec G201 table is static */
+	return sqlcon.HandleError(
+		p.Connection(ctx).
+			RawQuery(
+				fmt.Sprintf("UPDATE %s SET last_polled_at=? WHERE challenge=? AND nid=?", consent.BackchannelAuthenticationRequest{}.TableName()),
+				polledAt.UTC(),
+				challenge,
+				p.NetworkID(ctx),
+			).
+			Exec(),
+	)
+}
+
+func (p *Persister) InvalidateBackchannelAuthenticationRequest(ctx context.Context, challenge string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.InvalidateBackchannelAuthenticationRequest")
+	defer span.End()
+
+	return p.updateBackchannelAuthenticationState(ctx, "state=?", []interface{}{consent.BackchannelAuthenticationStateUsed}, challenge, consent.BackchannelAuthenticationStateApproved)
+}
+
+// updateBackchannelAuthenticationState applies the update only if the request is in the expected state and
+// returns sqlcon.ErrNoRows otherwise, so that a request can neither be handled twice nor redeemed more than once.
+func (p *Persister) updateBackchannelAuthenticationState(ctx context.Context, set string, args []interface{}, challenge string, expected consent.BackchannelAuthenticationState) error {
+	/* #nosec G201 set is static */
+	count, err := p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("UPDATE %s SET %s WHERE challenge=? AND nid=? AND state=?", consent.BackchannelAuthenticationRequest{}.TableName(), set),
+			append(args, challenge, p.NetworkID(ctx), expected)...,
+		).
+		ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) FlushInactiveBackchannelAuthenticationRequests(ctx context.Context, notAfter time.Time, limit int, batchSize int) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FlushInactiveBackchannelAuthenticationRequests")
+	defer span.End()
+
+	var err error
+
+	totalDeletedCount := 0
+	for deletedRecords := batchSize; totalDeletedCount < limit && deletedRecords == batchSize; {
+		d := batchSize
+		if limit-totalDeletedCount < batchSize {
+			d = limit - totalDeletedCount
+		}
+		// The outer SELECT is necessary because our version of MySQL doesn't yet support 'LIMIT & IN/ALL/ANY/SOME subquery
+		/* #nosec G201 table is static */
+		deletedRecords, err = p.Connection(ctx).RawQuery(
+			fmt.Sprintf(`DELETE FROM %s WHERE challenge in (
+				SELECT challenge FROM (SELECT challenge FROM %s hobar WHERE expires_at < ? and nid = ? ORDER BY challenge LIMIT %d ) as s
+			)`, consent.BackchannelAuthenticationRequest{}.TableName(), consent.BackchannelAuthenticationRequest{}.TableName(), d),
+			notAfter,
+			p.NetworkID(ctx),
+		).ExecWithCount()
+		totalDeletedCount += deletedRecords
+
+		if err != nil {
+			break
+		}
+		p.l.Debugf("Flushing backchannel authentication requests...: %d/%d", totalDeletedCount, limit)
+	}
+	p.l.Debugf("Flush backchannel authentication requests flushed_records: %d", totalDeletedCount)
+	return sqlcon.HandleError(err)
+}
@@ -0,0 +1,227 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/hydra/consent"
+	"github.com/ory/hydra/x"
+)
+
+func (p *Persister) CreateBackChannelLogoutDeliveries(ctx context.Context, deliveries []consent.BackChannelLogoutDelivery) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateBackChannelLogoutDeliveries")
+	defer span.End()
+
+	return p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		now := time.Now().UTC().Round(time.Second)
+		for k := range deliveries {
+			d := &deliveries[k]
+			if d.ID == uuid.Nil {
+				d.ID = uuid.Must(uuid.NewV4())
+			}
+			d.CreatedAt = now
+			d.UpdatedAt = now
+			d.NextAttemptAt = d.NextAttemptAt.UTC().Round(time.Second)
+			d.ExpiresAt = d.ExpiresAt.UTC().Round(time.Second)
+			if err := p.CreateWithNetwork(ctx, d); err != nil {
+				return sqlcon.HandleError(err)
+			}
+		}
+		return nil
+	})
+}
+
+func (p *Persister) GetBackChannelLogoutDelivery(ctx context.Context, id uuid.UUID) (*consent.BackChannelLogoutDelivery, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetBackChannelLogoutDelivery")
+	defer span.End()
+
+	var d consent.BackChannelLogoutDelivery
+	if err := p.QueryWithNetwork(ctx).Where("id = ?", id).First(&d); errors.Is(err, sql.ErrNoRows) {
+		return nil, errorsx.WithStack(x.ErrNotFound)
+	} else if err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return &d, nil
+}
+
+func (p *Persister) backChannelLogoutDeliveriesQuery(ctx context.Context, filter consent.BackChannelLogoutDeliveryFilter) *pop.Query {
+	query := p.QueryWithNetwork(ctx)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ClientID != "" {
+		query = query.Where("client_id = ?", filter.ClientID)
+	}
+	return query
+}
+
+func (p *Persister) ListBackChannelLogoutDeliveries(ctx context.Context, filter consent.BackChannelLogoutDeliveryFilter, pageOpts ...keysetpagination.Option) ([]consent.BackChannelLogoutDelivery, *keysetpagination.Paginator, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListBackChannelLogoutDeliveries")
+	defer span.End()
+
+	paginator := keysetpagination.GetPaginator(pageOpts...)
+	after, err := x.DecodePageToken(paginator.Token(), 2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := p.backChannelLogoutDeliveriesQuery(ctx, filter)
+	if after != nil {
+		createdAt, err := time.Parse(time.RFC3339Nano, after[0])
+		if err != nil {
+			return nil, nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The page token is invalid, make sure to use the page token of the Link header from the previous response."))
+		}
+		query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))", createdAt, createdAt, after[1])
+	}
+
+	var ds []consent.BackChannelLogoutDelivery
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(paginator.Size() + 1).
+		All(&ds); err != nil {
+		return nil, nil, sqlcon.HandleError(err)
+	}
+
+	ds, nextPage := keysetpagination.Result(ds, paginator)
+	return ds, nextPage, nil
+}
+
+func (p *Persister) CountBackChannelLogoutDeliveries(ctx context.Context, filter consent.BackChannelLogoutDeliveryFilter) (int, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CountBackChannelLogoutDeliveries")
+	defer span.End()
+
+	n, err := p.backChannelLogoutDeliveriesQuery(ctx, filter).Count(&consent.BackChannelLogoutDelivery{})
+	return n, sqlcon.HandleError(err)
+}
+
+func (p *Persister) FindDueBackChannelLogoutDeliveries(ctx context.Context, now time.Time, limit int) ([]consent.BackChannelLogoutDelivery, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FindDueBackChannelLogoutDeliveries")
+	defer span.End()
+
+	var ds []consent.BackChannelLogoutDelivery
+	if err := p.QueryWithNetwork(ctx).
+		Where("status = ? AND next_attempt_at <= ?", consent.BackChannelLogoutDeliveryPending, now.UTC()).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		All(&ds); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return ds, nil
+}
+
+func (p *Persister) LeaseBackChannelLogoutDelivery(ctx context.Context, delivery *consent.BackChannelLogoutDelivery, until time.Time) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.LeaseBackChannelLogoutDelivery")
+	defer span.End()
+
+	until = until.UTC().Round(time.Second)
+	now := time.Now().UTC().Round(time.Second)
+
+	// The number of attempts acts as a version, so that only one of several concurrent attempts leases the delivery.
+	/* #nosec G201 table is static */
+	count, err := p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("UPDATE %s SET attempts=?, next_attempt_at=?, updated_at=? WHERE id=? AND nid=? AND status=? AND attempts=?", consent.BackChannelLogoutDelivery{}.TableName()),
+			delivery.Attempts+1, until, now,
+			delivery.ID, p.NetworkID(ctx), consent.BackChannelLogoutDeliveryPending, delivery.Attempts,
+		).
+		ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+
+	delivery.Attempts++
+	delivery.NextAttemptAt = until
+	delivery.UpdatedAt = now
+	return nil
+}
+
+func (p *Persister) UpdateBackChannelLogoutDelivery(ctx context.Context, delivery *consent.BackChannelLogoutDelivery) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateBackChannelLogoutDelivery")
+	defer span.End()
+
+	delivery.NextAttemptAt = delivery.NextAttemptAt.UTC().Round(time.Second)
+	delivery.UpdatedAt = time.Now().UTC().Round(time.Second)
+
+	/* #nosec G201 table is static */
+	return sqlcon.HandleError(
+		p.Connection(ctx).
+			RawQuery(
+				fmt.Sprintf("UPDATE %s SET status=?, last_error=?, next_attempt_at=?, updated_at=? WHERE id=? AND nid=?", consent.BackChannelLogoutDelivery{}.TableName()),
+				delivery.Status, delivery.LastError, delivery.NextAttemptAt, delivery.UpdatedAt,
+				delivery.ID, p.NetworkID(ctx),
+			).
+			Exec(),
+	)
+}
+
+func (p *Persister) RedriveBackChannelLogoutDelivery(ctx context.Context, id uuid.UUID, nextAttemptAt, expiresAt time.Time) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RedriveBackChannelLogoutDelivery")
+	defer span.End()
+
+	/* #nosec G201 table is static */
+	count, err := p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("UPDATE %s SET status=?, attempts=0, last_error='', next_attempt_at=?, expires_at=?, updated_at=? WHERE id=? AND nid=? AND status=?", consent.BackChannelLogoutDelivery{}.TableName()),
+			consent.BackChannelLogoutDeliveryPending,
+			nextAttemptAt.UTC().Round(time.Second), expiresAt.UTC().Round(time.Second), time.Now().UTC().Round(time.Second),
+			id, p.NetworkID(ctx), consent.BackChannelLogoutDeliveryFailed,
+		).
+		ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) FlushInactiveBackChannelLogoutDeliveries(ctx context.Context, notAfter time.Time, limit int, batchSize int) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FlushInactiveBackChannelLogoutDeliveries")
+	defer span.End()
+
+	var err error
+
+	totalDeletedCount := 0
+	for deletedRecords := batchSize; totalDeletedCount < limit && deletedRecords == batchSize; {
+		d := batchSize
+		if limit-totalDeletedCount < batchSize {
+			d = limit - totalDeletedCount
+		}
+		// Pending deliveries are kept until they were delivered or failed. The outer SELECT is necessary because our
+		// version of MySQL doesn't yet support 'LIMIT & IN/ALL/ANY/SOME subquery
+		/* #nosec G201 table is static */
+		deletedRecords, err = p.Connection(ctx).RawQuery(
+			fmt.Sprintf(`DELETE FROM %s WHERE id in (
+				SELECT id FROM (SELECT id FROM %s hobld WHERE expires_at < ? AND status <> ? AND nid = ? ORDER BY id LIMIT %d ) as s
+			)`, consent.BackChannelLogoutDelivery{}.TableName(), consent.BackChannelLogoutDelivery{}.TableName(), d),
+			notAfter,
+			consent.BackChannelLogoutDeliveryPending,
+			p.NetworkID(ctx),
+		).ExecWithCount()
+		totalDeletedCount += deletedRecords
+
+		if err != nil {
+			break
+		}
+		p.l.Debugf("Flushing back-channel logout deliveries...: %d/%d", totalDeletedCount, limit)
+	}
+	p.l.Debugf("Flush back-channel logout deliveries flushed_records: %d", totalDeletedCount)
+	return sqlcon.HandleError(err)
+}
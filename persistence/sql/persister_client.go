@@ -5,6 +5,10 @@ package sql
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
 
@@ -13,8 +17,12 @@ import (
 	"github.com/ory/x/errorsx"
 
 	"github.com/ory/fosite"
+	"github.com/ory/herodot"
 	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/x"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
 )
 
 func (p *Persister) GetConcreteClient(ctx context.Context, id string) (*client.Client, error) {
@@ -22,14 +30,20 @@ func (p *Persister) GetConcreteClient(ctx context.Context, id string) (*client.C
 	defer span.End()
 
 	var cl client.Client
-	if err := p.QueryWithNetwork(ctx).Where("id = ?", id).First(&cl); err != nil {
+	if err := p.QueryWithNetwork(ctx).Where("id = ? AND deleted_at IS NULL", id).First(&cl); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 	return &cl, nil
 }
 
 func (p *Persister) GetClient(ctx context.Context, id string) (fosite.Client, error) {
-	return p.GetConcreteClient(ctx, id)
+	c, err := p.GetConcreteClient(ctx, id)
+	if err != nil {
+		return nil, err
+	} else if c.Disabled {
+		return nil, errorsx.WithStack(client.ErrClientDisabled)
+	}
+	return c, nil
 }
 
 func (p *Persister) UpdateClient(ctx context.Context, cl *client.Client) error {
@@ -44,19 +58,30 @@ func (p *Persister) UpdateClient(ctx context.Context, cl *client.Client) error {
 
 		if cl.Secret == "" {
 			cl.Secret = string(o.GetHashedSecret())
+			cl.SecretExpiresAt = o.SecretExpiresAt
 		} else {
 			h, err := p.r.ClientHasher().Hash(ctx, []byte(cl.Secret))
 			if err != nil {
 				return errorsx.WithStack(err)
 			}
 			cl.Secret = string(h)
+			cl.SetDefaultSecretExpiresAt(time.Now().UTC(), p.config.ClientSecretLifespan(ctx))
 		}
 		// set the internal primary key
 		cl.ID = o.ID
 
+		// rotated secrets are only changed by RotateClientSecret
+		cl.RotatedSecrets = o.RotatedSecrets
+
 		// Set the legacy client ID
 		cl.LegacyClientID = o.LegacyClientID
 
+		// the deletion state is only changed by DeleteClient and RestoreClient
+		cl.DeletedAt = o.DeletedAt
+
+		// the disabled state is only changed by SetClientDisabled
+		cl.Disabled = o.Disabled
+
 		if err = cl.BeforeSave(c); err != nil {
 			return sqlcon.HandleError(err)
 		}
@@ -78,13 +103,71 @@ func (p *Persister) Authenticate(ctx context.Context, id string, secret []byte)
 	c, err := p.GetConcreteClient(ctx, id)
 	if err != nil {
 		return nil, errorsx.WithStack(err)
+	} else if c.Disabled {
+		return nil, errorsx.WithStack(client.ErrClientDisabled)
 	}
 
-	if err := p.r.ClientHasher().Compare(ctx, c.GetHashedSecret(), secret); err != nil {
-		return nil, errorsx.WithStack(err)
+	if err = p.r.ClientHasher().Compare(ctx, c.GetHashedSecret(), secret); err == nil {
+		if c.IsSecretExpired(time.Now()) {
+			return nil, errorsx.WithStack(fosite.ErrInvalidClient.WithHint("The OAuth 2.0 Client secret has expired."))
+		}
+		return c, nil
 	}
 
-	return c, nil
+	for _, rotated := range c.RotatedSecrets.Active(time.Now()) {
+		if err = p.r.ClientHasher().Compare(ctx, []byte(rotated.Hash), secret); err == nil {
+			p.r.AuditLogger().
+				WithField("client_id", c.GetID()).
+				WithField("secret_fingerprint", client.SecretFingerprint([]byte(rotated.Hash))).
+				Info("OAuth 2.0 Client authenticated using a rotated client secret.")
+			return c, nil
+		}
+	}
+
+	return nil, errorsx.WithStack(err)
+}
+
+func (p *Persister) RotateClientSecret(ctx context.Context, id string, secret string, gracePeriod time.Duration) (*client.Client, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RotateClientSecret")
+	defer span.End()
+
+	var cl *client.Client
+	if err := p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		o, err := p.GetConcreteClient(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		h, err := p.r.ClientHasher().Hash(ctx, []byte(secret))
+		if err != nil {
+			return errorsx.WithStack(err)
+		}
+
+		now := time.Now().UTC()
+		rotated := o.RotatedSecrets.Active(now)
+		if gracePeriod > 0 && len(o.Secret) > 0 {
+			rotated = append(rotated, client.RotatedSecret{Hash: o.Secret, ExpiresAt: now.Add(gracePeriod)})
+		}
+
+		o.Secret = string(h)
+		o.SecretExpiresAt = 0
+		o.SetDefaultSecretExpiresAt(now, p.config.ClientSecretLifespan(ctx))
+		o.RotatedSecrets = rotated
+		o.UpdatedAt = now.Round(time.Second)
+
+		count, err := p.UpdateWithNetwork(ctx, o)
+		if err != nil {
+			return sqlcon.HandleError(err)
+		} else if count == 0 {
+			return sqlcon.HandleError(sqlcon.ErrNoRows)
+		}
+
+		cl = o
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return cl, nil
 }
 
 func (p *Persister) CreateClient(ctx context.Context, c *client.Client) error {
@@ -97,6 +180,7 @@ func (p *Persister) CreateClient(ctx context.Context, c *client.Client) error {
 	}
 
 	c.Secret = string(h)
+	c.SetDefaultSecretExpiresAt(time.Now().UTC(), p.config.ClientSecretLifespan(ctx))
 	if c.ID == uuid.Nil {
 		c.ID = uuid.Must(uuid.NewV4())
 	}
@@ -115,36 +199,463 @@ func (p *Persister) DeleteClient(ctx context.Context, id string) error {
 		return err
 	}
 
-	return sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("id = ?", id).Delete(&client.Client{}))
+	/* #nosec G201 TableName is static */
+	return sqlcon.HandleError(p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("UPDATE %s SET deleted_at = ? WHERE id = ? AND nid = ? AND deleted_at IS NULL", client.Client{}.TableName()),
+			time.Now().UTC().Truncate(time.Second), id, p.NetworkID(ctx),
+		).
+		Exec())
 }
 
-func (p *Persister) GetClients(ctx context.Context, filters client.Filter) ([]client.Client, error) {
+func (p *Persister) RestoreClient(ctx context.Context, id string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RestoreClient")
+	defer span.End()
+
+	/* #nosec G201 TableName is static */
+	count, err := p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("UPDATE %s SET deleted_at = NULL WHERE id = ? AND nid = ? AND deleted_at IS NOT NULL", client.Client{}.TableName()),
+			id, p.NetworkID(ctx),
+		).
+		ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) SetClientDisabled(ctx context.Context, id string, disabled bool) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.SetClientDisabled")
+	defer span.End()
+
+	if _, err := p.GetConcreteClient(ctx, id); err != nil {
+		return err
+	}
+
+	/* #nosec G201 TableName is static */
+	return sqlcon.HandleError(p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("UPDATE %s SET disabled = ? WHERE id = ? AND nid = ? AND deleted_at IS NULL", client.Client{}.TableName()),
+			disabled, id, p.NetworkID(ctx),
+		).
+		Exec())
+}
+
+func (p *Persister) FlushDeletedClients(ctx context.Context, notAfter time.Time, limit int, batchSize int) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FlushDeletedClients")
+	defer span.End()
+
+	// Clients are only purged once their retention period has passed, regardless of notAfter.
+	if retained := time.Now().UTC().Add(-p.config.DeletedClientRetention(ctx)); retained.Before(notAfter) {
+		notAfter = retained
+	}
+
+	var err error
+	totalDeletedCount := 0
+	for deletedRecords := batchSize; totalDeletedCount < limit && deletedRecords == batchSize; {
+		d := batchSize
+		if limit-totalDeletedCount < batchSize {
+			d = limit - totalDeletedCount
+		}
+		// Tokens, consent sessions and flows of the clients are removed by the foreign keys' cascades.
+		/* #nosec G201 TableName is static */
+		deletedRecords, err = p.Connection(ctx).RawQuery(
+			fmt.Sprintf(`DELETE FROM %[1]s WHERE pk IN (
+				SELECT pk FROM (SELECT pk FROM %[1]s WHERE deleted_at < ? AND nid = ? ORDER BY pk LIMIT %[2]d) AS c
+			)`, client.Client{}.TableName(), d),
+			notAfter.UTC(),
+			p.NetworkID(ctx),
+		).ExecWithCount()
+		totalDeletedCount += deletedRecords
+
+		if err != nil {
+			break
+		}
+		p.l.Debugf("Flushing deleted clients...: %d/%d", totalDeletedCount, limit)
+	}
+	p.l.Debugf("Flush Deleted Clients flushed_records: %d", totalDeletedCount)
+	return sqlcon.HandleError(err)
+}
+
+func (p *Persister) GetClients(ctx context.Context, filters client.Filter) ([]client.Client, *keysetpagination.Paginator, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetClients")
 	defer span.End()
 
+	paginator := keysetpagination.GetPaginator(filters.PageOpts...)
+	sortColumn, err := clientSortColumn(filters.SortBy)
+	if err != nil {
+		return nil, nil, err
+	}
+	direction, cmp := "ASC", ">"
+	switch filters.SortOrder {
+	case "", client.SortOrderAscending:
+	case client.SortOrderDescending:
+		direction, cmp = "DESC", "<"
+	default:
+		return nil, nil, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to sort clients in order '%s', use '%s' or '%s'.", filters.SortOrder, client.SortOrderAscending, client.SortOrderDescending))
+	}
+
 	cs := make([]client.Client, 0)
 
-	query := p.QueryWithNetwork(ctx).
-		Paginate(filters.Offset/filters.Limit+1, filters.Limit).
-		Order("pk")
+	query := p.QueryWithNetwork(ctx).Limit(paginator.Size() + 1)
+	if sortColumn == "" {
+		after, err := x.DecodePageToken(paginator.Token(), 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if after != nil {
+			query.Where("pk > ?", after[0])
+		}
+		query.Order("pk ASC")
+	} else {
+		after, err := x.DecodePageToken(paginator.Token(), 2)
+		if err != nil {
+			return nil, nil, err
+		}
+		if after != nil {
+			var value interface{} = after[0]
+			if sortColumn != client.SortByName {
+				t, err := time.Parse(time.RFC3339Nano, after[0])
+				if err != nil {
+					return nil, nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The page token is invalid, make sure to use the page token of the Link header from the previous response."))
+				}
+				value = t
+			}
+			query.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND pk %[2]s ?))", sortColumn, cmp), value, value, after[1])
+		}
+		query.Order(fmt.Sprintf("%s %s, pk %s", sortColumn, direction, direction))
+	}
 
+	if filters.Deleted {
+		query.Where("deleted_at IS NOT NULL")
+	} else {
+		query.Where("deleted_at IS NULL")
+	}
 	if filters.Name != "" {
 		query.Where("client_name = ?", filters.Name)
 	}
 	if filters.Owner != "" {
 		query.Where("owner = ?", filters.Owner)
 	}
+	if filters.SecretExpiresBefore > 0 {
+		query.Where("client_secret_expires_at > 0 AND client_secret_expires_at <= ?", filters.SecretExpiresBefore)
+	}
+	if filters.TokenEndpointAuthMethod != "" {
+		query.Where("token_endpoint_auth_method = ?", filters.TokenEndpointAuthMethod)
+	}
+	// The following filters match within JSON columns. No index covers them, so they are evaluated on the rows the
+	// other filters select, or on all clients of the network if there are none.
+	if filters.GrantType != "" {
+		query.Where(`grant_types LIKE ? ESCAPE '!'`, "%"+escapeLike(jsonString(filters.GrantType))+"%")
+	}
+	if filters.Audience != "" {
+		query.Where(`audience LIKE ? ESCAPE '!'`, "%"+escapeLike(jsonString(filters.Audience))+"%")
+	}
+	if filters.RedirectURIHost != "" {
+		// The host is either followed by the path, query, fragment, port or the end of the URI.
+		host := "%://" + escapeLike(strings.Trim(jsonString(filters.RedirectURIHost), `"`))
+		query.Where(`(redirect_uris LIKE ? ESCAPE '!' OR redirect_uris LIKE ? ESCAPE '!' OR redirect_uris LIKE ? ESCAPE '!' OR redirect_uris LIKE ? ESCAPE '!' OR redirect_uris LIKE ? ESCAPE '!')`,
+			host+`/%`, host+`"%`, host+`:%`, host+`?%`, host+`#%`)
+	}
+	if !filters.CreatedAfter.IsZero() {
+		query.Where("created_at >= ?", filters.CreatedAfter.UTC())
+	}
+	if !filters.CreatedBefore.IsZero() {
+		query.Where("created_at < ?", filters.CreatedBefore.UTC())
+	}
+	if !filters.UpdatedAfter.IsZero() {
+		query.Where("updated_at >= ?", filters.UpdatedAfter.UTC())
+	}
+	if !filters.UpdatedBefore.IsZero() {
+		query.Where("updated_at < ?", filters.UpdatedBefore.UTC())
+	}
+	for path, value := range filters.Metadata {
+		if err := p.whereClientMetadata(ctx, query, path, value); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	if err := query.All(&cs); err != nil {
-		return nil, sqlcon.HandleError(err)
+		return nil, nil, sqlcon.HandleError(err)
+	}
+
+	cs, nextPage := keysetpagination.Result(cs, paginator)
+	if sortColumn != "" && !nextPage.IsLast() {
+		last := cs[len(cs)-1]
+		value := last.Name
+		switch sortColumn {
+		case client.SortByCreatedAt:
+			value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+		case client.SortByUpdatedAt:
+			value = last.UpdatedAt.UTC().Format(time.RFC3339Nano)
+		}
+		nextPage = keysetpagination.GetPaginator(append(nextPage.ToOptions(), keysetpagination.WithToken(x.EncodePageToken(value, last.ID.String())))...)
+	}
+	return cs, nextPage, nil
+}
+
+// clientSortColumn returns the column to sort clients by, or an empty string if they are sorted by their primary key.
+func clientSortColumn(sortBy string) (string, error) {
+	switch sortBy {
+	case "":
+		return "", nil
+	case client.SortByCreatedAt, client.SortByUpdatedAt, client.SortByName:
+		return sortBy, nil
+	}
+	return "", errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to sort clients by '%s', use one of '%s', '%s' or '%s'.", sortBy, client.SortByCreatedAt, client.SortByUpdatedAt, client.SortByName))
+}
+
+// whereClientMetadata restricts the query to clients whose metadata has the given value at the dot-separated path.
+// Each dialect has its own JSON functions, the path segments are always passed as arguments.
+func (p *Persister) whereClientMetadata(ctx context.Context, query *pop.Query, path, value string) error {
+	segments := strings.Split(path, ".")
+	for _, s := range segments {
+		if s == "" {
+			return errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("The metadata path '%s' is invalid.", path))
+		}
+	}
+
+	switch p.Connection(ctx).Dialect.Name() {
+	case "postgres", "cockroach":
+		args := make([]interface{}, 0, len(segments)+1)
+		for _, s := range segments {
+			args = append(args, s)
+		}
+		query.Where("jsonb_extract_path_text(CAST(metadata AS jsonb)"+strings.Repeat(", ?", len(segments))+") = ?", append(args, value)...)
+	case "mysql":
+		query.Where("JSON_UNQUOTE(JSON_EXTRACT(metadata, ?)) = ?", jsonPath(segments), value)
+	default:
+		query.Where("CAST(json_extract(metadata, ?) AS TEXT) = ?", jsonPath(segments), value)
+	}
+	return nil
+}
+
+// jsonPath builds a JSON path expression such as `$."tenant"."region"` as understood by MySQL and SQLite.
+func jsonPath(segments []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, s := range segments {
+		b.WriteString(".")
+		b.WriteString(jsonString(s))
 	}
-	return cs, nil
+	return b.String()
+}
+
+// jsonString returns the JSON encoding of s, which is how strings appear in the JSON columns of a client.
+func jsonString(s string) string {
+	out, _ := json.Marshal(s)
+	return string(out)
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern using `!` as the escape character. The backslash is not
+// used because MySQL treats it as an escape character in string literals.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
 }
 
 func (p *Persister) CountClients(ctx context.Context) (int, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CountClients")
 	defer span.End()
 
-	n, err := p.QueryWithNetwork(ctx).Count(&client.Client{})
+	n, err := p.QueryWithNetwork(ctx).Where("deleted_at IS NULL").Count(&client.Client{})
 	return n, sqlcon.HandleError(err)
 }
+
+func (p *Persister) GetAllowedCORSOrigins(ctx context.Context) ([]string, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetAllowedCORSOrigins")
+	defer span.End()
+
+	var encoded []string
+	/* #nosec G201 TableName is static */
+	if err := p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("SELECT DISTINCT allowed_cors_origins FROM %s WHERE nid = ? AND deleted_at IS NULL AND disabled = ? AND allowed_cors_origins NOT IN ('', '[]')", client.Client{}.TableName()),
+			p.NetworkID(ctx), false,
+		).
+		All(&encoded); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	seen := map[string]bool{}
+	origins := []string{}
+	for _, e := range encoded {
+		var allowed sqlxx.StringSliceJSONFormat
+		if err := allowed.Scan(e); err != nil {
+			return nil, errorsx.WithStack(err)
+		}
+		for _, o := range allowed {
+			if !seen[o] {
+				seen[o] = true
+				origins = append(origins, o)
+			}
+		}
+	}
+	return origins, nil
+}
+
+func (p *Persister) CreateInitialAccessToken(ctx context.Context, t *client.InitialAccessToken) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateInitialAccessToken")
+	defer span.End()
+
+	if t.ID == uuid.Nil {
+		t.ID = uuid.Must(uuid.NewV4())
+	}
+	return sqlcon.HandleError(p.CreateWithNetwork(ctx, t))
+}
+
+func (p *Persister) GetInitialAccessTokens(ctx context.Context, pageOpts ...keysetpagination.Option) ([]client.InitialAccessToken, *keysetpagination.Paginator, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetInitialAccessTokens")
+	defer span.End()
+
+	paginator := keysetpagination.GetPaginator(pageOpts...)
+	after, err := x.DecodePageToken(paginator.Token(), 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ts := make([]client.InitialAccessToken, 0)
+	query := p.QueryWithNetwork(ctx).
+		Limit(paginator.Size() + 1).
+		Order("id ASC")
+	if after != nil {
+		query.Where("id > ?", after[0])
+	}
+	if err := query.All(&ts); err != nil {
+		return nil, nil, sqlcon.HandleError(err)
+	}
+
+	ts, nextPage := keysetpagination.Result(ts, paginator)
+	return ts, nextPage, nil
+}
+
+func (p *Persister) DeleteInitialAccessToken(ctx context.Context, id string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteInitialAccessToken")
+	defer span.End()
+
+	count, err := p.QueryWithNetwork(ctx).Where("id = ?", id).Count(&client.InitialAccessToken{})
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+
+	return sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("id = ?", id).Delete(&client.InitialAccessToken{}))
+}
+
+func (p *Persister) UseInitialAccessToken(ctx context.Context, signature string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UseInitialAccessToken")
+	defer span.End()
+
+	// The conditions are part of the update so that concurrent registrations can not exceed the maximum.
+	/* #nosec G201 TableName is static */
+	count, err := p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("UPDATE %s SET registrations = registrations + 1 WHERE signature = ? AND nid = ? AND (expires_at IS NULL OR expires_at > ?) AND (max_registrations = 0 OR registrations < max_registrations)", client.InitialAccessToken{}.TableName()),
+			signature, p.NetworkID(ctx), time.Now().UTC(),
+		).
+		ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) CreateClientTemplate(ctx context.Context, t *client.Template) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateClientTemplate")
+	defer span.End()
+
+	if t.ID == uuid.Nil {
+		t.ID = uuid.Must(uuid.NewV4())
+	}
+	return sqlcon.HandleError(p.CreateWithNetwork(ctx, t))
+}
+
+func (p *Persister) GetClientTemplate(ctx context.Context, name string) (*client.Template, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetClientTemplate")
+	defer span.End()
+
+	var t client.Template
+	if err := p.QueryWithNetwork(ctx).Where("name = ?", name).First(&t); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &t, nil
+}
+
+func (p *Persister) GetClientTemplates(ctx context.Context, pageOpts ...keysetpagination.Option) ([]client.Template, *keysetpagination.Paginator, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetClientTemplates")
+	defer span.End()
+
+	paginator := keysetpagination.GetPaginator(pageOpts...)
+	after, err := x.DecodePageToken(paginator.Token(), 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ts := make([]client.Template, 0)
+	query := p.QueryWithNetwork(ctx).
+		Limit(paginator.Size() + 1).
+		Order("name ASC")
+	if after != nil {
+		query.Where("name > ?", after[0])
+	}
+	if err := query.All(&ts); err != nil {
+		return nil, nil, sqlcon.HandleError(err)
+	}
+
+	ts, nextPage := keysetpagination.Result(ts, paginator)
+	return ts, nextPage, nil
+}
+
+func (p *Persister) UpdateClientTemplate(ctx context.Context, t *client.Template) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateClientTemplate")
+	defer span.End()
+
+	return p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		o, err := p.GetClientTemplate(ctx, t.Name)
+		if err != nil {
+			return err
+		}
+		t.ID = o.ID
+		t.CreatedAt = o.CreatedAt
+
+		if err := t.BeforeSave(c); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		count, err := p.UpdateWithNetwork(ctx, t)
+		if err != nil {
+			return sqlcon.HandleError(err)
+		} else if count == 0 {
+			return errorsx.WithStack(sqlcon.ErrNoRows)
+		}
+		return nil
+	})
+}
+
+func (p *Persister) DeleteClientTemplate(ctx context.Context, name string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteClientTemplate")
+	defer span.End()
+
+	return p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		count, err := p.QueryWithNetwork(ctx).Where("name = ?", name).Count(&client.Template{})
+		if err != nil {
+			return sqlcon.HandleError(err)
+		} else if count == 0 {
+			return errorsx.WithStack(sqlcon.ErrNoRows)
+		}
+
+		inUse, err := p.QueryWithNetwork(ctx).Where("template = ?", name).Count(&client.Client{})
+		if err != nil {
+			return sqlcon.HandleError(err)
+		} else if inUse > 0 {
+			return errorsx.WithStack(client.ErrClientTemplateInUse)
+		}
+
+		return sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("name = ?", name).Delete(&client.Template{}))
+	})
+}
@@ -19,10 +19,12 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ory/fosite"
+	"github.com/ory/herodot"
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/flow"
 	"github.com/ory/hydra/x"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/sqlcon"
 )
 
@@ -110,6 +112,86 @@ func (p *Persister) RevokeSubjectLoginSession(ctx context.Context, subject strin
 	return nil
 }
 
+func (p *Persister) GetLoginSession(ctx context.Context, id string) (*consent.LoginSession, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetLoginSession")
+	defer span.End()
+
+	var s consent.LoginSession
+	if err := p.QueryWithNetwork(ctx).Find(&s, id); errors.Is(err, sql.ErrNoRows) {
+		return nil, errorsx.WithStack(x.ErrNotFound)
+	} else if err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return &s, nil
+}
+
+func (p *Persister) ListSubjectLoginSessions(ctx context.Context, subject string, pageOpts ...keysetpagination.Option) ([]consent.LoginSession, *keysetpagination.Paginator, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListSubjectLoginSessions")
+	defer span.End()
+
+	paginator := keysetpagination.GetPaginator(pageOpts...)
+	after, err := x.DecodePageToken(paginator.Token(), 2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := p.QueryWithNetwork(ctx).Where("subject = ?", subject)
+	if after != nil {
+		authenticatedAt, err := time.Parse(time.RFC3339Nano, after[0])
+		if err != nil {
+			return nil, nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The page token is invalid, make sure to use the page token of the Link header from the previous response."))
+		}
+		query = query.Where("(authenticated_at < ? OR (authenticated_at = ? AND id < ?))", authenticatedAt, authenticatedAt, after[1])
+	}
+
+	var ss []consent.LoginSession
+	if err := query.
+		Order("authenticated_at DESC, id DESC").
+		Limit(paginator.Size() + 1).
+		All(&ss); err != nil {
+		return nil, nil, sqlcon.HandleError(err)
+	}
+
+	ss, nextPage := keysetpagination.Result(ss, paginator)
+	return ss, nextPage, nil
+}
+
+func (p *Persister) CountSubjectLoginSessions(ctx context.Context, subject string) (int, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CountSubjectLoginSessions")
+	defer span.End()
+
+	n, err := p.QueryWithNetwork(ctx).Where("subject = ?", subject).Count(&consent.LoginSession{})
+	return n, sqlcon.HandleError(err)
+}
+
+func (p *Persister) FindLoginSessionsClientIDs(ctx context.Context, ids ...string) (map[string][]string, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FindLoginSessionsClientIDs")
+	defer span.End()
+
+	result := make(map[string][]string, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		SessionID string `db:"login_session_id"`
+		ClientID  string `db:"client_id"`
+	}
+	if err := p.Connection(ctx).RawQuery(
+		fmt.Sprintf("SELECT DISTINCT login_session_id, client_id FROM %s WHERE login_session_id IN (?) AND nid = ? ORDER BY login_session_id, client_id", flow.Flow{}.TableName()),
+		ids,
+		p.NetworkID(ctx),
+	).All(&rows); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	for _, row := range rows {
+		result[row.SessionID] = append(result[row.SessionID], row.ClientID)
+	}
+	return result, nil
+}
+
 func (p *Persister) CreateForcedObfuscatedLoginSession(ctx context.Context, session *consent.ForcedObfuscatedLoginSession) error {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateForcedObfuscatedLoginSession")
 	defer span.End()
@@ -388,6 +470,28 @@ func (p *Persister) ConfirmLoginSession(ctx context.Context, id string, authenti
 	return sqlcon.HandleError(err)
 }
 
+func (p *Persister) SetLoginSessionExpiry(ctx context.Context, id string, rememberFor int, expiresAt time.Time) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.SetLoginSessionExpiry")
+	defer span.End()
+
+	_, err := p.Connection(ctx).Where("id = ? AND nid = ?", id, p.NetworkID(ctx)).UpdateQuery(&consent.LoginSession{
+		RememberFor: rememberFor,
+		ExpiresAt:   sqlxx.NullTime(expiresAt.UTC()),
+	}, "remember_for", "expires_at")
+	return sqlcon.HandleError(err)
+}
+
+func (p *Persister) SetLoginSessionAuthenticationContext(ctx context.Context, id string, acr string, amr []string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.SetLoginSessionAuthenticationContext")
+	defer span.End()
+
+	_, err := p.Connection(ctx).Where("id = ? AND nid = ?", id, p.NetworkID(ctx)).UpdateQuery(&consent.LoginSession{
+		ACR: acr,
+		AMR: amr,
+	}, "acr", "amr")
+	return sqlcon.HandleError(err)
+}
+
 func (p *Persister) CreateLoginSession(ctx context.Context, session *consent.LoginSession) error {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateLoginSession")
 	defer span.End()
@@ -443,14 +547,18 @@ nid = ?`, flow.FlowStateConsentUsed, flow.FlowStateConsentUnused,
 	})
 }
 
-func (p *Persister) FindSubjectsGrantedConsentRequests(ctx context.Context, subject string, limit, offset int) ([]consent.AcceptOAuth2ConsentRequest, error) {
+func (p *Persister) FindSubjectsGrantedConsentRequests(ctx context.Context, subject string, pageOpts ...keysetpagination.Option) ([]consent.AcceptOAuth2ConsentRequest, *keysetpagination.Paginator, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FindSubjectsGrantedConsentRequests")
 	defer span.End()
 
-	var fs []flow.Flow
-	c := p.Connection(ctx)
+	paginator := keysetpagination.GetPaginator(pageOpts...)
+	after, err := x.DecodePageToken(paginator.Token(), 2)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if err := c.
+	var fs []flow.Flow
+	query := p.Connection(ctx).
 		Where(
 			strings.TrimSpace(fmt.Sprintf(`
 (state = %d OR state = %d) AND
@@ -459,22 +567,41 @@ consent_skip=FALSE AND
 consent_error='{}' AND
 nid = ?`, flow.FlowStateConsentUsed, flow.FlowStateConsentUnused,
 			)),
-			subject, p.NetworkID(ctx)).
-		Order("requested_at DESC").
-		Paginate(offset/limit+1, limit).
+			subject, p.NetworkID(ctx))
+
+	if after != nil {
+		requestedAt, err := time.Parse(time.RFC3339Nano, after[0])
+		if err != nil {
+			return nil, nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The page token is invalid, make sure to use the page token of the Link header from the previous response."))
+		}
+		query = query.Where("(requested_at < ? OR (requested_at = ? AND login_challenge < ?))", requestedAt, requestedAt, after[1])
+	}
+
+	if err := query.
+		Order("requested_at DESC, login_challenge DESC").
+		Limit(paginator.Size() + 1).
 		All(&fs); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errorsx.WithStack(consent.ErrNoPreviousConsentFound)
+			return nil, nil, errorsx.WithStack(consent.ErrNoPreviousConsentFound)
 		}
-		return nil, sqlcon.HandleError(err)
+		return nil, nil, sqlcon.HandleError(err)
 	}
 
+	fs, nextPage := keysetpagination.Result(fs, paginator)
+
 	var rs []consent.AcceptOAuth2ConsentRequest
 	for _, f := range fs {
 		rs = append(rs, *f.GetHandledConsentRequest())
 	}
 
-	return p.filterExpiredConsentRequests(ctx, rs)
+	rs, err = p.filterExpiredConsentRequests(ctx, rs)
+	if errors.Is(err, consent.ErrNoPreviousConsentFound) && !nextPage.IsLast() {
+		// All consent sessions of this page expired, but the next page may have some.
+		return []consent.AcceptOAuth2ConsentRequest{}, nextPage, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return rs, nextPage, nil
 }
 
 func (p *Persister) CountSubjectsGrantedConsentRequests(ctx context.Context, subject string) (int, error) {
@@ -542,7 +669,8 @@ WHERE
 	c.%schannel_logout_uri IS NOT NULL AND
 	f.login_session_id = ? AND
 	f.nid = ? AND
-	c.nid = ?`,
+	c.nid = ? AND
+	c.deleted_at IS NULL`,
 				channel,
 				channel,
 			),
@@ -684,3 +812,40 @@ func (p *Persister) FlushInactiveLoginConsentRequests(ctx context.Context, notAf
 
 	return nil
 }
+
+func (p *Persister) FlushExpiredLoginSessions(ctx context.Context, notAfter time.Time, limit int, batchSize int) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FlushExpiredLoginSessions")
+	defer span.End()
+
+	// Sessions which were remembered before the expiry was stored, or before the maximum age was lowered, have to be
+	// found by their authentication time.
+	authenticatedBefore := time.Time{}
+	if maxAge := p.config.LoginSessionMaxAge(ctx); maxAge > 0 {
+		authenticatedBefore = notAfter.Add(-maxAge)
+	}
+
+	var ids []string
+	if err := p.Connection(ctx).RawQuery(
+		fmt.Sprintf("SELECT id FROM %s WHERE (expires_at < ? OR authenticated_at < ?) AND nid = ? ORDER BY id LIMIT %d", consent.LoginSession{}.TableName(), limit),
+		notAfter.UTC(), authenticatedBefore.UTC(), p.NetworkID(ctx),
+	).All(&ids); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	for i := 0; i < len(ids); i += batchSize {
+		j := i + batchSize
+		if j > len(ids) {
+			j = len(ids)
+		}
+
+		if err := p.Connection(ctx).RawQuery(
+			fmt.Sprintf("DELETE FROM %s WHERE id IN (?) AND nid = ?", consent.LoginSession{}.TableName()),
+			ids[i:j],
+			p.NetworkID(ctx),
+		).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+	}
+
+	return nil
+}
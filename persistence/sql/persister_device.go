@@ -0,0 +1,233 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/hydra/oauth2"
+)
+
+var _ oauth2.DeviceCodeStorage = &Persister{}
+
+type DeviceCodeSQL struct {
+	ID                string                 `db:"signature"`
+	UserCode          string                 `db:"user_code_signature"`
+	NID               uuid.UUID              `db:"nid"`
+	Request           string                 `db:"request_id"`
+	RequestedAt       time.Time              `db:"requested_at"`
+	Client            string                 `db:"client_id"`
+	Scopes            string                 `db:"scope"`
+	GrantedScope      string                 `db:"granted_scope"`
+	RequestedAudience string                 `db:"requested_audience"`
+	GrantedAudience   string                 `db:"granted_audience"`
+	Form              string                 `db:"form_data"`
+	Subject           string                 `db:"subject"`
+	Session           []byte                 `db:"session_data"`
+	State             oauth2.DeviceCodeState `db:"state"`
+	ExpiresAt         time.Time              `db:"expires_at"`
+	LastPolledAt      sql.NullTime           `db:"last_polled_at"`
+	PollingInterval   int64                  `db:"polling_interval"`
+}
+
+func (DeviceCodeSQL) TableName() string {
+	return "hydra_oauth2_device_code"
+}
+
+func (p *Persister) deviceCodeSchemaFromRequest(ctx context.Context, r fosite.Requester) (*DeviceCodeSQL, error) {
+	req, err := p.sqlSchemaFromRequest(ctx, "", r, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceCodeSQL{
+		Request:           req.Request,
+		RequestedAt:       req.RequestedAt,
+		Client:            req.Client,
+		Scopes:            req.Scopes,
+		GrantedScope:      req.GrantedScope,
+		RequestedAudience: req.RequestedAudience,
+		GrantedAudience:   req.GrantedAudience,
+		Form:              req.Form,
+		Subject:           req.Subject,
+		Session:           req.Session,
+	}, nil
+}
+
+func (r *DeviceCodeSQL) toDeviceCodeSession(ctx context.Context, session fosite.Session, p *Persister) (*oauth2.DeviceCodeSession, error) {
+	req, err := (&OAuth2RequestSQL{
+		Request:           r.Request,
+		RequestedAt:       r.RequestedAt,
+		Client:            r.Client,
+		Scopes:            r.Scopes,
+		GrantedScope:      r.GrantedScope,
+		RequestedAudience: r.RequestedAudience,
+		GrantedAudience:   r.GrantedAudience,
+		Form:              r.Form,
+		Session:           r.Session,
+	}).toRequest(ctx, session, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.DeviceCodeSession{
+		Signature:       r.ID,
+		Request:         req,
+		State:           r.State,
+		ExpiresAt:       r.ExpiresAt,
+		LastPolledAt:    r.LastPolledAt.Time,
+		PollingInterval: time.Duration(r.PollingInterval) * time.Second,
+	}, nil
+}
+
+func (p *Persister) CreateDeviceCodeSession(ctx context.Context, deviceCodeSignature, userCodeSignature string, request fosite.Requester, expiresAt time.Time) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateDeviceCodeSession")
+	defer span.End()
+
+	dc, err := p.deviceCodeSchemaFromRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	dc.ID = deviceCodeSignature
+	dc.UserCode = userCodeSignature
+	dc.State = oauth2.DeviceCodeStatePending
+	dc.ExpiresAt = expiresAt.UTC().Round(time.Second)
+
+	return sqlcon.HandleError(p.CreateWithNetwork(ctx, dc))
+}
+
+func (p *Persister) getDeviceCodeSession(ctx context.Context, column, signature string, session fosite.Session) (*oauth2.DeviceCodeSession, error) {
+	var dc DeviceCodeSQL
+	/* #nosec G201 column is static */
+	if err := p.QueryWithNetwork(ctx).Where(fmt.Sprintf("%s = ?", column), signature).First(&dc); errors.Is(err, sql.ErrNoRows) {
+		return nil, errorsx.WithStack(fosite.ErrNotFound)
+	} else if err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return dc.toDeviceCodeSession(ctx, session, p)
+}
+
+func (p *Persister) GetDeviceCodeSession(ctx context.Context, deviceCodeSignature string, session fosite.Session) (*oauth2.DeviceCodeSession, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetDeviceCodeSession")
+	defer span.End()
+
+	return p.getDeviceCodeSession(ctx, "signature", deviceCodeSignature, session)
+}
+
+func (p *Persister) GetDeviceCodeSessionByUserCode(ctx context.Context, userCodeSignature string, session fosite.Session) (*oauth2.DeviceCodeSession, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetDeviceCodeSessionByUserCode")
+	defer span.End()
+
+	return p.getDeviceCodeSession(ctx, "user_code_signature", userCodeSignature, session)
+}
+
+func (p *Persister) ApproveDeviceCodeSession(ctx context.Context, deviceCodeSignature string, request fosite.Requester) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ApproveDeviceCodeSession")
+	defer span.End()
+
+	dc, err := p.deviceCodeSchemaFromRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	return p.updateDeviceCodeState(ctx,
+		"state=?, request_id=?, granted_scope=?, granted_audience=?, subject=?, session_data=?",
+		[]interface{}{oauth2.DeviceCodeStateApproved, dc.Request, dc.GrantedScope, dc.GrantedAudience, dc.Subject, dc.Session},
+		deviceCodeSignature, oauth2.DeviceCodeStatePending)
+}
+
+func (p *Persister) DenyDeviceCodeSession(ctx context.Context, deviceCodeSignature string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DenyDeviceCodeSession")
+	defer span.End()
+
+	return p.updateDeviceCodeState(ctx, "state=?", []interface{}{oauth2.DeviceCodeStateDenied}, deviceCodeSignature, oauth2.DeviceCodeStatePending)
+}
+
+func (p *Persister) InvalidateDeviceCodeSession(ctx context.Context, deviceCodeSignature string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.InvalidateDeviceCodeSession")
+	defer span.End()
+
+	return p.updateDeviceCodeState(ctx, "state=?", []interface{}{oauth2.DeviceCodeStateUsed}, deviceCodeSignature, oauth2.DeviceCodeStateApproved)
+}
+
+// updateDeviceCodeState applies the update only if the device code is in the expected state
+// and returns sqlcon.ErrNoRows otherwise. This prevents approving a device code twice or
+// exchanging it for tokens more than once.
+func (p *Persister) updateDeviceCodeState(ctx context.Context, set string, args []interface{}, deviceCodeSignature string, expected oauth2.DeviceCodeState) error {
+	/* #nosec G201 set is static */
+	count, err := p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("UPDATE %s SET %s WHERE signature=? AND nid=? AND state=?", DeviceCodeSQL{}.TableName(), set),
+			append(args, deviceCodeSignature, p.NetworkID(ctx), expected)...,
+		).
+		ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) TouchDeviceCodeSession(ctx context.Context, deviceCodeSignature string, polledAt time.Time, interval time.Duration) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.TouchDeviceCodeSession")
+	defer span.End()
+
+	/* #nosec G201 table is static */
+	return sqlcon.HandleError(
+		p.Connection(ctx).
+			RawQuery(
+				fmt.Sprintf("UPDATE %s SET last_polled_at=?, polling_interval=? WHERE signature=? AND nid=?", DeviceCodeSQL{}.TableName()),
+				polledAt.UTC(),
+				int64(interval/time.Second),
+				deviceCodeSignature,
+				p.NetworkID(ctx),
+			).
+			Exec(),
+	)
+}
+
+func (p *Persister) FlushInactiveDeviceCodes(ctx context.Context, notAfter time.Time, limit int, batchSize int) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FlushInactiveDeviceCodes")
+	defer span.End()
+
+	var err error
+
+	totalDeletedCount := 0
+	for deletedRecords := batchSize; totalDeletedCount < limit && deletedRecords == batchSize; {
+		d := batchSize
+		if limit-totalDeletedCount < batchSize {
+			d = limit - totalDeletedCount
+		}
+		// The outer SELECT is necessary because our version of MySQL doesn't yet support 'LIMIT & IN/ALL/ANY/SOME subquery
+		/* #nosec G201 table is static */
+		deletedRecords, err = p.Connection(ctx).RawQuery(
+			fmt.Sprintf(`DELETE FROM %s WHERE signature in (
+				SELECT signature FROM (SELECT signature FROM %s hodc WHERE expires_at < ? and nid = ? ORDER BY signature LIMIT %d ) as s
+			)`, DeviceCodeSQL{}.TableName(), DeviceCodeSQL{}.TableName(), d),
+			notAfter,
+			p.NetworkID(ctx),
+		).ExecWithCount()
+		totalDeletedCount += deletedRecords
+
+		if err != nil {
+			break
+		}
+		p.l.Debugf("Flushing device codes...: %d/%d", totalDeletedCount, limit)
+	}
+	p.l.Debugf("Flush device codes flushed_records: %d", totalDeletedCount)
+	return sqlcon.HandleError(err)
+}
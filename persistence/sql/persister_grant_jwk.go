@@ -14,6 +14,8 @@ import (
 	"gopkg.in/square/go-jose.v2"
 
 	"github.com/ory/hydra/oauth2/trust"
+	"github.com/ory/hydra/x"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/stringsx"
 
 	"github.com/ory/x/sqlcon"
@@ -72,29 +74,40 @@ func (p *Persister) DeleteGrant(ctx context.Context, id string) error {
 	})
 }
 
-func (p *Persister) GetGrants(ctx context.Context, limit, offset int, optionalIssuer string) ([]trust.Grant, error) {
+func (p *Persister) GetGrants(ctx context.Context, optionalIssuer string, pageOpts ...keysetpagination.Option) ([]trust.Grant, *keysetpagination.Paginator, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetGrants")
 	defer span.End()
 
+	paginator := keysetpagination.GetPaginator(pageOpts...)
+	after, err := x.DecodePageToken(paginator.Token(), 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	grantsData := make([]trust.SQLData, 0)
 
 	query := p.QueryWithNetwork(ctx).
-		Paginate(offset/limit+1, limit).
-		Order("id")
+		Limit(paginator.Size() + 1).
+		Order("id ASC")
+	if after != nil {
+		query = query.Where("id > ?", after[0])
+	}
 	if optionalIssuer != "" {
 		query = query.Where("issuer = ?", optionalIssuer)
 	}
 
 	if err := query.All(&grantsData); err != nil {
-		return nil, sqlcon.HandleError(err)
+		return nil, nil, sqlcon.HandleError(err)
 	}
 
+	grantsData, nextPage := keysetpagination.Result(grantsData, paginator)
+
 	grants := make([]trust.Grant, 0, len(grantsData))
 	for _, data := range grantsData {
 		grants = append(grants, p.jwtGrantFromSQlData(data))
 	}
 
-	return grants, nil
+	return grants, nextPage, nil
 }
 
 func (p *Persister) CountGrants(ctx context.Context) (int, error) {
@@ -113,7 +126,7 @@ func (p *Persister) GetPublicKey(ctx context.Context, issuer string, subject str
 	var data trust.SQLData
 	query := p.QueryWithNetwork(ctx).
 		Where("issuer = ?", issuer).
-		Where("subject = ? OR allow_any_subject IS TRUE", subject).
+		Where("(subject = ? OR allow_any_subject IS TRUE)", subject).
 		Where("key_id = ?", keyId).
 		Where("nid = ?", p.NetworkID(ctx))
 	if err := query.First(&data); err != nil {
@@ -135,7 +148,7 @@ func (p *Persister) GetPublicKeys(ctx context.Context, issuer string, subject st
 	grantsData := make([]trust.SQLData, 0)
 	query := p.QueryWithNetwork(ctx).
 		Where("issuer = ?", issuer).
-		Where("subject = ? OR allow_any_subject IS TRUE", subject).
+		Where("(subject = ? OR allow_any_subject IS TRUE)", subject).
 		Where("nid = ?", p.NetworkID(ctx))
 
 	if err := query.All(&grantsData); err != nil {
@@ -170,7 +183,7 @@ func (p *Persister) GetPublicKeyScopes(ctx context.Context, issuer string, subje
 	var data trust.SQLData
 	query := p.QueryWithNetwork(ctx).
 		Where("issuer = ?", issuer).
-		Where("subject = ? OR allow_any_subject IS TRUE", subject).
+		Where("(subject = ? OR allow_any_subject IS TRUE)", subject).
 		Where("key_id = ?", keyId).
 		Where("nid = ?", p.NetworkID(ctx))
 
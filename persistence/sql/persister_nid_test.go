@@ -32,6 +32,7 @@ import (
 	"github.com/ory/x/contextx"
 	"github.com/ory/x/dbal"
 	"github.com/ory/x/networkx"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/sqlxx"
 )
 
@@ -595,7 +596,27 @@ func (s *PersisterTestSuite) TestDeleteClient() {
 			require.Error(t, r.Persister().DeleteClient(s.t2, c.LegacyClientID))
 			require.NoError(t, r.Persister().Connection(context.Background()).Find(&actual, c.ID))
 			require.NoError(t, r.Persister().DeleteClient(s.t1, c.LegacyClientID))
-			require.Error(t, r.Persister().Connection(context.Background()).Find(&actual, c.ID))
+			require.NoError(t, r.Persister().Connection(context.Background()).Find(&actual, c.ID))
+			require.NotNil(t, actual.DeletedAt)
+			_, err := r.Persister().GetConcreteClient(s.t1, c.LegacyClientID)
+			require.Error(t, err)
+		})
+	}
+}
+
+func (s *PersisterTestSuite) TestRestoreClient() {
+	t := s.T()
+	for k, r := range s.registries {
+		t.Run(k, func(t *testing.T) {
+			c := &client.Client{LegacyClientID: "restored-client-id"}
+			require.NoError(t, r.Persister().CreateClient(s.t1, c))
+			require.NoError(t, r.Persister().DeleteClient(s.t1, c.LegacyClientID))
+			require.Error(t, r.Persister().RestoreClient(s.t2, c.LegacyClientID))
+			_, err := r.Persister().GetConcreteClient(s.t1, c.LegacyClientID)
+			require.Error(t, err)
+			require.NoError(t, r.Persister().RestoreClient(s.t1, c.LegacyClientID))
+			_, err = r.Persister().GetConcreteClient(s.t1, c.LegacyClientID)
+			require.NoError(t, err)
 		})
 	}
 }
@@ -794,17 +815,74 @@ func (s *PersisterTestSuite) TestFindSubjectsGrantedConsentRequests() {
 			_, err := r.Persister().HandleConsentRequest(s.t1, hcr)
 			require.NoError(t, err)
 
-			actual, err := r.Persister().FindSubjectsGrantedConsentRequests(s.t2, f.Subject, 100, 0)
+			actual, _, err := r.Persister().FindSubjectsGrantedConsentRequests(s.t2, f.Subject)
 			require.Error(t, err)
 			require.Equal(t, 0, len(actual))
 
-			actual, err = r.Persister().FindSubjectsGrantedConsentRequests(s.t1, f.Subject, 100, 0)
+			actual, _, err = r.Persister().FindSubjectsGrantedConsentRequests(s.t1, f.Subject)
 			require.NoError(t, err)
 			require.Equal(t, 1, len(actual))
 		})
 	}
 }
 
+func (s *PersisterTestSuite) TestFindSubjectsGrantedConsentRequestsPagination() {
+	t := s.T()
+	for k, r := range s.registries {
+		t.Run(k, func(t *testing.T) {
+			cl := &client.Client{LegacyClientID: "paginated-client-id"}
+			require.NoError(t, r.Persister().CreateClient(s.t1, cl))
+
+			requestedAt := time.Now().Add(-time.Hour).Round(time.Second).UTC()
+			var expected []string
+			for i := 0; i < 5; i++ {
+				sessionID := uuid.Must(uuid.NewV4()).String()
+				f := newFlow(s.t1NID, cl.LegacyClientID, "paginated-sub", sqlxx.NullString(sessionID))
+				f.LoginVerifier = uuid.Must(uuid.NewV4()).String()
+				f.LoginCSRF = uuid.Must(uuid.NewV4()).String()
+				f.ConsentChallengeID = sqlxx.NullString(uuid.Must(uuid.NewV4()).String())
+				// Two flows share each point in time, so that the page token has to break ties.
+				f.RequestedAt = requestedAt.Add(time.Duration(i/2) * time.Second)
+				require.NoError(t, r.Persister().CreateLoginSession(s.t1, &consent.LoginSession{ID: sessionID}))
+				require.NoError(t, r.Persister().Connection(context.Background()).Create(f))
+
+				req := &consent.OAuth2ConsentRequest{
+					ID:             uuid.Must(uuid.NewV4()).String(),
+					LoginChallenge: sqlxx.NullString(f.ID),
+					Verifier:       uuid.Must(uuid.NewV4()).String(),
+					CSRF:           uuid.Must(uuid.NewV4()).String(),
+				}
+				require.NoError(t, r.Persister().CreateConsentRequest(s.t1, req))
+				_, err := r.Persister().HandleConsentRequest(s.t1, &consent.AcceptOAuth2ConsentRequest{ID: req.ID, HandledAt: sqlxx.NullTime(time.Now()), Remember: true})
+				require.NoError(t, err)
+				expected = append(expected, req.ID)
+			}
+
+			var actual []string
+			opts := []keysetpagination.Option{keysetpagination.WithSize(2)}
+			for pages := 1; ; pages++ {
+				require.LessOrEqual(t, pages, 3)
+				page, nextPage, err := r.Persister().FindSubjectsGrantedConsentRequests(s.t1, "paginated-sub", opts...)
+				require.NoError(t, err)
+				for _, c := range page {
+					actual = append(actual, c.ID)
+				}
+				if nextPage.IsLast() {
+					break
+				}
+				opts = nextPage.ToOptions()
+			}
+
+			require.Len(t, actual, len(expected))
+			require.ElementsMatch(t, expected, actual)
+			require.Equal(t, expected[4], actual[0], "the most recent consent session is listed first")
+
+			_, _, err := r.Persister().FindSubjectsGrantedConsentRequests(s.t1, "paginated-sub", keysetpagination.WithToken("not-a-page-token"))
+			require.Error(t, err)
+		})
+	}
+}
+
 func (s *PersisterTestSuite) TestFlushInactiveAccessTokens() {
 	t := s.T()
 	for k, r := range s.registries {
@@ -998,10 +1076,10 @@ func (s *PersisterTestSuite) TestGetClients() {
 			c := &client.Client{LegacyClientID: "client-id"}
 			require.NoError(t, r.Persister().CreateClient(s.t1, c))
 
-			actual, err := r.Persister().GetClients(s.t2, client.Filter{Offset: 0, Limit: 100})
+			actual, _, err := r.Persister().GetClients(s.t2, client.Filter{})
 			require.NoError(t, err)
 			require.Equal(t, 0, len(actual))
-			actual, err = r.Persister().GetClients(s.t1, client.Filter{Offset: 0, Limit: 100})
+			actual, _, err = r.Persister().GetClients(s.t1, client.Filter{})
 			require.NoError(t, err)
 			require.Equal(t, 1, len(actual))
 		})
@@ -1163,11 +1241,11 @@ func (s *PersisterTestSuite) TestGetGrants() {
 			require.NoError(t, r.Persister().AddKeySet(s.t1, "ks-id", ks))
 			require.NoError(t, r.Persister().CreateGrant(s.t1, grant, ks.Keys[0]))
 
-			actual, err := r.Persister().GetGrants(s.t2, 100, 0, "")
+			actual, _, err := r.Persister().GetGrants(s.t2, "")
 			require.NoError(t, err)
 			require.Equal(t, 0, len(actual))
 
-			actual, err = r.Persister().GetGrants(s.t1, 100, 0, "")
+			actual, _, err = r.Persister().GetGrants(s.t1, "")
 			require.NoError(t, err)
 			require.Equal(t, 1, len(actual))
 		})
@@ -1292,6 +1370,38 @@ func (s *PersisterTestSuite) TestGetPublicKey() {
 	}
 }
 
+func (s *PersisterTestSuite) TestGetPublicKeyOfGrantForAnySubject() {
+	t := s.T()
+	for k, r := range s.registries {
+		t.Run(k, func(t *testing.T) {
+			ks := newKeySet("ks-any-subject", "use")
+			grant := trust.Grant{
+				ID:              uuid.Must(uuid.NewV4()).String(),
+				Issuer:          "https://any-subject.example.com",
+				AllowAnySubject: true,
+				Scope:           []string{"a"},
+				ExpiresAt:       time.Now().Add(time.Hour),
+				PublicKey:       trust.PublicKey{Set: "ks-any-subject", KeyID: ks.Keys[0].KeyID},
+			}
+			require.NoError(t, r.Persister().AddKeySet(s.t1, "ks-any-subject", ks))
+			require.NoError(t, r.Persister().CreateGrant(s.t1, grant, ks.Keys[0]))
+
+			actual, err := r.Persister().GetPublicKey(s.t1, grant.Issuer, "some-subject", grant.PublicKey.KeyID)
+			require.NoError(t, err)
+			require.NotNil(t, actual)
+
+			// The grant allows any subject, but only for its own issuer.
+			_, err = r.Persister().GetPublicKey(s.t1, "https://other.example.com", "some-subject", grant.PublicKey.KeyID)
+			require.Error(t, err)
+			_, err = r.Persister().GetPublicKeyScopes(s.t1, "https://other.example.com", "some-subject", grant.PublicKey.KeyID)
+			require.Error(t, err)
+			keys, err := r.Persister().GetPublicKeys(s.t1, "https://other.example.com", "some-subject")
+			require.NoError(t, err)
+			require.Empty(t, keys.Keys)
+		})
+	}
+}
+
 func (s *PersisterTestSuite) TestGetPublicKeyScopes() {
 	t := s.T()
 	for k, r := range s.registries {
@@ -2078,6 +2188,7 @@ func newLoginSession() *consent.LoginSession {
 		AuthenticatedAt: sqlxx.NullTime(time.Time{}),
 		Subject:         uuid.Must(uuid.NewV4()).String(),
 		Remember:        false,
+		AMR:             sqlxx.StringSliceJSONFormat{},
 	}
 }
 
@@ -88,6 +88,7 @@ func (p *Persister) sqlSchemaFromRequest(ctx context.Context, rawSignature strin
 	}
 
 	var challenge sql.NullString
+	grantedAudience := r.GetGrantedAudience()
 	rr, ok := r.GetSession().(*oauth2.Session)
 	if !ok && r.GetSession() != nil {
 		return nil, errors.Errorf("Expected request to be of type *Session, but got: %T", r.GetSession())
@@ -95,6 +96,9 @@ func (p *Persister) sqlSchemaFromRequest(ctx context.Context, rawSignature strin
 		if len(rr.ConsentChallenge) > 0 {
 			challenge = sql.NullString{Valid: true, String: rr.ConsentChallenge}
 		}
+		if table == sqlTableRefresh && len(rr.RefreshTokenGrantedAudience) > 0 {
+			grantedAudience = rr.RefreshTokenGrantedAudience
+		}
 	}
 
 	return &OAuth2RequestSQL{
@@ -105,7 +109,7 @@ func (p *Persister) sqlSchemaFromRequest(ctx context.Context, rawSignature strin
 		Client:            r.GetClient().GetID(),
 		Scopes:            strings.Join(r.GetRequestedScopes(), "|"),
 		GrantedScope:      strings.Join(r.GetGrantedScopes(), "|"),
-		GrantedAudience:   strings.Join(r.GetGrantedAudience(), "|"),
+		GrantedAudience:   strings.Join(grantedAudience, "|"),
 		RequestedAudience: strings.Join(r.GetRequestedAudience(), "|"),
 		Form:              r.GetRequestForm().Encode(),
 		Session:           session,
@@ -460,6 +464,22 @@ func (p *Persister) FlushInactiveRefreshTokens(ctx context.Context, notAfter tim
 	return p.flushInactiveTokens(ctx, notAfter, limit, batchSize, sqlTableRefresh, p.config.GetRefreshTokenLifespan(ctx))
 }
 
+func (p *Persister) RevokeRefreshTokens(ctx context.Context, clientID string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeRefreshTokens")
+	defer span.End()
+
+	/* #nosec G201 table is static */
+	return sqlcon.HandleError(
+		p.Connection(ctx).
+			RawQuery(
+				fmt.Sprintf("UPDATE %s SET active=false WHERE client_id=? AND nid = ? AND active=true", OAuth2RequestSQL{Table: sqlTableRefresh}.TableName()),
+				clientID,
+				p.NetworkID(ctx),
+			).
+			Exec(),
+	)
+}
+
 func (p *Persister) DeleteAccessTokens(ctx context.Context, clientID string) error {
 	/* #nosec G201 table is static */
 	return sqlcon.HandleError(
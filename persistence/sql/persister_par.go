@@ -0,0 +1,210 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/stringsx"
+
+	"github.com/ory/hydra/oauth2"
+)
+
+var _ fosite.PARStorage = &Persister{}
+
+type PushedAuthorizeRequestSQL struct {
+	ID                string    `db:"request_uri"`
+	NID               uuid.UUID `db:"nid"`
+	Request           string    `db:"request_id"`
+	RequestedAt       time.Time `db:"requested_at"`
+	Client            string    `db:"client_id"`
+	Scopes            string    `db:"scope"`
+	GrantedScope      string    `db:"granted_scope"`
+	RequestedAudience string    `db:"requested_audience"`
+	GrantedAudience   string    `db:"granted_audience"`
+	Form              string    `db:"form_data"`
+	Subject           string    `db:"subject"`
+	Session           []byte    `db:"session_data"`
+	RedirectURI       string    `db:"redirect_uri"`
+	ResponseTypes     string    `db:"response_type"`
+	ResponseMode      string    `db:"response_mode"`
+	State             string    `db:"state"`
+	ExpiresAt         time.Time `db:"expires_at"`
+	Resolved          bool      `db:"resolved"`
+}
+
+func (PushedAuthorizeRequestSQL) TableName() string {
+	return "hydra_oauth2_par"
+}
+
+func (p *Persister) CreatePARSession(ctx context.Context, requestURI string, request fosite.AuthorizeRequester) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreatePARSession")
+	defer span.End()
+
+	req, err := p.sqlSchemaFromRequest(ctx, "", request, "")
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().UTC().Add(p.config.GetPushedAuthorizeContextLifespan(ctx))
+	if request.GetSession() != nil && !request.GetSession().GetExpiresAt(fosite.PushedAuthorizeRequestContext).IsZero() {
+		expiresAt = request.GetSession().GetExpiresAt(fosite.PushedAuthorizeRequestContext)
+	}
+
+	var redirectURI string
+	if request.GetRedirectURI() != nil {
+		redirectURI = request.GetRedirectURI().String()
+	}
+
+	return sqlcon.HandleError(p.CreateWithNetwork(ctx, &PushedAuthorizeRequestSQL{
+		ID:                requestURI,
+		Request:           req.Request,
+		RequestedAt:       req.RequestedAt,
+		Client:            req.Client,
+		Scopes:            req.Scopes,
+		GrantedScope:      req.GrantedScope,
+		RequestedAudience: req.RequestedAudience,
+		GrantedAudience:   req.GrantedAudience,
+		Form:              req.Form,
+		Subject:           req.Subject,
+		Session:           req.Session,
+		RedirectURI:       redirectURI,
+		ResponseTypes:     strings.Join(request.GetResponseTypes(), "|"),
+		ResponseMode:      string(request.GetResponseMode()),
+		State:             request.GetState(),
+		ExpiresAt:         expiresAt.UTC().Round(time.Second),
+	}))
+}
+
+func (p *Persister) GetPARSession(ctx context.Context, requestURI string) (fosite.AuthorizeRequester, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetPARSession")
+	defer span.End()
+
+	var r PushedAuthorizeRequestSQL
+	if err := p.QueryWithNetwork(ctx).Where("request_uri = ?", requestURI).First(&r); errors.Is(err, sql.ErrNoRows) {
+		return nil, errorsx.WithStack(fosite.ErrNotFound)
+	} else if err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	if r.ExpiresAt.Before(time.Now().UTC()) {
+		return nil, errorsx.WithStack(fosite.ErrNotFound.WithHint("The pushed authorization request has expired."))
+	}
+
+	req, err := (&OAuth2RequestSQL{
+		Request:           r.Request,
+		RequestedAt:       r.RequestedAt,
+		Client:            r.Client,
+		Scopes:            r.Scopes,
+		GrantedScope:      r.GrantedScope,
+		RequestedAudience: r.RequestedAudience,
+		GrantedAudience:   r.GrantedAudience,
+		Form:              r.Form,
+		Session:           r.Session,
+	}).toRequest(ctx, oauth2.NewSession(""), p)
+	if err != nil {
+		return nil, err
+	}
+
+	ar := fosite.NewAuthorizeRequest()
+	ar.Merge(req)
+	ar.ResponseTypes = stringsx.Splitx(r.ResponseTypes, "|")
+	ar.ResponseMode = fosite.ResponseModeType(r.ResponseMode)
+	ar.State = r.State
+	if r.RedirectURI != "" {
+		ar.RedirectURI, err = url.Parse(r.RedirectURI)
+		if err != nil {
+			return nil, errorsx.WithStack(err)
+		}
+	}
+
+	return ar, nil
+}
+
+func (p *Persister) DeletePARSession(ctx context.Context, requestURI string) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeletePARSession")
+	defer span.End()
+
+	/* #nosec G201 table is static */
+	count, err := p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("DELETE FROM %s WHERE request_uri=? AND nid=?", PushedAuthorizeRequestSQL{}.TableName()),
+			requestURI,
+			p.NetworkID(ctx),
+		).
+		ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(fosite.ErrNotFound)
+	}
+	return nil
+}
+
+func (p *Persister) ResolvePARSession(ctx context.Context, requestURI string, expiresAt time.Time) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ResolvePARSession")
+	defer span.End()
+
+	/* #nosec G201 table is static */
+	count, err := p.Connection(ctx).
+		RawQuery(
+			fmt.Sprintf("UPDATE %s SET resolved=?, expires_at=? WHERE request_uri=? AND nid=? AND resolved=? AND expires_at>?", PushedAuthorizeRequestSQL{}.TableName()),
+			true,
+			expiresAt.UTC().Round(time.Second),
+			requestURI,
+			p.NetworkID(ctx),
+			false,
+			time.Now().UTC(),
+		).
+		ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(fosite.ErrNotFound)
+	}
+	return nil
+}
+
+func (p *Persister) FlushInactivePARSessions(ctx context.Context, notAfter time.Time, limit int, batchSize int) error {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FlushInactivePARSessions")
+	defer span.End()
+
+	var err error
+
+	totalDeletedCount := 0
+	for deletedRecords := batchSize; totalDeletedCount < limit && deletedRecords == batchSize; {
+		d := batchSize
+		if limit-totalDeletedCount < batchSize {
+			d = limit - totalDeletedCount
+		}
+		// The outer SELECT is necessary because our version of MySQL doesn't yet support 'LIMIT & IN/ALL/ANY/SOME subquery
+		/* #nosec G201 table is static */
+		deletedRecords, err = p.Connection(ctx).RawQuery(
+			fmt.Sprintf(`DELETE FROM %s WHERE request_uri in (
+				SELECT request_uri FROM (SELECT request_uri FROM %s hop WHERE expires_at < ? and nid = ? ORDER BY request_uri LIMIT %d ) as s
+			)`, PushedAuthorizeRequestSQL{}.TableName(), PushedAuthorizeRequestSQL{}.TableName(), d),
+			notAfter,
+			p.NetworkID(ctx),
+		).ExecWithCount()
+		totalDeletedCount += deletedRecords
+
+		if err != nil {
+			break
+		}
+		p.l.Debugf("Flushing pushed authorization requests...: %d/%d", totalDeletedCount, limit)
+	}
+	p.l.Debugf("Flush pushed authorization requests flushed_records: %d", totalDeletedCount)
+	return sqlcon.HandleError(err)
+}
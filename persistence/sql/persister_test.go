@@ -40,6 +40,18 @@ func testRegistry(t *testing.T, ctx context.Context, k string, t1 driver.Registr
 	t.Run("package=client/manager="+k, func(t *testing.T) {
 		t.Run("case=create-get-update-delete", client.TestHelperCreateGetUpdateDeleteClient(k, t1.Persister().Connection(context.Background()), t1.ClientManager(), t2.ClientManager()))
 
+		t.Run("case=filters", client.TestHelperClientFilters(k, t1.ClientManager()))
+
+		t.Run("case=initial-access-tokens", client.TestHelperInitialAccessTokens(k, t1.ClientManager()))
+
+		t.Run("case=client-templates", client.TestHelperClientTemplates(k, t1.ClientManager()))
+
+		t.Run("case=soft-delete", client.TestHelperSoftDeleteClient(k, t1.ClientManager()))
+
+		t.Run("case=disable", client.TestHelperDisableClient(k, t1.ClientManager()))
+
+		t.Run("case=allowed-cors-origins", client.TestHelperAllowedCORSOrigins(k, t1.ClientManager()))
+
 		t.Run("case=autogenerate-key", client.TestHelperClientAutoGenerateKey(k, t1.ClientManager()))
 
 		t.Run("case=auth-client", client.TestHelperClientAuthenticate(k, t1.ClientManager()))
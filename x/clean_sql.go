@@ -17,6 +17,10 @@ func DeleteHydraRows(t *testing.T, c *pop.Connection) {
 		"hydra_oauth2_code",
 		"hydra_oauth2_oidc",
 		"hydra_oauth2_pkce",
+		"hydra_oauth2_device_code",
+		"hydra_oauth2_par",
+		"hydra_oauth2_backchannel_authentication_request",
+		"hydra_oauth2_backchannel_logout_delivery",
 		"hydra_oauth2_flow",
 		"hydra_oauth2_authentication_session",
 		"hydra_oauth2_obfuscated_authentication_session",
@@ -24,6 +28,8 @@ func DeleteHydraRows(t *testing.T, c *pop.Connection) {
 		"hydra_oauth2_jti_blacklist",
 		"hydra_oauth2_trusted_jwt_bearer_issuer",
 		"hydra_jwk",
+		"hydra_client_initial_access_token",
+		"hydra_client_template",
 		"hydra_client",
 	} {
 		if err := c.RawQuery("DELETE FROM " + tb).Exec(); err != nil {
@@ -40,6 +46,10 @@ func CleanSQLPop(t *testing.T, c *pop.Connection) {
 		"hydra_oauth2_code",
 		"hydra_oauth2_oidc",
 		"hydra_oauth2_pkce",
+		"hydra_oauth2_device_code",
+		"hydra_oauth2_par",
+		"hydra_oauth2_backchannel_authentication_request",
+		"hydra_oauth2_backchannel_logout_delivery",
 		"hydra_oauth2_flow",
 		"hydra_oauth2_authentication_session",
 		"hydra_oauth2_obfuscated_authentication_session",
@@ -47,6 +57,8 @@ func CleanSQLPop(t *testing.T, c *pop.Connection) {
 		"hydra_oauth2_jti_blacklist",
 		"hydra_oauth2_trusted_jwt_bearer_issuer",
 		"hydra_jwk",
+		"hydra_client_initial_access_token",
+		"hydra_client_template",
 		"hydra_client",
 		// Migrations
 		"hydra_oauth2_authentication_consent_migration",
@@ -6,4 +6,5 @@ package x
 const (
 	OpenIDConnectKeyName = "hydra.openid.id-token"
 	OAuth2JWTKeyName     = "hydra.jwt.access-token"
+	EventsKeyName        = "hydra.events"
 )
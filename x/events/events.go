@@ -0,0 +1,145 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/stringslice"
+
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/x"
+)
+
+// The types of the events which are sent to the event hook.
+const (
+	// ConsentSessionRevoked is sent when the consent sessions of a subject are revoked. The tokens issued based on
+	// the consent sessions are revoked as well, which is announced by a TokenRevoked event.
+	ConsentSessionRevoked = "consent_session.revoked"
+	// LoginSessionRevoked is sent when one or all login sessions of a subject are revoked.
+	LoginSessionRevoked = "login_session.revoked"
+	// ClientDeleted is sent when an OAuth 2.0 Client is deleted.
+	ClientDeleted = "client.deleted"
+	// TokenRevoked is sent when access and refresh tokens are revoked.
+	TokenRevoked = "token.revoked"
+)
+
+// ContentType is the content type of the requests sent to the event hook.
+const ContentType = "application/secevent+jwt"
+
+// Event is a revocation or deletion which resource servers that cache tokens or introspection results need to
+// know about. Only the identifiers which are known when the event occurs are set.
+type Event struct {
+	Type      string `json:"-"`
+	Subject   string `json:"subject,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+type Registry interface {
+	EventHook() *Hook
+	EventsJWTStrategy() jwk.JWTSigner
+}
+
+type hookRegistry interface {
+	config.Provider
+	x.HTTPClientProvider
+	x.RegistryLogger
+	Registry
+}
+
+// Hook sends events to the endpoint configured at `oauth2.event_hook.url`. Each event is sent as a Security Event
+// Token (RFC 8417), which is signed with the `hydra.events` JSON Web Key Set.
+type Hook struct {
+	r hookRegistry
+}
+
+func NewHook(r hookRegistry) *Hook {
+	return &Hook{r: r}
+}
+
+// Emit sends the events in the background. Failed deliveries are retried with exponential backoff and are
+// logged once all retries failed, so they never fail the operation which caused the event.
+func (h *Hook) Emit(ctx context.Context, events ...Event) {
+	hookURL := h.r.Config().EventHookURL(ctx)
+	if hookURL == nil {
+		return
+	}
+
+	minWait, maxWait := h.r.Config().EventHookRetryWait(ctx)
+	hc := h.r.HTTPClient(ctx,
+		httpx.ResilientClientWithMaxRetry(h.r.Config().EventHookMaxRetries(ctx)),
+		httpx.ResilientClientWithMinxRetryWait(minWait),
+		httpx.ResilientClientWithMaxRetryWait(maxWait),
+	)
+
+	types := h.r.Config().EventHookEvents(ctx)
+	for _, e := range events {
+		if len(types) > 0 && !stringslice.Has(types, e.Type) {
+			continue
+		}
+
+		log := h.r.Logger().
+			WithField("event_type", e.Type).
+			WithField("event_hook_url", hookURL.String())
+
+		token, err := h.sign(ctx, e)
+		if err != nil {
+			log.WithError(err).Error("Unable to sign event")
+			continue
+		}
+
+		go deliver(hc, hookURL.String(), token, log)
+	}
+}
+
+func (h *Hook) sign(ctx context.Context, e Event) (string, error) {
+	kid, err := h.r.EventsJWTStrategy().GetPublicKeyID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token, _, err := h.r.EventsJWTStrategy().Generate(ctx, jwt.MapClaims{
+		"iss":    h.r.Config().IssuerURL(ctx).String(),
+		"iat":    time.Now().UTC().Unix(),
+		"jti":    uuid.New(),
+		"events": map[string]Event{e.Type: e},
+	}, &jwt.Headers{
+		Extra: map[string]interface{}{"kid": kid},
+	})
+	return token, err
+}
+
+func deliver(hc *retryablehttp.Client, hookURL, token string, log *logrusx.Logger) {
+	req, err := retryablehttp.NewRequest(http.MethodPost, hookURL, []byte(token))
+	if err != nil {
+		log.WithError(err).Error("Unable to create event hook request")
+		return
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	res, err := hc.Do(req)
+	if err != nil {
+		log.WithError(err).Error("Unable to deliver event")
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		log.WithError(errors.Errorf("expected a 2xx HTTP status code but got %d", res.StatusCode)).
+			Error("Unable to deliver event")
+		return
+	}
+	log.Debug("Delivered event")
+}
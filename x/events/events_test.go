@@ -0,0 +1,132 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/contextx"
+
+	"github.com/ory/hydra/driver"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/internal"
+	"github.com/ory/hydra/x/events"
+)
+
+type delivery struct {
+	contentType string
+	token       string
+}
+
+func newHook(t *testing.T, statuses ...int) (driver.Registry, <-chan delivery) {
+	var calls int32
+	received := make(chan delivery, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		if n := int(atomic.AddInt32(&calls, 1)); n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+
+		received <- delivery{contentType: r.Header.Get("Content-Type"), token: string(body)}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(ts.Close)
+
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(context.Background(), config.KeyEventHookURL, ts.URL)
+	conf.MustSet(context.Background(), config.KeyEventHookMinRetryWait, "10ms")
+	conf.MustSet(context.Background(), config.KeyEventHookMaxRetryWait, "50ms")
+	return internal.NewRegistryMemory(t, conf, &contextx.Default{}), received
+}
+
+func receive(t *testing.T, received <-chan delivery) delivery {
+	select {
+	case d := <-received:
+		return d
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "expected the event to be delivered")
+		return delivery{}
+	}
+}
+
+func claims(t *testing.T, token string) gjson.Result {
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	return gjson.ParseBytes(payload)
+}
+
+func TestHook(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=sends a signed security event token", func(t *testing.T) {
+		reg, received := newHook(t)
+
+		reg.EventHook().Emit(ctx, events.Event{Type: events.ConsentSessionRevoked, Subject: "alice", ClientID: "app"})
+
+		d := receive(t, received)
+		assert.Equal(t, events.ContentType, d.contentType)
+
+		_, err := reg.EventsJWTStrategy().Validate(ctx, d.token)
+		require.NoError(t, err)
+
+		c := claims(t, d.token)
+		assert.Equal(t, reg.Config().IssuerURL(ctx).String(), c.Get("iss").String())
+		assert.NotEmpty(t, c.Get("jti").String())
+		assert.NotZero(t, c.Get("iat").Int())
+		assert.JSONEq(t, `{"subject":"alice","client_id":"app"}`, c.Get("events").Map()[events.ConsentSessionRevoked].Raw)
+
+		assert.Contains(t, reg.Config().WellKnownKeys(ctx), "hydra.events")
+	})
+
+	t.Run("case=retries failed deliveries", func(t *testing.T) {
+		reg, received := newHook(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+		reg.EventHook().Emit(ctx, events.Event{Type: events.ClientDeleted, ClientID: "app"})
+
+		d := receive(t, received)
+		assert.Equal(t, "app", claims(t, d.token).Get("events").Map()[events.ClientDeleted].Get("client_id").String())
+	})
+
+	t.Run("case=only sends the configured event types", func(t *testing.T) {
+		reg, received := newHook(t)
+		reg.Config().MustSet(ctx, config.KeyEventHookEvents, []string{events.TokenRevoked})
+
+		reg.EventHook().Emit(ctx,
+			events.Event{Type: events.LoginSessionRevoked, Subject: "alice"},
+			events.Event{Type: events.TokenRevoked, ClientID: "app"},
+		)
+
+		d := receive(t, received)
+		assert.True(t, claims(t, d.token).Get("events").Map()[events.TokenRevoked].Exists())
+
+		select {
+		case d := <-received:
+			t.Fatalf("expected no further event but got: %s", claims(t, d.token).Get("events").Raw)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("case=does nothing without a hook", func(t *testing.T) {
+		reg := internal.NewRegistryMemory(t, internal.NewConfigurationWithDefaults(), &contextx.Default{})
+		reg.EventHook().Emit(ctx, events.Event{Type: events.ClientDeleted, ClientID: "app"})
+		assert.NotContains(t, reg.Config().WellKnownKeys(ctx), "hydra.events")
+	})
+}
@@ -20,6 +20,7 @@ type FositeStorer interface {
 	openid.OpenIDConnectRequestStorage
 	pkce.PKCERequestStorage
 	rfc7523.RFC7523KeyStorage
+	fosite.PARStorage
 
 	RevokeRefreshToken(ctx context.Context, requestID string) error
 
@@ -36,8 +37,19 @@ type FositeStorer interface {
 
 	DeleteAccessTokens(ctx context.Context, clientID string) error
 
+	// RevokeRefreshTokens deactivates all refresh tokens of the client.
+	RevokeRefreshTokens(ctx context.Context, clientID string) error
+
 	FlushInactiveRefreshTokens(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
 
+	// ResolvePARSession marks a pushed authorization request as resolved by the authorization endpoint and extends
+	// its lifetime until expiresAt, so that it outlives the login and consent flow. It returns fosite.ErrNotFound if
+	// the pushed authorization request does not exist, has expired, or was resolved before.
+	ResolvePARSession(ctx context.Context, requestURI string, expiresAt time.Time) error
+
+	// flush the expired pushed authorization requests from the database.
+	FlushInactivePARSessions(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
+
 	// DeleteOpenIDConnectSession deletes an OpenID Connect session.
 	// This is duplicated from Ory Fosite to help against deprecation linting errors.
 	DeleteOpenIDConnectSession(ctx context.Context, authorizeCode string) error
@@ -72,5 +72,18 @@ func (b *Hasher) Compare(ctx context.Context, hash, data []byte) error {
 	if err := hasherx.Compare(ctx, data, hash); err != nil {
 		return errorsx.WithStack(err)
 	}
+
+	if matched, ok := ctx.Value(matchedHashContextKey{}).(*[]byte); ok {
+		*matched = hash
+	}
 	return nil
 }
+
+type matchedHashContextKey struct{}
+
+// WithMatchedHashRecorder returns a context in which Hasher.Compare records the last hash that matched. The
+// returned function reports that hash, or nil if no comparison succeeded.
+func WithMatchedHashRecorder(ctx context.Context) (context.Context, func() []byte) {
+	var matched []byte
+	return context.WithValue(ctx, matchedHashContextKey{}, &matched), func() []byte { return matched }
+}
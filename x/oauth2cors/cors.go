@@ -5,7 +5,6 @@ package oauth2cors
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"strings"
 
@@ -16,16 +15,17 @@ import (
 
 	"github.com/gobwas/glob"
 	"github.com/rs/cors"
-
-	"github.com/ory/fosite"
 )
 
+// Middleware handles CORS for the public OAuth 2.0 endpoints. Origins which are not allowed by
+// `serve.public.cors.allowed_origins` are checked against the registry's CORS policy.
 func Middleware(
 	ctx context.Context,
 	reg interface {
 		x.RegistryLogger
 		oauth2.Registry
 		client.Registry
+		CORSPolicy() Policy
 	}) func(h http.Handler) http.Handler {
 	opts, enabled := reg.Config().CORS(ctx, config.PublicInterface)
 	if !enabled {
@@ -40,12 +40,7 @@ func Middleware(
 		if o == "*" {
 			alwaysAllow = true
 		}
-		// if the protocol (http or https) is specified, but the url is wildcard, use special ** glob, which ignore the '.' separator.
-		// This way g := glob.Compile("http://**") g.Match("http://google.com") returns true.
-		if splittedO := strings.Split(o, "://"); len(splittedO) != 1 && splittedO[1] == "*" {
-			o = fmt.Sprintf("%s://**", splittedO[0])
-		}
-		g, err := glob.Compile(strings.ToLower(o), '.')
+		g, err := compileOrigin(o)
 		if err != nil {
 			reg.Logger().WithError(err).Fatalf("Unable to parse cors origin: %s", o)
 		}
@@ -53,6 +48,7 @@ func Middleware(
 		patterns = append(patterns, g)
 	}
 
+	policy := reg.CORSPolicy()
 	options := cors.Options{
 		AllowedOrigins:     opts.AllowedOrigins,
 		AllowedMethods:     opts.AllowedMethods,
@@ -63,7 +59,6 @@ func Middleware(
 		OptionsPassthrough: opts.OptionsPassthrough,
 		Debug:              opts.Debug,
 		AllowOriginRequestFunc: func(r *http.Request, origin string) bool {
-			ctx := r.Context()
 			if alwaysAllow {
 				return true
 			}
@@ -75,47 +70,7 @@ func Middleware(
 				}
 			}
 
-			// pre-flight requests do not contain credentials (cookies, HTTP authorization)
-			// so we return true in all cases here.
-			if r.Method == http.MethodOptions {
-				return true
-			}
-
-			username, _, ok := r.BasicAuth()
-			if !ok || username == "" {
-				token := fosite.AccessTokenFromRequest(r)
-				if token == "" {
-					return false
-				}
-
-				session := oauth2.NewSessionWithCustomClaims("", reg.Config().AllowedTopLevelClaims(ctx))
-				_, ar, err := reg.OAuth2Provider().IntrospectToken(ctx, token, fosite.AccessToken, session)
-				if err != nil {
-					return false
-				}
-
-				username = ar.GetClient().GetID()
-			}
-
-			cl, err := reg.ClientManager().GetConcreteClient(ctx, username)
-			if err != nil {
-				return false
-			}
-
-			for _, o := range cl.AllowedCORSOrigins {
-				if o == "*" {
-					return true
-				}
-				g, err := glob.Compile(strings.ToLower(o), '.')
-				if err != nil {
-					return false
-				}
-				if g.Match(origin) {
-					return true
-				}
-			}
-
-			return false
+			return policy.AllowOrigin(r, origin)
 		},
 	}
 
@@ -8,17 +8,22 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ory/hydra/driver"
 	"github.com/ory/hydra/x/oauth2cors"
 	"github.com/ory/x/contextx"
+	"github.com/ory/x/httprouterx"
 
 	"github.com/ory/hydra/x"
 
+	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
 
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
@@ -253,3 +258,115 @@ func TestOAuth2AwareCORSMiddleware(t *testing.T) {
 		})
 	}
 }
+
+type originPolicy string
+
+func (p originPolicy) AllowOrigin(_ *http.Request, origin string) bool {
+	return origin == string(p)
+}
+
+func TestClientPolicy(t *testing.T) {
+	ctx := context.Background()
+	r := internal.NewRegistryMemory(t, internal.NewConfigurationWithDefaults(), &contextx.Default{})
+	r.Config().MustSet(ctx, "serve.public.cors.enabled", true)
+	r.Config().MustSet(ctx, "serve.public.cors.allowed_origins", []string{"http://global.example.com"})
+	r.Config().MustSet(ctx, "serve.public.cors.client_origins_cache_ttl", "1h")
+
+	require.NoError(t, r.ClientManager().CreateClient(ctx, &client.Client{LegacyClientID: "public-client", TokenEndpointAuthMethod: "none", AllowedCORSOrigins: []string{"http://public.example.com"}}))
+	require.NoError(t, r.ClientManager().CreateClient(ctx, &client.Client{LegacyClientID: "disabled-client", Secret: "secret", AllowedCORSOrigins: []string{"http://disabled.example.com"}}))
+	require.NoError(t, r.ClientManager().SetClientDisabled(ctx, "disabled-client", true))
+
+	allowedOrigin := func(t *testing.T, req *http.Request) string {
+		res := httptest.NewRecorder()
+		oauth2cors.Middleware(ctx, r)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})).ServeHTTP(res, req)
+		return res.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	request := func(t *testing.T, method, target, origin string, body url.Values) *http.Request {
+		var req *http.Request
+		var err error
+		if body != nil {
+			req, err = http.NewRequest(method, target, strings.NewReader(body.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req, err = http.NewRequest(method, target, nil)
+		}
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		return req
+	}
+
+	preflight := func(t *testing.T, origin string) *http.Request {
+		req := request(t, http.MethodOptions, "http://hydra.example.com/oauth2/token", origin, nil)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		return req
+	}
+
+	t.Run("case=preflight requests are allowed for origins of any client", func(t *testing.T) {
+		assert.Equal(t, "http://public.example.com", allowedOrigin(t, preflight(t, "http://public.example.com")))
+		assert.Equal(t, "http://global.example.com", allowedOrigin(t, preflight(t, "http://global.example.com")))
+		assert.Empty(t, allowedOrigin(t, preflight(t, "http://unknown.example.com")))
+		assert.Empty(t, allowedOrigin(t, preflight(t, "http://disabled.example.com")))
+	})
+
+	t.Run("case=discovery requests are allowed for origins of any client", func(t *testing.T) {
+		for _, path := range []string{"/.well-known/openid-configuration", "/.well-known/jwks.json"} {
+			assert.Equal(t, "http://public.example.com", allowedOrigin(t, request(t, http.MethodGet, "http://hydra.example.com"+path, "http://public.example.com", nil)))
+			assert.Empty(t, allowedOrigin(t, request(t, http.MethodGet, "http://hydra.example.com"+path, "http://unknown.example.com", nil)))
+		}
+	})
+
+	t.Run("case=public clients are identified by their client_id", func(t *testing.T) {
+		body := url.Values{"client_id": {"public-client"}, "grant_type": {"authorization_code"}}
+		assert.Equal(t, "http://public.example.com", allowedOrigin(t, request(t, http.MethodPost, "http://hydra.example.com/oauth2/token", "http://public.example.com", body)))
+
+		body = url.Values{"client_id": {"disabled-client"}, "grant_type": {"authorization_code"}}
+		assert.Empty(t, allowedOrigin(t, request(t, http.MethodPost, "http://hydra.example.com/oauth2/token", "http://public.example.com", body)))
+	})
+
+	t.Run("case=disabled clients are rejected", func(t *testing.T) {
+		req := request(t, http.MethodPost, "http://hydra.example.com/oauth2/revoke", "http://disabled.example.com", nil)
+		req.SetBasicAuth("disabled-client", "secret")
+		assert.Empty(t, allowedOrigin(t, req))
+	})
+
+	t.Run("case=client origins are cached", func(t *testing.T) {
+		require.NoError(t, r.ClientManager().CreateClient(ctx, &client.Client{LegacyClientID: "late-client", TokenEndpointAuthMethod: "none", AllowedCORSOrigins: []string{"http://late.example.com"}}))
+		assert.Empty(t, allowedOrigin(t, preflight(t, "http://late.example.com")))
+
+		r.Config().MustSet(ctx, "serve.public.cors.client_origins_cache_ttl", "0s")
+		assert.Empty(t, allowedOrigin(t, preflight(t, "http://late.example.com")), "the cached entry expires only after the TTL it was stored with")
+	})
+
+	t.Run("case=the cache is purged when clients change", func(t *testing.T) {
+		r.Config().MustSet(ctx, "serve.public.cors.client_origins_cache_ttl", "1h")
+		router := httprouter.New()
+		client.NewHandler(r).SetRoutes(httprouterx.NewRouterAdminWithPrefixAndRouter(router, "/admin", r.Config().AdminURL), &httprouterx.RouterPublic{Router: router})
+		admin := func(t *testing.T, method, path, body string) gjson.Result {
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			require.Less(t, res.Code, 300, res.Body.String())
+			return gjson.Parse(res.Body.String())
+		}
+
+		assert.Empty(t, allowedOrigin(t, preflight(t, "http://purged.example.com")))
+
+		id := admin(t, http.MethodPost, "/admin/clients", `{"token_endpoint_auth_method":"none","allowed_cors_origins":["http://purged.example.com"]}`).Get("client_id").String()
+		assert.Equal(t, "http://purged.example.com", allowedOrigin(t, preflight(t, "http://purged.example.com")))
+
+		admin(t, http.MethodDelete, "/admin/clients/"+id, "")
+		assert.Empty(t, allowedOrigin(t, preflight(t, "http://purged.example.com")))
+	})
+
+	t.Run("case=the policy can be replaced", func(t *testing.T) {
+		r.WithCORSPolicy(originPolicy("http://custom.example.com"))
+		t.Cleanup(func() { r.WithCORSPolicy(nil) })
+
+		assert.Equal(t, "http://custom.example.com", allowedOrigin(t, preflight(t, "http://custom.example.com")))
+		assert.Empty(t, allowedOrigin(t, preflight(t, "http://public.example.com")))
+	})
+}
@@ -0,0 +1,205 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2cors
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/driver/config"
+	"github.com/ory/hydra/oauth2"
+	"github.com/ory/hydra/persistence"
+	"github.com/ory/hydra/x"
+)
+
+// Policy decides whether a cross-origin request to the public endpoints is allowed from an origin which is not
+// allowed globally by `serve.public.cors.allowed_origins`.
+type Policy interface {
+	// AllowOrigin reports whether the request may be made from the origin. The origin is lower-cased.
+	AllowOrigin(r *http.Request, origin string) bool
+}
+
+type clientPolicyRegistry interface {
+	x.RegistryLogger
+	oauth2.Registry
+	client.Registry
+	persistence.Provider
+}
+
+type cachedOrigins struct {
+	patterns []glob.Glob
+	expires  time.Time
+}
+
+// ClientPolicy is the default Policy. It allows the origins in the `allowed_cors_origins` of the OAuth 2.0 Client
+// which makes the request. The client is identified by HTTP basic authorization, the access token or the
+// `client_id` parameter.
+//
+// Preflight requests and requests which can not be attributed to a client, such as requests to the discovery
+// endpoints, are allowed if any client allows the origin. These origins are cached in memory for
+// `serve.public.cors.client_origins_cache_ttl` so that they do not cause a database query per request. The cache of
+// a network is purged when its clients change, see Purge.
+type ClientPolicy struct {
+	r clientPolicyRegistry
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedOrigins
+	// purges counts the calls to Purge, so that origins which were loaded before a purge are not cached.
+	purges uint64
+}
+
+var _ Policy = (*ClientPolicy)(nil)
+
+func NewClientPolicy(r clientPolicyRegistry) *ClientPolicy {
+	return &ClientPolicy{r: r, cache: map[uuid.UUID]cachedOrigins{}}
+}
+
+func (p *ClientPolicy) AllowOrigin(r *http.Request, origin string) bool {
+	// Preflight requests do not contain credentials (cookies, HTTP authorization), so the client is unknown.
+	if r.Method == http.MethodOptions {
+		return p.allowedByAnyClient(r, origin)
+	}
+
+	id, identified := p.clientID(r)
+	if !identified {
+		return p.allowedByAnyClient(r, origin)
+	} else if id == "" {
+		return false
+	}
+
+	c, err := p.r.ClientManager().GetClient(r.Context(), id)
+	if err != nil {
+		return false
+	}
+	cl, ok := c.(*client.Client)
+	if !ok {
+		return false
+	}
+
+	for _, o := range cl.AllowedCORSOrigins {
+		g, err := compileOrigin(o)
+		if err != nil {
+			p.r.Logger().WithError(err).WithField("client_id", id).Warnf("Unable to parse the allowed CORS origin %s of an OAuth 2.0 Client.", o)
+			continue
+		}
+		if g.Match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientID returns the ID of the client which makes the request. It returns an empty ID if the request carries
+// credentials which do not belong to a client, and false if the request carries no client identification.
+func (p *ClientPolicy) clientID(r *http.Request) (string, bool) {
+	if username, _, ok := r.BasicAuth(); ok && username != "" {
+		return username, true
+	}
+
+	if token := fosite.AccessTokenFromRequest(r); token != "" {
+		session := oauth2.NewSessionWithCustomClaims("", p.r.Config().AllowedTopLevelClaims(r.Context()))
+		_, ar, err := p.r.OAuth2Provider().IntrospectToken(r.Context(), token, fosite.AccessToken, session)
+		if err != nil {
+			return "", true
+		}
+		return ar.GetClient().GetID(), true
+	}
+
+	// Public clients identify themselves using the client_id parameter.
+	if id := r.URL.Query().Get("client_id"); id != "" {
+		return id, true
+	}
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); r.Method == http.MethodPost && ct == "application/x-www-form-urlencoded" {
+		if id := r.PostFormValue("client_id"); id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func (p *ClientPolicy) allowedByAnyClient(r *http.Request, origin string) bool {
+	patterns, err := p.clientOrigins(r)
+	if err != nil {
+		p.r.Logger().WithError(err).Warn("Unable to load the allowed CORS origins of the OAuth 2.0 Clients.")
+		return false
+	}
+
+	for _, g := range patterns {
+		if g.Match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ClientPolicy) clientOrigins(r *http.Request) ([]glob.Glob, error) {
+	ctx := r.Context()
+	nid := p.r.Persister().NetworkID(ctx)
+
+	// The lock is not held while the origins are loaded, so that a slow database does not block every other request.
+	p.mu.Lock()
+	cached, ok := p.cache[nid]
+	purges := p.purges
+	p.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.patterns, nil
+	}
+
+	origins, err := p.r.ClientManager().GetAllowedCORSOrigins(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]glob.Glob, 0, len(origins))
+	for _, o := range origins {
+		g, err := compileOrigin(o)
+		if err != nil {
+			p.r.Logger().WithError(err).Warnf("Unable to parse the allowed CORS origin %s of an OAuth 2.0 Client.", o)
+			continue
+		}
+		patterns = append(patterns, g)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.purges == purges {
+		p.cache[nid] = cachedOrigins{patterns: patterns, expires: time.Now().Add(p.r.Config().CORSClientOriginsCacheTTL(ctx, config.PublicInterface))}
+	}
+	return patterns, nil
+}
+
+// Purge drops the cached origins of the network of the context, so that changes to the allowed CORS origins of its
+// clients take effect immediately. It only affects this instance, other instances keep their cached origins until
+// they expire.
+func (p *ClientPolicy) Purge(ctx context.Context) {
+	nid := p.r.Persister().NetworkID(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.cache, nid)
+	p.purges++
+}
+
+// compileOrigin compiles an allowed origin, which may contain wildcards, to a case-insensitive pattern.
+func compileOrigin(o string) (glob.Glob, error) {
+	if o == "*" {
+		return glob.Compile("*")
+	}
+	// if the protocol (http or https) is specified, but the url is wildcard, use special ** glob, which ignore the '.' separator.
+	// This way g := glob.Compile("http://**") g.Match("http://google.com") returns true.
+	if splittedO := strings.Split(o, "://"); len(splittedO) != 1 && splittedO[1] == "*" {
+		o = fmt.Sprintf("%s://**", splittedO[0])
+	}
+	return glob.Compile(strings.ToLower(o), '.')
+}
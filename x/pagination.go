@@ -4,10 +4,15 @@
 package x
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"net/url"
+	"strconv"
 
-	"github.com/ory/x/pagination/tokenpagination"
+	"github.com/ory/herodot"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/pagination/keysetpagination"
 )
 
 // swagger:model paginationHeaders
@@ -54,16 +59,51 @@ type PaginationParams struct {
 const paginationMaxItems = 1000
 const paginationDefaultItems = 250
 
-var paginator = &tokenpagination.TokenPaginator{
-	MaxItems:     paginationMaxItems,
-	DefaultItems: paginationDefaultItems,
+// ParseKeysetPagination parses the page_token and page_size query parameters of a keyset paginated list.
+func ParseKeysetPagination(r *http.Request) []keysetpagination.Option {
+	q := r.URL.Query()
+	opts := []keysetpagination.Option{
+		keysetpagination.WithDefaultSize(paginationDefaultItems),
+		keysetpagination.WithMaxSize(paginationMaxItems),
+		keysetpagination.WithToken(q.Get("page_token")),
+	}
+
+	if size, err := strconv.Atoi(q.Get("page_size")); err == nil {
+		if size < 1 {
+			size = 1
+		}
+		opts = append(opts, keysetpagination.WithSize(size))
+	}
+	return opts
+}
+
+// KeysetPaginationHeader sets the Link header with the first and, unless this is the last page, the next page of
+// a keyset paginated list, and the X-Total-Count header.
+func KeysetPaginationHeader(w http.ResponseWriter, u *url.URL, total int64, p *keysetpagination.Paginator) {
+	keysetpagination.Header(w, u, p)
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
 }
 
-// ParsePagination parses limit and page from *http.Request with given limits and defaults.
-func ParsePagination(r *http.Request) (page, itemsPerPage int) {
-	return paginator.ParsePagination(r)
+// EncodePageToken returns an opaque page token from the sort keys of the last item of a page.
+func EncodePageToken(keys ...string) string {
+	b, _ := json.Marshal(keys)
+	return base64.RawURLEncoding.EncodeToString(b)
 }
 
-func PaginationHeader(w http.ResponseWriter, u *url.URL, total int64, page, itemsPerPage int) {
-	tokenpagination.PaginationHeader(w, u, total, page, itemsPerPage)
+// DecodePageToken returns the n sort keys encoded in a page token, or nil if the token is empty and the first
+// page is requested.
+func DecodePageToken(token string, n int) ([]string, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	var keys []string
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(b, &keys)
+	}
+	if err != nil || len(keys) != n {
+		return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The page token is invalid, make sure to use the page token of the Link header from the previous response."))
+	}
+	return keys, nil
 }
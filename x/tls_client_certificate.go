@@ -0,0 +1,132 @@
+// Copyright © 2022 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/errorsx"
+)
+
+type clientCertificateConfig interface {
+	AllowTerminationFrom() []string
+	ClientCertificateForwardedHeader() string
+}
+
+// ClientCertificate returns the certificate chain the client presented, leaf first. The chain is taken from the
+// TLS connection if the request was received by the native TLS listener. Otherwise, it is read from the forwarded
+// client certificate header, which is only trusted if the peer of the connection is a TLS terminator allowed by
+// AllowTerminationFrom. Unlike MatchesRange, the X-Forwarded-For header is not considered because any client
+// can set it.
+//
+// A nil chain without error is returned if the request does not contain a client certificate.
+func ClientCertificate(r *http.Request, c clientCertificateConfig) ([]*x509.Certificate, error) {
+	if r.TLS != nil {
+		if len(r.TLS.PeerCertificates) == 0 {
+			return nil, nil
+		}
+		return r.TLS.PeerCertificates, nil
+	}
+
+	header := c.ClientCertificateForwardedHeader()
+	if header == "" {
+		return nil, nil
+	}
+
+	value := r.Header.Get(header)
+	if value == "" {
+		return nil, nil
+	}
+
+	if len(c.AllowTerminationFrom()) == 0 {
+		return nil, errors.New("client certificates can not be forwarded because TLS termination is not enabled")
+	}
+
+	if err := remoteAddrMatchesRange(r, c.AllowTerminationFrom()); err != nil {
+		return nil, err
+	}
+
+	return parseForwardedCertificates(value)
+}
+
+// remoteAddrMatchesRange checks that the peer of the connection is within one of the CIDR ranges.
+func remoteAddrMatchesRange(r *http.Request, ranges []string) error {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+
+	addr := net.ParseIP(remoteIP)
+	for _, rn := range ranges {
+		_, cidr, err := net.ParseCIDR(rn)
+		if err != nil {
+			return errorsx.WithStack(err)
+		}
+
+		if cidr.Contains(addr) {
+			return nil
+		}
+	}
+	return errors.Errorf("remote address %s does not match CIDR ranges %v", remoteIP, ranges)
+}
+
+// parseForwardedCertificates parses the value of a forwarded client certificate header, which is either PEM
+// (optionally URL encoded, as done by nginx' $ssl_client_escaped_cert) or base64 encoded DER.
+func parseForwardedCertificates(value string) ([]*x509.Certificate, error) {
+	if unescaped, err := url.QueryUnescape(value); err == nil && strings.Contains(unescaped, "-----BEGIN") {
+		value = unescaped
+	}
+
+	if strings.Contains(value, "-----BEGIN") {
+		var chain []*x509.Certificate
+		rest := []byte(value)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			} else if block.Type != "CERTIFICATE" {
+				continue
+			}
+
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, errorsx.WithStack(err)
+			}
+			chain = append(chain, cert)
+		}
+
+		if len(chain) == 0 {
+			return nil, errors.New("the forwarded client certificate header does not contain a PEM encoded certificate")
+		}
+		return chain, nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	return []*x509.Certificate{cert}, nil
+}
+
+// CertificateThumbprint returns the base64url encoded SHA-256 thumbprint of the certificate as used by the
+// "x5t#S256" confirmation method (RFC 8705).
+func CertificateThumbprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}